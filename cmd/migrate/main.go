@@ -0,0 +1,72 @@
+// Command migrate applies or rolls back schema migrations without starting the server.
+// It reads the same DATABASE_* environment variables as the server.
+//
+//	go run ./cmd/migrate              # apply pending migrations
+//	go run ./cmd/migrate -down 38     # roll back every migration newer than 38
+//	go run ./cmd/migrate -status      # list migrations and whether they're applied
+//
+// The server applies pending migrations when it starts, so roll back before deploying the
+// older binary, not before restarting the current one.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"moviedb/internal/database"
+)
+
+func main() {
+	down := flag.Int("down", -1, "roll back to this version, keeping it applied")
+	status := flag.Bool("status", false, "list migrations and whether they are applied")
+	flag.Parse()
+
+	dbDriver := getEnv("DATABASE_DRIVER", database.DriverSQLite)
+	dataSource := getEnv("DATABASE_PATH", "./moviedb.db")
+	if dbDriver == database.DriverPostgres {
+		dataSource = getEnv("DATABASE_URL", "")
+		if dataSource == "" {
+			log.Fatal("DATABASE_URL environment variable is required when DATABASE_DRIVER=postgres")
+		}
+	}
+	db, err := database.Connect(dbDriver, dataSource)
+	if err != nil {
+		log.Fatal("Database connection failed:", err)
+	}
+	defer db.Close()
+
+	switch {
+	case *status:
+		states, err := database.GetMigrationStates(db)
+		if err != nil {
+			log.Fatal("Failed to get migrations:", err)
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.Applied {
+				applied = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if !state.Known {
+				applied += " (unknown to this binary)"
+			}
+			fmt.Printf("%03d %-30s %s\n", state.Version, state.Name, applied)
+		}
+	case *down >= 0:
+		if err := database.MigrateDown(db, *down); err != nil {
+			log.Fatal("Rollback failed:", err)
+		}
+	default:
+		if err := database.RunMigrations(db); err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
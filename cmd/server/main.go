@@ -3,17 +3,30 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"moviedb"
 	"moviedb/internal/auth"
 	"moviedb/internal/database"
 	"moviedb/internal/handlers"
+	"moviedb/internal/logging"
 	"moviedb/internal/services"
+	"moviedb/internal/types"
 )
 
 
+// How long in-flight requests get to finish once the server is asked to stop
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// Get environment variables
 	dbPath := getEnv("DATABASE_PATH", "./moviedb.db")
@@ -30,26 +43,63 @@ func main() {
 		log.Fatal("TMDB_API_KEY environment variable is required")
 	}
 
-	// Initialize database
-	db, err := database.Connect(dbPath)
+	// Services log through the default logger, so set it up before creating them
+	logLevel, err := logging.ParseLevel(getEnv("LOG_LEVEL", "info"))
+	if err != nil {
+		log.Fatal("Invalid LOG_LEVEL:", err)
+	}
+	slog.SetDefault(logging.New(os.Stderr, logLevel))
+
+	// Initialize database: SQLite at DATABASE_PATH, or Postgres at DATABASE_URL when
+	// DATABASE_DRIVER=postgres
+	dbDriver := getEnv("DATABASE_DRIVER", database.DriverSQLite)
+	dataSource := dbPath
+	if dbDriver == database.DriverPostgres {
+		dataSource = getEnv("DATABASE_URL", "")
+		if dataSource == "" {
+			fatal("DATABASE_URL environment variable is required when DATABASE_DRIVER=postgres")
+		}
+	}
+	db, err := database.Connect(dbDriver, dataSource)
 	if err != nil {
-		log.Fatal("Database connection failed:", err)
+		fatal("Database connection failed", "error", err)
 	}
 	defer db.Close()
 
 	// Run migrations
 	if err := database.RunMigrations(db); err != nil {
-		log.Fatal("Migration failed:", err)
+		fatal("Migration failed", "error", err)
 	}
 
 	// Initialize auth middleware
 	authMiddleware, err := auth.NewMiddleware(auth0Domain, auth0Audience)
 	if err != nil {
-		log.Fatal("Failed to create auth middleware:", err)
+		fatal("Failed to create auth middleware", "error", err)
+	}
+
+	// Admin endpoints are restricted to these Auth0 user IDs (comma-separated)
+	auth.SetAdminIDs(strings.Split(getEnv("ADMIN_AUTH0_IDS", ""), ","))
+
+	// Allow deployments to extend the watch-status vocabulary (e.g. "rewatching,paused")
+	if extraStatuses := getEnv("EXTRA_MOVIE_STATUSES", ""); extraStatuses != "" {
+		types.RegisterMovieStatuses(strings.Split(extraStatuses, ",")...)
 	}
 
 	// Initialize TMDB client and services
 	tmdbClient := services.NewTMDBClient(tmdbAPIKey)
+
+	// Cache TMDB responses to save rate-limit budget unless TMDB_RESPONSE_CACHE=false
+	if getEnv("TMDB_RESPONSE_CACHE", "true") != "false" {
+		searchTTL, err := time.ParseDuration(getEnv("TMDB_CACHE_SEARCH_TTL", services.DefaultTMDBSearchCacheTTL.String()))
+		if err != nil {
+			fatal("Invalid TMDB_CACHE_SEARCH_TTL", "error", err)
+		}
+		detailsTTL, err := time.ParseDuration(getEnv("TMDB_CACHE_DETAILS_TTL", services.DefaultTMDBDetailsCacheTTL.String()))
+		if err != nil {
+			fatal("Invalid TMDB_CACHE_DETAILS_TTL", "error", err)
+		}
+		tmdbClient.EnableResponseCache(db, searchTTL, detailsTTL)
+	}
 	movieSyncService := services.NewMovieSyncService(db, tmdbClient)
 
 	// Start movie sync scheduler
@@ -57,29 +107,54 @@ func main() {
 
 	// Initialize enhanced Plex integration
 	plexIntegration := services.NewPlexIntegrationManager(db, tmdbClient)
+	// Relay connections are only used when no direct connection responds unless PLEX_AVOID_RELAY=false
+	plexIntegration.PlexgoClient().SetAvoidRelay(getEnv("PLEX_AVOID_RELAY", "true") != "false")
+
+	// Library enumeration fetches a few pages at a time to keep load on Plex servers low
+	pageSize, _ := strconv.Atoi(getEnv("PLEX_PAGE_SIZE", "100"))
+	pageConcurrency, _ := strconv.Atoi(getEnv("PLEX_PAGE_CONCURRENCY", "3"))
+	plexIntegration.PlexgoClient().SetLibraryPaging(pageSize, pageConcurrency)
+
+	// A slow or offline Plex server holds up the request or job waiting on it until these run out
+	plexRequestTimeout, err := time.ParseDuration(getEnv("PLEX_REQUEST_TIMEOUT", services.DefaultPlexRequestTimeout.String()))
+	if err != nil {
+		fatal("Invalid PLEX_REQUEST_TIMEOUT", "error", err)
+	}
+	plexLibraryPageTimeout, err := time.ParseDuration(getEnv("PLEX_LIBRARY_PAGE_TIMEOUT", services.DefaultPlexLibraryPageTimeout.String()))
+	if err != nil {
+		fatal("Invalid PLEX_LIBRARY_PAGE_TIMEOUT", "error", err)
+	}
+	services.SetPlexTimeouts(plexRequestTimeout, plexLibraryPageTimeout)
 	
+	// Background services run until the server receives SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Start Plex background services
-	ctx := context.Background()
 	if err := plexIntegration.Start(ctx); err != nil {
-		log.Fatal("Failed to start Plex integration:", err)
+		fatal("Failed to start Plex integration", "error", err)
 	}
-	
-	// Setup graceful shutdown for Plex services
-	defer func() {
-		if err := plexIntegration.Stop(); err != nil {
-			log.Printf("Error stopping Plex integration: %v", err)
-		}
-	}()
+
+	// Purge lists deleted longer ago than they can be restored
+	go services.ScheduleListPurge(ctx, db, services.ListPurgeInterval)
+
+	// Friends' now playing shares the integration's Plex client and its connection latency cache
+	nowPlaying := services.NewNowPlayingService(db, plexIntegration.PlexgoClient())
 
 	// Initialize handlers
-	movieHandler := handlers.NewMovieHandler(db, tmdbClient)
+	movieHandler := handlers.NewMovieHandler(db, tmdbClient, plexIntegration.RateLimiter())
 	userHandler := handlers.NewUserHandler(db)
-	feedHandler := handlers.NewFeedHandler(db)
-	listHandler := handlers.NewListHandler(db)
+	feedHandler := handlers.NewFeedHandler(db, nowPlaying)
+	listHandler := handlers.NewListHandler(db, tmdbClient, plexIntegration.ListImporter(), plexIntegration.MovieCacheQueue())
 	syncHandler := handlers.NewSyncHandler(movieSyncService)
-	plexHandler := handlers.NewPlexHandler(db)
+	plexHandler := handlers.NewPlexHandler(db, nowPlaying)
 	plexSyncHandler := handlers.NewPlexSyncHandler(db, tmdbClient)
 	watchProvidersHandler := handlers.NewWatchProvidersHandler(db, tmdbClient, services.NewPlexClient())
+	moviePageHandler := handlers.NewMoviePageHandler(db, movieHandler, watchProvidersHandler)
+	collectionHandler := handlers.NewCollectionHandler(db, tmdbClient)
+	ratingsImportHandler := handlers.NewRatingsImportHandler(db, plexIntegration.RatingsImporter())
+	adminHandler := handlers.NewAdminHandler(db)
+	healthHandler := handlers.NewHealthHandler(db, tmdbClient, plexIntegration.JobManager(), plexIntegration.RateLimiter())
 	
 	// Initialize enhanced Plex sync handler
 	plexSyncEnhancedHandler := handlers.NewPlexSyncEnhancedHandler(plexIntegration.SyncService(), authMiddleware)
@@ -88,10 +163,11 @@ func main() {
 	mux := http.NewServeMux()
 
 	// Health check (no auth required)
-	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	mux.HandleFunc("GET /health", healthHandler.Ready)
+	mux.HandleFunc("GET /health/live", healthHandler.Live)
+
+	// Prometheus metrics for the TMDB rate limiter and background jobs
+	mux.Handle("GET /metrics", promhttp.HandlerFor(plexIntegration.MetricsRegistry(), promhttp.HandlerOpts{}))
 
 	// Create auth middleware wrapper
 	requireAuth := auth.RequireAuth(authMiddleware)
@@ -102,27 +178,60 @@ func main() {
 	mux.HandleFunc("POST /api/me/setup", requireAuth(http.HandlerFunc(userHandler.SetupUser)).ServeHTTP)
 	mux.HandleFunc("GET /api/me/preferences", requireAuth(http.HandlerFunc(userHandler.GetUserPreferences)).ServeHTTP)
 	mux.HandleFunc("PUT /api/me/preferences", requireAuth(http.HandlerFunc(userHandler.UpdateUserPreferences)).ServeHTTP)
+	mux.HandleFunc("PUT /api/me/preferences/providers/{providerId}", requireAuth(http.HandlerFunc(userHandler.AddPreferredProvider)).ServeHTTP)
+	mux.HandleFunc("DELETE /api/me/preferences/providers/{providerId}", requireAuth(http.HandlerFunc(userHandler.RemovePreferredProvider)).ServeHTTP)
 	mux.HandleFunc("GET /api/users", requireAuth(http.HandlerFunc(userHandler.GetUsers)).ServeHTTP)
 	mux.HandleFunc("GET /api/users/{id}", requireAuth(http.HandlerFunc(userHandler.GetUser)).ServeHTTP)
 	mux.HandleFunc("GET /api/users/{id}/lists", requireAuth(http.HandlerFunc(userHandler.GetUserLists)).ServeHTTP)
 	mux.HandleFunc("GET /api/users/{id}/movies", requireAuth(http.HandlerFunc(userHandler.GetUserMovies)).ServeHTTP)
+	mux.HandleFunc("GET /api/users/{id}/ratings/histogram", requireAuth(http.HandlerFunc(userHandler.GetUserRatingHistogram)).ServeHTTP)
 	mux.HandleFunc("POST /api/users/{id}/friend", requireAuth(http.HandlerFunc(userHandler.AddFriend)).ServeHTTP)
 	mux.HandleFunc("DELETE /api/users/{id}/friend", requireAuth(http.HandlerFunc(userHandler.RemoveFriend)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/friend-requests", requireAuth(http.HandlerFunc(userHandler.GetFriendRequests)).ServeHTTP)
+	mux.HandleFunc("POST /api/friend-requests/{id}/accept", requireAuth(http.HandlerFunc(userHandler.AcceptFriendRequest)).ServeHTTP)
+	mux.HandleFunc("POST /api/friend-requests/{id}/decline", requireAuth(http.HandlerFunc(userHandler.DeclineFriendRequest)).ServeHTTP)
 
 	// Movie routes
 	mux.HandleFunc("GET /api/movies", requireAuth(http.HandlerFunc(movieHandler.SearchMovies)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/discover", requireAuth(http.HandlerFunc(movieHandler.DiscoverMovies)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/trending", requireAuth(http.HandlerFunc(movieHandler.GetTrendingMovies)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/formats", requireAuth(http.HandlerFunc(movieHandler.GetFormatOptions)).ServeHTTP)
 	mux.HandleFunc("GET /api/movies/{id}", requireAuth(http.HandlerFunc(movieHandler.GetMovie)).ServeHTTP)
 	mux.HandleFunc("POST /api/movies/{id}/status", requireAuth(http.HandlerFunc(movieHandler.UpdateMovieStatus)).ServeHTTP)
+	mux.HandleFunc("POST /api/movies/status/batch", requireAuth(http.HandlerFunc(movieHandler.UpdateMovieStatusBatch)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/{id}/watch-log", requireAuth(http.HandlerFunc(movieHandler.GetMovieWatchLog)).ServeHTTP)
+	mux.HandleFunc("POST /api/movies/{id}/watch-log", requireAuth(http.HandlerFunc(movieHandler.AddMovieWatch)).ServeHTTP)
+	mux.HandleFunc("PUT /api/watch-log/{id}", requireAuth(http.HandlerFunc(movieHandler.UpdateWatch)).ServeHTTP)
+	mux.HandleFunc("DELETE /api/watch-log/{id}", requireAuth(http.HandlerFunc(movieHandler.DeleteWatch)).ServeHTTP)
 	mux.HandleFunc("POST /api/movies/{id}/rating", requireAuth(http.HandlerFunc(movieHandler.RateMovie)).ServeHTTP)
 	mux.HandleFunc("POST /api/movies/{id}/notes", requireAuth(http.HandlerFunc(movieHandler.UpdateNotes)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/{id}/similar", requireAuth(http.HandlerFunc(movieHandler.GetSimilarMovies)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/{id}/recommendations", requireAuth(http.HandlerFunc(movieHandler.GetRecommendedMovies)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/{id}/me", requireAuth(http.HandlerFunc(movieHandler.GetMyMovie)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/{id}/full", requireAuth(http.HandlerFunc(moviePageHandler.GetMovieFull)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/{id}/owned", requireAuth(http.HandlerFunc(movieHandler.GetOwnedFormats)).ServeHTTP)
 	mux.HandleFunc("POST /api/movies/{id}/owned", requireAuth(http.HandlerFunc(movieHandler.UpdateOwnedFormats)).ServeHTTP)
+	mux.HandleFunc("POST /api/movies/{id}/favorite", requireAuth(http.HandlerFunc(movieHandler.ToggleFavorite)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/favorites", requireAuth(http.HandlerFunc(movieHandler.GetFavorites)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/stats", requireAuth(http.HandlerFunc(movieHandler.GetMyStats)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/recommendations", requireAuth(http.HandlerFunc(movieHandler.GetMyRecommendations)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/watch-log", requireAuth(http.HandlerFunc(movieHandler.GetMyWatchLog)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/notes/search", requireAuth(http.HandlerFunc(movieHandler.SearchNotes)).ServeHTTP)
+	mux.HandleFunc("POST /api/me/ratings/import", requireAuth(http.HandlerFunc(ratingsImportHandler.ImportRatings)).ServeHTTP)
 
 	// List routes
 	mux.HandleFunc("GET /api/lists", requireAuth(http.HandlerFunc(listHandler.GetLists)).ServeHTTP)
 	mux.HandleFunc("POST /api/lists", requireAuth(http.HandlerFunc(listHandler.CreateList)).ServeHTTP)
+	mux.HandleFunc("POST /api/lists/import", requireAuth(http.HandlerFunc(listHandler.ImportLetterboxdList)).ServeHTTP)
+	mux.HandleFunc("POST /api/lists/batch", requireAuth(http.HandlerFunc(listHandler.GetListsBatch)).ServeHTTP)
 	mux.HandleFunc("GET /api/lists/{id}", requireAuth(http.HandlerFunc(listHandler.GetList)).ServeHTTP)
 	mux.HandleFunc("PUT /api/lists/{id}", requireAuth(http.HandlerFunc(listHandler.UpdateList)).ServeHTTP)
 	mux.HandleFunc("DELETE /api/lists/{id}", requireAuth(http.HandlerFunc(listHandler.DeleteList)).ServeHTTP)
+	mux.HandleFunc("POST /api/lists/{id}/restore", requireAuth(http.HandlerFunc(listHandler.RestoreList)).ServeHTTP)
+	mux.HandleFunc("PUT /api/lists/{id}/order", requireAuth(http.HandlerFunc(listHandler.ReorderList)).ServeHTTP)
+	mux.HandleFunc("POST /api/lists/{id}/clone", requireAuth(http.HandlerFunc(listHandler.CloneList)).ServeHTTP)
+	mux.HandleFunc("GET /api/lists/{id}/export", requireAuth(http.HandlerFunc(listHandler.ExportList)).ServeHTTP)
+	mux.HandleFunc("GET /api/lists/{id}/history", requireAuth(http.HandlerFunc(listHandler.GetListHistory)).ServeHTTP)
 	mux.HandleFunc("POST /api/lists/{id}/movies/{movieId}", requireAuth(http.HandlerFunc(listHandler.AddMovieToList)).ServeHTTP)
 	mux.HandleFunc("DELETE /api/lists/{id}/movies/{movieId}", requireAuth(http.HandlerFunc(listHandler.RemoveMovieFromList)).ServeHTTP)
 	mux.HandleFunc("GET /api/movies/{movieId}/lists", requireAuth(http.HandlerFunc(listHandler.GetMovieInLists)).ServeHTTP)
@@ -131,9 +240,12 @@ func main() {
 	// Feed routes
 	mux.HandleFunc("GET /api/feed/friends", requireAuth(http.HandlerFunc(feedHandler.GetFriendsFeed)).ServeHTTP)
 	mux.HandleFunc("GET /api/feed/global", requireAuth(http.HandlerFunc(feedHandler.GetGlobalFeed)).ServeHTTP)
+	mux.HandleFunc("GET /api/feed/now-playing", requireAuth(http.HandlerFunc(feedHandler.GetFriendsNowPlaying)).ServeHTTP)
 	mux.HandleFunc("POST /api/posts/{id}/like", requireAuth(http.HandlerFunc(feedHandler.LikePost)).ServeHTTP)
 	mux.HandleFunc("DELETE /api/posts/{id}/like", requireAuth(http.HandlerFunc(feedHandler.UnlikePost)).ServeHTTP)
 	mux.HandleFunc("POST /api/posts/{id}/comments", requireAuth(http.HandlerFunc(feedHandler.AddComment)).ServeHTTP)
+	mux.HandleFunc("PUT /api/comments/{id}", requireAuth(http.HandlerFunc(feedHandler.UpdateComment)).ServeHTTP)
+	mux.HandleFunc("DELETE /api/comments/{id}", requireAuth(http.HandlerFunc(feedHandler.DeleteComment)).ServeHTTP)
 
 	// Sync routes
 	mux.HandleFunc("POST /api/sync/movies", requireAuth(http.HandlerFunc(syncHandler.TriggerMovieSync)).ServeHTTP)
@@ -144,23 +256,51 @@ func main() {
 	mux.HandleFunc("GET /api/plex/auth/check", requireAuth(http.HandlerFunc(plexHandler.CheckPlexAuth)).ServeHTTP)
 	mux.HandleFunc("GET /api/plex/status", requireAuth(http.HandlerFunc(plexHandler.GetPlexStatus)).ServeHTTP)
 	mux.HandleFunc("DELETE /api/plex/disconnect", requireAuth(http.HandlerFunc(plexHandler.DisconnectPlex)).ServeHTTP)
+	mux.HandleFunc("GET /api/plex/home-users", requireAuth(http.HandlerFunc(plexHandler.GetHomeUsers)).ServeHTTP)
+	mux.HandleFunc("PUT /api/plex/home-user", requireAuth(http.HandlerFunc(plexHandler.SelectHomeUser)).ServeHTTP)
+	mux.HandleFunc("GET /api/plex/now-playing", requireAuth(http.HandlerFunc(plexHandler.GetNowPlaying)).ServeHTTP)
+	mux.HandleFunc("GET /api/plex/webhook-url", requireAuth(http.HandlerFunc(plexHandler.GetWebhookURL)).ServeHTTP)
+	mux.HandleFunc("POST /api/plex/webhook-url/regenerate", requireAuth(http.HandlerFunc(plexHandler.RegenerateWebhookURL)).ServeHTTP)
+
+	// Plex webhooks (authenticated by the token in the URL)
+	mux.HandleFunc("POST /api/plex/webhook/{token}", plexHandler.ReceiveWebhook)
 
 	// Plex sync routes
 	mux.HandleFunc("POST /api/plex/sync", requireAuth(http.HandlerFunc(plexSyncHandler.SyncPlexLibrary)).ServeHTTP)
 	mux.HandleFunc("GET /api/plex/mappings", requireAuth(http.HandlerFunc(plexSyncHandler.GetPlexMappings)).ServeHTTP)
 	mux.HandleFunc("GET /api/plex/mappings/search", requireAuth(http.HandlerFunc(plexSyncHandler.SearchPlexMappings)).ServeHTTP)
+	mux.HandleFunc("PUT /api/plex/mappings/{id}", requireAuth(http.HandlerFunc(plexSyncHandler.UpdatePlexMapping)).ServeHTTP)
+	mux.HandleFunc("DELETE /api/plex/mappings/{id}", requireAuth(http.HandlerFunc(plexSyncHandler.DeletePlexMapping)).ServeHTTP)
 	
 	// Enhanced Plex sync routes
 	mux.HandleFunc("POST /api/plex/sync/enhanced", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.TriggerFullSync)).ServeHTTP)
+	mux.HandleFunc("POST /api/plex/sync/match", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.TriggerTMDBMatching)).ServeHTTP)
+	mux.HandleFunc("POST /api/plex/rematch", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.TriggerRematch)).ServeHTTP)
 	mux.HandleFunc("GET /api/plex/sync/status/{jobId}", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.GetJobStatus)).ServeHTTP)
 	mux.HandleFunc("POST /api/plex/sync/{jobId}/cancel", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.CancelJob)).ServeHTTP)
+	mux.HandleFunc("GET /api/plex/jobs/{jobId}/events", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.StreamJobEvents)).ServeHTTP)
 	mux.HandleFunc("GET /api/plex/libraries", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.GetUserLibraries)).ServeHTTP)
+	mux.HandleFunc("POST /api/plex/libraries/{id}/sync", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.TriggerLibrarySync)).ServeHTTP)
+	mux.HandleFunc("GET /api/plex/unmatched", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.GetUnmatchedItems)).ServeHTTP)
+	mux.HandleFunc("POST /api/plex/unmatched/{itemId}/match", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.MatchUnmatchedItem)).ServeHTTP)
 	mux.HandleFunc("GET /api/plex/jobs", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.GetUserJobs)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/plex-movies", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.GetPlexMovies)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/plex/search", requireAuth(http.HandlerFunc(plexSyncEnhancedHandler.SearchLibrary)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/plex-history", requireAuth(http.HandlerFunc(plexHandler.GetPlexHistory)).ServeHTTP)
+	mux.HandleFunc("GET /api/me/continue-watching", requireAuth(http.HandlerFunc(plexHandler.GetContinueWatching)).ServeHTTP)
+
+	// Admin routes
+	mux.HandleFunc("POST /api/admin/sync-all", requireAuth(auth.RequireAdmin(http.HandlerFunc(plexSyncEnhancedHandler.SyncAllUsers))).ServeHTTP)
+	mux.HandleFunc("GET /api/admin/migrations", requireAuth(auth.RequireAdmin(http.HandlerFunc(adminHandler.GetMigrations))).ServeHTTP)
 
 	// Watch providers routes
 	mux.HandleFunc("GET /api/movies/{id}/watch-providers", requireAuth(http.HandlerFunc(watchProvidersHandler.GetMovieWatchProviders)).ServeHTTP)
+	mux.HandleFunc("GET /api/movies/{id}/plex-locations", requireAuth(http.HandlerFunc(watchProvidersHandler.GetMoviePlexLocations)).ServeHTTP)
 	mux.HandleFunc("POST /api/watch-providers/clear-cache", requireAuth(http.HandlerFunc(watchProvidersHandler.ClearExpiredCache)).ServeHTTP)
 
+	// Collection routes
+	mux.HandleFunc("GET /api/collections/{id}/ownership", requireAuth(http.HandlerFunc(collectionHandler.GetCollectionOwnership)).ServeHTTP)
+
 	// SPA routes - serve index.html for client-side routing
 	spaRoutes := []string{"/movies", "/community", "/lists", "/profile", "/search", "/settings"}
 	for _, route := range spaRoutes {
@@ -188,21 +328,57 @@ func main() {
 	staticDir := getEnv("STATIC_DIR", "./web/dist")
 	if _, err := os.Stat(staticDir); err == nil {
 		// Development mode - serve from disk
-		log.Println("Serving static files from disk:", staticDir)
+		slog.Info("Serving static files from disk", "dir", staticDir)
 		fs := http.FileServer(http.Dir(staticDir))
 		mux.Handle("/", addCacheHeaders(fs))
 	} else {
 		// Production mode - serve embedded files
-		log.Println("Serving embedded static files")
+		slog.Info("Serving embedded static files")
 		distFS, err := moviedb.GetDistFS()
 		if err != nil {
-			log.Fatal("Failed to create sub filesystem:", err)
+			fatal("Failed to create sub filesystem", "error", err)
 		}
 		mux.Handle("/", addCacheHeaders(http.FileServer(http.FS(distFS))))
 	}
 
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, mux))
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: handlers.LogRequests(handlers.RecoverPanics(mux)),
+	}
+
+	go func() {
+		slog.Info("Server starting", "port", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Server failed", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	// A second signal kills the process right away
+	stop()
+	slog.Info("Shutting down")
+
+	// Stop accepting connections and let in-flight requests finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("HTTP server shutdown did not complete", "error", err)
+	}
+
+	// Running jobs are put back to pending and resume on the next start
+	movieSyncService.StopSyncScheduler()
+	if err := plexIntegration.Stop(); err != nil {
+		slog.Error("Failed to stop Plex integration", "error", err)
+	}
+
+	slog.Info("Server stopped")
+}
+
+// fatal logs an error and exits. Unlike log.Fatal, the message is still written when
+// LOG_LEVEL filters out info records.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,13 @@
+-- Owned formats per user movie, normalized so collections can be filtered by format
+CREATE TABLE user_movie_formats (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_movie_id INTEGER NOT NULL,
+    format TEXT NOT NULL, -- e.g. 'bluray', '4k_uhd', 'digital'
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_movie_id) REFERENCES user_movies(id) ON DELETE CASCADE,
+    UNIQUE(user_movie_id, format)
+);
+
+-- Indexes for format filtering
+CREATE INDEX idx_user_movie_formats_format ON user_movie_formats(format, user_movie_id);
+CREATE INDEX idx_user_movie_formats_user_movie ON user_movie_formats(user_movie_id);
@@ -0,0 +1,9 @@
+-- Copy existing owned_formats JSON arrays into the normalized user_movie_formats table
+INSERT OR IGNORE INTO user_movie_formats (user_movie_id, format)
+SELECT um.id, lower(trim(f.value))
+FROM user_movies um, json_each(um.owned_formats) f
+WHERE um.owned_formats IS NOT NULL
+  AND json_valid(um.owned_formats)
+  AND json_type(um.owned_formats) = 'array'
+  AND f.type = 'text'
+  AND trim(f.value) != '';
@@ -0,0 +1,5 @@
+-- Favorite flag on user movies, independent of rating
+ALTER TABLE user_movies ADD COLUMN favorite BOOLEAN NOT NULL DEFAULT 0;
+
+-- Index for favorites listing
+CREATE INDEX idx_user_movies_favorite ON user_movies(user_id, favorite);
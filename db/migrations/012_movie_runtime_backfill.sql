@@ -0,0 +1,2 @@
+-- Track when a missing runtime was last looked up so the backfill doesn't retry the same movies every run
+ALTER TABLE movies ADD COLUMN runtime_checked_at DATETIME;
@@ -0,0 +1,4 @@
+-- Selected Plex Home (managed) user profile for syncing
+ALTER TABLE user_plex_tokens ADD COLUMN plex_home_user_uuid TEXT;
+ALTER TABLE user_plex_tokens ADD COLUMN plex_home_user_title TEXT;
+ALTER TABLE user_plex_tokens ADD COLUMN plex_profile_token TEXT; -- Token for the selected profile (NULL = account owner)
@@ -0,0 +1,4 @@
+-- Which kind of connection (local, remote, relay) was used to reach a server and its libraries
+ALTER TABLE plex_servers ADD COLUMN connection_type TEXT;
+ALTER TABLE plex_servers ADD COLUMN connection_latency_ms INTEGER;
+ALTER TABLE plex_libraries ADD COLUMN connection_type TEXT;
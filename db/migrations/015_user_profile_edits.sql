@@ -0,0 +1,5 @@
+-- Users can override their Auth0 display name and pick a username
+ALTER TABLE users ADD COLUMN name_overridden BOOLEAN NOT NULL DEFAULT 0; -- Set once the user edits their name; Auth0 sync then leaves it alone
+
+-- Usernames are unique regardless of case
+CREATE UNIQUE INDEX idx_users_username_nocase ON users(username COLLATE NOCASE);
@@ -0,0 +1,2 @@
+-- Friendship is a follow: user_id follows friend_id. Index the reverse direction for follower lookups.
+CREATE INDEX idx_friends_friend_id ON friends(friend_id);
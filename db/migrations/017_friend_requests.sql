@@ -0,0 +1,10 @@
+-- Friendships become mutual and start as requests ('pending' or 'accepted').
+-- Existing one-way follows turn into pending requests; pairs that follow each other become friends.
+ALTER TABLE friends ADD COLUMN status TEXT NOT NULL DEFAULT 'pending';
+
+UPDATE friends SET status = 'accepted'
+WHERE EXISTS (
+    SELECT 1 FROM friends r WHERE r.user_id = friends.friend_id AND r.friend_id = friends.user_id
+);
+
+CREATE INDEX idx_friends_friend_status ON friends(friend_id, status);
@@ -0,0 +1,12 @@
+-- Lists are ordered by position so users can rank movies. Backfill positions from the
+-- existing newest-first order, breaking ties on id.
+ALTER TABLE list_movies ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE list_movies SET position = (
+    SELECT COUNT(*) FROM list_movies lm
+    WHERE lm.list_id = list_movies.list_id
+      AND (lm.added_at > list_movies.added_at
+           OR (lm.added_at = list_movies.added_at AND lm.id > list_movies.id))
+);
+
+CREATE INDEX idx_list_movies_list_position ON list_movies(list_id, position);
@@ -0,0 +1,13 @@
+-- One row per matched movie a user can watch, however many servers or libraries carry it.
+-- The same film on two servers has two plex_library_items rows; this view counts it once.
+CREATE VIEW user_available_movies AS
+SELECT upa.user_id,
+       pli.tmdb_id,
+       COUNT(*) AS copy_count,
+       COUNT(DISTINCT pl.server_id) AS server_count,
+       MIN(pli.added_at) AS first_added_at
+FROM plex_library_items pli
+JOIN plex_libraries pl ON pli.library_id = pl.id
+JOIN user_plex_access upa ON pl.id = upa.library_id
+WHERE upa.is_active = 1 AND pli.is_active = 1 AND pli.tmdb_id IS NOT NULL
+GROUP BY upa.user_id, pli.tmdb_id;
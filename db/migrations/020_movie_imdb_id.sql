@@ -0,0 +1,2 @@
+-- Keep the IMDb id of cached movies for exports. Filled in as movies are fetched from TMDB.
+ALTER TABLE movies ADD COLUMN imdb_id TEXT;
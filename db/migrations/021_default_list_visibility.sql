@@ -0,0 +1,2 @@
+-- Visibility for new lists created without an explicit is_public ('private' or 'public')
+ALTER TABLE user_preferences ADD COLUMN default_list_visibility TEXT NOT NULL DEFAULT 'private';
@@ -0,0 +1,2 @@
+-- Notes search scans only the user's movies that have notes
+CREATE INDEX idx_user_movies_user_notes ON user_movies(user_id) WHERE notes IS NOT NULL AND notes != '';
@@ -0,0 +1,17 @@
+-- TV shows cached from TMDB. Kept apart from movies since TMDB movie and TV ids overlap.
+CREATE TABLE tv_shows (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tmdb_id INTEGER UNIQUE NOT NULL,
+    title TEXT NOT NULL,
+    year INTEGER, -- First air year
+    poster_url TEXT,
+    synopsis TEXT,
+    genres TEXT, -- JSON array as string
+    number_of_seasons INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Matched TMDB TV id for items from show libraries (tmdb_id stays movie-only)
+ALTER TABLE plex_library_items ADD COLUMN tmdb_tv_id INTEGER REFERENCES tv_shows(tmdb_id);
+
+CREATE INDEX idx_plex_library_items_tmdb_tv_id ON plex_library_items(tmdb_tv_id);
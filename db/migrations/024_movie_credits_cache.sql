@@ -0,0 +1,10 @@
+-- Trimmed TMDB cast and crew per movie, refreshed once expired
+CREATE TABLE IF NOT EXISTS movie_credits_cache (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tmdb_id INTEGER UNIQUE NOT NULL,
+    credits_data TEXT NOT NULL, -- JSON of the trimmed cast, directors and writers
+    cached_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_movie_credits_expires ON movie_credits_cache(expires_at);
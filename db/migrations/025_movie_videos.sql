@@ -0,0 +1,3 @@
+-- YouTube trailers and teasers from TMDB, stored with the movie as a JSON array.
+-- NULL means they haven't been fetched yet; '[]' means the movie has none.
+ALTER TABLE movies ADD COLUMN videos TEXT;
@@ -0,0 +1,10 @@
+-- Raw TMDB API responses keyed by a hash of endpoint + query parameters
+CREATE TABLE IF NOT EXISTS tmdb_response_cache (
+    cache_key TEXT PRIMARY KEY,
+    endpoint TEXT NOT NULL,
+    response_body BLOB NOT NULL,
+    cached_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tmdb_response_cache_expires ON tmdb_response_cache(expires_at);
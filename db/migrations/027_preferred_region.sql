@@ -0,0 +1,2 @@
+-- Country (ISO 3166-1 alpha-2, e.g. 'NO') used for watch providers; NULL uses the server default
+ALTER TABLE user_preferences ADD COLUMN preferred_region TEXT;
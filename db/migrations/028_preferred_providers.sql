@@ -0,0 +1,2 @@
+-- TMDB provider ids of the streaming services the user subscribes to, as a JSON array
+ALTER TABLE user_preferences ADD COLUMN preferred_providers TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,2 @@
+-- Whether friends can see what the user is currently watching on Plex (opt-out)
+ALTER TABLE user_preferences ADD COLUMN share_now_playing BOOLEAN NOT NULL DEFAULT 1;
@@ -0,0 +1,20 @@
+-- Completed Plex plays, recorded by full syncs from each movie's per-user view count and
+-- last viewed time. One row per play seen; tmdb_id is filled in once the item is matched.
+CREATE TABLE plex_watch_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    library_item_id INTEGER, -- plex_library_items row the play was seen on
+    plex_guid TEXT NOT NULL,
+    title TEXT NOT NULL,
+    year INTEGER,
+    tmdb_id INTEGER,
+    view_count INTEGER NOT NULL, -- Plex's play count after this play
+    watched_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (library_item_id) REFERENCES plex_library_items(id) ON DELETE SET NULL,
+    UNIQUE(user_id, plex_guid, watched_at)
+);
+
+CREATE INDEX idx_plex_watch_history_user_watched ON plex_watch_history(user_id, watched_at);
+CREATE INDEX idx_plex_watch_history_tmdb_id ON plex_watch_history(tmdb_id);
@@ -0,0 +1,14 @@
+-- Partial Plex plays for "continue watching", one row per user and Plex item. Full syncs
+-- replace a user's rows; now-playing lookups update them in between.
+CREATE TABLE user_movie_progress (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    plex_guid TEXT NOT NULL,
+    view_offset_ms INTEGER NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id, plex_guid)
+);
+
+CREATE INDEX idx_user_movie_progress_user_updated ON user_movie_progress(user_id, updated_at);
@@ -0,0 +1,4 @@
+-- Secret in each user's Plex webhook URL, identifying whose account the events belong to
+ALTER TABLE user_plex_tokens ADD COLUMN webhook_token TEXT;
+
+CREATE UNIQUE INDEX idx_user_plex_tokens_webhook_token ON user_plex_tokens(webhook_token);
@@ -0,0 +1,3 @@
+-- Movies added to lists before their details were fetched. The row only has its TMDB id
+-- until a background movie_cache job fills it in.
+ALTER TABLE movies ADD COLUMN caching BOOLEAN NOT NULL DEFAULT 0;
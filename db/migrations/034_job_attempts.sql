@@ -0,0 +1,3 @@
+-- Failed jobs are retried with backoff while attempts < max_attempts
+ALTER TABLE sync_jobs ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sync_jobs ADD COLUMN max_attempts INTEGER NOT NULL DEFAULT 3;
@@ -0,0 +1,14 @@
+-- Who added or removed which movie from a list, and when
+CREATE TABLE list_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    list_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL, -- The user who made the change
+    movie_id INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('add', 'remove')),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (list_id) REFERENCES lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_list_audit_list_created ON list_audit(list_id, created_at);
@@ -0,0 +1,2 @@
+-- How often the user's Plex libraries are synced automatically: 'off', 'daily' or 'weekly'
+ALTER TABLE user_preferences ADD COLUMN plex_auto_sync TEXT NOT NULL DEFAULT 'off';
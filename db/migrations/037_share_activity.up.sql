@@ -0,0 +1,2 @@
+-- Whether marking movies watched and rating them creates feed posts (opt-out)
+ALTER TABLE user_preferences ADD COLUMN share_activity BOOLEAN NOT NULL DEFAULT 1;
@@ -0,0 +1 @@
+ALTER TABLE post_comments DROP COLUMN updated_at;
@@ -0,0 +1,2 @@
+-- Set when a comment's content is edited
+ALTER TABLE post_comments ADD COLUMN updated_at DATETIME;
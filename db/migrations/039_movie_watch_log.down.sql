@@ -0,0 +1,2 @@
+-- Rewatches are lost; user_movies.watched_date still has the most recent watch
+DROP TABLE movie_watch_log;
@@ -0,0 +1,21 @@
+-- Every time a user watched a movie, so rewatches are kept. user_movies.watched_date
+-- stays the most recent watch.
+CREATE TABLE movie_watch_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    movie_id INTEGER NOT NULL,
+    watched_at DATETIME NOT NULL,
+    rating REAL, -- Optional rating for this viewing, on the user rating scale
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (movie_id) REFERENCES movies(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_movie_watch_log_user_movie ON movie_watch_log(user_id, movie_id, watched_at);
+CREATE INDEX idx_movie_watch_log_user_watched ON movie_watch_log(user_id, watched_at);
+
+-- Start every watched movie's log with the watch we know about
+INSERT INTO movie_watch_log (user_id, movie_id, watched_at)
+SELECT user_id, movie_id, watched_date
+FROM user_movies
+WHERE status = 'watched' AND watched_date IS NOT NULL;
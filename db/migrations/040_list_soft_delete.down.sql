@@ -0,0 +1,3 @@
+-- Lists that are deleted but not yet purged become visible again
+DROP INDEX idx_lists_deleted_at;
+ALTER TABLE lists DROP COLUMN deleted_at;
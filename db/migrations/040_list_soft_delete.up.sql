@@ -0,0 +1,4 @@
+-- Deleted lists are kept for a while so they can be restored, then purged
+ALTER TABLE lists ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX idx_lists_deleted_at ON lists(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE plex_tmdb_mappings DROP COLUMN is_override;
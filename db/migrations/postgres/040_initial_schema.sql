@@ -0,0 +1,418 @@
+-- PostgreSQL schema, equivalent to the SQLite schema after migrations 001-040. Later schema
+-- changes get a migration with the same version in both directories.
+--
+-- Queries are shared with SQLite, so the types follow it: booleans are 0/1 integers and
+-- timestamps are compared with the ones the application passes in.
+
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    auth0_id TEXT UNIQUE NOT NULL,
+    email TEXT NOT NULL,
+    name TEXT NOT NULL,
+    username TEXT UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    avatar_url TEXT,
+    name_overridden INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE movies (
+    id SERIAL PRIMARY KEY,
+    tmdb_id INTEGER UNIQUE NOT NULL,
+    title TEXT NOT NULL,
+    year INTEGER,
+    poster_url TEXT,
+    synopsis TEXT,
+    runtime INTEGER,
+    genres TEXT, -- JSON array as string
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    runtime_checked_at TIMESTAMPTZ,
+    imdb_id TEXT,
+    videos TEXT,
+    caching INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE tv_shows (
+    id SERIAL PRIMARY KEY,
+    tmdb_id INTEGER UNIQUE NOT NULL,
+    title TEXT NOT NULL,
+    year INTEGER, -- First air year
+    poster_url TEXT,
+    synopsis TEXT,
+    genres TEXT, -- JSON array as string
+    number_of_seasons INTEGER,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_movies (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    movie_id INTEGER NOT NULL REFERENCES movies(id),
+    status TEXT NOT NULL DEFAULT 'not_watched',
+    rating DOUBLE PRECISION, -- On the user rating scale, in half points
+    watched_date TIMESTAMPTZ,
+    notes TEXT,
+    owned_formats TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    favorite INTEGER NOT NULL DEFAULT 0,
+    UNIQUE(user_id, movie_id)
+);
+
+CREATE TABLE lists (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    name TEXT NOT NULL,
+    description TEXT,
+    is_public INTEGER DEFAULT 1,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE TABLE list_movies (
+    id SERIAL PRIMARY KEY,
+    list_id INTEGER NOT NULL REFERENCES lists(id),
+    movie_id INTEGER NOT NULL REFERENCES movies(id),
+    added_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    position INTEGER NOT NULL DEFAULT 0,
+    UNIQUE(list_id, movie_id)
+);
+
+CREATE TABLE friends (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    friend_id INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    status TEXT NOT NULL DEFAULT 'pending',
+    UNIQUE(user_id, friend_id)
+);
+
+CREATE TABLE feed_posts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    type TEXT NOT NULL,
+    movie_id INTEGER REFERENCES movies(id),
+    list_id INTEGER REFERENCES lists(id),
+    content TEXT,
+    rating DOUBLE PRECISION,
+    metadata TEXT, -- JSON for additional data
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE post_likes (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES feed_posts(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(post_id, user_id)
+);
+
+CREATE TABLE post_comments (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES feed_posts(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE user_preferences (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    dark_mode INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    default_list_visibility TEXT NOT NULL DEFAULT 'private',
+    preferred_region TEXT,
+    preferred_providers TEXT NOT NULL DEFAULT '[]',
+    share_now_playing INTEGER NOT NULL DEFAULT 1,
+    plex_auto_sync TEXT NOT NULL DEFAULT 'off',
+    share_activity INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE user_plex_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id), -- One Plex account per user
+    plex_token TEXT NOT NULL,
+    plex_username TEXT,
+    plex_email TEXT,
+    plex_thumb TEXT,
+    server_count INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    plex_friendly_name TEXT,
+    plex_home_user_uuid TEXT,
+    plex_home_user_title TEXT,
+    plex_profile_token TEXT,
+    webhook_token TEXT
+);
+
+CREATE TABLE plex_auth_attempts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    pin_id TEXT NOT NULL,
+    pin_code TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    completed INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE plex_tmdb_mappings (
+    id SERIAL PRIMARY KEY,
+    plex_guid TEXT NOT NULL UNIQUE,
+    tmdb_id INTEGER NOT NULL REFERENCES movies(tmdb_id),
+    title TEXT NOT NULL,
+    year INTEGER,
+    plex_rating_key TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(plex_guid, tmdb_id)
+);
+
+CREATE TABLE watch_providers_cache (
+    id SERIAL PRIMARY KEY,
+    tmdb_id INTEGER NOT NULL,
+    region_code TEXT NOT NULL DEFAULT 'US',
+    providers_data TEXT NOT NULL, -- JSON data of watch providers
+    cached_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(tmdb_id, region_code)
+);
+
+CREATE TABLE plex_availability_cache (
+    id SERIAL PRIMARY KEY,
+    tmdb_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    is_available INTEGER NOT NULL DEFAULT 0,
+    plex_servers TEXT, -- JSON array of servers where movie is available
+    cached_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(tmdb_id, user_id)
+);
+
+CREATE TABLE plex_servers (
+    id SERIAL PRIMARY KEY,
+    machine_id TEXT UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    owner_user_id INTEGER,
+    base_url TEXT,
+    version TEXT,
+    platform TEXT,
+    last_synced_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    connection_type TEXT,
+    connection_latency_ms INTEGER
+);
+
+CREATE TABLE plex_libraries (
+    id SERIAL PRIMARY KEY,
+    server_id INTEGER NOT NULL REFERENCES plex_servers(id) ON DELETE CASCADE,
+    section_key INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    type TEXT NOT NULL,
+    agent TEXT,
+    scanner TEXT,
+    language TEXT,
+    uuid TEXT,
+    item_count INTEGER DEFAULT 0,
+    last_synced_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    connection_type TEXT,
+    UNIQUE(server_id, section_key)
+);
+
+CREATE TABLE user_plex_access (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    library_id INTEGER NOT NULL REFERENCES plex_libraries(id) ON DELETE CASCADE,
+    access_level TEXT DEFAULT 'read',
+    discovered_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_verified_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    is_active INTEGER DEFAULT 1,
+    UNIQUE(user_id, library_id)
+);
+
+CREATE TABLE plex_library_items (
+    id SERIAL PRIMARY KEY,
+    library_id INTEGER NOT NULL REFERENCES plex_libraries(id) ON DELETE CASCADE,
+    plex_rating_key TEXT NOT NULL,
+    plex_guid TEXT NOT NULL,
+    title TEXT NOT NULL,
+    year INTEGER,
+    tmdb_id INTEGER REFERENCES movies(tmdb_id),
+    type TEXT NOT NULL,
+    metadata_json TEXT,
+    added_at TIMESTAMPTZ,
+    updated_at_plex TIMESTAMPTZ,
+    last_matched_at TIMESTAMPTZ,
+    matching_attempts INTEGER DEFAULT 0,
+    is_active INTEGER DEFAULT 1,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    tmdb_tv_id INTEGER REFERENCES tv_shows(tmdb_id),
+    UNIQUE(library_id, plex_rating_key)
+);
+
+CREATE TABLE sync_jobs (
+    id SERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    library_id INTEGER REFERENCES plex_libraries(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    progress INTEGER DEFAULT 0,
+    current_step TEXT,
+    total_items INTEGER DEFAULT 0,
+    processed_items INTEGER DEFAULT 0,
+    successful_items INTEGER DEFAULT 0,
+    failed_items INTEGER DEFAULT 0,
+    error_message TEXT,
+    metadata_json TEXT,
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3
+);
+
+CREATE TABLE tmdb_rate_limits (
+    id SERIAL PRIMARY KEY,
+    time_window_start TIMESTAMPTZ NOT NULL,
+    requests_count INTEGER DEFAULT 0,
+    last_request_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO tmdb_rate_limits (time_window_start, requests_count)
+VALUES (CURRENT_TIMESTAMP, 0);
+
+CREATE TABLE user_movie_formats (
+    id SERIAL PRIMARY KEY,
+    user_movie_id INTEGER NOT NULL REFERENCES user_movies(id) ON DELETE CASCADE,
+    format TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_movie_id, format)
+);
+
+CREATE TABLE movie_credits_cache (
+    id SERIAL PRIMARY KEY,
+    tmdb_id INTEGER UNIQUE NOT NULL,
+    credits_data TEXT NOT NULL,
+    cached_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE tmdb_response_cache (
+    cache_key TEXT PRIMARY KEY,
+    endpoint TEXT NOT NULL,
+    response_body BYTEA NOT NULL,
+    cached_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE plex_watch_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    library_item_id INTEGER REFERENCES plex_library_items(id) ON DELETE SET NULL,
+    plex_guid TEXT NOT NULL,
+    title TEXT NOT NULL,
+    year INTEGER,
+    tmdb_id INTEGER,
+    view_count INTEGER NOT NULL,
+    watched_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, plex_guid, watched_at)
+);
+
+CREATE TABLE user_movie_progress (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plex_guid TEXT NOT NULL,
+    view_offset_ms INTEGER NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(user_id, plex_guid)
+);
+
+CREATE TABLE list_audit (
+    id SERIAL PRIMARY KEY,
+    list_id INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    movie_id INTEGER NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('add', 'remove')),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE movie_watch_log (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    movie_id INTEGER NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    watched_at TIMESTAMPTZ NOT NULL,
+    rating DOUBLE PRECISION,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE VIEW user_available_movies AS
+SELECT upa.user_id,
+       pli.tmdb_id,
+       COUNT(*) AS copy_count,
+       COUNT(DISTINCT pl.server_id) AS server_count,
+       MIN(pli.added_at) AS first_added_at
+FROM plex_library_items pli
+JOIN plex_libraries pl ON pli.library_id = pl.id
+JOIN user_plex_access upa ON pl.id = upa.library_id
+WHERE upa.is_active = 1 AND pli.is_active = 1 AND pli.tmdb_id IS NOT NULL
+GROUP BY upa.user_id, pli.tmdb_id;
+
+CREATE INDEX idx_users_auth0_id ON users(auth0_id);
+CREATE UNIQUE INDEX idx_users_username_nocase ON users(LOWER(username));
+CREATE INDEX idx_movies_tmdb_id ON movies(tmdb_id);
+CREATE INDEX idx_user_movies_user_id ON user_movies(user_id);
+CREATE INDEX idx_user_movies_status ON user_movies(status);
+CREATE INDEX idx_user_movies_favorite ON user_movies(user_id, favorite);
+CREATE INDEX idx_user_movies_user_notes ON user_movies(user_id) WHERE notes IS NOT NULL AND notes != '';
+CREATE INDEX idx_lists_deleted_at ON lists(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_list_movies_list_position ON list_movies(list_id, position);
+CREATE INDEX idx_friends_user_id ON friends(user_id);
+CREATE INDEX idx_friends_friend_id ON friends(friend_id);
+CREATE INDEX idx_friends_friend_status ON friends(friend_id, status);
+CREATE INDEX idx_feed_posts_user_id ON feed_posts(user_id);
+CREATE INDEX idx_feed_posts_created_at ON feed_posts(created_at);
+CREATE INDEX idx_user_preferences_user_id ON user_preferences(user_id);
+CREATE INDEX idx_user_plex_tokens_user_id ON user_plex_tokens(user_id);
+CREATE UNIQUE INDEX idx_user_plex_tokens_webhook_token ON user_plex_tokens(webhook_token);
+CREATE INDEX idx_plex_auth_attempts_user_id ON plex_auth_attempts(user_id);
+CREATE INDEX idx_plex_auth_attempts_pin_id ON plex_auth_attempts(pin_id);
+CREATE INDEX idx_plex_tmdb_mappings_plex_guid ON plex_tmdb_mappings(plex_guid);
+CREATE INDEX idx_plex_tmdb_mappings_tmdb_id ON plex_tmdb_mappings(tmdb_id);
+CREATE INDEX idx_plex_tmdb_mappings_title ON plex_tmdb_mappings(title);
+CREATE INDEX idx_watch_providers_tmdb_region ON watch_providers_cache(tmdb_id, region_code);
+CREATE INDEX idx_watch_providers_expires ON watch_providers_cache(expires_at);
+CREATE INDEX idx_plex_availability_user_tmdb ON plex_availability_cache(user_id, tmdb_id);
+CREATE INDEX idx_plex_availability_expires ON plex_availability_cache(expires_at);
+CREATE INDEX idx_plex_servers_machine_id ON plex_servers(machine_id);
+CREATE INDEX idx_plex_libraries_server_id ON plex_libraries(server_id);
+CREATE INDEX idx_plex_libraries_type ON plex_libraries(type);
+CREATE INDEX idx_user_plex_access_user_id ON user_plex_access(user_id);
+CREATE INDEX idx_user_plex_access_library_id ON user_plex_access(library_id);
+CREATE INDEX idx_user_plex_access_active ON user_plex_access(is_active);
+CREATE INDEX idx_plex_library_items_library_id ON plex_library_items(library_id);
+CREATE INDEX idx_plex_library_items_plex_guid ON plex_library_items(plex_guid);
+CREATE INDEX idx_plex_library_items_tmdb_id ON plex_library_items(tmdb_id);
+CREATE INDEX idx_plex_library_items_tmdb_tv_id ON plex_library_items(tmdb_tv_id);
+CREATE INDEX idx_plex_library_items_type ON plex_library_items(type);
+CREATE INDEX idx_plex_library_items_active ON plex_library_items(is_active);
+CREATE INDEX idx_sync_jobs_status ON sync_jobs(status);
+CREATE INDEX idx_sync_jobs_user_id ON sync_jobs(user_id);
+CREATE INDEX idx_sync_jobs_created_at ON sync_jobs(created_at);
+CREATE INDEX idx_user_movie_formats_format ON user_movie_formats(format, user_movie_id);
+CREATE INDEX idx_user_movie_formats_user_movie ON user_movie_formats(user_movie_id);
+CREATE INDEX idx_movie_credits_expires ON movie_credits_cache(expires_at);
+CREATE INDEX idx_tmdb_response_cache_expires ON tmdb_response_cache(expires_at);
+CREATE INDEX idx_plex_watch_history_user_watched ON plex_watch_history(user_id, watched_at);
+CREATE INDEX idx_plex_watch_history_tmdb_id ON plex_watch_history(tmdb_id);
+CREATE INDEX idx_user_movie_progress_user_updated ON user_movie_progress(user_id, updated_at);
+CREATE INDEX idx_list_audit_list_created ON list_audit(list_id, created_at);
+CREATE INDEX idx_movie_watch_log_user_movie ON movie_watch_log(user_id, movie_id, watched_at);
+CREATE INDEX idx_movie_watch_log_user_watched ON movie_watch_log(user_id, watched_at);
@@ -0,0 +1,2 @@
+-- Set when a user corrected a mapping by hand; syncs and cleanup leave these alone
+ALTER TABLE plex_tmdb_mappings ADD COLUMN is_override INTEGER NOT NULL DEFAULT 0;
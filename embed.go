@@ -8,7 +8,15 @@ import (
 //go:embed all:web/dist
 var staticFiles embed.FS
 
+//go:embed db/migrations/*.sql db/migrations/postgres/*.sql
+var migrationFiles embed.FS
+
 // GetDistFS returns the embedded dist filesystem
 func GetDistFS() (fs.FS, error) {
 	return fs.Sub(staticFiles, "web/dist")
 }
+
+// GetMigrationsFS returns the embedded migrations filesystem
+func GetMigrationsFS() (fs.FS, error) {
+	return fs.Sub(migrationFiles, "db/migrations")
+}
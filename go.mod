@@ -5,12 +5,21 @@ go 1.22
 require (
 	github.com/LukeHagar/plexgo v0.23.0
 	github.com/auth0/go-jwt-middleware/v2 v2.2.0
+	github.com/lib/pq v1.9.0
 	github.com/mattn/go-sqlite3 v1.14.17
+	github.com/prometheus/client_golang v1.19.1
+	golang.org/x/sync v0.5.0
 )
 
 require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/ericlagergren/decimal v0.0.0-20221120152707-495c53812d05 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
 	golang.org/x/crypto v0.4.0 // indirect
-	golang.org/x/sync v0.5.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
 	gopkg.in/go-jose/go-jose.v2 v2.6.1 // indirect
 )
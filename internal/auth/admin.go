@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	adminIDsMu sync.RWMutex
+	adminIDs   = map[string]bool{}
+)
+
+// SetAdminIDs configures which Auth0 user IDs may use admin endpoints
+func SetAdminIDs(ids []string) {
+	adminIDsMu.Lock()
+	defer adminIDsMu.Unlock()
+
+	adminIDs = map[string]bool{}
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			adminIDs[id] = true
+		}
+	}
+}
+
+// IsAdmin reports whether the Auth0 user ID is configured as an admin
+func IsAdmin(auth0ID string) bool {
+	adminIDsMu.RLock()
+	defer adminIDsMu.RUnlock()
+
+	return adminIDs[auth0ID]
+}
+
+// RequireAdmin rejects requests from users who aren't admins. It must run after RequireAuth.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetUserFromContext(r.Context())
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !IsAdmin(user.Auth0ID) {
+			http.Error(w, "Admin access required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -7,7 +7,20 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func Connect(dbPath string) (*sql.DB, error) {
+// Connect opens the database: a file path for DriverSQLite, or a connection string (URL or
+// key=value pairs) for DriverPostgres
+func Connect(driverName, dataSource string) (*sql.DB, error) {
+	switch driverName {
+	case DriverSQLite:
+		return connectSQLite(dataSource)
+	case DriverPostgres:
+		return connectPostgres(dataSource)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q: must be %s or %s", driverName, DriverSQLite, DriverPostgres)
+	}
+}
+
+func connectSQLite(dbPath string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -38,4 +51,22 @@ func Connect(dbPath string) (*sql.DB, error) {
 	db.SetMaxIdleConns(5)   // Keep some connections idle
 	
 	return db, nil
-}
\ No newline at end of file
+}
+
+func connectPostgres(dataSource string) (*sql.DB, error) {
+	db, err := sql.Open(postgresDriverName, dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Several instances share the server's connection limit
+	db.SetMaxOpenConns(20)
+	db.SetMaxIdleConns(5)
+
+	return db, nil
+}
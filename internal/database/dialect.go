@@ -0,0 +1,219 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Supported values of DATABASE_DRIVER
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// postgresDriverName is the name the Postgres driver is registered under with the dialect
+// layer wrapped around it
+const postgresDriverName = "moviedb-postgres"
+
+func init() {
+	sql.Register(postgresDriverName, &postgresDriver{})
+}
+
+// Dialect holds the few pieces of SQL that SQLite and PostgreSQL spell differently and that
+// the Postgres driver can't translate on its own. Queries are otherwise written for SQLite:
+// the Postgres driver rewrites ? placeholders to $1, $2, ..., LIKE to ILIKE and bool
+// arguments to 0/1, since boolean columns are integers on both databases.
+type Dialect struct {
+	Driver string
+}
+
+// DialectOf returns the dialect of an open database
+func DialectOf(db *sql.DB) Dialect {
+	if _, ok := db.Driver().(*postgresDriver); ok {
+		return Dialect{Driver: DriverPostgres}
+	}
+	return Dialect{Driver: DriverSQLite}
+}
+
+// YearMonth returns an expression formatting a timestamp column as YYYY-MM
+func (d Dialect) YearMonth(column string) string {
+	if d.Driver == DriverPostgres {
+		return fmt.Sprintf("to_char(%s, 'YYYY-MM')", column)
+	}
+	return fmt.Sprintf("substr(%s, 1, 7)", column)
+}
+
+// JSONArrayElements returns a table expression, to join against, with one row per element
+// of the JSON array in a text column. Each element's text is in its value column; invalid
+// or empty JSON has no elements.
+func (d Dialect) JSONArrayElements(column, alias string) string {
+	if d.Driver == DriverPostgres {
+		return fmt.Sprintf("LATERAL json_array_elements_text(CASE WHEN %[1]s LIKE '[%%' THEN %[1]s ELSE '[]' END::json) %[2]s(value)", column, alias)
+	}
+	return fmt.Sprintf("json_each(CASE WHEN json_valid(%[1]s) THEN %[1]s ELSE '[]' END) %[2]s", column, alias)
+}
+
+// JSONField returns an expression for a top-level field of the JSON object in a text column
+func (d Dialect) JSONField(column, field string) string {
+	if d.Driver == DriverPostgres {
+		return fmt.Sprintf("(%s::json ->> '%s')", column, field)
+	}
+	return fmt.Sprintf("json_extract(%s, '$.%s')", column, field)
+}
+
+// MigrationsDir returns the directory of the migrations filesystem the dialect's migrations
+// are read from
+func (d Dialect) MigrationsDir() string {
+	if d.Driver == DriverPostgres {
+		return "postgres"
+	}
+	return "."
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation
+func IsUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Name() == "unique_violation"
+	}
+	return false
+}
+
+// postgresDriver opens lib/pq connections that translate SQLite-style queries
+type postgresDriver struct {
+	pq.Driver
+}
+
+func (d *postgresDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresConn{Conn: conn}, nil
+}
+
+// postgresConn rebinds every query before handing it to lib/pq
+type postgresConn struct {
+	driver.Conn
+}
+
+func (c *postgresConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebindPostgres(query))
+}
+
+func (c *postgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return queryer.QueryContext(ctx, rebindPostgres(query), args)
+}
+
+func (c *postgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return execer.ExecContext(ctx, rebindPostgres(query), args)
+}
+
+func (c *postgresConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *postgresConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// CheckNamedValue converts arguments the way database/sql does by default, then stores
+// bools as 0/1 for the integer columns that hold them
+func (c *postgresConn) CheckNamedValue(nv *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if b, ok := value.(bool); ok {
+		value = int64(0)
+		if b {
+			value = int64(1)
+		}
+	}
+	nv.Value = value
+	return nil
+}
+
+// rebindPostgres rewrites a SQLite-style query for Postgres: ? placeholders become $1, $2,
+// ... and LIKE becomes ILIKE, as SQLite's LIKE ignores case. Strings, quoted identifiers
+// and comments are left alone.
+func rebindPostgres(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	placeholder := 0
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := skipQuoted(query, i)
+			if end >= len(query) {
+				end = len(query) - 1
+			}
+			b.WriteString(query[i : end+1])
+			i = end
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+4])
+			i += end + 3
+		case c == '?':
+			placeholder++
+			fmt.Fprintf(&b, "$%d", placeholder)
+		case isIdentifierByte(c):
+			start := i
+			for i+1 < len(query) && isIdentifierByte(query[i+1]) {
+				i++
+			}
+			word := query[start : i+1]
+			if strings.EqualFold(word, "LIKE") {
+				word = "ILIKE"
+			}
+			b.WriteString(word)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// isIdentifierByte reports whether c can be part of a keyword or unquoted identifier
+func isIdentifierByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
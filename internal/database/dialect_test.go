@@ -0,0 +1,391 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRebindPostgres(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"placeholders", "SELECT * FROM movies WHERE id = ? AND year > ?", "SELECT * FROM movies WHERE id = $1 AND year > $2"},
+		{"like", "SELECT id FROM movies WHERE title like ? OR overview LIKE ?", "SELECT id FROM movies WHERE title ILIKE $1 OR overview ILIKE $2"},
+		{"words containing like", "SELECT likes, unlike_count FROM posts", "SELECT likes, unlike_count FROM posts"},
+		{"strings", "SELECT 'why?' AS q, 'it''s ?' FROM t WHERE a = ?", "SELECT 'why?' AS q, 'it''s ?' FROM t WHERE a = $1"},
+		{"quoted identifier", `SELECT "like?" FROM t WHERE a = ?`, `SELECT "like?" FROM t WHERE a = $1`},
+		{"line comment", "SELECT 1 -- like this?\nWHERE a = ?", "SELECT 1 -- like this?\nWHERE a = $1"},
+		{"block comment", "SELECT /* ? LIKE */ a FROM t WHERE b = ?", "SELECT /* ? LIKE */ a FROM t WHERE b = $1"},
+		{"unterminated string", "SELECT 'a?", "SELECT 'a?"},
+		{"unterminated comment", "SELECT ? /* ?", "SELECT $1 /* ?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rebindPostgres(tt.query); got != tt.want {
+				t.Errorf("rebindPostgres(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// queryPackages are the directories, relative to this package, whose hand-written SQL has
+// to run on both SQLite and Postgres
+var queryPackages = []string{".", "../handlers", "../services"}
+
+// sourceQuery is a SQL statement written as a string constant in the Go source
+type sourceQuery struct {
+	pos      string // file:line
+	sql      string
+	complete bool // False for fmt format strings and queries joined with runtime values
+}
+
+// formatVerb matches the fmt verbs of a query built with fmt.Sprintf
+var formatVerb = regexp.MustCompile(`%[-+# 0-9.]*[sdvq]`)
+
+// sourceQueries returns every string constant in queryPackages that holds a SELECT, INSERT,
+// UPDATE, DELETE or CREATE statement. String literals joined with + are read as one, leaving
+// out the values joined in at runtime.
+func sourceQueries(t *testing.T) []sourceQuery {
+	t.Helper()
+
+	var queries []sourceQuery
+	fset := token.NewFileSet()
+	for _, dir := range queryPackages {
+		pkgs, err := parser.ParseDir(fset, dir, func(info fs.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, 0)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", dir, err)
+		}
+
+		for _, pkg := range pkgs {
+			for _, file := range pkg.Files {
+				ast.Inspect(file, func(n ast.Node) bool {
+					expr, ok := n.(ast.Expr)
+					if !ok {
+						return true
+					}
+					value, literal, constant := joinedString(expr)
+					if !literal {
+						return true
+					}
+					if isSQLStatement(value) {
+						position := fset.Position(expr.Pos())
+						queries = append(queries, sourceQuery{
+							pos:      fmt.Sprintf("%s:%d", filepath.Base(position.Filename), position.Line),
+							sql:      value,
+							complete: constant && !formatVerb.MatchString(value),
+						})
+					}
+					return false
+				})
+			}
+		}
+	}
+
+	if len(queries) == 0 {
+		t.Fatal("no queries found")
+	}
+	return queries
+}
+
+// joinedString returns the value of a string literal or of strings joined with +, in which
+// operands that aren't string literals count as empty. literal reports whether there was a
+// literal at all and constant whether every operand was one.
+func joinedString(expr ast.Expr) (value string, literal, constant bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false, false
+		}
+		value, err := strconv.Unquote(e.Value)
+		return value, err == nil, err == nil
+	case *ast.ParenExpr:
+		return joinedString(e.X)
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false, false
+		}
+		x, literalX, constantX := joinedString(e.X)
+		y, literalY, constantY := joinedString(e.Y)
+		return x + y, literalX || literalY, constantX && constantY
+	}
+	return "", false, false
+}
+
+// isSQLStatement reports whether s starts like a SQL statement
+func isSQLStatement(s string) bool {
+	words := strings.Fields(s)
+	if len(words) < 3 {
+		return false
+	}
+	switch words[0] {
+	case "SELECT", "WITH":
+		return true
+	case "INSERT":
+		return words[1] == "INTO"
+	case "UPDATE":
+		return words[2] == "SET"
+	case "DELETE":
+		return words[1] == "FROM"
+	case "CREATE":
+		return words[1] == "TABLE" || words[1] == "INDEX" || words[1] == "UNIQUE"
+	}
+	return false
+}
+
+// sqlToken is a word, operator or punctuation of a query; strings and comments are dropped
+type sqlToken struct {
+	text string
+	word bool
+}
+
+// tokenizeSQL splits a query into tokens, upper-casing words
+func tokenizeSQL(query string) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i)
+			tokens = append(tokens, sqlToken{text: "''"})
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 3
+		case isIdentifierByte(c):
+			start := i
+			for i+1 < len(query) && (isIdentifierByte(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{text: strings.ToUpper(query[start : i+1]), word: true})
+		default:
+			if i+1 < len(query) {
+				switch op := query[i : i+2]; op {
+				case "<>", "!=", "<=", ">=", "||", "::":
+					tokens = append(tokens, sqlToken{text: op})
+					i++
+					continue
+				}
+			}
+			tokens = append(tokens, sqlToken{text: string(c)})
+		}
+	}
+	return tokens
+}
+
+// closingParen returns the index of the token closing the parenthesis at open
+func closingParen(tokens []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// sqliteOnlyFunctions are SQLite functions Postgres doesn't have; see Dialect for portable
+// versions of the ones the code needs
+var sqliteOnlyFunctions = map[string]bool{
+	"DATETIME": true, "STRFTIME": true, "JULIANDAY": true, "IFNULL": true, "INSTR": true,
+	"GROUP_CONCAT": true, "JSON_EXTRACT": true, "JSON_EACH": true, "JSON_VALID": true,
+	"PRINTF": true, "TOTAL": true, "LAST_INSERT_ROWID": true, "CHANGES": true,
+}
+
+// numericFunctions reject boolean arguments on Postgres
+var numericFunctions = map[string]bool{"COALESCE": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true}
+
+// booleanOperators make the expression they are in boolean
+var booleanOperators = map[string]bool{
+	"=": true, "<>": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"IS": true, "LIKE": true, "IN": true, "BETWEEN": true, "AND": true, "OR": true, "NOT": true, "EXISTS": true,
+}
+
+// clauseKeywords can follow a table expression, so they can't be its alias
+var clauseKeywords = map[string]bool{
+	"WHERE": true, "GROUP": true, "ORDER": true, "LIMIT": true, "OFFSET": true, "HAVING": true,
+	"JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "CROSS": true, "FULL": true, "ON": true,
+	"UNION": true, "EXCEPT": true, "INTERSECT": true,
+}
+
+// postgresProblems lists what SQLite accepts in a query but Postgres rejects: derived tables
+// without an alias (required before Postgres 16), booleans in numeric functions such as
+// COALESCE, TRUE/FALSE against the integer columns booleans are stored in, and SQLite-only
+// functions and syntax
+func postgresProblems(query string) []string {
+	tokens := tokenizeSQL(query)
+	var problems []string
+
+	for i, tok := range tokens {
+		if !tok.word {
+			continue
+		}
+		next := func(offset int) string {
+			if i+offset < len(tokens) {
+				return tokens[i+offset].text
+			}
+			return ""
+		}
+
+		switch {
+		case (tok.text == "FROM" || tok.text == "JOIN") && next(1) == "(" && (next(2) == "SELECT" || next(2) == "WITH" || next(2) == "VALUES"):
+			// A subquery that doesn't end is the start of a query built at runtime
+			end := closingParen(tokens, i+1)
+			if end < len(tokens) && (end+1 == len(tokens) || !tokens[end+1].word || clauseKeywords[tokens[end+1].text]) {
+				problems = append(problems, "subquery in "+tok.text+" has no alias")
+			}
+
+		case numericFunctions[tok.text] && next(1) == "(":
+			if booleanArgument(tokens[i+2 : closingParen(tokens, i+1)]) {
+				problems = append(problems, tok.text+"() of a boolean expression, use CASE WHEN ... THEN 1 ELSE 0 END")
+			}
+
+		case sqliteOnlyFunctions[tok.text] && next(1) == "(":
+			problems = append(problems, "SQLite-only function "+tok.text+"()")
+
+		case tok.text == "INSERT" && next(1) == "OR", tok.text == "REPLACE" && next(1) == "INTO" && (i == 0 || tokens[i-1].text != "OR"):
+			problems = append(problems, "SQLite-only "+tok.text+" "+next(1)+", use ON CONFLICT")
+
+		case tok.text == "TRUE" || tok.text == "FALSE":
+			problems = append(problems, tok.text+" compared against an integer column, use 1 or 0")
+		}
+	}
+
+	return problems
+}
+
+// booleanArgument reports whether any comma-separated argument in tokens is a boolean
+// expression. Parenthesized groups and CASE expressions are skipped, as comparisons in
+// them don't make the argument itself boolean.
+func booleanArgument(tokens []sqlToken) bool {
+	depth, cases := 0, 0
+	for _, tok := range tokens {
+		switch {
+		case tok.text == "(":
+			depth++
+		case tok.text == ")":
+			depth--
+		case depth > 0:
+		case tok.text == "CASE":
+			cases++
+		case tok.text == "END":
+			cases--
+		case cases == 0 && booleanOperators[tok.text]:
+			return true
+		}
+	}
+	return false
+}
+
+func TestPostgresProblems(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"SELECT COUNT(*) FROM (SELECT DISTINCT movie_id FROM user_movies) AS m", 0},
+		{"SELECT COUNT(*) FROM (SELECT DISTINCT movie_id FROM user_movies) m WHERE 1 = 1", 0},
+		{"SELECT COUNT(*) FROM (SELECT DISTINCT movie_id FROM user_movies)", 1},
+		{"SELECT * FROM (SELECT id FROM a) WHERE id > 1", 1},
+		{"SELECT * FROM a JOIN (SELECT id FROM b) ON a.id = b.id", 1},
+		{"SELECT * FROM a WHERE id IN (SELECT id FROM b)", 0},
+		{"SELECT f.id FROM (SELECT id FROM friends WHERE id IN (?", 0}, // Continued at runtime
+		{"SELECT COALESCE(ps.owner_user_id = upa.user_id, 0) AS owned FROM ps", 1},
+		{"SELECT MAX(a.user_id = ?) FROM a", 1},
+		{"SELECT CASE WHEN ps.owner_user_id = upa.user_id THEN 1 ELSE 0 END AS owned FROM ps", 0},
+		{"SELECT COALESCE(MAX(CASE WHEN a = b THEN 1 ELSE 0 END), 0) FROM t", 0},
+		{"SELECT COALESCE((SELECT 1 FROM t WHERE a = b), 0), SUM(x) FROM t", 0},
+		{"SELECT * FROM t WHERE created_at > datetime('now')", 1},
+		{"INSERT OR REPLACE INTO t (a) VALUES (?)", 1},
+		{"REPLACE INTO t (a) VALUES (?)", 1},
+		{"SELECT * FROM t WHERE is_active = TRUE", 1},
+		{"SELECT 'datetime(x)', \"TRUE\" FROM t -- COALESCE(a = b)", 0},
+	}
+
+	for _, tt := range tests {
+		if got := postgresProblems(tt.query); len(got) != tt.want {
+			t.Errorf("postgresProblems(%q) = %q, want %d problems", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestSourceQueriesArePortable checks the hand-written SQL of the database, handlers and
+// services packages for what SQLite accepts but Postgres rejects
+func TestSourceQueriesArePortable(t *testing.T) {
+	for _, query := range sourceQueries(t) {
+		for _, problem := range postgresProblems(query.sql) {
+			t.Errorf("%s: %s", query.pos, problem)
+		}
+	}
+}
+
+// TestSourceQueriesPrepare prepares every complete hand-written query against migrated
+// databases, which checks that the tables and columns they use exist and, on Postgres, that
+// the query plans. Set MOVIEDB_TEST_POSTGRES_URL to a throwaway Postgres database to include
+// Postgres.
+func TestSourceQueriesPrepare(t *testing.T) {
+	databases := map[string]*sql.DB{DriverSQLite: openTestDB(t)}
+	if dataSource := os.Getenv("MOVIEDB_TEST_POSTGRES_URL"); dataSource != "" {
+		db, err := Connect(DriverPostgres, dataSource)
+		if err != nil {
+			t.Fatalf("failed to connect to Postgres: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		databases[DriverPostgres] = db
+	} else {
+		t.Log("MOVIEDB_TEST_POSTGRES_URL not set, preparing queries on SQLite only")
+	}
+
+	queries := sourceQueries(t)
+	for driverName, db := range databases {
+		if err := RunMigrations(db); err != nil {
+			t.Fatalf("failed to migrate %s: %v", driverName, err)
+		}
+		// Tables the code creates itself
+		for _, query := range queries {
+			if query.complete && strings.HasPrefix(strings.TrimSpace(query.sql), "CREATE") {
+				if _, err := db.Exec(query.sql); err != nil {
+					t.Errorf("%s on %s: %v", query.pos, driverName, err)
+				}
+			}
+		}
+
+		for _, query := range queries {
+			if !query.complete {
+				continue
+			}
+			stmt, err := db.Prepare(query.sql)
+			if err != nil {
+				t.Errorf("%s on %s: %v", query.pos, driverName, err)
+				continue
+			}
+			stmt.Close()
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"moviedb/internal/types"
+)
+
+// CreateFeedPost adds a post about a movie to userID's activity
+func CreateFeedPost(db *sql.DB, userID int, postType string, movieID int, rating *float64) error {
+	_, err := db.Exec(`
+		INSERT INTO feed_posts (user_id, type, movie_id, rating, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, postType, movieID, rating, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to create feed post: %w", err)
+	}
+	return nil
+}
+
+// feedQuery selects feed posts with their author, movie, like and comment counts and whether
+// the viewing user (the first argument) liked them; filter is added to the WHERE clause
+const feedQuery = `
+	SELECT p.id, p.user_id, p.type, p.movie_id, p.list_id, p.content, p.rating, p.metadata, p.created_at,
+	       u.name, u.username, u.avatar_url, m.tmdb_id, m.title, m.poster_url,
+	       (SELECT COUNT(*) FROM post_likes pl WHERE pl.post_id = p.id),
+	       (SELECT COUNT(*) FROM post_comments pc WHERE pc.post_id = p.id),
+	       EXISTS (SELECT 1 FROM post_likes pl WHERE pl.post_id = p.id AND pl.user_id = ?)
+	FROM feed_posts p
+	JOIN users u ON u.id = p.user_id
+	LEFT JOIN movies m ON m.id = p.movie_id
+	WHERE %s
+	ORDER BY p.created_at DESC, p.id DESC
+	LIMIT ? OFFSET ?
+`
+
+// GetFriendsFeed returns posts by userID and their accepted friends, newest first
+func GetFriendsFeed(db *sql.DB, userID, limit, offset int) ([]types.FeedItem, error) {
+	return queryFeed(db, fmt.Sprintf(feedQuery, `
+		p.user_id = ? OR p.user_id IN (SELECT friend_id FROM friends WHERE user_id = ? AND status = ?)
+	`), userID, userID, userID, types.FriendStatusAccepted, limit, offset)
+}
+
+// GetGlobalFeed returns everyone's posts as seen by userID, newest first
+func GetGlobalFeed(db *sql.DB, userID, limit, offset int) ([]types.FeedItem, error) {
+	return queryFeed(db, fmt.Sprintf(feedQuery, "1 = 1"), userID, limit, offset)
+}
+
+func queryFeed(db *sql.DB, query string, args ...interface{}) ([]types.FeedItem, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+	defer rows.Close()
+
+	items := []types.FeedItem{}
+	for rows.Next() {
+		var item types.FeedItem
+		err := rows.Scan(&item.ID, &item.UserID, &item.Type, &item.MovieID, &item.ListID, &item.Content,
+			&item.Rating, &item.Metadata, &item.Created, &item.UserName, &item.Username, &item.UserAvatarURL,
+			&item.TMDBID, &item.MovieTitle, &item.PosterURL, &item.LikeCount, &item.CommentCount, &item.LikedByMe)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read feed post: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// GetFeedPostOwner returns the id of the user who made a post
+func GetFeedPostOwner(db *sql.DB, postID int) (int, error) {
+	var userID int
+	err := db.QueryRow("SELECT user_id FROM feed_posts WHERE id = ?", postID).Scan(&userID)
+	return userID, err
+}
+
+// AddPostComment adds userID's comment to a post
+func AddPostComment(db *sql.DB, postID, userID int, content string) (*types.PostComment, error) {
+	var commentID int
+	err := db.QueryRow(`
+		INSERT INTO post_comments (post_id, user_id, content, created_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`, postID, userID, content, time.Now()).Scan(&commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add comment: %w", err)
+	}
+	return GetPostComment(db, commentID)
+}
+
+// GetPostComment returns a comment by id
+func GetPostComment(db *sql.DB, commentID int) (*types.PostComment, error) {
+	var comment types.PostComment
+	err := db.QueryRow(`
+		SELECT id, post_id, user_id, content, created_at, updated_at
+		FROM post_comments WHERE id = ?
+	`, commentID).Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.Created, &comment.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// UpdatePostComment replaces a comment's content
+func UpdatePostComment(db *sql.DB, commentID int, content string) (*types.PostComment, error) {
+	_, err := db.Exec("UPDATE post_comments SET content = ?, updated_at = ? WHERE id = ?", content, time.Now(), commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+	return GetPostComment(db, commentID)
+}
+
+// DeletePostComment removes a comment
+func DeletePostComment(db *sql.DB, commentID int) error {
+	if _, err := db.Exec("DELETE FROM post_comments WHERE id = ?", commentID); err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	return nil
+}
+
+// LikePost records userID liking a post; liking a post twice has no effect
+func LikePost(db *sql.DB, postID, userID int) error {
+	_, err := db.Exec(`
+		INSERT INTO post_likes (post_id, user_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(post_id, user_id) DO NOTHING
+	`, postID, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to like post: %w", err)
+	}
+	return nil
+}
+
+// UnlikePost removes userID's like from a post
+func UnlikePost(db *sql.DB, postID, userID int) error {
+	if _, err := db.Exec("DELETE FROM post_likes WHERE post_id = ? AND user_id = ?", postID, userID); err != nil {
+		return fmt.Errorf("failed to unlike post: %w", err)
+	}
+	return nil
+}
+
+// CountPostLikes returns how many users like a post
+func CountPostLikes(db *sql.DB, postID int) (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM post_likes WHERE post_id = ?", postID).Scan(&count)
+	return count, err
+}
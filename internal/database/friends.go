@@ -0,0 +1,253 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"moviedb/internal/types"
+)
+
+// Friendships are mutual and start as requests. A pending friends row (user_id, friend_id)
+// is a request from user_id to friend_id. Accepting it marks the row accepted and adds the
+// reverse accepted row, so each accepted friendship is stored once per direction.
+
+// AddFriend sends a friend request from userID to friendID and returns the resulting status.
+// Sending it twice is a no-op. If friendID already asked userID, both become friends right away.
+func AddFriend(db *sql.DB, userID, friendID int) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var reverseStatus string
+	err = tx.QueryRow(`
+		SELECT status FROM friends WHERE user_id = ? AND friend_id = ?
+	`, friendID, userID).Scan(&reverseStatus)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to check reverse request: %w", err)
+	}
+
+	status := types.FriendStatusPending
+	if err == nil {
+		// They already asked (or are friends) - accept into a single mutual friendship
+		status = types.FriendStatusAccepted
+		if _, err := tx.Exec(`
+			UPDATE friends SET status = ? WHERE user_id = ? AND friend_id = ?
+		`, types.FriendStatusAccepted, friendID, userID); err != nil {
+			return "", fmt.Errorf("failed to accept reverse request: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO friends (user_id, friend_id, status, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, friend_id) DO UPDATE SET
+			status = CASE WHEN excluded.status = 'accepted' THEN 'accepted' ELSE friends.status END
+	`, userID, friendID, status, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to add friend: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		SELECT status FROM friends WHERE user_id = ? AND friend_id = ?
+	`, userID, friendID).Scan(&status)
+	if err != nil {
+		return "", fmt.Errorf("failed to get friend status: %w", err)
+	}
+
+	return status, tx.Commit()
+}
+
+// RemoveFriend ends a friendship (or withdraws a request) in both directions
+func RemoveFriend(db *sql.DB, userID, friendID int) error {
+	_, err := db.Exec(`
+		DELETE FROM friends
+		WHERE (user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)
+	`, userID, friendID, friendID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove friend: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingFriendRequests returns the requests waiting for userID to accept or decline, newest first
+func GetPendingFriendRequests(db *sql.DB, userID int) ([]types.FriendRequest, error) {
+	rows, err := db.Query(`
+		SELECT f.id, f.created_at, u.auth0_id, u.name, u.username, u.avatar_url
+		FROM friends f
+		JOIN users u ON f.user_id = u.id
+		WHERE f.friend_id = ? AND f.status = ?
+		ORDER BY f.created_at DESC
+	`, userID, types.FriendStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query friend requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []types.FriendRequest{}
+	for rows.Next() {
+		var req types.FriendRequest
+		if err := rows.Scan(&req.ID, &req.Created, &req.FromAuth0ID, &req.FromName, &req.FromUsername, &req.FromAvatarURL); err != nil {
+			continue
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
+// RespondToFriendRequest accepts or declines a pending request addressed to userID.
+// Returns sql.ErrNoRows if there is no such pending request.
+func RespondToFriendRequest(db *sql.DB, userID, requestID int, accept bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var requesterID int
+	err = tx.QueryRow(`
+		SELECT user_id FROM friends WHERE id = ? AND friend_id = ? AND status = ?
+	`, requestID, userID, types.FriendStatusPending).Scan(&requesterID)
+	if err != nil {
+		return err
+	}
+
+	if accept {
+		if _, err := tx.Exec("UPDATE friends SET status = ? WHERE id = ?", types.FriendStatusAccepted, requestID); err != nil {
+			return fmt.Errorf("failed to accept friend request: %w", err)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO friends (user_id, friend_id, status, created_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(user_id, friend_id) DO UPDATE SET status = excluded.status
+		`, userID, requesterID, types.FriendStatusAccepted, time.Now()); err != nil {
+			return fmt.Errorf("failed to add reverse friendship: %w", err)
+		}
+	} else {
+		if _, err := tx.Exec("DELETE FROM friends WHERE id = ?", requestID); err != nil {
+			return fmt.Errorf("failed to decline friend request: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetFriendCount returns how many accepted friends userID has
+func GetFriendCount(db *sql.DB, userID int) (int, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM friends WHERE user_id = ? AND status = ?
+	`, userID, types.FriendStatusAccepted).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count friends: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetFriendsWatched returns, for each of the given movie ids, up to limit accepted friends of
+// userID who watched it (most recent first) and how many friends watched it in total.
+// Movies no friend has watched are left out of the map.
+func GetFriendsWatched(db *sql.DB, userID int, movieIDs []int, limit int) (map[int]*types.FriendsWatched, error) {
+	watched := make(map[int]*types.FriendsWatched)
+	if len(movieIDs) == 0 {
+		return watched, nil
+	}
+
+	args := []interface{}{userID, types.FriendStatusAccepted, types.MovieStatusWatched}
+	for _, id := range movieIDs {
+		args = append(args, id)
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(`
+		SELECT fw.movie_id, fw.friend_id, fw.avatar_url, fw.total
+		FROM (
+			SELECT um.movie_id, u.id as friend_id, u.avatar_url,
+			       COUNT(*) OVER (PARTITION BY um.movie_id) as total,
+			       ROW_NUMBER() OVER (PARTITION BY um.movie_id ORDER BY um.watched_date DESC, um.updated_at DESC) as rank
+			FROM friends f
+			JOIN user_movies um ON um.user_id = f.friend_id
+			JOIN users u ON u.id = f.friend_id
+			WHERE f.user_id = ? AND f.status = ? AND um.status = ?
+			  AND um.movie_id IN (?`+strings.Repeat(", ?", len(movieIDs)-1)+`)
+		) AS fw
+		WHERE fw.rank <= ?
+		ORDER BY fw.movie_id, fw.rank
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query friends watched: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID, total int
+		var friend types.FriendWatched
+		if err := rows.Scan(&movieID, &friend.ID, &friend.AvatarURL, &total); err != nil {
+			continue
+		}
+
+		entry, ok := watched[movieID]
+		if !ok {
+			entry = &types.FriendsWatched{Total: total}
+			watched[movieID] = entry
+		}
+		entry.Friends = append(entry.Friends, friend)
+	}
+
+	return watched, rows.Err()
+}
+
+// GetFriendsRating returns the average rating userID's accepted friends gave a movie and
+// how many of them rated it. The average is nil when no friend has rated it.
+func GetFriendsRating(db *sql.DB, userID, movieID int) (*float64, int, error) {
+	var average sql.NullFloat64
+	var count int
+	err := db.QueryRow(`
+		SELECT AVG(um.rating), COUNT(um.rating)
+		FROM friends f
+		JOIN user_movies um ON um.user_id = f.friend_id
+		WHERE f.user_id = ? AND f.status = ? AND um.movie_id = ? AND um.rating IS NOT NULL
+	`, userID, types.FriendStatusAccepted, movieID).Scan(&average, &count)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get friends rating: %w", err)
+	}
+	if !average.Valid {
+		return nil, 0, nil
+	}
+	return &average.Float64, count, nil
+}
+
+// GetFriendsSharingNowPlaying returns userID's accepted friends who have connected Plex and
+// haven't turned off sharing what they're watching
+func GetFriendsSharingNowPlaying(db *sql.DB, userID int) ([]types.User, error) {
+	rows, err := db.Query(`
+		SELECT u.id, u.auth0_id, u.name, u.username, u.avatar_url
+		FROM friends f
+		JOIN users u ON u.id = f.friend_id
+		JOIN user_plex_tokens t ON t.user_id = u.id
+		LEFT JOIN user_preferences p ON p.user_id = u.id
+		WHERE f.user_id = ? AND f.status = ? AND COALESCE(p.share_now_playing, 1) = 1
+		ORDER BY u.name
+	`, userID, types.FriendStatusAccepted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query friends sharing now playing: %w", err)
+	}
+	defer rows.Close()
+
+	friends := []types.User{}
+	for rows.Next() {
+		var friend types.User
+		if err := rows.Scan(&friend.ID, &friend.Auth0ID, &friend.Name, &friend.Username, &friend.AvatarURL); err != nil {
+			continue
+		}
+		friends = append(friends, friend)
+	}
+
+	return friends, nil
+}
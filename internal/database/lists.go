@@ -0,0 +1,343 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"moviedb/internal/types"
+)
+
+// ErrMovieNotInList is returned when a reorder references a movie that isn't in the list
+var ErrMovieNotInList = errors.New("movie is not in list")
+
+// AddMovieToList appends a movie to the end of a list and records userID as having added it
+func AddMovieToList(db *sql.DB, listID, movieID, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.Exec(`
+		INSERT INTO list_movies (list_id, movie_id, position, added_at)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), -1) + 1 FROM list_movies WHERE list_id = ?), ?)
+	`, listID, movieID, listID, now)
+	if err != nil {
+		return fmt.Errorf("failed to add movie to list: %w", err)
+	}
+
+	if err := recordListChange(tx, listID, movieID, userID, types.ListAuditAdd, now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveMovieFromList removes a movie from a list and records userID as having removed it. It
+// reports whether the movie was in the list.
+func RemoveMovieFromList(db *sql.DB, listID, movieID, userID int) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM list_movies WHERE list_id = ? AND movie_id = ?", listID, movieID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove movie from list: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to count removed movies: %w", err)
+	}
+	if removed == 0 {
+		return false, nil
+	}
+
+	if err := recordListChange(tx, listID, movieID, userID, types.ListAuditRemove, time.Now()); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// recordListChange adds an entry to a list's audit log
+func recordListChange(tx *sql.Tx, listID, movieID, userID int, action string, at time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO list_audit (list_id, user_id, movie_id, action, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, listID, userID, movieID, action, at)
+	if err != nil {
+		return fmt.Errorf("failed to record list change: %w", err)
+	}
+	return nil
+}
+
+// GetListHistory returns a list's audit log, newest first
+func GetListHistory(db *sql.DB, listID, limit, offset int) ([]types.ListAuditEntry, error) {
+	rows, err := db.Query(`
+		SELECT la.id, la.action, la.user_id, u.name, m.tmdb_id, m.title, la.created_at
+		FROM list_audit la
+		JOIN users u ON u.id = la.user_id
+		JOIN movies m ON m.id = la.movie_id
+		WHERE la.list_id = ?
+		ORDER BY la.created_at DESC, la.id DESC
+		LIMIT ? OFFSET ?
+	`, listID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []types.ListAuditEntry{}
+	for rows.Next() {
+		var entry types.ListAuditEntry
+		if err := rows.Scan(&entry.ID, &entry.Action, &entry.UserID, &entry.UserName, &entry.TMDBID, &entry.Title, &entry.Created); err != nil {
+			return nil, fmt.Errorf("failed to read list history: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// ReorderListMovies rewrites list positions to follow the given TMDB ids. Movies in the list
+// that aren't mentioned keep their relative order after the ordered ones.
+func ReorderListMovies(db *sql.DB, listID int, tmdbIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT lm.id, m.tmdb_id
+		FROM list_movies lm
+		JOIN movies m ON lm.movie_id = m.id
+		WHERE lm.list_id = ?
+		ORDER BY lm.position, lm.added_at DESC, lm.id DESC
+	`, listID)
+	if err != nil {
+		return fmt.Errorf("failed to get list movies: %w", err)
+	}
+
+	var current []int
+	rowByTMDBID := make(map[int]int)
+	for rows.Next() {
+		var rowID, tmdbID int
+		if err := rows.Scan(&rowID, &tmdbID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan list movie: %w", err)
+		}
+		current = append(current, rowID)
+		rowByTMDBID[tmdbID] = rowID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get list movies: %w", err)
+	}
+
+	ordered := make([]int, 0, len(current))
+	placed := make(map[int]bool)
+	for _, tmdbID := range tmdbIDs {
+		rowID, ok := rowByTMDBID[tmdbID]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrMovieNotInList, tmdbID)
+		}
+		if placed[rowID] {
+			continue
+		}
+		placed[rowID] = true
+		ordered = append(ordered, rowID)
+	}
+	for _, rowID := range current {
+		if !placed[rowID] {
+			ordered = append(ordered, rowID)
+		}
+	}
+
+	for position, rowID := range ordered {
+		if _, err := tx.Exec(`UPDATE list_movies SET position = ? WHERE id = ?`, position, rowID); err != nil {
+			return fmt.Errorf("failed to update list position: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUserListIDsWithMovie returns the ids of userID's lists that contain the movie
+func GetUserListIDsWithMovie(db *sql.DB, userID, movieID int) ([]int, error) {
+	rows, err := db.Query(`
+		SELECT l.id
+		FROM lists l
+		JOIN list_movies lm ON l.id = lm.list_id
+		WHERE l.user_id = ? AND l.deleted_at IS NULL AND lm.movie_id = ?
+	`, userID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lists with movie: %w", err)
+	}
+	defer rows.Close()
+
+	listIDs := []int{}
+	for rows.Next() {
+		var listID int
+		if err := rows.Scan(&listID); err != nil {
+			return nil, fmt.Errorf("failed to read list id: %w", err)
+		}
+		listIDs = append(listIDs, listID)
+	}
+
+	return listIDs, rows.Err()
+}
+
+// CloneList copies a list and all of its movies into a new private list owned by userID.
+// The copy keeps the source's order and is named after it with a " (copy)" suffix.
+// It returns the new list's id and how many movies were copied.
+func CloneList(db *sql.DB, sourceListID, userID int) (int, int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var newListID int
+	err = tx.QueryRow(`
+		INSERT INTO lists (user_id, name, description, is_public, created_at)
+		SELECT ?, name || ' (copy)', description, 0, ?
+		FROM lists WHERE id = ?
+		RETURNING id
+	`, userID, time.Now(), sourceListID).Scan(&newListID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create list copy: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO list_movies (list_id, movie_id, position, added_at)
+		SELECT ?, movie_id, position, added_at
+		FROM list_movies WHERE list_id = ?
+	`, newListID, sourceListID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to copy list movies: %w", err)
+	}
+
+	movieCount, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count copied movies: %w", err)
+	}
+
+	// The copy's history starts with the cloning user adding every movie
+	_, err = tx.Exec(`
+		INSERT INTO list_audit (list_id, user_id, movie_id, action, created_at)
+		SELECT ?, ?, movie_id, ?, ?
+		FROM list_movies WHERE list_id = ?
+	`, newListID, userID, types.ListAuditAdd, time.Now(), newListID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to record copied movies: %w", err)
+	}
+
+	return newListID, int(movieCount), tx.Commit()
+}
+
+// CreateListWithMovies creates a list holding the movies, in the given order, and records
+// userID as having added each of them. Nothing is created when any step fails.
+func CreateListWithMovies(db *sql.DB, userID int, name, description string, isPublic bool, movieIDs []int) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var listID int
+	err = tx.QueryRow(`
+		INSERT INTO lists (user_id, name, description, is_public, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, userID, name, description, isPublic, now).Scan(&listID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create list: %w", err)
+	}
+
+	for position, movieID := range movieIDs {
+		_, err := tx.Exec(`
+			INSERT INTO list_movies (list_id, movie_id, position, added_at)
+			VALUES (?, ?, ?, ?)
+		`, listID, movieID, position, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to add movie to list: %w", err)
+		}
+
+		if err := recordListChange(tx, listID, movieID, userID, types.ListAuditAdd, now); err != nil {
+			return 0, err
+		}
+	}
+
+	return listID, tx.Commit()
+}
+
+// SoftDeleteList marks a list deleted. It is hidden everywhere but can be restored until it
+// is purged.
+func SoftDeleteList(db *sql.DB, listID int) error {
+	if _, err := db.Exec("UPDATE lists SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now(), listID); err != nil {
+		return fmt.Errorf("failed to delete list: %w", err)
+	}
+	return nil
+}
+
+// GetListDeletion returns a list's owner and when it was deleted (nil if it isn't)
+func GetListDeletion(db *sql.DB, listID int) (int, *time.Time, error) {
+	var userID int
+	var deletedAt *time.Time
+	err := db.QueryRow("SELECT user_id, deleted_at FROM lists WHERE id = ?", listID).Scan(&userID, &deletedAt)
+	if err != nil {
+		return 0, nil, err
+	}
+	return userID, deletedAt, nil
+}
+
+// RestoreList undoes a soft delete
+func RestoreList(db *sql.DB, listID int) error {
+	if _, err := db.Exec("UPDATE lists SET deleted_at = NULL WHERE id = ?", listID); err != nil {
+		return fmt.Errorf("failed to restore list: %w", err)
+	}
+	return nil
+}
+
+// PurgeDeletedLists permanently removes lists deleted before cutoff along with their movies
+// and history. Feed posts about a purged list are kept without it. It returns how many lists
+// were purged.
+func PurgeDeletedLists(db *sql.DB, cutoff time.Time) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const purged = `SELECT id FROM lists WHERE deleted_at IS NOT NULL AND deleted_at < ?`
+	if _, err := tx.Exec(`DELETE FROM list_movies WHERE list_id IN (`+purged+`)`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to purge list movies: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM list_audit WHERE list_id IN (`+purged+`)`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to purge list history: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE feed_posts SET list_id = NULL WHERE list_id IN (`+purged+`)`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to detach feed posts: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM lists WHERE deleted_at IS NOT NULL AND deleted_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge lists: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count purged lists: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return count, nil
+}
@@ -3,30 +3,51 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"moviedb"
 )
 
 type Migration struct {
-	Version int
-	Name    string
-	SQL     string
+	Version    int
+	Name       string
+	SQL        string
+	Statements []string // SQL split into individual statements
+
+	// From the paired NNN_name.down.sql file; empty when the migration can't be rolled back
+	DownSQL        string
+	DownStatements []string
+}
+
+// Values of schema_migrations.direction: whether a version was last applied or rolled back
+const (
+	directionUp   = "up"
+	directionDown = "down"
+)
+
+// migrationsDir holds the SQLite migration files; Postgres has its own in a subdirectory
+// (see Dialect.MigrationsDir). The binary embeds both.
+const migrationsDir = "db/migrations"
+
+// MigrationState is one migration known to the binary or recorded in the database
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+	Known     bool       `json:"known"` // False when the database has a version this binary doesn't ship
 }
 
 func RunMigrations(db *sql.DB) error {
-	// Create migrations table if it doesn't exist
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS schema_migrations (
-			version INTEGER PRIMARY KEY,
-			name TEXT NOT NULL,
-			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+	if err := ensureMigrationsTable(db); err != nil {
+		return err
 	}
 
 	// Get applied migrations
@@ -36,7 +57,7 @@ func RunMigrations(db *sql.DB) error {
 	}
 
 	// Load migration files
-	migrations, err := loadMigrations("db/migrations")
+	migrations, err := loadMigrations(DialectOf(db).MigrationsDir())
 	if err != nil {
 		return fmt.Errorf("failed to load migrations: %w", err)
 	}
@@ -47,15 +68,147 @@ func RunMigrations(db *sql.DB) error {
 			if err := applyMigration(db, migration); err != nil {
 				return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
 			}
-			fmt.Printf("Applied migration %d: %s\n", migration.Version, migration.Name)
+			slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
 		}
 	}
 
 	return nil
 }
 
+// MigrateDown rolls back every applied migration newer than toVersion, newest first. Each
+// needs a down migration; if any doesn't, nothing is rolled back.
+func MigrateDown(db *sql.DB, toVersion int) error {
+	if err := ensureMigrationsTable(db); err != nil {
+		return err
+	}
+
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	migrations, err := loadMigrations(DialectOf(db).MigrationsDir())
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var versions []int
+	for version := range applied {
+		if version > toVersion {
+			versions = append(versions, version)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	// Check every migration can be rolled back before touching the schema
+	for _, version := range versions {
+		migration, ok := byVersion[version]
+		if !ok {
+			return fmt.Errorf("cannot roll back migration %d: not known to this binary", version)
+		}
+		if len(migration.DownStatements) == 0 {
+			return fmt.Errorf("cannot roll back migration %d (%s): it has no down migration", version, migration.Name)
+		}
+	}
+
+	for _, version := range versions {
+		migration := byVersion[version]
+		if err := revertMigration(db, migration); err != nil {
+			return fmt.Errorf("failed to roll back migration %d: %w", version, err)
+		}
+		slog.Info("Rolled back migration", "version", migration.Version, "name", migration.Name)
+	}
+
+	return nil
+}
+
+// ensureMigrationsTable creates schema_migrations, and adds the direction column to tables
+// created before migrations could be rolled back
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			direction TEXT NOT NULL DEFAULT 'up'
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	if _, err := db.Exec("SELECT direction FROM schema_migrations LIMIT 1"); err != nil {
+		_, err = db.Exec("ALTER TABLE schema_migrations ADD COLUMN direction TEXT NOT NULL DEFAULT 'up'")
+		if err != nil {
+			return fmt.Errorf("failed to add direction to migrations table: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetMigrationStates compares the migrations shipped with the binary against those
+// recorded in schema_migrations, ordered by version
+func GetMigrationStates(db *sql.DB) ([]MigrationState, error) {
+	migrations, err := loadMigrations(DialectOf(db).MigrationsDir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	states := make(map[int]*MigrationState)
+	for _, migration := range migrations {
+		states[migration.Version] = &MigrationState{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Known:   true,
+		}
+	}
+
+	rows, err := db.Query("SELECT version, name, applied_at, direction FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var name, direction string
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &name, &appliedAt, &direction); err != nil {
+			return nil, err
+		}
+
+		state, ok := states[version]
+		if !ok {
+			state = &MigrationState{Version: version, Name: name}
+			states[version] = state
+		}
+		if direction == directionUp {
+			state.Applied = true
+			state.AppliedAt = &appliedAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationState, 0, len(states))
+	for _, state := range states {
+		result = append(result, *state)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
 func getAppliedMigrations(db *sql.DB) (map[int]bool, error) {
-	rows, err := db.Query("SELECT version FROM schema_migrations")
+	rows, err := db.Query("SELECT version FROM schema_migrations WHERE direction = ?", directionUp)
 	if err != nil {
 		return nil, err
 	}
@@ -73,13 +226,28 @@ func getAppliedMigrations(db *sql.DB) (map[int]bool, error) {
 	return applied, rows.Err()
 }
 
+// migrationsFS returns the migrations on disk when running from a checkout, so new ones are
+// picked up without rebuilding, and the ones embedded in the binary otherwise
+func migrationsFS() (fs.FS, error) {
+	if info, err := os.Stat(migrationsDir); err == nil && info.IsDir() {
+		return os.DirFS(migrationsDir), nil
+	}
+	return moviedb.GetMigrationsFS()
+}
+
 func loadMigrations(dir string) ([]Migration, error) {
-	files, err := ioutil.ReadDir(dir)
+	fsys, err := migrationsFS()
 	if err != nil {
 		return nil, err
 	}
 
-	var migrations []Migration
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	downs := make(map[int]string) // Down file names, to report downs without an up
 	for _, file := range files {
 		if !strings.HasSuffix(file.Name(), ".sql") {
 			continue
@@ -97,20 +265,45 @@ func loadMigrations(dir string) ([]Migration, error) {
 		}
 
 		// Read SQL content
-		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		content, err := fs.ReadFile(fsys, path.Join(dir, file.Name()))
 		if err != nil {
 			return nil, err
 		}
 
-		// Extract name from filename
+		// Extract name and direction from filename: NNN_name.sql and NNN_name.up.sql are
+		// applied, NNN_name.down.sql rolls NNN back
 		name := strings.TrimSuffix(file.Name(), ".sql")
+		down := strings.HasSuffix(name, ".down")
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".down"), ".up")
 		name = strings.Join(strings.Split(name, "_")[1:], "_")
 
-		migrations = append(migrations, Migration{
-			Version: version,
-			Name:    name,
-			SQL:     string(content),
-		})
+		statements := splitSQLStatements(string(content))
+		if err := validateMigrationStatements(statements); err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %w", file.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if down {
+			migration.DownSQL = string(content)
+			migration.DownStatements = statements
+			downs[version] = file.Name()
+		} else {
+			migration.Name = name
+			migration.SQL = string(content)
+			migration.Statements = statements
+		}
+	}
+
+	var migrations []Migration
+	for version, migration := range byVersion {
+		if _, hasDown := downs[version]; hasDown && migration.SQL == "" {
+			return nil, fmt.Errorf("invalid migration %s: down migration without an up migration", downs[version])
+		}
+		migrations = append(migrations, *migration)
 	}
 
 	// Sort by version
@@ -121,6 +314,157 @@ func loadMigrations(dir string) ([]Migration, error) {
 	return migrations, nil
 }
 
+// transactionControlKeywords start statements that would end or nest the transaction a
+// migration runs in, leaving the database half-migrated if a later statement failed
+var transactionControlKeywords = map[string]bool{
+	"BEGIN":     true,
+	"COMMIT":    true,
+	"END":       true,
+	"ROLLBACK":  true,
+	"SAVEPOINT": true,
+	"RELEASE":   true,
+	"VACUUM":    true,
+}
+
+// validateMigrationStatements rejects migrations that manage transactions themselves.
+// Each migration is applied in a single transaction together with its schema_migrations
+// row, so its statements must not begin, commit or roll back one.
+func validateMigrationStatements(statements []string) error {
+	for _, statement := range statements {
+		words := sqlWords(stripSQLCommentsAndStrings(statement))
+		if len(words) > 0 && transactionControlKeywords[words[0]] {
+			return fmt.Errorf("migration must not contain %s: migrations already run in a transaction", words[0])
+		}
+	}
+
+	return nil
+}
+
+// splitSQLStatements splits a migration file into its statements. Semicolons inside
+// strings, comments and CREATE TRIGGER bodies (BEGIN ... END) don't end a statement.
+func splitSQLStatements(migrationSQL string) []string {
+	var statements []string
+	start := 0
+
+	for i := 0; i < len(migrationSQL); i++ {
+		switch c := migrationSQL[i]; {
+		case c == '-' && i+1 < len(migrationSQL) && migrationSQL[i+1] == '-':
+			for i < len(migrationSQL) && migrationSQL[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(migrationSQL) && migrationSQL[i+1] == '*':
+			end := strings.Index(migrationSQL[i+2:], "*/")
+			if end < 0 {
+				i = len(migrationSQL)
+			} else {
+				i += end + 3
+			}
+		case c == '\'' || c == '"':
+			i = skipQuoted(migrationSQL, i)
+		case c == ';':
+			statement := migrationSQL[start:i]
+			if inTriggerBody(sqlWords(stripSQLCommentsAndStrings(statement))) {
+				continue
+			}
+			if strings.TrimSpace(stripSQLCommentsAndStrings(statement)) != "" {
+				statements = append(statements, strings.TrimSpace(statement))
+			}
+			start = i + 1
+		}
+	}
+
+	// A final statement doesn't need a semicolon
+	if rest := migrationSQL[start:]; strings.TrimSpace(stripSQLCommentsAndStrings(rest)) != "" {
+		statements = append(statements, strings.TrimSpace(rest))
+	}
+
+	return statements
+}
+
+// inTriggerBody reports whether a statement so far is a CREATE TRIGGER whose body hasn't
+// reached its END yet. CASE expressions in the body have their own END.
+func inTriggerBody(words []string) bool {
+	if !isCreateTrigger(words) {
+		return false
+	}
+
+	ends, cases := 0, 0
+	for _, word := range words {
+		switch word {
+		case "CASE":
+			cases++
+		case "END":
+			ends++
+		}
+	}
+	return ends <= cases
+}
+
+// sqlWords returns the upper-cased keywords and identifiers of a statement
+func sqlWords(statement string) []string {
+	return strings.FieldsFunc(strings.ToUpper(statement), func(r rune) bool {
+		return !(r == '_' || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	})
+}
+
+// skipQuoted returns the index of the quote closing the string starting at i.
+// A doubled quote inside a string is an escaped quote.
+func skipQuoted(migrationSQL string, i int) int {
+	quote := migrationSQL[i]
+	for i++; i < len(migrationSQL); i++ {
+		if migrationSQL[i] == quote {
+			if i+1 < len(migrationSQL) && migrationSQL[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return i
+}
+
+// stripSQLCommentsAndStrings blanks out comments and quoted strings so keywords and
+// semicolons inside them are not mistaken for statements
+func stripSQLCommentsAndStrings(migrationSQL string) string {
+	var b strings.Builder
+	for i := 0; i < len(migrationSQL); i++ {
+		c := migrationSQL[i]
+		switch {
+		case c == '-' && i+1 < len(migrationSQL) && migrationSQL[i+1] == '-':
+			for i < len(migrationSQL) && migrationSQL[i] != '\n' {
+				i++
+			}
+			b.WriteByte('\n')
+		case c == '/' && i+1 < len(migrationSQL) && migrationSQL[i+1] == '*':
+			end := strings.Index(migrationSQL[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+			b.WriteByte(' ')
+		case c == '\'' || c == '"':
+			i = skipQuoted(migrationSQL, i)
+			b.WriteString(" '' ")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// isCreateTrigger reports whether the upper-cased statement words are CREATE [TEMP] TRIGGER
+func isCreateTrigger(words []string) bool {
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+	if words[1] == "TEMP" || words[1] == "TEMPORARY" {
+		return len(words) > 2 && words[2] == "TRIGGER"
+	}
+	return words[1] == "TRIGGER"
+}
+
+// applyMigration runs a migration's statements and records its version in one transaction,
+// so a failing statement leaves neither partial schema changes nor a recorded version behind
 func applyMigration(db *sql.DB, migration Migration) error {
 	tx, err := db.Begin()
 	if err != nil {
@@ -128,19 +472,52 @@ func applyMigration(db *sql.DB, migration Migration) error {
 	}
 	defer tx.Rollback()
 
-	// Execute migration SQL
-	if _, err := tx.Exec(migration.SQL); err != nil {
-		return fmt.Errorf("failed to execute migration SQL: %w", err)
+	// Execute each statement on its own; not every driver configuration runs more than
+	// the first statement of a multi-statement Exec
+	for i, statement := range migration.Statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to execute migration statement %d: %w", i+1, err)
+		}
 	}
 
-	// Record migration as applied
-	_, err = tx.Exec(
-		"INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
-		migration.Version, migration.Name,
+	// Record migration as applied; a version that was rolled back before is applied again
+	_, err = tx.Exec(`
+		INSERT INTO schema_migrations (version, name, direction) VALUES (?, ?, ?)
+		ON CONFLICT(version) DO UPDATE SET
+			name = excluded.name,
+			direction = excluded.direction,
+			applied_at = CURRENT_TIMESTAMP`,
+		migration.Version, migration.Name, directionUp,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to record migration: %w", err)
 	}
 
+	return tx.Commit()
+}
+
+// revertMigration runs a migration's down statements and records the rollback in one
+// transaction
+func revertMigration(db *sql.DB, migration Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, statement := range migration.DownStatements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to execute down migration statement %d: %w", i+1, err)
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE schema_migrations SET direction = ?, applied_at = CURRENT_TIMESTAMP WHERE version = ?",
+		directionDown, migration.Version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record rollback: %w", err)
+	}
+
 	return tx.Commit()
 }
\ No newline at end of file
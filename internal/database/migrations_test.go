@@ -0,0 +1,227 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openTestDB opens an empty SQLite database in a temporary directory
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", t.TempDir()+"/test.db?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testMigration builds a migration the way loadMigrations does, validation included
+func testMigration(t *testing.T, version int, name, migrationSQL string) Migration {
+	t.Helper()
+
+	statements := splitSQLStatements(migrationSQL)
+	if err := validateMigrationStatements(statements); err != nil {
+		t.Fatalf("migration %d is invalid: %v", version, err)
+	}
+	return Migration{Version: version, Name: name, SQL: migrationSQL, Statements: statements}
+}
+
+func TestValidateMigrationStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr string // Empty when the migration is valid
+	}{
+		{"plain statements", "CREATE TABLE a (id INTEGER); INSERT INTO a VALUES (1);", ""},
+		{"keyword inside a string", "INSERT INTO a (note) VALUES ('BEGIN; COMMIT;');", ""},
+		{"keyword inside a comment", "-- COMMIT when done\nCREATE TABLE a (id INTEGER);", ""},
+		{"trigger body", "CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE a SET id = 1; END;", ""},
+		{"begin", "BEGIN TRANSACTION; CREATE TABLE a (id INTEGER); COMMIT;", "BEGIN"},
+		{"commit midway", "CREATE TABLE a (id INTEGER); COMMIT; CREATE TABLE b (id INTEGER);", "COMMIT"},
+		{"lower case rollback", "create table a (id integer);\nrollback;", "ROLLBACK"},
+		{"savepoint after a comment", "/* checkpoint */ SAVEPOINT s1; CREATE TABLE a (id INTEGER);", "SAVEPOINT"},
+		{"vacuum", "DROP TABLE a; VACUUM;", "VACUUM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMigrationStatements(splitSQLStatements(tt.sql))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("no error, want one about %s", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("error %q doesn't mention %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFailingMigrationLeavesNothingApplied(t *testing.T) {
+	db := openTestDB(t)
+	if err := ensureMigrationsTable(db); err != nil {
+		t.Fatalf("failed to create schema_migrations: %v", err)
+	}
+
+	first := testMigration(t, 1, "create_notes", `
+		CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL);
+		INSERT INTO notes (body) VALUES ('kept');
+	`)
+	if err := applyMigration(db, first); err != nil {
+		t.Fatalf("failed to apply migration 1: %v", err)
+	}
+
+	// The last statement fails after the table, index and rows have been created
+	failing := testMigration(t, 2, "add_tags", `
+		CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE INDEX idx_tags_name ON tags(name);
+		INSERT INTO tags (name) VALUES ('drama');
+		ALTER TABLE notes ADD COLUMN tag_id INTEGER;
+		UPDATE notes SET body = 'changed';
+		INSERT INTO missing_table (id) VALUES (1);
+	`)
+	err := applyMigration(db, failing)
+	if err == nil {
+		t.Fatal("migration 2 applied, want an error")
+	}
+	if !strings.Contains(err.Error(), "statement 6") {
+		t.Errorf("error %q doesn't name the failing statement", err)
+	}
+
+	var objects int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name IN ('tags', 'idx_tags_name')`).Scan(&objects); err != nil {
+		t.Fatalf("failed to read the schema: %v", err)
+	}
+	if objects != 0 {
+		t.Errorf("%d objects of the failed migration exist, want none", objects)
+	}
+
+	var columns int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('notes') WHERE name = 'tag_id'`).Scan(&columns); err != nil {
+		t.Fatalf("failed to read notes columns: %v", err)
+	}
+	if columns != 0 {
+		t.Error("notes.tag_id exists after the failed migration")
+	}
+
+	var body string
+	if err := db.QueryRow(`SELECT body FROM notes`).Scan(&body); err != nil {
+		t.Fatalf("failed to read notes: %v", err)
+	}
+	if body != "kept" {
+		t.Errorf("notes body = %q, want the update rolled back", body)
+	}
+
+	applied, err := getAppliedMigrations(db)
+	if err != nil {
+		t.Fatalf("failed to read schema_migrations: %v", err)
+	}
+	if len(applied) != 1 || !applied[1] {
+		t.Errorf("applied migrations = %v, want only version 1", applied)
+	}
+
+	// A migration that would commit halfway is rejected before it runs
+	statements := splitSQLStatements("CREATE TABLE tags (id INTEGER); COMMIT; INSERT INTO missing_table VALUES (1);")
+	if err := validateMigrationStatements(statements); err == nil {
+		t.Error("migration committing halfway passed validation")
+	}
+}
+
+func TestSplitSQLStatements(t *testing.T) {
+	content, err := os.ReadFile("testdata/split_statements.sql")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	want := []string{
+		"-- Fixture for splitSQLStatements: semicolons in strings and comments, a trigger body\n" +
+			"-- and trailing whitespace; don't end a statement early; (this comment has one)\n\n" +
+			"CREATE TABLE notes (\n" +
+			"    id INTEGER PRIMARY KEY,\n" +
+			"    body TEXT NOT NULL DEFAULT 'a; b' -- default has a semicolon; so does this comment\n" +
+			")",
+		"/* A block comment;\n" +
+			"   spanning lines; with semicolons */\n" +
+			"INSERT INTO notes (body) VALUES ('it''s; quoted'), (\"double; quoted\")",
+		"CREATE TRIGGER notes_touch AFTER UPDATE ON notes\n" +
+			"BEGIN\n" +
+			"    UPDATE notes SET body = CASE WHEN NEW.body = '' THEN 'empty;' ELSE NEW.body END WHERE id = NEW.id;\n" +
+			"END",
+		"UPDATE notes SET body = 'last; one' WHERE id = 1",
+	}
+
+	got := splitSQLStatements(string(content))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %d statements, want %d:\n%s", len(got), len(want), strings.Join(got, "\n----\n"))
+	}
+
+	// The statements run as split
+	db := openTestDB(t)
+	for i, statement := range got {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("statement %d failed: %v", i+1, err)
+		}
+	}
+	var body string
+	if err := db.QueryRow("SELECT body FROM notes WHERE id = 1").Scan(&body); err != nil {
+		t.Fatalf("failed to read notes: %v", err)
+	}
+	if body != "last; one" {
+		t.Errorf("body = %q, want %q", body, "last; one")
+	}
+}
+
+func TestSplitSQLStatementsEdgeCases(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"only whitespace and comments", "  \n-- nothing; here\n/* or; here */\n\t", nil},
+		{"no final semicolon", "SELECT 1;\nSELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"trailing whitespace", "SELECT 1;  \n\t\n", []string{"SELECT 1"}},
+		{"empty statements", ";; SELECT 1 ;;", []string{"SELECT 1"}},
+		{"escaped quote", "SELECT 'a'';b'; SELECT 2", []string{"SELECT 'a'';b'", "SELECT 2"}},
+		{"unterminated string", "SELECT 'a;b", []string{"SELECT 'a;b"}},
+		{"comment after the last statement", "SELECT 1; -- done;", []string{"SELECT 1"}},
+		{
+			"temp trigger with a CASE",
+			"CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN SELECT CASE WHEN 1 THEN 2 END; END; SELECT 3;",
+			[]string{"CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN SELECT CASE WHEN 1 THEN 2 END; END", "SELECT 3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitSQLStatements(tt.sql); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSQLStatements(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadMigrations loads the shipped migrations of both dialects, each of which must split
+// and validate
+func TestLoadMigrations(t *testing.T) {
+	for _, dialect := range []Dialect{{Driver: DriverSQLite}, {Driver: DriverPostgres}} {
+		migrations, err := loadMigrations(dialect.MigrationsDir())
+		if err != nil {
+			t.Fatalf("failed to load %s migrations: %v", dialect.MigrationsDir(), err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("no migrations in %s", dialect.MigrationsDir())
+		}
+		for _, migration := range migrations {
+			if len(migration.Statements) == 0 {
+				t.Errorf("%s migration %d has no statements", dialect.MigrationsDir(), migration.Version)
+			}
+		}
+	}
+}
@@ -2,6 +2,8 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,17 +11,24 @@ import (
 )
 
 // GetOrCreateUser finds a user by Auth0 ID or creates a new one
-// Auth0 is treated as the source of truth - existing users are updated with latest info
+// Auth0 is treated as the source of truth - existing users are updated with latest info.
+// The username is never synced from Auth0, and the name only until the user edits it.
 func GetOrCreateUser(db *sql.DB, auth0ID, email, name, avatarURL string) (*types.User, error) {
 	// First try to find existing user
 	var user types.User
+	var nameOverridden bool
 	err := db.QueryRow(`
-		SELECT id, auth0_id, email, name, username, avatar_url, created_at 
+		SELECT id, auth0_id, email, name, username, avatar_url, created_at, name_overridden
 		FROM users 
 		WHERE auth0_id = ?
-	`, auth0ID).Scan(&user.ID, &user.Auth0ID, &user.Email, &user.Name, &user.Username, &user.AvatarURL, &user.Created)
+	`, auth0ID).Scan(&user.ID, &user.Auth0ID, &user.Email, &user.Name, &user.Username, &user.AvatarURL, &user.Created, &nameOverridden)
 
 	if err == nil {
+		// Keep a user-chosen name
+		if nameOverridden {
+			name = user.Name
+		}
+
 		// User exists, check if Auth0 data has changed
 		avatarChanged := (user.AvatarURL == nil && avatarURL != "") || (user.AvatarURL != nil && *user.AvatarURL != avatarURL)
 		if user.Email != email || user.Name != name || avatarChanged {
@@ -53,23 +62,20 @@ func GetOrCreateUser(db *sql.DB, auth0ID, email, name, avatarURL string) (*types
 	}
 
 	// User doesn't exist, create new one
-	result, err := db.Exec(`
+	var userID int
+	err = db.QueryRow(`
 		INSERT INTO users (auth0_id, email, name, avatar_url, created_at) 
 		VALUES (?, ?, ?, ?, ?)
-	`, auth0ID, email, name, avatarURL, time.Now())
+		RETURNING id
+	`, auth0ID, email, name, avatarURL, time.Now()).Scan(&userID)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	userID, err := result.LastInsertId()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user ID: %w", err)
-	}
-
 	// Return the newly created user
 	user = types.User{
-		ID:      int(userID),
+		ID:      userID,
 		Auth0ID: auth0ID,
 		Email:   email,
 		Name:    name,
@@ -85,14 +91,19 @@ func GetOrCreateUser(db *sql.DB, auth0ID, email, name, avatarURL string) (*types
 // GetUserPreferences gets user preferences, creating default ones if they don't exist
 func GetUserPreferences(db *sql.DB, userID int) (*types.UserPreferences, error) {
 	var prefs types.UserPreferences
+	var providersJSON string
 	err := db.QueryRow(`
-		SELECT id, user_id, dark_mode, created_at, updated_at 
+		SELECT id, user_id, dark_mode, default_list_visibility, preferred_region, preferred_providers, share_now_playing, plex_auto_sync, share_activity, created_at, updated_at 
 		FROM user_preferences 
 		WHERE user_id = ?
-	`, userID).Scan(&prefs.ID, &prefs.UserID, &prefs.DarkMode, &prefs.Created, &prefs.Updated)
+	`, userID).Scan(&prefs.ID, &prefs.UserID, &prefs.DarkMode, &prefs.DefaultListVisibility, &prefs.PreferredRegion, &providersJSON, &prefs.ShareNowPlaying, &prefs.PlexAutoSync, &prefs.ShareActivity, &prefs.Created, &prefs.Updated)
 
 	if err == nil {
 		// Preferences exist
+		prefs.PreferredProviders = []int{}
+		if err := json.Unmarshal([]byte(providersJSON), &prefs.PreferredProviders); err != nil {
+			return nil, fmt.Errorf("failed to parse preferred providers: %w", err)
+		}
 		return &prefs, nil
 	}
 
@@ -102,43 +113,101 @@ func GetUserPreferences(db *sql.DB, userID int) (*types.UserPreferences, error)
 	}
 
 	// Preferences don't exist, create default ones
-	result, err := db.Exec(`
+	var prefsID int
+	err = db.QueryRow(`
 		INSERT INTO user_preferences (user_id, dark_mode, created_at, updated_at) 
 		VALUES (?, ?, ?, ?)
-	`, userID, false, time.Now(), time.Now())
+		RETURNING id
+	`, userID, false, time.Now(), time.Now()).Scan(&prefsID)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user preferences: %w", err)
 	}
 
-	prefsID, err := result.LastInsertId()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get preferences ID: %w", err)
-	}
-
 	// Return the newly created preferences
 	prefs = types.UserPreferences{
-		ID:       int(prefsID),
-		UserID:   userID,
-		DarkMode: false,
-		Created:  time.Now(),
-		Updated:  time.Now(),
+		ID:                    prefsID,
+		UserID:                userID,
+		DarkMode:              false,
+		DefaultListVisibility: types.ListVisibilityPrivate,
+		PreferredProviders:    []int{},
+		ShareNowPlaying:       true,
+		PlexAutoSync:          types.PlexAutoSyncOff,
+		ShareActivity:         true,
+		Created:               time.Now(),
+		Updated:               time.Now(),
 	}
 
 	return &prefs, nil
 }
 
-// UpdateUserPreferences updates user preferences
-func UpdateUserPreferences(db *sql.DB, userID int, darkMode bool) error {
-	_, err := db.Exec(`
+// UpdateUserPreferences saves the user's preferences
+func UpdateUserPreferences(db *sql.DB, userID int, prefs *types.UserPreferences) error {
+	providers := prefs.PreferredProviders
+	if providers == nil {
+		providers = []int{}
+	}
+	providersJSON, err := json.Marshal(providers)
+	if err != nil {
+		return fmt.Errorf("failed to encode preferred providers: %w", err)
+	}
+
+	_, err = db.Exec(`
 		UPDATE user_preferences 
-		SET dark_mode = ?, updated_at = ? 
+		SET dark_mode = ?, default_list_visibility = ?, preferred_region = ?, preferred_providers = ?, share_now_playing = ?, plex_auto_sync = ?, share_activity = ?, updated_at = ? 
 		WHERE user_id = ?
-	`, darkMode, time.Now(), userID)
+	`, prefs.DarkMode, prefs.DefaultListVisibility, prefs.PreferredRegion, string(providersJSON), prefs.ShareNowPlaying, prefs.PlexAutoSync, prefs.ShareActivity, time.Now(), userID)
 
 	if err != nil {
 		return fmt.Errorf("failed to update user preferences: %w", err)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// ErrUsernameTaken is returned when another user already has the requested username
+var ErrUsernameTaken = errors.New("username already taken")
+
+// UpdateUserProfile updates the user's display name and/or username (nil leaves a field unchanged).
+// Setting a name marks it as overridden so later Auth0 logins don't replace it.
+func UpdateUserProfile(db *sql.DB, userID int, name, username *string) (*types.User, error) {
+	if username != nil {
+		var existingID int
+		err := db.QueryRow(`
+			SELECT id FROM users WHERE LOWER(username) = LOWER(?) AND id != ?
+		`, *username, userID).Scan(&existingID)
+		if err == nil {
+			return nil, ErrUsernameTaken
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to check username: %w", err)
+		}
+
+		if _, err := db.Exec("UPDATE users SET username = ? WHERE id = ?", *username, userID); err != nil {
+			// Lost a race with another user claiming the same name
+			if IsUniqueViolation(err) {
+				return nil, ErrUsernameTaken
+			}
+			return nil, fmt.Errorf("failed to update username: %w", err)
+		}
+	}
+
+	if name != nil {
+		_, err := db.Exec("UPDATE users SET name = ?, name_overridden = 1 WHERE id = ?", *name, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update name: %w", err)
+		}
+	}
+
+	var user types.User
+	err := db.QueryRow(`
+		SELECT id, auth0_id, email, name, username, avatar_url, created_at
+		FROM users
+		WHERE id = ?
+	`, userID).Scan(&user.ID, &user.Auth0ID, &user.Email, &user.Name, &user.Username, &user.AvatarURL, &user.Created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
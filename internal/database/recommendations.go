@@ -0,0 +1,82 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"moviedb/internal/types"
+)
+
+// GetRecommendationSeeds returns up to limit of the user's movies rated at least minRating,
+// highest rated and most recently updated first
+func GetRecommendationSeeds(db *sql.DB, userID int, minRating float64, limit int) ([]types.RecommendationSeed, error) {
+	rows, err := db.Query(`
+		SELECT m.tmdb_id, m.title, um.rating, m.genres
+		FROM user_movies um
+		JOIN movies m ON m.id = um.movie_id
+		WHERE um.user_id = ? AND um.rating >= ?
+		ORDER BY um.rating DESC, um.updated_at DESC
+		LIMIT ?
+	`, userID, minRating, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get highly rated movies: %w", err)
+	}
+	defer rows.Close()
+
+	seeds := []types.RecommendationSeed{}
+	for rows.Next() {
+		var seed types.RecommendationSeed
+		var genres sql.NullString
+		if err := rows.Scan(&seed.TMDBID, &seed.Title, &seed.Rating, &genres); err != nil {
+			return nil, fmt.Errorf("failed to read highly rated movie: %w", err)
+		}
+
+		seed.Genres = []string{}
+		if genres.Valid {
+			// Genres are a JSON array of names; a malformed value just means no genres
+			json.Unmarshal([]byte(genres.String), &seed.Genres)
+		}
+		seeds = append(seeds, seed)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read highly rated movies: %w", err)
+	}
+
+	return seeds, nil
+}
+
+// GetUserKnownTMDBIDs returns the TMDB ids of every movie the user has a user_movies row for
+// or has in any of their lists
+func GetUserKnownTMDBIDs(db *sql.DB, userID int) (map[int]bool, error) {
+	rows, err := db.Query(`
+		SELECT m.tmdb_id
+		FROM user_movies um
+		JOIN movies m ON m.id = um.movie_id
+		WHERE um.user_id = ?
+		UNION
+		SELECT m.tmdb_id
+		FROM list_movies lm
+		JOIN lists l ON l.id = lm.list_id
+		JOIN movies m ON m.id = lm.movie_id
+		WHERE l.user_id = ? AND l.deleted_at IS NULL
+	`, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user's movies: %w", err)
+	}
+	defer rows.Close()
+
+	known := make(map[int]bool)
+	for rows.Next() {
+		var tmdbID int
+		if err := rows.Scan(&tmdbID); err != nil {
+			return nil, fmt.Errorf("failed to read user's movie: %w", err)
+		}
+		known[tmdbID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read user's movies: %w", err)
+	}
+
+	return known, nil
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"moviedb/internal/types"
+)
+
+// How much of the stats summary is returned
+const (
+	statsMonths    = 12
+	statsTopGenres = 10
+)
+
+// userPlays selects a (movie_id, watched_at) row per time userID watched a movie: every watch
+// log entry, plus movies marked watched some other way (status updates, imports, Plex) that
+// have no log entries. Takes the user id twice and then the watched status.
+const userPlays = `
+	SELECT wl.movie_id, wl.watched_at
+	FROM movie_watch_log wl
+	WHERE wl.user_id = ?
+	UNION ALL
+	SELECT um.movie_id, um.watched_date
+	FROM user_movies um
+	WHERE um.user_id = ? AND um.status = ? AND NOT EXISTS (
+		SELECT 1 FROM movie_watch_log wl WHERE wl.user_id = um.user_id AND wl.movie_id = um.movie_id
+	)`
+
+// GetUserMovieStats summarizes userID's movies: watched movies, plays (rewatches count
+// again) and their runtime, ratings, plays per month for the 12 months up to now, and the
+// most watched genres
+func GetUserMovieStats(db *sql.DB, userID int, now time.Time) (*types.MovieStats, error) {
+	stats := &types.MovieStats{RatingScale: types.UserRatingScale}
+
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM user_movies WHERE user_id = ? AND status = ?
+	`, userID, types.MovieStatusWatched).Scan(&stats.TotalWatched)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count watched movies: %w", err)
+	}
+
+	var runtimeMinutes int
+	err = db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(m.runtime), 0)
+		FROM (`+userPlays+`) p
+		JOIN movies m ON m.id = p.movie_id
+	`, userID, userID, types.MovieStatusWatched).Scan(&stats.TotalPlays, &runtimeMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count plays: %w", err)
+	}
+	stats.TotalRuntimeHours = math.Round(float64(runtimeMinutes)/60*10) / 10
+
+	if err := addRatingStats(db, userID, stats); err != nil {
+		return nil, err
+	}
+	if err := addMonthlyStats(db, userID, now, stats); err != nil {
+		return nil, err
+	}
+	if err := addGenreStats(db, userID, stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// addRatingStats fills in the average rating and how many movies got each rating
+func addRatingStats(db *sql.DB, userID int, stats *types.MovieStats) error {
+	rows, err := db.Query(`
+		SELECT rating, COUNT(*)
+		FROM user_movies
+		WHERE user_id = ? AND rating IS NOT NULL
+		GROUP BY rating
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get rating distribution: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[float64]int)
+	total := 0.0
+	for rows.Next() {
+		var rating float64
+		var count int
+		if err := rows.Scan(&rating, &count); err != nil {
+			return fmt.Errorf("failed to read rating distribution: %w", err)
+		}
+		counts[types.RoundRating(rating)] += count
+		stats.RatedCount += count
+		total += rating * float64(count)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rating distribution: %w", err)
+	}
+
+	values := types.RatingValues()
+	stats.RatingDistribution = make([]types.RatingCount, 0, len(values))
+	for _, rating := range values {
+		stats.RatingDistribution = append(stats.RatingDistribution, types.RatingCount{Rating: rating, Count: counts[rating]})
+	}
+	if stats.RatedCount > 0 {
+		average := math.Round(total/float64(stats.RatedCount)*100) / 100
+		stats.AverageRating = &average
+	}
+
+	return nil
+}
+
+// addMonthlyStats fills in how many plays there were in each of the last 12 months,
+// including the current one
+func addMonthlyStats(db *sql.DB, userID int, now time.Time, stats *types.MovieStats) error {
+	firstMonth := time.Date(now.Year(), now.Month()-statsMonths+1, 1, 0, 0, 0, 0, now.Location())
+
+	month := DialectOf(db).YearMonth("p.watched_at")
+	rows, err := db.Query(`
+		SELECT `+month+` AS month, COUNT(*)
+		FROM (`+userPlays+`) p
+		WHERE p.watched_at IS NOT NULL AND `+month+` >= ?
+		GROUP BY month
+	`, userID, userID, types.MovieStatusWatched, firstMonth.Format("2006-01"))
+	if err != nil {
+		return fmt.Errorf("failed to get watched per month: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var month string
+		var count int
+		if err := rows.Scan(&month, &count); err != nil {
+			return fmt.Errorf("failed to read watched per month: %w", err)
+		}
+		counts[month] = count
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read watched per month: %w", err)
+	}
+
+	stats.WatchedPerMonth = make([]types.MonthCount, 0, statsMonths)
+	for i := 0; i < statsMonths; i++ {
+		month := firstMonth.AddDate(0, i, 0).Format("2006-01")
+		stats.WatchedPerMonth = append(stats.WatchedPerMonth, types.MonthCount{Month: month, Count: counts[month]})
+	}
+
+	return nil
+}
+
+// addGenreStats fills in the most watched genres by plays, from the movies' genres JSON
+func addGenreStats(db *sql.DB, userID int, stats *types.MovieStats) error {
+	rows, err := db.Query(`
+		SELECT g.value, COUNT(*) AS watched
+		FROM (`+userPlays+`) p
+		JOIN movies m ON m.id = p.movie_id
+		CROSS JOIN `+DialectOf(db).JSONArrayElements("m.genres", "g")+`
+		GROUP BY g.value
+		ORDER BY watched DESC, g.value
+		LIMIT ?
+	`, userID, userID, types.MovieStatusWatched, statsTopGenres)
+	if err != nil {
+		return fmt.Errorf("failed to get top genres: %w", err)
+	}
+	defer rows.Close()
+
+	stats.TopGenres = []types.GenreCount{}
+	for rows.Next() {
+		var genre types.GenreCount
+		if err := rows.Scan(&genre.Genre, &genre.Count); err != nil {
+			return fmt.Errorf("failed to read top genres: %w", err)
+		}
+		stats.TopGenres = append(stats.TopGenres, genre)
+	}
+
+	return rows.Err()
+}
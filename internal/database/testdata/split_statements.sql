@@ -0,0 +1,21 @@
+-- Fixture for splitSQLStatements: semicolons in strings and comments, a trigger body
+-- and trailing whitespace; don't end a statement early; (this comment has one)
+
+CREATE TABLE notes (
+    id INTEGER PRIMARY KEY,
+    body TEXT NOT NULL DEFAULT 'a; b' -- default has a semicolon; so does this comment
+);
+
+/* A block comment;
+   spanning lines; with semicolons */
+INSERT INTO notes (body) VALUES ('it''s; quoted'), ("double; quoted");   
+
+CREATE TRIGGER notes_touch AFTER UPDATE ON notes
+BEGIN
+    UPDATE notes SET body = CASE WHEN NEW.body = '' THEN 'empty;' ELSE NEW.body END WHERE id = NEW.id;
+END;
+
+;;  
+	
+UPDATE notes SET body = 'last; one' WHERE id = 1  
+   	  
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"moviedb/internal/types"
+)
+
+const userMovieColumns = `id, user_id, movie_id, status, rating, watched_date, notes, owned_formats, favorite, created_at, updated_at`
+
+// GetMovieIDByTMDBID returns the local movie id for a TMDB id without fetching it (sql.ErrNoRows if not cached)
+func GetMovieIDByTMDBID(db *sql.DB, tmdbID int) (int, error) {
+	var movieID int
+	err := db.QueryRow("SELECT id FROM movies WHERE tmdb_id = ?", tmdbID).Scan(&movieID)
+	return movieID, err
+}
+
+// GetUserMovie returns the user's interaction row for a movie (sql.ErrNoRows if none)
+func GetUserMovie(db *sql.DB, userID, movieID int) (*types.UserMovie, error) {
+	var um types.UserMovie
+	err := db.QueryRow(`
+		SELECT `+userMovieColumns+`
+		FROM user_movies
+		WHERE user_id = ? AND movie_id = ?
+	`, userID, movieID).Scan(&um.ID, &um.UserID, &um.MovieID, &um.Status, &um.Rating, &um.WatchedDate,
+		&um.Notes, &um.OwnedFormats, &um.Favorite, &um.Created, &um.Updated)
+	if err != nil {
+		return nil, err
+	}
+
+	return &um, nil
+}
+
+// GetOrCreateUserMovie returns the user's interaction row for a movie, creating an empty one if needed
+func GetOrCreateUserMovie(db *sql.DB, userID, movieID int) (*types.UserMovie, error) {
+	_, err := db.Exec(`
+		INSERT INTO user_movies (user_id, movie_id, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, movie_id) DO NOTHING
+	`, userID, movieID, types.MovieStatusNotWatched, time.Now(), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user movie: %w", err)
+	}
+
+	um, err := GetUserMovie(db, userID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user movie: %w", err)
+	}
+
+	return um, nil
+}
+
+// ToggleUserMovieFavorite flips the favorite flag for a user movie and returns the updated row
+func ToggleUserMovieFavorite(db *sql.DB, userID, movieID int) (*types.UserMovie, error) {
+	if _, err := GetOrCreateUserMovie(db, userID, movieID); err != nil {
+		return nil, err
+	}
+
+	_, err := db.Exec(`
+		UPDATE user_movies
+		SET favorite = NOT favorite, updated_at = ?
+		WHERE user_id = ? AND movie_id = ?
+	`, time.Now(), userID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to toggle favorite: %w", err)
+	}
+
+	um, err := GetUserMovie(db, userID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user movie: %w", err)
+	}
+
+	return um, nil
+}
+
+// GetUserMovieFormats returns the owned formats recorded for a user movie
+func GetUserMovieFormats(db *sql.DB, userMovieID int) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT format
+		FROM user_movie_formats
+		WHERE user_movie_id = ?
+		ORDER BY id
+	`, userMovieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query owned formats: %w", err)
+	}
+	defer rows.Close()
+
+	formats := []string{}
+	for rows.Next() {
+		var format string
+		if err := rows.Scan(&format); err != nil {
+			continue
+		}
+		formats = append(formats, format)
+	}
+
+	return formats, nil
+}
+
+// SetUserMovieFormats replaces the owned formats for a user movie.
+// The normalized user_movie_formats rows are the source of truth; the legacy
+// owned_formats JSON column is kept in sync so existing readers keep working.
+func SetUserMovieFormats(db *sql.DB, userMovieID int, formats []string) error {
+	normalized := make([]string, 0, len(formats))
+	seen := make(map[string]bool)
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" || seen[format] {
+			continue
+		}
+		seen[format] = true
+		normalized = append(normalized, format)
+	}
+
+	formatsJSON, err := json.Marshal(normalized)
+	if err != nil {
+		return fmt.Errorf("failed to encode owned formats: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_movie_formats WHERE user_movie_id = ?", userMovieID); err != nil {
+		return fmt.Errorf("failed to clear owned formats: %w", err)
+	}
+
+	for _, format := range normalized {
+		if _, err := tx.Exec(`
+			INSERT INTO user_movie_formats (user_movie_id, format)
+			VALUES (?, ?)
+		`, userMovieID, format); err != nil {
+			return fmt.Errorf("failed to store owned format %s: %w", format, err)
+		}
+	}
+
+	var ownedFormats interface{}
+	if len(normalized) > 0 {
+		ownedFormats = string(formatsJSON)
+	}
+	if _, err := tx.Exec(`
+		UPDATE user_movies
+		SET owned_formats = ?, updated_at = ?
+		WHERE id = ?
+	`, ownedFormats, time.Now(), userMovieID); err != nil {
+		return fmt.Errorf("failed to update user movie: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// upsertUserMovieStatus sets the status of a user movie, keeping the first watched_date
+const upsertUserMovieStatus = `
+	INSERT INTO user_movies (user_id, movie_id, status, watched_date, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(user_id, movie_id) DO UPDATE SET
+		status = excluded.status,
+		watched_date = COALESCE(user_movies.watched_date, excluded.watched_date),
+		updated_at = excluded.updated_at
+`
+
+// SetUserMovieStatus upserts the watch status for a user movie and returns the updated row.
+// Marking a movie watched sets watched_date the first time.
+func SetUserMovieStatus(db *sql.DB, userID, movieID int, status string) (*types.UserMovie, error) {
+	now := time.Now()
+	var watchedDate interface{}
+	if status == types.MovieStatusWatched {
+		watchedDate = now
+	}
+
+	_, err := db.Exec(upsertUserMovieStatus, userID, movieID, status, watchedDate, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update movie status: %w", err)
+	}
+
+	um, err := GetUserMovie(db, userID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user movie: %w", err)
+	}
+
+	return um, nil
+}
+
+// SetUserMovieStatuses sets the same watch status for several of a user's movies in a single
+// transaction, so either all of them are updated or none are
+func SetUserMovieStatuses(db *sql.DB, userID int, movieIDs []int, status string) error {
+	now := time.Now()
+	var watchedDate interface{}
+	if status == types.MovieStatusWatched {
+		watchedDate = now
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(upsertUserMovieStatus)
+	if err != nil {
+		return fmt.Errorf("failed to prepare status update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, movieID := range movieIDs {
+		if _, err := stmt.Exec(userID, movieID, status, watchedDate, now, now); err != nil {
+			return fmt.Errorf("failed to update status of movie %d: %w", movieID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetUserMovieRating stores a rating for a user movie and returns the updated row.
+// Rating a movie implies it was watched, so the status is promoted to watched
+// and watched_date is stamped if it wasn't already.
+func SetUserMovieRating(db *sql.DB, userID, movieID int, rating float64) (*types.UserMovie, error) {
+	now := time.Now()
+	_, err := db.Exec(`
+		INSERT INTO user_movies (user_id, movie_id, status, rating, watched_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, movie_id) DO UPDATE SET
+			rating = excluded.rating,
+			status = excluded.status,
+			watched_date = COALESCE(user_movies.watched_date, excluded.watched_date),
+			updated_at = excluded.updated_at
+	`, userID, movieID, types.MovieStatusWatched, rating, now, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save rating: %w", err)
+	}
+
+	um, err := GetUserMovie(db, userID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user movie: %w", err)
+	}
+
+	return um, nil
+}
+
+// SetUserMovieNotes stores (or clears, when notes is nil) the user's notes for a movie
+func SetUserMovieNotes(db *sql.DB, userID, movieID int, notes *string) (*types.UserMovie, error) {
+	now := time.Now()
+	_, err := db.Exec(`
+		INSERT INTO user_movies (user_id, movie_id, status, notes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, movie_id) DO UPDATE SET
+			notes = excluded.notes,
+			updated_at = excluded.updated_at
+	`, userID, movieID, types.MovieStatusNotWatched, notes, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save notes: %w", err)
+	}
+
+	um, err := GetUserMovie(db, userID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user movie: %w", err)
+	}
+
+	return um, nil
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"moviedb/internal/types"
+)
+
+const watchLogColumns = `id, user_id, movie_id, watched_at, rating, created_at`
+
+// GetWatchLogEntry returns a watch log entry by id
+func GetWatchLogEntry(db *sql.DB, entryID int) (*types.WatchLogEntry, error) {
+	var entry types.WatchLogEntry
+	err := db.QueryRow(`SELECT `+watchLogColumns+` FROM movie_watch_log WHERE id = ?`, entryID).Scan(
+		&entry.ID, &entry.UserID, &entry.MovieID, &entry.WatchedAt, &entry.Rating, &entry.Created)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetMovieWatchLog returns every time userID watched a movie, most recent first
+func GetMovieWatchLog(db *sql.DB, userID, movieID int) ([]types.WatchLogEntry, error) {
+	rows, err := db.Query(`
+		SELECT `+watchLogColumns+`
+		FROM movie_watch_log
+		WHERE user_id = ? AND movie_id = ?
+		ORDER BY watched_at DESC, id DESC
+	`, userID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watch log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []types.WatchLogEntry{}
+	for rows.Next() {
+		var entry types.WatchLogEntry
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.MovieID, &entry.WatchedAt, &entry.Rating, &entry.Created); err != nil {
+			return nil, fmt.Errorf("failed to read watch log entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read watch log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetUserWatchLog returns a page of userID's watches across all movies, most recent first
+func GetUserWatchLog(db *sql.DB, userID, limit, offset int) ([]types.WatchLogItem, error) {
+	rows, err := db.Query(`
+		SELECT wl.id, wl.user_id, wl.movie_id, wl.watched_at, wl.rating, wl.created_at,
+		       m.tmdb_id, m.title, m.year, m.poster_url
+		FROM movie_watch_log wl
+		JOIN movies m ON m.id = wl.movie_id
+		WHERE wl.user_id = ?
+		ORDER BY wl.watched_at DESC, wl.id DESC
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watch log: %w", err)
+	}
+	defer rows.Close()
+
+	items := []types.WatchLogItem{}
+	for rows.Next() {
+		var item types.WatchLogItem
+		if err := rows.Scan(&item.ID, &item.UserID, &item.MovieID, &item.WatchedAt, &item.Rating, &item.Created,
+			&item.TMDBID, &item.Title, &item.Year, &item.PosterURL); err != nil {
+			return nil, fmt.Errorf("failed to read watch log entry: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read watch log: %w", err)
+	}
+
+	return items, nil
+}
+
+// AddWatchLogEntry records userID watching a movie and marks the movie watched, with
+// watched_date the most recent watch. A movie marked watched before it had any log entries
+// first gets an entry for that watch, so adding a rewatch doesn't lose it.
+func AddWatchLogEntry(db *sql.DB, userID, movieID int, watchedAt time.Time, rating *float64) (*types.WatchLogEntry, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.Exec(`
+		INSERT INTO movie_watch_log (user_id, movie_id, watched_at, created_at)
+		SELECT user_id, movie_id, watched_date, ?
+		FROM user_movies um
+		WHERE um.user_id = ? AND um.movie_id = ? AND um.watched_date IS NOT NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM movie_watch_log wl WHERE wl.user_id = um.user_id AND wl.movie_id = um.movie_id
+		  )
+	`, now, userID, movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log earlier watch: %w", err)
+	}
+
+	var entryID int
+	err = tx.QueryRow(`
+		INSERT INTO movie_watch_log (user_id, movie_id, watched_at, rating, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, userID, movieID, watchedAt, rating, now).Scan(&entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add watch: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_movies (user_id, movie_id, status, watched_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, movie_id) DO UPDATE SET
+			status = excluded.status,
+			updated_at = excluded.updated_at
+	`, userID, movieID, types.MovieStatusWatched, watchedAt, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark movie watched: %w", err)
+	}
+	if err := syncWatchedDate(tx, userID, movieID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit watch: %w", err)
+	}
+
+	return GetWatchLogEntry(db, entryID)
+}
+
+// UpdateWatchLogEntry changes when a watch happened and its rating
+func UpdateWatchLogEntry(db *sql.DB, entry *types.WatchLogEntry, watchedAt time.Time, rating *float64) (*types.WatchLogEntry, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE movie_watch_log SET watched_at = ?, rating = ? WHERE id = ?", watchedAt, rating, entry.ID); err != nil {
+		return nil, fmt.Errorf("failed to update watch: %w", err)
+	}
+	if err := syncWatchedDate(tx, entry.UserID, entry.MovieID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit watch: %w", err)
+	}
+
+	return GetWatchLogEntry(db, entry.ID)
+}
+
+// DeleteWatchLogEntry removes a watch. The movie keeps its status; its watched_date falls
+// back to the previous watch, or is cleared when none are left.
+func DeleteWatchLogEntry(db *sql.DB, entry *types.WatchLogEntry) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM movie_watch_log WHERE id = ?", entry.ID); err != nil {
+		return fmt.Errorf("failed to delete watch: %w", err)
+	}
+	if err := syncWatchedDate(tx, entry.UserID, entry.MovieID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// syncWatchedDate sets a user movie's watched_date to its most recent logged watch
+func syncWatchedDate(tx *sql.Tx, userID, movieID int) error {
+	_, err := tx.Exec(`
+		UPDATE user_movies
+		SET watched_date = (
+			SELECT MAX(watched_at) FROM movie_watch_log WHERE user_id = ? AND movie_id = ?
+		), updated_at = ?
+		WHERE user_id = ? AND movie_id = ?
+	`, userID, movieID, time.Now(), userID, movieID)
+	if err != nil {
+		return fmt.Errorf("failed to update watched date: %w", err)
+	}
+	return nil
+}
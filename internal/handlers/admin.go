@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"moviedb/internal/database"
+)
+
+// AdminHandler serves operational endpoints for admins
+type AdminHandler struct {
+	db *sql.DB
+}
+
+func NewAdminHandler(db *sql.DB) *AdminHandler {
+	return &AdminHandler{db: db}
+}
+
+// GetMigrations reports which schema migrations are applied and which the running binary
+// knows about, to spot schema drift after a deploy. Pending migrations are known but not
+// applied; unknown ones are applied by a newer binary than this one.
+func (h *AdminHandler) GetMigrations(w http.ResponseWriter, r *http.Request) {
+	states, err := database.GetMigrationStates(h.db)
+	if err != nil {
+		http.Error(w, "Failed to get migrations", http.StatusInternalServerError)
+		return
+	}
+
+	applied := []int{}
+	known := []int{}
+	pending := []int{}
+	unknown := []int{}
+	currentVersion, latestVersion := 0, 0
+
+	for _, state := range states {
+		if state.Applied {
+			applied = append(applied, state.Version)
+			currentVersion = state.Version
+		}
+		if state.Known {
+			known = append(known, state.Version)
+			latestVersion = state.Version
+		}
+		if state.Known && !state.Applied {
+			pending = append(pending, state.Version)
+		}
+		if state.Applied && !state.Known {
+			unknown = append(unknown, state.Version)
+		}
+	}
+
+	response := map[string]interface{}{
+		"current_version":  currentVersion,
+		"latest_version":   latestVersion,
+		"up_to_date":       len(pending) == 0 && len(unknown) == 0,
+		"applied_versions": applied,
+		"known_versions":   known,
+		"pending":          pending,
+		"unknown_applied":  unknown,
+		"migrations":       states,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
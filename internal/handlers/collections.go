@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
+	"moviedb/internal/services"
+	"moviedb/internal/utils"
+)
+
+type CollectionHandler struct {
+	db         *sql.DB
+	tmdbClient *services.TMDBClient
+}
+
+func NewCollectionHandler(db *sql.DB, tmdbClient *services.TMDBClient) *CollectionHandler {
+	return &CollectionHandler{
+		db:         db,
+		tmdbClient: tmdbClient,
+	}
+}
+
+// GetCollectionOwnership lists a TMDB collection's movies and marks which ones the user
+// has on Plex or owns in a physical/digital format ("you own 5 of 8")
+func (h *CollectionHandler) GetCollectionOwnership(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	collectionID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil || collectionID <= 0 {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	collection, err := h.tmdbClient.GetCollection(collectionID)
+	if err != nil {
+		http.Error(w, "Collection not found", http.StatusNotFound)
+		return
+	}
+
+	// Oldest first, unreleased entries last
+	parts := collection.Parts
+	sort.SliceStable(parts, func(i, j int) bool {
+		if parts[i].ReleaseDate == "" || parts[j].ReleaseDate == "" {
+			return parts[j].ReleaseDate == "" && parts[i].ReleaseDate != ""
+		}
+		return parts[i].ReleaseDate < parts[j].ReleaseDate
+	})
+
+	tmdbIDs := make([]int, len(parts))
+	for i, part := range parts {
+		tmdbIDs[i] = part.ID
+	}
+
+	onPlex, err := h.getPlexTMDBIDs(user.ID, tmdbIDs)
+	if err != nil {
+		http.Error(w, "Failed to check Plex availability", http.StatusInternalServerError)
+		return
+	}
+
+	ownedFormats, err := h.getOwnedFormats(user.ID, tmdbIDs)
+	if err != nil {
+		http.Error(w, "Failed to check owned formats", http.StatusInternalServerError)
+		return
+	}
+
+	movies := make([]map[string]interface{}, 0, len(parts))
+	ownedCount := 0
+	for _, part := range parts {
+		formats := ownedFormats[part.ID]
+		if formats == nil {
+			formats = []string{}
+		}
+		owned := onPlex[part.ID] || len(formats) > 0
+		if owned {
+			ownedCount++
+		}
+
+		movies = append(movies, map[string]interface{}{
+			"tmdb_id":       part.ID,
+			"title":         part.Title,
+			"year":          services.ExtractYear(part.ReleaseDate),
+			"poster_url":    h.tmdbClient.GetPosterURL(part.PosterPath, "w500"),
+			"on_plex":       onPlex[part.ID],
+			"owned_formats": formats,
+			"owned":         owned,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":          collection.ID,
+		"name":        collection.Name,
+		"overview":    collection.Overview,
+		"poster_url":  h.tmdbClient.GetPosterURL(collection.PosterPath, "w500"),
+		"movies":      movies,
+		"owned_count": ownedCount,
+		"total":       len(movies),
+	})
+}
+
+// getPlexTMDBIDs returns which of the TMDB ids are in a Plex library the user can access
+func (h *CollectionHandler) getPlexTMDBIDs(userID int, tmdbIDs []int) (map[int]bool, error) {
+	found := make(map[int]bool)
+	if len(tmdbIDs) == 0 {
+		return found, nil
+	}
+
+	args := []interface{}{userID}
+	for _, id := range tmdbIDs {
+		args = append(args, id)
+	}
+
+	rows, err := h.db.Query(`
+		SELECT DISTINCT pli.tmdb_id
+		FROM plex_library_items pli
+		JOIN user_plex_access upa ON pli.library_id = upa.library_id
+		WHERE upa.user_id = ? AND upa.is_active = 1 AND pli.is_active = 1
+		  AND pli.tmdb_id IN (`+placeholders(len(tmdbIDs))+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tmdbID int
+		if err := rows.Scan(&tmdbID); err != nil {
+			continue
+		}
+		found[tmdbID] = true
+	}
+
+	return found, nil
+}
+
+// getOwnedFormats returns the user's owned formats keyed by TMDB id
+func (h *CollectionHandler) getOwnedFormats(userID int, tmdbIDs []int) (map[int][]string, error) {
+	formats := make(map[int][]string)
+	if len(tmdbIDs) == 0 {
+		return formats, nil
+	}
+
+	args := []interface{}{userID}
+	for _, id := range tmdbIDs {
+		args = append(args, id)
+	}
+
+	rows, err := h.db.Query(`
+		SELECT m.tmdb_id, umf.format
+		FROM user_movie_formats umf
+		JOIN user_movies um ON umf.user_movie_id = um.id
+		JOIN movies m ON um.movie_id = m.id
+		WHERE um.user_id = ? AND m.tmdb_id IN (`+placeholders(len(tmdbIDs))+`)
+		ORDER BY umf.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tmdbID int
+		var format string
+		if err := rows.Scan(&tmdbID, &format); err != nil {
+			continue
+		}
+		formats[tmdbID] = append(formats[tmdbID], format)
+	}
+
+	return formats, nil
+}
+
+// placeholders returns "?, ?, ..." for n query arguments
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
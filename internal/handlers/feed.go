@@ -2,38 +2,411 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
+	"moviedb/internal/services"
+	"moviedb/internal/types"
+	"moviedb/internal/utils"
 )
 
+// maxCommentLength is the maximum number of characters in a comment
+const maxCommentLength = 2000
+
+// nowPlayingConcurrency bounds how many friends' Plex servers are queried at once
+const nowPlayingConcurrency = 4
+
 type FeedHandler struct {
-	db *sql.DB
+	db         *sql.DB
+	nowPlaying *services.NowPlayingService
+	logger     *slog.Logger
 }
 
-func NewFeedHandler(db *sql.DB) *FeedHandler {
-	return &FeedHandler{db: db}
+func NewFeedHandler(db *sql.DB, nowPlaying *services.NowPlayingService) *FeedHandler {
+	return &FeedHandler{db: db, nowPlaying: nowPlaying, logger: slog.Default()}
 }
 
+// GetFriendsFeed returns the posts of the user and their friends, newest first
 func (h *FeedHandler) GetFriendsFeed(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement friends feed
-	w.WriteHeader(http.StatusNotImplemented)
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, limit := feedPage(r)
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	posts, err := database.GetFriendsFeed(h.db, user.ID, limit, (page-1)*limit)
+	if err != nil {
+		http.Error(w, "Failed to get feed", http.StatusInternalServerError)
+		return
+	}
+
+	writeFeed(w, posts, page, limit)
 }
 
+// GetGlobalFeed returns everyone's posts, newest first
 func (h *FeedHandler) GetGlobalFeed(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement global feed
-	w.WriteHeader(http.StatusNotImplemented)
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, limit := feedPage(r)
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	posts, err := database.GetGlobalFeed(h.db, user.ID, limit, (page-1)*limit)
+	if err != nil {
+		http.Error(w, "Failed to get feed", http.StatusInternalServerError)
+		return
+	}
+
+	writeFeed(w, posts, page, limit)
+}
+
+// feedPage reads ?page= and ?limit= (20 per page by default, at most 100)
+func feedPage(r *http.Request) (int, int) {
+	page := utils.GetQueryParamInt(r, "page", 1)
+	limit := utils.GetQueryParamInt(r, "limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+func writeFeed(w http.ResponseWriter, posts []types.FeedItem, page, limit int) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"posts":        posts,
+		"page":         page,
+		"limit":        limit,
+		"rating_scale": types.UserRatingScale, // Scale of the rated posts' ratings
+	})
 }
 
+// LikePost marks a post as liked by the user
 func (h *FeedHandler) LikePost(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement like post
-	w.WriteHeader(http.StatusNotImplemented)
+	h.setPostLike(w, r, true)
 }
 
+// UnlikePost removes the user's like from a post
 func (h *FeedHandler) UnlikePost(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement unlike post
-	w.WriteHeader(http.StatusNotImplemented)
+	h.setPostLike(w, r, false)
+}
+
+// setPostLike likes or unlikes a post and responds with its new like count
+func (h *FeedHandler) setPostLike(w http.ResponseWriter, r *http.Request, liked bool) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := database.GetFeedPostOwner(h.db, postID); err == sql.ErrNoRows {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get post", http.StatusInternalServerError)
+		return
+	}
+
+	if liked {
+		err = database.LikePost(h.db, postID, user.ID)
+	} else {
+		err = database.UnlikePost(h.db, postID, user.ID)
+	}
+	if err != nil {
+		http.Error(w, "Failed to update like", http.StatusInternalServerError)
+		return
+	}
+
+	likeCount, err := database.CountPostLikes(h.db, postID)
+	if err != nil {
+		http.Error(w, "Failed to count likes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"post_id":     postID,
+		"liked_by_me": liked,
+		"like_count":  likeCount,
+	})
 }
 
+// AddComment adds the user's comment to a post
 func (h *FeedHandler) AddComment(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement add comment
-	w.WriteHeader(http.StatusNotImplemented)
-}
\ No newline at end of file
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.AddCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	content, ok := validateCommentContent(w, req.Content)
+	if !ok {
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := database.GetFeedPostOwner(h.db, postID); err == sql.ErrNoRows {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get post", http.StatusInternalServerError)
+		return
+	}
+
+	comment, err := database.AddPostComment(h.db, postID, user.ID, content)
+	if err != nil {
+		http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// UpdateComment changes the content of one of the user's comments
+func (h *FeedHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.UpdateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	content, ok := validateCommentContent(w, req.Content)
+	if !ok {
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	comment, err := database.GetPostComment(h.db, commentID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get comment", http.StatusInternalServerError)
+		return
+	}
+	if comment.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	comment, err = database.UpdatePostComment(h.db, commentID, content)
+	if err != nil {
+		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// DeleteComment removes a comment. Its author and the owner of the post it's on can delete it.
+func (h *FeedHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	comment, err := database.GetPostComment(h.db, commentID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get comment", http.StatusInternalServerError)
+		return
+	}
+
+	if comment.UserID != user.ID {
+		postOwnerID, err := database.GetFeedPostOwner(h.db, comment.PostID)
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, "Failed to get post", http.StatusInternalServerError)
+			return
+		}
+		if err == sql.ErrNoRows || postOwnerID != user.ID {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := database.DeletePostComment(h.db, commentID); err != nil {
+		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// validateCommentContent trims a comment and checks it isn't empty or too long, writing a
+// 400 and reporting false when it is
+func validateCommentContent(w http.ResponseWriter, content string) (string, bool) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		http.Error(w, "Comment can't be empty", http.StatusBadRequest)
+		return "", false
+	}
+	if utf8.RuneCountInString(content) > maxCommentLength {
+		http.Error(w, fmt.Sprintf("Comment must be at most %d characters", maxCommentLength), http.StatusBadRequest)
+		return "", false
+	}
+	return content, true
+}
+
+// GetFriendsNowPlaying returns what the user's friends are currently watching on Plex. Friends
+// who turned off sharing, or whose Plex can't be reached, are left out.
+func (h *FeedHandler) GetFriendsNowPlaying(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	friends, err := database.GetFriendsSharingNowPlaying(h.db, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get friends", http.StatusInternalServerError)
+		return
+	}
+
+	playing := make([][]services.NowPlayingItem, len(friends))
+	sem := make(chan struct{}, nowPlayingConcurrency)
+	var wg sync.WaitGroup
+	for i, friend := range friends {
+		wg.Add(1)
+		go func(i, friendID int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			items, err := h.nowPlaying.GetNowPlaying(r.Context(), int64(friendID))
+			if err != nil {
+				h.logger.Warn("Failed to get now playing for friend", "friend_id", friendID, "error", err)
+				return
+			}
+			playing[i] = items
+		}(i, friend.ID)
+	}
+	wg.Wait()
+
+	entries := []map[string]interface{}{}
+	for i, friend := range friends {
+		if len(playing[i]) == 0 {
+			continue
+		}
+		entries = append(entries, map[string]interface{}{
+			"user": map[string]interface{}{
+				"id":         friend.ID,
+				"auth0_id":   friend.Auth0ID,
+				"name":       friend.Name,
+				"username":   friend.Username,
+				"avatar_url": friend.AvatarURL,
+			},
+			"now_playing": playing[i],
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"friends": entries,
+	})
+}
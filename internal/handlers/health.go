@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"moviedb/internal/services"
+)
+
+// How long the readiness check waits for the database to answer
+const healthDBTimeout = 2 * time.Second
+
+// HealthHandler serves the liveness and readiness checks
+type HealthHandler struct {
+	db          *sql.DB
+	tmdbClient  *services.TMDBClient
+	jobManager  *services.JobManager
+	rateLimiter *services.TMDBRateLimiter
+}
+
+func NewHealthHandler(db *sql.DB, tmdbClient *services.TMDBClient, jobManager *services.JobManager, rateLimiter *services.TMDBRateLimiter) *HealthHandler {
+	return &HealthHandler{
+		db:          db,
+		tmdbClient:  tmdbClient,
+		jobManager:  jobManager,
+		rateLimiter: rateLimiter,
+	}
+}
+
+// Ready reports the state of each subsystem. It is 200 when the database is reachable and
+// 503 otherwise; the other subsystems are reported but don't make the server unready.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthDBTimeout)
+	defer cancel()
+
+	status, code := "ok", http.StatusOK
+	dbStatus := map[string]interface{}{"ok": true}
+	if err := h.db.PingContext(ctx); err != nil {
+		status, code = "unavailable", http.StatusServiceUnavailable
+		dbStatus = map[string]interface{}{"ok": false, "error": err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   status,
+		"database": dbStatus,
+		"job_manager": map[string]interface{}{
+			"running": h.jobManager.IsRunning(),
+		},
+		"rate_limiter": map[string]interface{}{
+			"available_tokens": h.rateLimiter.AvailableTokens(),
+		},
+		"tmdb": map[string]interface{}{
+			"api_key_valid": h.tmdbClient.IsValidAPIKey(),
+		},
+	})
+}
+
+// Live reports that the process is up, without checking anything, for liveness probes
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
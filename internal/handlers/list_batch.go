@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
+	"moviedb/internal/types"
+)
+
+// Limits for fetching lists in a batch
+const (
+	maxBatchLists  = 20
+	maxBatchMovies = 500 // Across all lists in the response
+)
+
+// batchList is a list as loaded for GetListsBatch
+type batchList struct {
+	id          int
+	userID      int
+	name        string
+	description string
+	isPublic    bool
+	createdAt   time.Time
+}
+
+// GetListsBatch returns several lists with their movies in one response, for views that show
+// many lists at once. Lists the user can't see, or that don't exist, are left out. Movies are
+// returned in list order until maxBatchMovies is reached; truncated marks lists cut short.
+func (h *ListHandler) GetListsBatch(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req types.BatchListsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.ListIDs) == 0 {
+		http.Error(w, "list_ids is required", http.StatusBadRequest)
+		return
+	}
+
+	// Drop duplicates, keeping the requested order
+	var listIDs []int
+	seen := make(map[int]bool)
+	for _, id := range req.ListIDs {
+		if !seen[id] {
+			seen[id] = true
+			listIDs = append(listIDs, id)
+		}
+	}
+	if len(listIDs) > maxBatchLists {
+		http.Error(w, fmt.Sprintf("At most %d lists can be fetched at once", maxBatchLists), http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	args := make([]interface{}, len(listIDs))
+	for i, id := range listIDs {
+		args[i] = id
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, user_id, name, description, is_public, created_at
+		FROM lists
+		WHERE id IN (?`+strings.Repeat(", ?", len(listIDs)-1)+`) AND deleted_at IS NULL
+	`, args...)
+	if err != nil {
+		http.Error(w, "Failed to get lists", http.StatusInternalServerError)
+		return
+	}
+
+	lists := make(map[int]batchList)
+	for rows.Next() {
+		var list batchList
+		if err := rows.Scan(&list.id, &list.userID, &list.name, &list.description, &list.isPublic, &list.createdAt); err != nil {
+			continue
+		}
+		// Check if user has access (owner or public list)
+		if list.userID != user.ID && !list.isPublic {
+			continue
+		}
+		lists[list.id] = list
+	}
+	rows.Close()
+
+	response := []map[string]interface{}{}
+	remaining := maxBatchMovies
+	for _, id := range listIDs {
+		list, ok := lists[id]
+		if !ok {
+			continue
+		}
+
+		movies, total, err := h.getBatchListMovies(list.id, remaining)
+		if err != nil {
+			http.Error(w, "Failed to get list movies", http.StatusInternalServerError)
+			return
+		}
+		remaining -= len(movies)
+
+		response = append(response, map[string]interface{}{
+			"id":          list.id,
+			"name":        list.name,
+			"description": list.description,
+			"is_public":   list.isPublic,
+			"created_at":  list.createdAt,
+			"movie_count": total,
+			"movies":      movies,
+			"is_owner":    list.userID == user.ID,
+			"truncated":   len(movies) < total,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"lists": response,
+	})
+}
+
+// getBatchListMovies returns up to limit movies of a list in list order, plus how many
+// movies the list has in total
+func (h *ListHandler) getBatchListMovies(listID, limit int) ([]map[string]interface{}, int, error) {
+	var total int
+	err := h.db.QueryRow(`
+		SELECT COUNT(DISTINCT lm.movie_id)
+		FROM list_movies lm
+		JOIN movies m ON lm.movie_id = m.id
+		WHERE lm.list_id = ?
+	`, listID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	movies := []map[string]interface{}{}
+	if limit <= 0 || total == 0 {
+		return movies, total, nil
+	}
+
+	rows, err := h.db.Query(`
+		SELECT DISTINCT m.id, m.tmdb_id, m.title, m.year, m.poster_url, m.synopsis, lm.position, lm.added_at, m.caching
+		FROM list_movies lm
+		JOIN movies m ON lm.movie_id = m.id
+		WHERE lm.list_id = ?
+		ORDER BY lm.position ASC, lm.added_at DESC
+		LIMIT ?
+	`, listID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID, tmdbID, position int
+		var title, synopsis string
+		var year *int
+		var posterURL *string
+		var addedAt time.Time
+		var caching bool
+
+		if err := rows.Scan(&movieID, &tmdbID, &title, &year, &posterURL, &synopsis, &position, &addedAt, &caching); err != nil {
+			continue
+		}
+
+		movie := map[string]interface{}{
+			"id":       movieID,
+			"tmdb_id":  tmdbID,
+			"title":    title,
+			"year":     year,
+			"synopsis": synopsis,
+			"position": position,
+			"added_at": addedAt,
+			"caching":  caching,
+		}
+
+		if posterURL != nil {
+			movie["poster_url"] = *posterURL
+		}
+
+		movies = append(movies, movie)
+	}
+
+	return movies, total, nil
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
+	"moviedb/internal/services"
+	"moviedb/internal/types"
+)
+
+// maxImportSize limits the size of uploaded import files
+const maxImportSize = 5 << 20
+
+// listImportInlineLimit is the largest list import processed within the request; bigger
+// exports are handed to a background job
+const listImportInlineLimit = 50
+
+// letterboxdExport is the parsed content of a Letterboxd CSV
+type letterboxdExport struct {
+	ListName        string // Only set for list exports
+	ListDescription string
+	Films           []services.ListImportFilm
+}
+
+// ImportLetterboxdList creates a new list from a Letterboxd CSV export (watched, watchlist,
+// ratings or list exports) uploaded as the multipart field "file". Each film is matched with
+// TMDB by title and year; films without a match are reported back rather than dropped. The
+// list is only created once matching is done. Small exports are imported right away; larger
+// ones return a job to poll, whose metadata holds the result when it finishes.
+// Optional form fields "name", "description" and "is_public" override the list settings.
+func (h *ListHandler) ImportLetterboxdList(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		http.Error(w, "Invalid upload: expected a multipart CSV file up to 5 MB", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	export, err := parseLetterboxdCSV(file)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid Letterboxd CSV: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Name the list from the form, the list export itself, or the uploaded filename
+	listName := strings.TrimSpace(r.FormValue("name"))
+	if listName == "" {
+		listName = export.ListName
+	}
+	if listName == "" {
+		listName = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+	if listName == "" {
+		listName = "Letterboxd import"
+	}
+
+	description := strings.TrimSpace(r.FormValue("description"))
+	if description == "" {
+		description = export.ListDescription
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Fall back to the user's preferred visibility when is_public isn't given
+	var isPublic bool
+	if value := r.FormValue("is_public"); value != "" {
+		isPublic, _ = strconv.ParseBool(value)
+	} else {
+		prefs, err := database.GetUserPreferences(h.db, user.ID)
+		if err != nil {
+			http.Error(w, "Failed to get preferences", http.StatusInternalServerError)
+			return
+		}
+		isPublic = prefs.DefaultListVisibility == types.ListVisibilityPublic
+	}
+
+	req := services.ListImportRequest{
+		Name:        listName,
+		Description: description,
+		IsPublic:    isPublic,
+		Films:       export.Films,
+	}
+
+	if len(req.Films) > listImportInlineLimit {
+		job, err := h.listImporter.StartImportJob(int64(user.ID), req)
+		if err != nil {
+			h.logger.Error("Failed to start Letterboxd import", "user_id", user.ID, "error", err)
+			http.Error(w, "Failed to start import", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"job_id":      job.ID,
+			"status":      string(job.Status),
+			"name":        listName,
+			"total_films": len(req.Films),
+			"status_url":  fmt.Sprintf("/api/plex/sync/status/%d", job.ID),
+		})
+		return
+	}
+
+	result, err := h.listImporter.ImportList(r.Context(), int64(user.ID), req)
+	if err != nil {
+		h.logger.Error("Letterboxd import failed", "user_id", user.ID, "error", err)
+		http.Error(w, "Failed to import list", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseLetterboxdCSV reads a Letterboxd export. Simple exports start with a header row
+// (Date,Name,Year,Letterboxd URI,...); list exports have a few lines of list metadata
+// followed by a blank line and the films table (Position,Name,Year,URL,Description).
+func parseLetterboxdCSV(reader io.Reader) (*letterboxdExport, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	export := &letterboxdExport{}
+	nameCol, yearCol := -1, -1
+	var header []string
+
+	for _, record := range records {
+		if len(record) == 0 || (len(record) == 1 && strings.TrimSpace(record[0]) == "") {
+			continue
+		}
+
+		columns := make(map[string]int)
+		for i, value := range record {
+			columns[strings.ToLower(strings.TrimSpace(value))] = i
+		}
+
+		// A header row starts a new section; the films section is the one with a Year column
+		if name, ok := columns["name"]; ok {
+			if year, ok := columns["year"]; ok {
+				nameCol, yearCol = name, year
+				header = record
+				continue
+			}
+			if _, ok := columns["date"]; ok && header == nil {
+				header = record
+				continue
+			}
+		}
+
+		if header == nil {
+			// Leading "Letterboxd list export v7" line
+			continue
+		}
+
+		if nameCol < 0 {
+			// List metadata row under Date,Name,Tags,URL,Description
+			for i, value := range record {
+				if i >= len(header) {
+					break
+				}
+				switch strings.ToLower(strings.TrimSpace(header[i])) {
+				case "name":
+					export.ListName = strings.TrimSpace(value)
+				case "description":
+					export.ListDescription = strings.TrimSpace(value)
+				}
+			}
+			continue
+		}
+
+		if nameCol >= len(record) {
+			continue
+		}
+
+		film := services.ListImportFilm{Title: strings.TrimSpace(record[nameCol])}
+		if film.Title == "" {
+			continue
+		}
+		if yearCol < len(record) {
+			film.Year, _ = strconv.Atoi(strings.TrimSpace(record[yearCol]))
+		}
+		export.Films = append(export.Films, film)
+	}
+
+	if nameCol < 0 {
+		return nil, fmt.Errorf("no Name and Year columns found")
+	}
+
+	return export, nil
+}
@@ -2,23 +2,34 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"moviedb/internal/auth"
 	"moviedb/internal/database"
+	"moviedb/internal/services"
 	"moviedb/internal/types"
 	"moviedb/internal/utils"
 )
 
 type ListHandler struct {
-	db *sql.DB
+	db           *sql.DB
+	tmdbClient   *services.TMDBClient
+	listImporter *services.ListImporter
+	movieCache   *services.MovieCacheQueue
+	logger       *slog.Logger
 }
 
-func NewListHandler(db *sql.DB) *ListHandler {
-	return &ListHandler{db: db}
+func NewListHandler(db *sql.DB, tmdbClient *services.TMDBClient, listImporter *services.ListImporter, movieCache *services.MovieCacheQueue) *ListHandler {
+	return &ListHandler{db: db, tmdbClient: tmdbClient, listImporter: listImporter, movieCache: movieCache, logger: slog.Default()}
 }
 
 func (h *ListHandler) GetLists(w http.ResponseWriter, r *http.Request) {
@@ -41,7 +52,7 @@ func (h *ListHandler) GetLists(w http.ResponseWriter, r *http.Request) {
 		       COUNT(lm.movie_id) as movie_count
 		FROM lists l
 		LEFT JOIN list_movies lm ON l.id = lm.list_id
-		WHERE l.user_id = ?
+		WHERE l.user_id = ? AND l.deleted_at IS NULL
 		GROUP BY l.id, l.name, l.description, l.is_public, l.created_at
 		ORDER BY l.created_at DESC
 	`, user.ID)
@@ -76,8 +87,7 @@ func (h *ListHandler) GetLists(w http.ResponseWriter, r *http.Request) {
 		lists = append(lists, list)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSONWithETag(w, r, map[string]interface{}{
 		"lists": lists,
 	})
 }
@@ -109,28 +119,37 @@ func (h *ListHandler) CreateList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fall back to the user's preferred visibility when is_public isn't given
+	var isPublic bool
+	if req.IsPublic != nil {
+		isPublic = *req.IsPublic
+	} else {
+		prefs, err := database.GetUserPreferences(h.db, user.ID)
+		if err != nil {
+			http.Error(w, "Failed to get preferences", http.StatusInternalServerError)
+			return
+		}
+		isPublic = prefs.DefaultListVisibility == types.ListVisibilityPublic
+	}
+
 	// Create list
-	result, err := h.db.Exec(`
+	var listID int
+	err = h.db.QueryRow(`
 		INSERT INTO lists (user_id, name, description, is_public, created_at)
 		VALUES (?, ?, ?, ?, ?)
-	`, user.ID, req.Name, req.Description, req.IsPublic, time.Now())
+		RETURNING id
+	`, user.ID, req.Name, req.Description, isPublic, time.Now()).Scan(&listID)
 	if err != nil {
 		http.Error(w, "Failed to create list", http.StatusInternalServerError)
 		return
 	}
 
-	listID, err := result.LastInsertId()
-	if err != nil {
-		http.Error(w, "Failed to get list ID", http.StatusInternalServerError)
-		return
-	}
-
 	// Return created list
 	response := map[string]interface{}{
-		"id":          int(listID),
+		"id":          listID,
 		"name":        req.Name,
 		"description": req.Description,
-		"is_public":   req.IsPublic,
+		"is_public":   isPublic,
 		"movie_count": 0,
 		"created_at":  time.Now(),
 	}
@@ -171,7 +190,7 @@ func (h *ListHandler) GetList(w http.ResponseWriter, r *http.Request) {
 	err = h.db.QueryRow(`
 		SELECT user_id, name, description, is_public, created_at
 		FROM lists 
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`, listID).Scan(&listUserID, &listName, &listDescription, &isPublic, &createdAt)
 	
 	if err == sql.ErrNoRows {
@@ -189,14 +208,52 @@ func (h *ListHandler) GetList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Parse pagination parameters
+	pageStr := r.URL.Query().Get("page")
+	limitStr := r.URL.Query().Get("limit")
+
+	// Default values
+	page := 1
+	limit := 20
+
+	if pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	offset := (page - 1) * limit
+
+	// Get total count for pagination
+	var totalCount int
+	err = h.db.QueryRow(`
+		SELECT COUNT(DISTINCT lm.movie_id)
+		FROM list_movies lm
+		JOIN movies m ON lm.movie_id = m.id
+		WHERE lm.list_id = ?
+	`, listID).Scan(&totalCount)
+	if err != nil {
+		http.Error(w, "Failed to count list movies", http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := (totalCount + limit - 1) / limit
+
 	// Get movies in this list
 	rows, err := h.db.Query(`
-		SELECT DISTINCT m.id, m.tmdb_id, m.title, m.year, m.poster_url, m.synopsis, lm.added_at
+		SELECT DISTINCT m.id, m.tmdb_id, m.title, m.year, m.poster_url, m.synopsis, lm.position, lm.added_at, m.caching
 		FROM list_movies lm
 		JOIN movies m ON lm.movie_id = m.id
 		WHERE lm.list_id = ?
-		ORDER BY lm.added_at DESC
-	`, listID)
+		ORDER BY lm.position ASC, lm.added_at DESC
+		LIMIT ? OFFSET ?
+	`, listID, limit, offset)
 	if err != nil {
 		http.Error(w, "Failed to get list movies", http.StatusInternalServerError)
 		return
@@ -205,13 +262,14 @@ func (h *ListHandler) GetList(w http.ResponseWriter, r *http.Request) {
 
 	var movies []map[string]interface{}
 	for rows.Next() {
-		var movieID, tmdbID int
+		var movieID, tmdbID, position int
 		var title, synopsis string
 		var year *int
 		var posterURL *string
 		var addedAt time.Time
+		var caching bool
 
-		err := rows.Scan(&movieID, &tmdbID, &title, &year, &posterURL, &synopsis, &addedAt)
+		err := rows.Scan(&movieID, &tmdbID, &title, &year, &posterURL, &synopsis, &position, &addedAt, &caching)
 		if err != nil {
 			continue
 		}
@@ -222,7 +280,9 @@ func (h *ListHandler) GetList(w http.ResponseWriter, r *http.Request) {
 			"title":    title,
 			"year":     year,
 			"synopsis": synopsis,
+			"position": position,
 			"added_at": addedAt,
+			"caching":  caching, // Details are still being fetched
 		}
 
 		if posterURL != nil {
@@ -232,19 +292,29 @@ func (h *ListHandler) GetList(w http.ResponseWriter, r *http.Request) {
 		movies = append(movies, movie)
 	}
 
-	response := map[string]interface{}{
-		"id":          listID,
-		"name":        listName,
-		"description": listDescription,
-		"is_public":   isPublic,
-		"created_at":  createdAt,
-		"movie_count": len(movies),
-		"movies":      movies,
-		"is_owner":    listUserID == user.ID,
+	if wantsFriendsWatched(r) {
+		if err := h.addFriendsWatched(user.ID, movies); err != nil {
+			http.Error(w, "Failed to get friends who watched", http.StatusInternalServerError)
+			return
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	response := map[string]interface{}{
+		"id":           listID,
+		"name":         listName,
+		"description":  listDescription,
+		"is_public":    isPublic,
+		"created_at":   createdAt,
+		"movie_count":  totalCount,
+		"movies":       movies,
+		"is_owner":     listUserID == user.ID,
+		"total":        totalCount,
+		"total_pages":  totalPages,
+		"current_page": page,
+		"per_page":     limit,
+	}
+
+	writeJSONWithETag(w, r, response)
 }
 
 func (h *ListHandler) UpdateList(w http.ResponseWriter, r *http.Request) {
@@ -263,7 +333,7 @@ func (h *ListHandler) UpdateList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse request body
-	var req types.CreateListRequest
+	var req types.UpdateListRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
@@ -284,7 +354,7 @@ func (h *ListHandler) UpdateList(w http.ResponseWriter, r *http.Request) {
 
 	// Verify list belongs to user
 	var listUserID int
-	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ?", listID).Scan(&listUserID)
+	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ? AND deleted_at IS NULL", listID).Scan(&listUserID)
 	if err == sql.ErrNoRows {
 		http.Error(w, "List not found", http.StatusNotFound)
 		return
@@ -365,7 +435,7 @@ func (h *ListHandler) DeleteList(w http.ResponseWriter, r *http.Request) {
 
 	// Verify list belongs to user
 	var listUserID int
-	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ?", listID).Scan(&listUserID)
+	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ? AND deleted_at IS NULL", listID).Scan(&listUserID)
 	if err == sql.ErrNoRows {
 		http.Error(w, "List not found", http.StatusNotFound)
 		return
@@ -379,27 +449,78 @@ func (h *ListHandler) DeleteList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete list movies first (foreign key constraint)
-	_, err = h.db.Exec("DELETE FROM list_movies WHERE list_id = ?", listID)
+	// Soft delete so the list can be restored; it is purged after the restore window
+	if err := database.SoftDeleteList(h.db, listID); err != nil {
+		http.Error(w, "Failed to delete list", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success":       true,
+		"message":       "List deleted successfully",
+		"restore_until": time.Now().Add(services.ListRestoreWindow),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RestoreList undoes deleting one of the user's lists. Lists deleted longer ago than the
+// restore window are gone (410), even if they haven't been purged yet.
+func (h *ListHandler) RestoreList(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to delete list movies", http.StatusInternalServerError)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Delete list
-	_, err = h.db.Exec("DELETE FROM lists WHERE id = ?", listID)
+	listID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Failed to delete list", http.StatusInternalServerError)
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
 		return
 	}
 
-	response := map[string]interface{}{
-		"success": true,
-		"message": "List deleted successfully",
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	listUserID, deletedAt, err := database.GetListDeletion(h.db, listID)
+	if err == sql.ErrNoRows {
+		// Purged lists are gone for good, but without a record we can't tell them apart
+		http.Error(w, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to verify list ownership", http.StatusInternalServerError)
+		return
+	}
+	if listUserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if deletedAt == nil {
+		http.Error(w, "List is not deleted", http.StatusConflict)
+		return
+	}
+	if time.Since(*deletedAt) > services.ListRestoreWindow {
+		http.Error(w, "List was deleted too long ago to restore", http.StatusGone)
+		return
+	}
+
+	if err := database.RestoreList(h.db, listID); err != nil {
+		http.Error(w, "Failed to restore list", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "List restored successfully",
+		"id":      listID,
+	})
 }
 
 func (h *ListHandler) AddMovieToList(w http.ResponseWriter, r *http.Request) {
@@ -434,7 +555,7 @@ func (h *ListHandler) AddMovieToList(w http.ResponseWriter, r *http.Request) {
 
 	// Verify list belongs to user
 	var listUserID int
-	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ?", listID).Scan(&listUserID)
+	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ? AND deleted_at IS NULL", listID).Scan(&listUserID)
 	if err == sql.ErrNoRows {
 		http.Error(w, "List not found", http.StatusNotFound)
 		return
@@ -448,16 +569,10 @@ func (h *ListHandler) AddMovieToList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Find or create movie in our database using TMDB ID
-	var movieID int
-	err = h.db.QueryRow("SELECT id FROM movies WHERE tmdb_id = ?", tmdbID).Scan(&movieID)
-	if err == sql.ErrNoRows {
-		// Movie doesn't exist in our database, we need to fetch it from TMDB first
-		http.Error(w, "Movie not found in database. Please view the movie details first to cache it.", http.StatusNotFound)
-		return
-	}
+	// Find movie in our database; one that hasn't been cached yet is fetched in the background
+	movieID, caching, err := h.movieCache.Enqueue(tmdbID)
 	if err != nil {
-		http.Error(w, "Failed to find movie", http.StatusInternalServerError)
+		http.Error(w, "Movie not found", http.StatusNotFound)
 		return
 	}
 
@@ -474,11 +589,8 @@ func (h *ListHandler) AddMovieToList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Add movie to list
-	_, err = h.db.Exec(`
-		INSERT INTO list_movies (list_id, movie_id, added_at)
-		VALUES (?, ?, ?)
-	`, listID, movieID, time.Now())
+	// Add movie to the end of the list
+	err = database.AddMovieToList(h.db, listID, movieID, user.ID)
 	if err != nil {
 		http.Error(w, "Failed to add movie to list", http.StatusInternalServerError)
 		return
@@ -487,9 +599,13 @@ func (h *ListHandler) AddMovieToList(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"success": true,
 		"message": "Movie added to list",
+		"caching": caching,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if caching {
+		w.WriteHeader(http.StatusAccepted)
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -525,7 +641,7 @@ func (h *ListHandler) RemoveMovieFromList(w http.ResponseWriter, r *http.Request
 
 	// Verify list belongs to user
 	var listUserID int
-	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ?", listID).Scan(&listUserID)
+	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ? AND deleted_at IS NULL", listID).Scan(&listUserID)
 	if err == sql.ErrNoRows {
 		http.Error(w, "List not found", http.StatusNotFound)
 		return
@@ -552,10 +668,7 @@ func (h *ListHandler) RemoveMovieFromList(w http.ResponseWriter, r *http.Request
 	}
 
 	// Remove movie from list
-	_, err = h.db.Exec(`
-		DELETE FROM list_movies 
-		WHERE list_id = ? AND movie_id = ?
-	`, listID, movieID)
+	_, err = database.RemoveMovieFromList(h.db, listID, movieID, user.ID)
 	if err != nil {
 		http.Error(w, "Failed to remove movie from list", http.StatusInternalServerError)
 		return
@@ -570,6 +683,381 @@ func (h *ListHandler) RemoveMovieFromList(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(response)
 }
 
+// GetListHistory returns who added and removed which movies from a list, newest first.
+// Only the list's owner can see it.
+func (h *ListHandler) GetListHistory(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	listID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	page := utils.GetQueryParamInt(r, "page", 1)
+	limit := utils.GetQueryParamInt(r, "limit", 50)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Verify list belongs to user
+	var listUserID int
+	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ? AND deleted_at IS NULL", listID).Scan(&listUserID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to verify list ownership", http.StatusInternalServerError)
+		return
+	}
+	if listUserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	history, err := database.GetListHistory(h.db, listID, limit, (page-1)*limit)
+	if err != nil {
+		http.Error(w, "Failed to get list history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"list_id": listID,
+		"history": history,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// ExportList streams a list's movies as CSV (default) or JSON for backup or migration.
+// Access follows the same owner-or-public rule as GetList.
+func (h *ListHandler) ExportList(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get path parameter
+	listIDStr := utils.GetPathParam(r, "id")
+	listID, err := strconv.Atoi(listIDStr)
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(utils.GetQueryParam(r, "format", "csv"))
+	if format != "csv" && format != "json" {
+		http.Error(w, "Invalid format: must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	var listName string
+	var isPublic bool
+	var listUserID int
+	err = h.db.QueryRow("SELECT user_id, name, is_public FROM lists WHERE id = ? AND deleted_at IS NULL", listID).Scan(&listUserID, &listName, &isPublic)
+	if err == sql.ErrNoRows {
+		http.Error(w, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get list", http.StatusInternalServerError)
+		return
+	}
+
+	// Check if user has access (owner or public list)
+	if listUserID != user.ID && !isPublic {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT m.title, m.year, m.tmdb_id, m.imdb_id, lm.added_at
+		FROM list_movies lm
+		JOIN movies m ON lm.movie_id = m.id
+		WHERE lm.list_id = ?
+		ORDER BY lm.position ASC, lm.added_at DESC
+	`, listID)
+	if err != nil {
+		http.Error(w, "Failed to get list movies", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	filename := exportFilename(listName) + "." + format
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		h.writeListExportJSON(w, rows)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"title", "year", "tmdb_id", "imdb_id", "added_at"})
+
+	for rows.Next() {
+		var title string
+		var year *int
+		var tmdbID int
+		var imdbID *string
+		var addedAt time.Time
+
+		if err := rows.Scan(&title, &year, &tmdbID, &imdbID, &addedAt); err != nil {
+			continue
+		}
+
+		yearStr := ""
+		if year != nil {
+			yearStr = strconv.Itoa(*year)
+		}
+		imdbStr := ""
+		if imdbID != nil {
+			imdbStr = *imdbID
+		}
+
+		writer.Write([]string{title, yearStr, strconv.Itoa(tmdbID), imdbStr, addedAt.Format(time.RFC3339)})
+	}
+
+	writer.Flush()
+}
+
+// writeListExportJSON streams export rows as a JSON array, one movie at a time
+func (h *ListHandler) writeListExportJSON(w http.ResponseWriter, rows *sql.Rows) {
+	encoder := json.NewEncoder(w)
+
+	w.Write([]byte("["))
+	first := true
+	for rows.Next() {
+		var movie struct {
+			Title   string    `json:"title"`
+			Year    *int      `json:"year"`
+			TMDBID  int       `json:"tmdb_id"`
+			IMDbID  *string   `json:"imdb_id"`
+			AddedAt time.Time `json:"added_at"`
+		}
+
+		if err := rows.Scan(&movie.Title, &movie.Year, &movie.TMDBID, &movie.IMDbID, &movie.AddedAt); err != nil {
+			continue
+		}
+
+		if !first {
+			w.Write([]byte(","))
+		}
+		first = false
+		encoder.Encode(movie)
+	}
+	w.Write([]byte("]\n"))
+}
+
+// exportFilename turns a list name into a safe download filename
+func exportFilename(name string) string {
+	filename := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		if r == ' ' {
+			return '_'
+		}
+		return -1
+	}, strings.TrimSpace(name))
+
+	if filename == "" {
+		return "list"
+	}
+	return filename
+}
+
+// CloneList copies a list the user can view (their own or a public one) into a new
+// private list owned by the user
+func (h *ListHandler) CloneList(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get path parameter
+	listIDStr := utils.GetPathParam(r, "id")
+	listID, err := strconv.Atoi(listIDStr)
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Check the source list exists and the user can view it
+	var listUserID int
+	var isPublic bool
+	err = h.db.QueryRow("SELECT user_id, is_public FROM lists WHERE id = ? AND deleted_at IS NULL", listID).Scan(&listUserID, &isPublic)
+	if err == sql.ErrNoRows {
+		http.Error(w, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get list", http.StatusInternalServerError)
+		return
+	}
+	if listUserID != user.ID && !isPublic {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	newListID, movieCount, err := database.CloneList(h.db, listID, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to clone list", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"id":          newListID,
+		"movie_count": movieCount,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReorderList sets the order of movies in a list from an ordered array of TMDB ids
+func (h *ListHandler) ReorderList(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get path parameter
+	listIDStr := utils.GetPathParam(r, "id")
+	listID, err := strconv.Atoi(listIDStr)
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req types.ReorderListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.TMDBIDs) == 0 {
+		http.Error(w, "tmdb_ids is required", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Verify list belongs to user
+	var listUserID int
+	err = h.db.QueryRow("SELECT user_id FROM lists WHERE id = ? AND deleted_at IS NULL", listID).Scan(&listUserID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to verify list ownership", http.StatusInternalServerError)
+		return
+	}
+	if listUserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	err = database.ReorderListMovies(h.db, listID, req.TMDBIDs)
+	if errors.Is(err, database.ErrMovieNotInList) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reorder list", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "List reordered",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// maxFriendsWatched caps how many friends are listed per movie in friends_watched
+const maxFriendsWatched = 3
+
+// wantsFriendsWatched reports whether the request asked for ?include=friends_watched
+func wantsFriendsWatched(r *http.Request) bool {
+	for _, include := range strings.Split(utils.GetQueryParam(r, "include", ""), ",") {
+		if strings.TrimSpace(include) == "friends_watched" {
+			return true
+		}
+	}
+	return false
+}
+
+// addFriendsWatched adds friends_watched and friends_watched_count to each movie, keyed by its "id"
+func (h *ListHandler) addFriendsWatched(userID int, movies []map[string]interface{}) error {
+	movieIDs := make([]int, 0, len(movies))
+	for _, movie := range movies {
+		movieIDs = append(movieIDs, movie["id"].(int))
+	}
+
+	watched, err := database.GetFriendsWatched(h.db, userID, movieIDs, maxFriendsWatched)
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		friends := []types.FriendWatched{}
+		total := 0
+		if entry, ok := watched[movie["id"].(int)]; ok {
+			friends = entry.Friends
+			total = entry.Total
+		}
+		movie["friends_watched"] = friends
+		movie["friends_watched_count"] = total
+	}
+
+	return nil
+}
+
 func (h *ListHandler) GetMovieInLists(w http.ResponseWriter, r *http.Request) {
 	authUser, err := auth.GetUserFromContext(r.Context())
 	if err != nil {
@@ -609,26 +1097,11 @@ func (h *ListHandler) GetMovieInLists(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get lists that contain this movie for this user
-	rows, err := h.db.Query(`
-		SELECT l.id
-		FROM lists l
-		JOIN list_movies lm ON l.id = lm.list_id
-		WHERE l.user_id = ? AND lm.movie_id = ?
-	`, user.ID, movieID)
+	listIDs, err := database.GetUserListIDsWithMovie(h.db, user.ID, movieID)
 	if err != nil {
 		http.Error(w, "Failed to get movie lists", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var listIDs []int
-	for rows.Next() {
-		var listID int
-		if err := rows.Scan(&listID); err != nil {
-			continue
-		}
-		listIDs = append(listIDs, listID)
-	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -643,6 +1116,18 @@ func (h *ListHandler) GetAllUserMovies(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Optional owned-format filter (e.g. ?format=4k_uhd), accepting the same aliases as
+	// UpdateOwnedFormats
+	var format string
+	if value := utils.GetQueryParam(r, "format", ""); strings.TrimSpace(value) != "" {
+		formats, err := types.NormalizeOwnedFormats([]string{value})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		format = formats[0]
+	}
+
 	// Get or create user in database
 	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
 	if err != nil {
@@ -651,15 +1136,29 @@ func (h *ListHandler) GetAllUserMovies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get all movies from all user's lists
-	rows, err := h.db.Query(`
+	query := `
 		SELECT DISTINCT m.id, m.tmdb_id, m.title, m.year, m.poster_url, m.synopsis, lm.added_at,
 		       l.id as list_id, l.name as list_name
 		FROM list_movies lm
 		JOIN movies m ON lm.movie_id = m.id
 		JOIN lists l ON lm.list_id = l.id
-		WHERE l.user_id = ?
-		ORDER BY lm.added_at DESC
-	`, user.ID)
+		WHERE l.user_id = ? AND l.deleted_at IS NULL`
+	args := []interface{}{user.ID}
+
+	if format != "" {
+		query += `
+		  AND EXISTS (
+			SELECT 1 FROM user_movies um
+			JOIN user_movie_formats umf ON umf.user_movie_id = um.id
+			WHERE um.user_id = l.user_id AND um.movie_id = m.id AND umf.format = ?
+		  )`
+		args = append(args, format)
+	}
+
+	query += `
+		ORDER BY lm.added_at DESC`
+
+	rows, err := h.db.Query(query, args...)
 	if err != nil {
 		http.Error(w, "Failed to get user movies", http.StatusInternalServerError)
 		return
@@ -697,6 +1196,13 @@ func (h *ListHandler) GetAllUserMovies(w http.ResponseWriter, r *http.Request) {
 		movies = append(movies, movie)
 	}
 
+	if wantsFriendsWatched(r) {
+		if err := h.addFriendsWatched(user.ID, movies); err != nil {
+			http.Error(w, "Failed to get friends who watched", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"movies": movies,
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// statusRecorder remembers the status code a handler wrote
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses (job progress events) working through the recorder
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// LogRequests logs the method, path, status and duration of every request. Health checks
+// and metrics scrapes are logged at debug level, since they come every few seconds.
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder, ok := w.(*statusRecorder)
+		if !ok {
+			recorder = &statusRecorder{ResponseWriter: w}
+		}
+
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if strings.HasPrefix(r.URL.Path, "/health") || r.URL.Path == "/metrics" {
+			level = slog.LevelDebug
+		}
+		slog.Log(r.Context(), level, "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"duration", time.Since(start),
+		)
+	})
+}
+
+// RecoverPanics turns a panicking handler into a 500 response and logs the stack trace,
+// instead of dropping the connection
+func RecoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder, ok := w.(*statusRecorder)
+		if !ok {
+			recorder = &statusRecorder{ResponseWriter: w}
+		}
+
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// Handlers abort responses on purpose with this; the server handles it quietly
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			slog.Error("panic serving request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", err,
+				"stack", string(debug.Stack()),
+			)
+			// Too late for an error status once the response has started
+			if recorder.status == 0 {
+				http.Error(recorder, "Internal server error", http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
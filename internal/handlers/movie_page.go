@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
+	"moviedb/internal/types"
+	"moviedb/internal/utils"
+)
+
+// MoviePageHandler serves everything the movie page shows in one response, composed from the
+// movie and watch provider handlers
+type MoviePageHandler struct {
+	db             *sql.DB
+	movies         *MovieHandler
+	watchProviders *WatchProvidersHandler
+	logger         *slog.Logger
+}
+
+func NewMoviePageHandler(db *sql.DB, movies *MovieHandler, watchProviders *WatchProvidersHandler) *MoviePageHandler {
+	return &MoviePageHandler{
+		db:             db,
+		movies:         movies,
+		watchProviders: watchProviders,
+		logger:         slog.Default(),
+	}
+}
+
+// GetMovieFull returns a movie's details together with the user's state for it (as in
+// /me), the ids of the user's lists containing it, watch providers for the user's region
+// and what the user's friends made of it. Watch providers are null when they can't be
+// fetched; the rest of the page is still returned.
+func (h *MoviePageHandler) GetMovieFull(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	region, ok := h.watchProviders.resolveRegion(r, user.ID)
+	if !ok {
+		http.Error(w, "Invalid region code", http.StatusBadRequest)
+		return
+	}
+
+	movie, ok := h.movies.loadMovie(w, r, tmdbID)
+	if !ok {
+		return
+	}
+
+	userState := map[string]interface{}{
+		"interacted":    false,
+		"status":        types.MovieStatusNotWatched,
+		"rating":        nil,
+		"watched_date":  nil,
+		"notes":         nil,
+		"owned_formats": []string{},
+		"favorite":      false,
+	}
+	listIDs := []int{}
+	friends := map[string]interface{}{
+		"watched":        types.FriendsWatched{Friends: []types.FriendWatched{}},
+		"average_rating": nil,
+		"rating_count":   0,
+	}
+
+	// Movies fetched from TMDB are cached as they're loaded; the user can only have state for
+	// one that is in the database
+	movieID, err := database.GetMovieIDByTMDBID(h.db, tmdbID)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Failed to get movie", http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		if err := h.movies.addUserMovieState(userState, user.ID, movieID); err != nil {
+			http.Error(w, "Failed to get user movie", http.StatusInternalServerError)
+			return
+		}
+
+		listIDs, err = database.GetUserListIDsWithMovie(h.db, user.ID, movieID)
+		if err != nil {
+			http.Error(w, "Failed to get movie lists", http.StatusInternalServerError)
+			return
+		}
+
+		watched, err := database.GetFriendsWatched(h.db, user.ID, []int{movieID}, maxFriendsWatched)
+		if err != nil {
+			http.Error(w, "Failed to get friends watched", http.StatusInternalServerError)
+			return
+		}
+		if entry, ok := watched[movieID]; ok {
+			friends["watched"] = entry
+		}
+
+		averageRating, ratingCount, err := database.GetFriendsRating(h.db, user.ID, movieID)
+		if err != nil {
+			http.Error(w, "Failed to get friends rating", http.StatusInternalServerError)
+			return
+		}
+		friends["average_rating"] = averageRating
+		friends["rating_count"] = ratingCount
+	}
+
+	var watchProviders interface{}
+	providers, err := h.watchProviders.service.GetWatchProviders(tmdbID, region, &user.ID)
+	if err != nil {
+		h.logger.Warn("Movie page: failed to get watch providers", "tmdb_id", tmdbID, "error", err)
+	} else {
+		watchProviders = providers
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tmdb_id":         tmdbID,
+		"movie":           movie,
+		"user":            userState,
+		"list_ids":        listIDs,
+		"watch_providers": watchProviders,
+		"friends":         friends,
+	})
+}
@@ -3,23 +3,51 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"golang.org/x/sync/singleflight"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
 	"moviedb/internal/services"
+	"moviedb/internal/types"
 	"moviedb/internal/utils"
 )
 
+// Cold-cache movie fetches hit TMDB, so each user gets a bounded budget
+const (
+	coldFetchLimit  = 30
+	coldFetchWindow = time.Minute
+)
+
+// maxNotesLength is the maximum number of characters in a user's movie notes
+const maxNotesLength = 5000
+
 type MovieHandler struct {
-	db         *sql.DB
-	tmdbClient *services.TMDBClient
+	db               *sql.DB
+	tmdbClient       *services.TMDBClient
+	rateLimiter      *services.TMDBRateLimiter
+	coldFetchLimiter *userRateLimiter
+	coldFetches      singleflight.Group // Coalesces concurrent TMDB fetches of the same movie
+	relatedCache     *relatedMoviesCache
+	logger           *slog.Logger
 }
 
-func NewMovieHandler(db *sql.DB, tmdbClient *services.TMDBClient) *MovieHandler {
+func NewMovieHandler(db *sql.DB, tmdbClient *services.TMDBClient, rateLimiter *services.TMDBRateLimiter) *MovieHandler {
 	return &MovieHandler{
-		db:         db,
-		tmdbClient: tmdbClient,
+		db:               db,
+		tmdbClient:       tmdbClient,
+		rateLimiter:      rateLimiter,
+		coldFetchLimiter: newUserRateLimiter(coldFetchLimit, coldFetchWindow),
+		relatedCache:     newRelatedMoviesCache(relatedMoviesCacheTTL),
+		logger:           slog.Default(),
 	}
 }
 
@@ -44,13 +72,191 @@ func (h *MovieHandler) SearchMovies(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Search TMDB for movies
-	searchResp, err := h.tmdbClient.SearchMovies(query, page)
+	tmdbClient := h.tmdbFor(r)
+	var searchResp *services.TMDBSearchResponse
+	err := h.rateLimiter.ExecuteWithRateLimit(func() error {
+		var err error
+		searchResp, err = tmdbClient.SearchMovies(query, page)
+		return err
+	}, 2, h.requestUserID(r)) // Priority 2 - user is waiting on the results
+	if err != nil {
+		h.logger.Error("Failed to search movies", "query", query, "error", err)
+		http.Error(w, "Failed to search movies", http.StatusBadGateway)
+		return
+	}
+
+	// Cache the results so they can be added to lists without opening their details first
+	if err := services.CacheSearchResults(h.db, h.tmdbClient, searchResp.Results); err != nil {
+		h.logger.Warn("Failed to cache search results", "query", query, "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.searchResultsResponse(searchResp))
+}
+
+// discoverSortOrders are the TMDB sort orders accepted by DiscoverMovies
+var discoverSortOrders = map[string]bool{
+	"popularity.desc":           true,
+	"popularity.asc":            true,
+	"vote_average.desc":         true,
+	"vote_average.asc":          true,
+	"primary_release_date.desc": true,
+	"primary_release_date.asc":  true,
+	"revenue.desc":              true,
+	"title.asc":                 true,
+}
+
+// DiscoverMovies browses TMDB movies without a title, e.g. "Sci-Fi from the 80s rated
+// above 7": ?genres=878&year_from=1980&year_to=1989&min_rating=7&sort=vote_average.desc
+func (h *MovieHandler) DiscoverMovies(w http.ResponseWriter, r *http.Request) {
+	params := services.DiscoverParams{
+		YearFrom:     utils.GetQueryParamInt(r, "year_from", 0),
+		YearTo:       utils.GetQueryParamInt(r, "year_to", 0),
+		MinVoteCount: utils.GetQueryParamInt(r, "min_votes", 0),
+		SortBy:       utils.GetQueryParam(r, "sort", "popularity.desc"),
+		Page:         utils.GetQueryParamInt(r, "page", 1),
+	}
+
+	for _, value := range strings.Split(utils.GetQueryParam(r, "genres", ""), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		genreID, err := strconv.Atoi(value)
+		if err != nil || genreID <= 0 {
+			http.Error(w, "Invalid genre id", http.StatusBadRequest)
+			return
+		}
+		params.GenreIDs = append(params.GenreIDs, genreID)
+	}
+
+	if value := utils.GetQueryParam(r, "min_rating", ""); value != "" {
+		voteAverage, err := strconv.ParseFloat(value, 64)
+		if err != nil || voteAverage < 0 || voteAverage > 10 {
+			http.Error(w, "min_rating must be between 0 and 10", http.StatusBadRequest)
+			return
+		}
+		params.MinVoteAverage = voteAverage
+	}
+
+	if params.YearFrom < 0 || params.YearTo < 0 || (params.YearTo > 0 && params.YearFrom > params.YearTo) {
+		http.Error(w, "Invalid year range", http.StatusBadRequest)
+		return
+	}
+
+	if !discoverSortOrders[params.SortBy] {
+		http.Error(w, "Invalid sort order", http.StatusBadRequest)
+		return
+	}
+
+	// TMDB serves at most 500 pages
+	if params.Page < 1 || params.Page > 500 {
+		http.Error(w, "page must be between 1 and 500", http.StatusBadRequest)
+		return
+	}
+
+	var searchResp *services.TMDBSearchResponse
+	err := h.rateLimiter.ExecuteWithRateLimit(func() error {
+		var err error
+		searchResp, err = h.tmdbClient.DiscoverMovies(params)
+		return err
+	}, 2, h.requestUserID(r)) // Priority 2 - user is browsing
 	if err != nil {
-		http.Error(w, "Failed to search movies", http.StatusInternalServerError)
+		h.logger.Error("Failed to discover movies", "error", err)
+		http.Error(w, "Failed to discover movies", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.searchResultsResponse(searchResp))
+}
+
+// GetTrendingMovies returns TMDB's trending movies for ?window=day|week (default week).
+// ?region=local gives a "trending near you" variant for the user's preferred region (or the
+// default region) and ?region=XX one for a given country. Pages are cached per region.
+func (h *MovieHandler) GetTrendingMovies(w http.ResponseWriter, r *http.Request) {
+	window := utils.GetQueryParam(r, "window", "week")
+	if window != "day" && window != "week" {
+		http.Error(w, "window must be day or week", http.StatusBadRequest)
+		return
+	}
+
+	// TMDB serves at most 500 pages
+	page := utils.GetQueryParamInt(r, "page", 1)
+	if page < 1 || page > 500 {
+		http.Error(w, "page must be between 1 and 500", http.StatusBadRequest)
 		return
 	}
 
-	// Convert TMDB movies to our format
+	region := ""
+	if value := utils.GetQueryParam(r, "region", ""); value == "local" {
+		region = h.localRegion(r)
+	} else if value != "" {
+		var ok bool
+		if region, ok = services.NormalizeRegion(value); !ok {
+			http.Error(w, "Invalid region code", http.StatusBadRequest)
+			return
+		}
+	}
+
+	cacheKey := fmt.Sprintf("trending:%s:%d", window, page)
+	if region != "" {
+		cacheKey = fmt.Sprintf("trending:region:%s:%d", region, page)
+	}
+
+	searchResp, ok := h.relatedCache.Get(cacheKey)
+	if !ok {
+		err := h.rateLimiter.ExecuteWithRateLimit(func() error {
+			var err error
+			if region != "" {
+				searchResp, err = h.tmdbClient.GetRegionalTrendingMovies(region, page)
+			} else {
+				searchResp, err = h.tmdbClient.GetTrendingMovies(window, page)
+			}
+			return err
+		}, 2, h.requestUserID(r)) // Priority 2 - user is browsing
+		if err != nil {
+			h.logger.Error("Failed to get trending movies", "error", err)
+			http.Error(w, "Failed to get trending movies", http.StatusBadGateway)
+			return
+		}
+
+		h.relatedCache.Set(cacheKey, searchResp)
+	}
+
+	response := h.searchResultsResponse(searchResp)
+	if region != "" {
+		response["region"] = region
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// localRegion returns the user's preferred region, or the default region
+func (h *MovieHandler) localRegion(r *http.Request) string {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		return services.DefaultWatchRegion
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		return services.DefaultWatchRegion
+	}
+
+	prefs, err := database.GetUserPreferences(h.db, user.ID)
+	if err == nil && prefs.PreferredRegion != nil {
+		if region, ok := services.NormalizeRegion(*prefs.PreferredRegion); ok {
+			return region
+		}
+	}
+
+	return services.DefaultWatchRegion
+}
+
+// searchResultsResponse converts a page of TMDB movies to our lightweight search format
+func (h *MovieHandler) searchResultsResponse(searchResp *services.TMDBSearchResponse) map[string]interface{} {
 	movies := make([]map[string]interface{}, len(searchResp.Results))
 	for i, tmdbMovie := range searchResp.Results {
 		posterURL := h.tmdbClient.GetPosterURL(tmdbMovie.PosterPath, "w500")
@@ -67,15 +273,12 @@ func (h *MovieHandler) SearchMovies(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response := map[string]interface{}{
+	return map[string]interface{}{
 		"results":       movies,
 		"page":          searchResp.Page,
 		"total_pages":   searchResp.TotalPages,
 		"total_results": searchResp.TotalResults,
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
 }
 
 func (h *MovieHandler) getPopularMoviesFromDB(page int) ([]map[string]interface{}, error) {
@@ -138,50 +341,53 @@ func (h *MovieHandler) GetMovie(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// First try to get from our database (by TMDB ID)
-	movie, err := h.getMovieFromDB(movieID)
-	if err == nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(movie)
+	movie, ok := h.loadMovie(w, r, movieID)
+	if !ok {
 		return
 	}
 
-	// If not found in DB, get from TMDB
-	tmdbMovie, err := h.tmdbClient.GetMovieDetails(movieID)
-	if err != nil {
+	writeJSONWithETag(w, r, movie)
+}
+
+// loadMovie returns a movie's details from the database, or from TMDB when it isn't cached
+// or a refresh was asked for. On failure it writes the error response and reports false.
+func (h *MovieHandler) loadMovie(w http.ResponseWriter, r *http.Request, movieID int) (map[string]interface{}, bool) {
+	// Short-circuit ids that cannot exist on TMDB
+	if !services.IsPlausibleTMDBID(movieID) {
 		http.Error(w, "Movie not found", http.StatusNotFound)
-		return
+		return nil, false
 	}
 
-	// Convert TMDB movie to our format
-	posterURL := h.tmdbClient.GetPosterURL(tmdbMovie.PosterPath, "w500")
-	backdropURL := h.tmdbClient.GetBackdropURL(tmdbMovie.BackdropPath, "w1280")
-	year := services.ExtractYear(tmdbMovie.ReleaseDate)
-
-	// Convert genres
-	genreNames := make([]string, len(tmdbMovie.Genres))
-	for i, genre := range tmdbMovie.Genres {
-		genreNames[i] = genre.Name
+	// First try to get from our database (by TMDB ID), unless a refresh was asked for
+	refresh := wantsRefresh(r)
+	// Movies cached from search results or imports only have the basic fields; treat them
+	// as a miss so the details get fetched
+	movie, complete, err := h.getMovieFromDB(movieID)
+	if err == nil && complete && !refresh {
+		h.addCredits(movie, movieID)
+		h.addVideos(movie, movieID)
+		return movie, true
 	}
 
-	// Get external IDs (IMDb, etc.)
-	externalIDs, err := h.tmdbClient.GetMovieExternalIDs(movieID)
-	if err != nil {
-		// Continue without external IDs if fetch fails
-		externalIDs = nil
+	// Cache miss - limit how often a single user can make us call TMDB
+	if !h.allowColdFetch(w, r) {
+		return nil, false
 	}
 
-	// Save movie to our database for future use
-	genresJSON, _ := json.Marshal(genreNames)
-	_, err = h.db.Exec(`
-		INSERT OR REPLACE INTO movies (tmdb_id, title, year, poster_url, synopsis, runtime, genres, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`, tmdbMovie.ID, tmdbMovie.Title, year, posterURL, tmdbMovie.Overview, tmdbMovie.Runtime, string(genresJSON), time.Now())
+	// If not found in DB, get from TMDB
+	fetched, err := h.fetchMovieFromTMDB(r, movieID)
 	if err != nil {
-		// Log error but continue - this is not critical
-		// TODO: Add proper logging
+		writeTMDBError(w, err, "Movie not found", "Failed to get movie")
+		return nil, false
 	}
 
+	tmdbMovie := fetched.details
+	externalIDs := fetched.externalIDs
+	posterURL := fetched.posterURL
+	backdropURL := h.tmdbClient.GetBackdropURL(tmdbMovie.BackdropPath, "w1280")
+	year := fetched.year
+	genreNames := fetched.genreNames
+
 	movie = map[string]interface{}{
 		"id":           tmdbMovie.ID,
 		"tmdb_id":      tmdbMovie.ID,
@@ -205,15 +411,202 @@ func (h *MovieHandler) GetMovie(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(movie)
+	h.addCredits(movie, movieID)
+	h.addVideos(movie, movieID)
+
+	return movie, true
+}
+
+// coldMovie is a movie fetched from TMDB on a cache miss, shared by every request that
+// was waiting on the same fetch
+type coldMovie struct {
+	details     *services.TMDBMovieDetails
+	externalIDs *services.TMDBExternalIDs // nil if the lookup failed
+	posterURL   string
+	year        *int
+	genreNames  []string
+}
+
+// fetchMovieFromTMDB fetches a movie's details and external ids from TMDB and caches the
+// movie in our database. Concurrent requests for the same movie share a single fetch so a
+// trending title opened by many users at once costs one set of TMDB calls.
+func (h *MovieHandler) fetchMovieFromTMDB(r *http.Request, movieID int) (*coldMovie, error) {
+	key := strconv.Itoa(movieID)
+	if wantsRefresh(r) {
+		key += ":refresh"
+	}
+
+	tmdbClient := h.tmdbFor(r)
+	userID := h.requestUserID(r)
+	result, err, _ := h.coldFetches.Do(key, func() (interface{}, error) {
+		var tmdbMovie *services.TMDBMovieDetails
+		err := h.rateLimiter.ExecuteWithRateLimit(func() error {
+			var err error
+			tmdbMovie, err = tmdbClient.GetMovieDetails(movieID)
+			return err
+		}, 2, userID) // Priority 2 - user is waiting on the detail page
+		if err != nil {
+			return nil, err
+		}
+
+		// Convert TMDB movie to our format
+		fetched := &coldMovie{
+			details:   tmdbMovie,
+			posterURL: h.tmdbClient.GetPosterURL(tmdbMovie.PosterPath, "w500"),
+			year:      services.ExtractYear(tmdbMovie.ReleaseDate),
+		}
+
+		// Convert genres
+		fetched.genreNames = make([]string, len(tmdbMovie.Genres))
+		for i, genre := range tmdbMovie.Genres {
+			fetched.genreNames[i] = genre.Name
+		}
+
+		// Get external IDs (IMDb, etc.); continue without them if the fetch fails
+		_ = h.rateLimiter.ExecuteWithRateLimit(func() error {
+			externalIDs, err := tmdbClient.GetMovieExternalIDs(movieID)
+			if err == nil {
+				fetched.externalIDs = externalIDs
+			}
+			return err
+		}, 2, userID)
+
+		imdbID := tmdbMovie.IMDbID
+		if fetched.externalIDs != nil && fetched.externalIDs.IMDbID != nil {
+			imdbID = fetched.externalIDs.IMDbID
+		}
+
+		// Save movie to our database for future use. Update in place rather than replacing the
+		// row: a movie cached from search results may already be on lists by its id.
+		genresJSON, _ := json.Marshal(fetched.genreNames)
+		_, err = h.db.Exec(`
+			INSERT INTO movies (tmdb_id, title, year, poster_url, synopsis, runtime, genres, imdb_id, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(tmdb_id) DO UPDATE SET
+				title = excluded.title,
+				year = excluded.year,
+				poster_url = excluded.poster_url,
+				synopsis = excluded.synopsis,
+				runtime = excluded.runtime,
+				genres = excluded.genres,
+				imdb_id = excluded.imdb_id,
+				caching = 0
+		`, tmdbMovie.ID, tmdbMovie.Title, fetched.year, fetched.posterURL, tmdbMovie.Overview, tmdbMovie.Runtime, string(genresJSON), imdbID, time.Now())
+		if err != nil {
+			// Log error but continue - this is not critical
+			h.logger.Warn("Failed to cache movie", "tmdb_id", tmdbMovie.ID, "error", err)
+		}
+
+		return fetched, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*coldMovie), nil
+}
+
+// addCredits adds the cached top-billed cast, directors and writers to a movie response.
+// Credits are optional: when TMDB can't be reached the arrays are left empty.
+func (h *MovieHandler) addCredits(movie map[string]interface{}, tmdbID int) {
+	credits, err := services.GetMovieCredits(h.db, h.tmdbClient, tmdbID)
+	if err != nil {
+		h.logger.Warn("Failed to get movie credits", "tmdb_id", tmdbID, "error", err)
+		credits = &services.MovieCredits{
+			Cast:      []services.CastMember{},
+			Directors: []services.CrewMember{},
+			Writers:   []services.CrewMember{},
+		}
+	}
+
+	movie["cast"] = credits.Cast
+	movie["directors"] = credits.Directors
+	movie["writers"] = credits.Writers
+}
+
+// addVideos adds the movie's YouTube trailers and teasers, official trailer first.
+// Like credits they are optional and left empty when TMDB can't be reached.
+func (h *MovieHandler) addVideos(movie map[string]interface{}, tmdbID int) {
+	videos, err := services.GetMovieVideos(h.db, h.tmdbClient, tmdbID)
+	if err != nil {
+		h.logger.Warn("Failed to get movie videos", "tmdb_id", tmdbID, "error", err)
+		videos = []services.MovieVideo{}
+	}
+
+	movie["videos"] = videos
 }
 
-func (h *MovieHandler) getMovieFromDB(tmdbID int) (map[string]interface{}, error) {
+// wantsRefresh reports whether the request asks to bypass cached TMDB data (?refresh=true)
+func wantsRefresh(r *http.Request) bool {
+	return utils.GetQueryParam(r, "refresh", "") == "true"
+}
+
+// tmdbFor returns the TMDB client for a request, skipping the response cache on refresh
+func (h *MovieHandler) tmdbFor(r *http.Request) *services.TMDBClient {
+	if wantsRefresh(r) {
+		return h.tmdbClient.WithoutCache()
+	}
+	return h.tmdbClient
+}
+
+// writeTMDBError writes the status matching a failed TMDB request: 404 with notFoundMessage
+// when TMDB doesn't have the item, 429 when we're over TMDB's rate limit and 502 otherwise
+func writeTMDBError(w http.ResponseWriter, err error, notFoundMessage, failedMessage string) {
+	switch {
+	case errors.Is(err, services.ErrTMDBNotFound):
+		http.Error(w, notFoundMessage, http.StatusNotFound)
+	case errors.Is(err, services.ErrTMDBRateLimited):
+		var rateLimitErr *services.RateLimitError
+		if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(rateLimitErr.RetryAfter.Seconds())+1))
+		}
+		http.Error(w, "Too many requests to TMDB, try again shortly", http.StatusTooManyRequests)
+	default:
+		slog.Error("TMDB request failed", "error", err)
+		http.Error(w, failedMessage, http.StatusBadGateway)
+	}
+}
+
+// allowColdFetch applies the per-user cold-cache budget, writing a 429 when it is exhausted
+func (h *MovieHandler) allowColdFetch(w http.ResponseWriter, r *http.Request) bool {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+
+	allowed, retryAfter := h.coldFetchLimiter.Allow(authUser.Auth0ID)
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		http.Error(w, "Too many uncached movie requests, please slow down", http.StatusTooManyRequests)
+		return false
+	}
+
+	return true
+}
+
+// requestUserID returns the database id of the requesting user, so their TMDB requests
+// take turns with other users'. It is 0 when the user can't be looked up.
+func (h *MovieHandler) requestUserID(r *http.Request) int64 {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		return 0
+	}
+
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		return 0
+	}
+	return int64(user.ID)
+}
+
+// getMovieFromDB returns a cached movie and whether its details are complete, which they
+// aren't for movies cached from search results with only the basic fields
+func (h *MovieHandler) getMovieFromDB(tmdbID int) (map[string]interface{}, bool, error) {
 	var id int
-	var title, synopsis, genres string
+	var title, synopsis string
 	var year, runtime *int
-	var posterURL *string
+	var posterURL, genres *string
 
 	err := h.db.QueryRow(`
 		SELECT id, title, year, poster_url, synopsis, runtime, genres
@@ -222,7 +615,7 @@ func (h *MovieHandler) getMovieFromDB(tmdbID int) (map[string]interface{}, error
 	`, tmdbID).Scan(&id, &title, &year, &posterURL, &synopsis, &runtime, &genres)
 
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	movie := map[string]interface{}{
@@ -239,25 +632,753 @@ func (h *MovieHandler) getMovieFromDB(tmdbID int) (map[string]interface{}, error
 		movie["poster_url"] = *posterURL
 	}
 
-	return movie, nil
+	return movie, genres != nil, nil
 }
 
-func (h *MovieHandler) UpdateMovieStatus(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement update movie status
-	w.WriteHeader(http.StatusNotImplemented)
+// GetMyMovie returns the caller's status, rating, notes and owned formats for a movie in one call.
+// It only reads: a movie that isn't cached locally is reported as not interacted with.
+func (h *MovieHandler) GetMyMovie(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Default "not interacted" payload
+	response := map[string]interface{}{
+		"tmdb_id":       tmdbID,
+		"movie":         nil,
+		"interacted":    false,
+		"status":        types.MovieStatusNotWatched,
+		"rating":        nil,
+		"watched_date":  nil,
+		"notes":         nil,
+		"owned_formats": []string{},
+		"favorite":      false,
+	}
+
+	// Any cached row counts, complete details or not: imports and ratings cache movies
+	// with only the basic fields
+	movie, _, err := h.getMovieFromDB(tmdbID)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Failed to get movie", http.StatusInternalServerError)
+		return
+	}
+
+	if movie != nil {
+		response["movie"] = movie
+
+		if err := h.addUserMovieState(response, user.ID, movie["id"].(int)); err != nil {
+			http.Error(w, "Failed to get user movie", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
-func (h *MovieHandler) RateMovie(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement rate movie
-	w.WriteHeader(http.StatusNotImplemented)
+// addUserMovieState fills in the user's status, rating, notes, owned formats and favorite
+// flag for a movie, leaving the defaults in state when the user hasn't interacted with it
+func (h *MovieHandler) addUserMovieState(state map[string]interface{}, userID, movieID int) error {
+	userMovie, err := database.GetUserMovie(h.db, userID, movieID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	formats, err := database.GetUserMovieFormats(h.db, userMovie.ID)
+	if err != nil {
+		return err
+	}
+
+	state["interacted"] = true
+	state["status"] = userMovie.Status
+	state["rating"] = userMovie.Rating
+	state["watched_date"] = userMovie.WatchedDate
+	state["notes"] = userMovie.Notes
+	state["owned_formats"] = formats
+	state["favorite"] = userMovie.Favorite
+	state["updated_at"] = userMovie.Updated
+	return nil
 }
 
-func (h *MovieHandler) UpdateNotes(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement update movie notes
-	w.WriteHeader(http.StatusNotImplemented)
+func (h *MovieHandler) UpdateMovieStatus(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req types.UpdateMovieStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate status against the allowed vocabulary
+	if err := types.ValidateMovieStatus(req.Status); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Resolve the local movie row, caching it from TMDB if needed
+	movieID, err := services.EnsureMovieCached(h.db, h.tmdbClient, tmdbID)
+	if err != nil {
+		writeTMDBError(w, err, "Movie not found", "Failed to get movie")
+		return
+	}
+
+	previous, err := database.GetUserMovie(h.db, user.ID, movieID)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Failed to get user movie", http.StatusInternalServerError)
+		return
+	}
+
+	userMovie, err := database.SetUserMovieStatus(h.db, user.ID, movieID, req.Status)
+	if err != nil {
+		http.Error(w, "Failed to update movie status", http.StatusInternalServerError)
+		return
+	}
+
+	if req.Status == types.MovieStatusWatched && (previous == nil || previous.Status != types.MovieStatusWatched) {
+		h.postActivity(user.ID, types.FeedPostWatched, movieID, userMovie.Rating)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(userMovie)
 }
 
-func (h *MovieHandler) UpdateOwnedFormats(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement update owned formats
-	w.WriteHeader(http.StatusNotImplemented)
-}
\ No newline at end of file
+// maxBatchStatusSize bounds how many movies a single batch status update may touch
+const maxBatchStatusSize = 200
+
+// UpdateMovieStatusBatch sets the same status for many movies at once, e.g. when a user
+// moves their watch history over. Movies are cached from TMDB as needed; ids that can't be
+// resolved are reported as failed and the rest are updated in a single transaction. Batch
+// updates don't post to the feed so an import doesn't flood friends' feeds.
+func (h *MovieHandler) UpdateMovieStatusBatch(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req types.BatchMovieStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.TMDBIDs) == 0 {
+		http.Error(w, "tmdb_ids is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.TMDBIDs) > maxBatchStatusSize {
+		http.Error(w, fmt.Sprintf("At most %d movies can be updated at once", maxBatchStatusSize), http.StatusBadRequest)
+		return
+	}
+
+	// Validate status against the allowed vocabulary
+	if err := types.ValidateMovieStatus(req.Status); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Resolve the local movie rows, caching movies from TMDB if needed
+	results := make([]types.BatchMovieStatusResult, 0, len(req.TMDBIDs))
+	seen := make(map[int]bool)
+	var movieIDs []int
+	var resolved []int // Indexes into results of the movies being updated
+	for _, tmdbID := range req.TMDBIDs {
+		if seen[tmdbID] {
+			continue
+		}
+		seen[tmdbID] = true
+
+		var movieID int
+		err := h.rateLimiter.ExecuteWithRateLimit(func() error {
+			var err error
+			movieID, err = services.EnsureMovieCached(h.db, h.tmdbClient, tmdbID)
+			return err
+		}, 1, int64(user.ID)) // Priority 1 - bulk user action
+		if err != nil {
+			h.logger.Warn("Batch status: failed to resolve movie", "tmdb_id", tmdbID, "error", err)
+			message := "Failed to get movie"
+			if errors.Is(err, services.ErrTMDBNotFound) {
+				message = "Movie not found"
+			}
+			results = append(results, types.BatchMovieStatusResult{TMDBID: tmdbID, Error: message})
+			continue
+		}
+
+		movieIDs = append(movieIDs, movieID)
+		resolved = append(resolved, len(results))
+		results = append(results, types.BatchMovieStatusResult{TMDBID: tmdbID})
+	}
+
+	if len(movieIDs) > 0 {
+		if err := database.SetUserMovieStatuses(h.db, user.ID, movieIDs, req.Status); err != nil {
+			h.logger.Error("Batch status: failed to update movies", "user_id", user.ID, "error", err)
+			http.Error(w, "Failed to update movie status", http.StatusInternalServerError)
+			return
+		}
+		for _, i := range resolved {
+			results[i].Success = true
+		}
+	}
+
+	updated := len(movieIDs)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  req.Status,
+		"updated": updated,
+		"failed":  len(results) - updated,
+		"results": results,
+	})
+}
+
+// postActivity adds a feed post about the user's activity on a movie, unless they turned off
+// sharing it. Failures are logged; the activity itself has already been saved.
+func (h *MovieHandler) postActivity(userID int, postType string, movieID int, rating *float64) {
+	prefs, err := database.GetUserPreferences(h.db, userID)
+	if err != nil {
+		h.logger.Error("Failed to get user preferences for feed post", "user_id", userID, "error", err)
+		return
+	}
+	if !prefs.ShareActivity {
+		return
+	}
+
+	if err := database.CreateFeedPost(h.db, userID, postType, movieID, rating); err != nil {
+		h.logger.Error("Failed to post activity", "type", postType, "user_id", userID, "error", err)
+	}
+}
+
+func (h *MovieHandler) RateMovie(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req types.RateMovieRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate rating against the scale and its half steps
+	if err := types.ValidateRating(req.Rating); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Resolve the local movie row, caching it from TMDB if needed
+	movieID, err := services.EnsureMovieCached(h.db, h.tmdbClient, tmdbID)
+	if err != nil {
+		writeTMDBError(w, err, "Movie not found", "Failed to get movie")
+		return
+	}
+
+	previous, err := database.GetUserMovie(h.db, user.ID, movieID)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Failed to get user movie", http.StatusInternalServerError)
+		return
+	}
+
+	userMovie, err := database.SetUserMovieRating(h.db, user.ID, movieID, req.Rating)
+	if err != nil {
+		http.Error(w, "Failed to save rating", http.StatusInternalServerError)
+		return
+	}
+
+	if previous == nil || previous.Rating == nil || *previous.Rating != req.Rating {
+		h.postActivity(user.ID, types.FeedPostRated, movieID, userMovie.Rating)
+	}
+
+	response := map[string]interface{}{
+		"tmdb_id":      tmdbID,
+		"rating":       userMovie.Rating,
+		"status":       userMovie.Status,
+		"watched_date": userMovie.WatchedDate,
+		"updated_at":   userMovie.Updated,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *MovieHandler) UpdateNotes(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req types.UpdateNotesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate notes length
+	if utf8.RuneCountInString(req.Notes) > maxNotesLength {
+		http.Error(w, fmt.Sprintf("Notes must be at most %d characters", maxNotesLength), http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Resolve the local movie row, caching it from TMDB if needed
+	movieID, err := services.EnsureMovieCached(h.db, h.tmdbClient, tmdbID)
+	if err != nil {
+		writeTMDBError(w, err, "Movie not found", "Failed to get movie")
+		return
+	}
+
+	// Empty notes clear the stored note
+	var notes *string
+	if strings.TrimSpace(req.Notes) != "" {
+		notes = &req.Notes
+	}
+
+	userMovie, err := database.SetUserMovieNotes(h.db, user.ID, movieID, notes)
+	if err != nil {
+		http.Error(w, "Failed to save notes", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"tmdb_id":    tmdbID,
+		"notes":      userMovie.Notes,
+		"updated_at": userMovie.Updated,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *MovieHandler) UpdateOwnedFormats(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req types.UpdateOwnedFormatsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate and dedupe formats
+	formats, err := types.NormalizeOwnedFormats(req.Formats)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Resolve the local movie row, caching it from TMDB if needed
+	movieID, err := services.EnsureMovieCached(h.db, h.tmdbClient, tmdbID)
+	if err != nil {
+		writeTMDBError(w, err, "Movie not found", "Failed to get movie")
+		return
+	}
+
+	userMovie, err := database.GetOrCreateUserMovie(h.db, user.ID, movieID)
+	if err != nil {
+		http.Error(w, "Failed to save owned formats", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.SetUserMovieFormats(h.db, userMovie.ID, formats); err != nil {
+		http.Error(w, "Failed to save owned formats", http.StatusInternalServerError)
+		return
+	}
+
+	stored, err := database.GetUserMovieFormats(h.db, userMovie.ID)
+	if err != nil {
+		http.Error(w, "Failed to get owned formats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tmdb_id": tmdbID,
+		"formats": stored,
+	})
+}
+
+// GetFormatOptions returns the owned formats UpdateOwnedFormats accepts, with display labels
+func (h *MovieHandler) GetFormatOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"formats": types.OwnedFormatOptions(),
+	})
+}
+
+func (h *MovieHandler) GetOwnedFormats(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Reading never caches the movie; unknown movies simply have no formats
+	formats := []string{}
+	movieID, err := database.GetMovieIDByTMDBID(h.db, tmdbID)
+	if err == nil {
+		userMovie, err := database.GetUserMovie(h.db, user.ID, movieID)
+		if err == nil {
+			formats, err = database.GetUserMovieFormats(h.db, userMovie.ID)
+		}
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, "Failed to get owned formats", http.StatusInternalServerError)
+			return
+		}
+	} else if err != sql.ErrNoRows {
+		http.Error(w, "Failed to get movie", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tmdb_id": tmdbID,
+		"formats": formats,
+	})
+}
+
+func (h *MovieHandler) ToggleFavorite(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Resolve the local movie row, caching it from TMDB if needed
+	movieID, err := services.EnsureMovieCached(h.db, h.tmdbClient, tmdbID)
+	if err != nil {
+		writeTMDBError(w, err, "Movie not found", "Failed to get movie")
+		return
+	}
+
+	userMovie, err := database.ToggleUserMovieFavorite(h.db, user.ID, movieID)
+	if err != nil {
+		http.Error(w, "Failed to update favorite", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(userMovie)
+}
+
+// GetMyStats returns a summary of the user's watched and rated movies
+func (h *MovieHandler) GetMyStats(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	stats, err := database.GetUserMovieStats(h.db, user.ID, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to get user stats", "user_id", user.ID, "error", err)
+		http.Error(w, "Failed to get stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (h *MovieHandler) GetFavorites(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get query parameters for pagination
+	page := utils.GetQueryParamInt(r, "page", 1)
+	limit := utils.GetQueryParamInt(r, "limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	var totalCount int
+	err = h.db.QueryRow(`
+		SELECT COUNT(*) FROM user_movies WHERE user_id = ? AND favorite = 1
+	`, user.ID).Scan(&totalCount)
+	if err != nil {
+		http.Error(w, "Failed to count favorites", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT m.id, m.tmdb_id, m.title, m.year, m.poster_url, m.synopsis,
+		       um.status, um.rating, um.updated_at
+		FROM user_movies um
+		JOIN movies m ON um.movie_id = m.id
+		WHERE um.user_id = ? AND um.favorite = 1
+		ORDER BY um.updated_at DESC
+		LIMIT ? OFFSET ?
+	`, user.ID, limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to get favorites", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	movies := []map[string]interface{}{}
+	for rows.Next() {
+		var movieID, tmdbID int
+		var title, status string
+		var year *int
+		var rating *float64
+		var posterURL, synopsis *string
+		var updatedAt time.Time
+
+		err := rows.Scan(&movieID, &tmdbID, &title, &year, &posterURL, &synopsis, &status, &rating, &updatedAt)
+		if err != nil {
+			continue
+		}
+
+		movie := map[string]interface{}{
+			"id":         movieID,
+			"tmdb_id":    tmdbID,
+			"title":      title,
+			"year":       year,
+			"synopsis":   synopsis,
+			"status":     status,
+			"rating":     rating,
+			"favorite":   true,
+			"updated_at": updatedAt,
+		}
+
+		if posterURL != nil {
+			movie["poster_url"] = *posterURL
+		}
+
+		movies = append(movies, movie)
+	}
+
+	response := map[string]interface{}{
+		"movies":       movies,
+		"count":        len(movies),
+		"total":        totalCount,
+		"total_pages":  (totalCount + limit - 1) / limit,
+		"current_page": page,
+		"per_page":     limit,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SearchNotes searches the current user's private movie notes. Only the caller's own notes
+// are ever searched or returned.
+func (h *MovieHandler) SearchNotes(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := strings.TrimSpace(utils.GetQueryParam(r, "q", ""))
+	if query == "" {
+		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := utils.GetQueryParamInt(r, "limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Escape LIKE wildcards so they match literally
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query)
+
+	rows, err := h.db.Query(`
+		SELECT m.id, m.tmdb_id, m.title, m.year, m.poster_url, um.status, um.rating, um.notes, um.updated_at
+		FROM user_movies um
+		JOIN movies m ON um.movie_id = m.id
+		WHERE um.user_id = ? AND um.notes IS NOT NULL AND um.notes != ''
+		  AND um.notes LIKE ? ESCAPE '\'
+		ORDER BY um.updated_at DESC
+		LIMIT ?
+	`, user.ID, "%"+escaped+"%", limit)
+	if err != nil {
+		http.Error(w, "Failed to search notes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	movies := []map[string]interface{}{}
+	for rows.Next() {
+		var movieID, tmdbID int
+		var title, status, notes string
+		var year *int
+		var rating *float64
+		var posterURL *string
+		var updatedAt time.Time
+
+		err := rows.Scan(&movieID, &tmdbID, &title, &year, &posterURL, &status, &rating, &notes, &updatedAt)
+		if err != nil {
+			continue
+		}
+
+		movie := map[string]interface{}{
+			"id":         movieID,
+			"tmdb_id":    tmdbID,
+			"title":      title,
+			"year":       year,
+			"status":     status,
+			"rating":     rating,
+			"notes":      notes,
+			"updated_at": updatedAt,
+		}
+
+		if posterURL != nil {
+			movie["poster_url"] = *posterURL
+		}
+
+		movies = append(movies, movie)
+	}
+
+	response := map[string]interface{}{
+		"query":  query,
+		"movies": movies,
+		"count":  len(movies),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
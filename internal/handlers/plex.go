@@ -1,10 +1,9 @@
 package handlers
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,12 +11,15 @@ import (
 	"moviedb/internal/auth"
 	"moviedb/internal/database"
 	"moviedb/internal/services"
+	"moviedb/internal/utils"
 )
 
 type PlexHandler struct {
 	db           *sql.DB
 	plexClient   *services.PlexClient   // Keep for authentication
 	plexgoClient *services.PlexgoClient // Use for server operations
+	nowPlaying   *services.NowPlayingService
+	logger       *slog.Logger
 }
 
 type PlexPinRequest struct {
@@ -27,20 +29,29 @@ type PlexPinRequest struct {
 }
 
 type PlexStatusResponse struct {
-	Connected    bool   `json:"connected"`
-	Username     string `json:"username,omitempty"`
-	FriendlyName string `json:"friendlyName,omitempty"`
-	Email        string `json:"email,omitempty"`
-	Thumb        string `json:"thumb,omitempty"`
-	ServerCount  int    `json:"serverCount,omitempty"`
-	ConnectedAt  string `json:"connectedAt,omitempty"`
+	Connected     bool   `json:"connected"`
+	Username      string `json:"username,omitempty"`
+	FriendlyName  string `json:"friendlyName,omitempty"`
+	Email         string `json:"email,omitempty"`
+	Thumb         string `json:"thumb,omitempty"`
+	ServerCount   int    `json:"serverCount,omitempty"`
+	ConnectedAt   string `json:"connectedAt,omitempty"`
+	HomeUserUUID  string `json:"homeUserUuid,omitempty"`
+	HomeUserTitle string `json:"homeUserTitle,omitempty"`
 }
 
-func NewPlexHandler(db *sql.DB) *PlexHandler {
+type SelectHomeUserRequest struct {
+	UUID string `json:"uuid"` // Empty resets to the account owner
+	PIN  string `json:"pin"`  // Required for protected profiles
+}
+
+func NewPlexHandler(db *sql.DB, nowPlaying *services.NowPlayingService) *PlexHandler {
 	return &PlexHandler{
 		db:           db,
 		plexClient:   services.NewPlexClient(),
 		plexgoClient: services.NewPlexgoClient(),
+		nowPlaying:   nowPlaying,
+		logger:       slog.Default(),
 	}
 }
 
@@ -71,7 +82,7 @@ func (h *PlexHandler) StartPlexAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Request PIN from Plex
-	pinResp, err := h.plexClient.RequestPin()
+	pinResp, err := h.plexClient.RequestPin(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to request Plex PIN", http.StatusInternalServerError)
 		return
@@ -146,7 +157,7 @@ func (h *PlexHandler) CheckPlexAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check PIN status with Plex
-	pinResp, err := h.plexClient.CheckPin(pinID)
+	pinResp, err := h.plexClient.CheckPin(r.Context(), pinID)
 	if err != nil {
 		http.Error(w, "Failed to check PIN status", http.StatusInternalServerError)
 		return
@@ -163,14 +174,14 @@ func (h *PlexHandler) CheckPlexAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// PIN has been authorized, get user info
-	plexUser, err := h.plexClient.GetUser(pinResp.AuthToken)
+	plexUser, err := h.plexClient.GetUser(r.Context(), pinResp.AuthToken)
 	if err != nil {
 		http.Error(w, "Failed to get Plex user info", http.StatusInternalServerError)
 		return
 	}
 
 	// Get server count using plexgo (automatically filtered by permissions)
-	ctx := context.Background()
+	ctx := r.Context()
 	servers, err := h.plexgoClient.GetServers(ctx, pinResp.AuthToken)
 	if err != nil {
 		// Don't fail if we can't get servers, just set count to 0
@@ -188,6 +199,9 @@ func (h *PlexHandler) CheckPlexAuth(w http.ResponseWriter, r *http.Request) {
 			plex_email = excluded.plex_email,
 			plex_thumb = excluded.plex_thumb,
 			server_count = excluded.server_count,
+			plex_home_user_uuid = NULL,
+			plex_home_user_title = NULL,
+			plex_profile_token = NULL,
 			updated_at = CURRENT_TIMESTAMP
 	`, user.ID, pinResp.AuthToken, plexUser.Username, plexUser.FriendlyName, plexUser.Email, plexUser.Thumb, len(servers))
 
@@ -203,7 +217,14 @@ func (h *PlexHandler) CheckPlexAuth(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		// Log error but don't fail the request
-		fmt.Printf("Failed to mark PIN attempt as completed: %v\n", err)
+		h.logger.Warn("Failed to mark PIN attempt as completed", "error", err)
+	}
+
+	// Fetch Plex Home profiles so the user can pick which one to sync
+	homeUsers, err := h.plexClient.GetHomeUsers(r.Context(), pinResp.AuthToken)
+	if err != nil {
+		// Accounts without Plex Home just sync as the account owner
+		homeUsers = []services.PlexHomeUser{}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -215,6 +236,7 @@ func (h *PlexHandler) CheckPlexAuth(w http.ResponseWriter, r *http.Request) {
 			"thumb":       plexUser.Thumb,
 			"serverCount": len(servers),
 		},
+		"homeUsers": homeUsers,
 	})
 }
 
@@ -235,13 +257,15 @@ func (h *PlexHandler) GetPlexStatus(w http.ResponseWriter, r *http.Request) {
 
 	var token, username, email, thumb string
 	var friendlyName *string // Use pointer to handle NULL
+	var homeUserUUID, homeUserTitle sql.NullString
 	var serverCount int
 	var createdAt time.Time
 
 	err = h.db.QueryRow(`
-		SELECT plex_token, plex_username, plex_friendly_name, plex_email, plex_thumb, server_count, created_at
+		SELECT plex_token, plex_username, plex_friendly_name, plex_email, plex_thumb, server_count, created_at,
+		       plex_home_user_uuid, plex_home_user_title
 		FROM user_plex_tokens WHERE user_id = ?
-	`, user.ID).Scan(&token, &username, &friendlyName, &email, &thumb, &serverCount, &createdAt)
+	`, user.ID).Scan(&token, &username, &friendlyName, &email, &thumb, &serverCount, &createdAt, &homeUserUUID, &homeUserTitle)
 
 	if err == sql.ErrNoRows {
 		// Not connected
@@ -262,13 +286,15 @@ func (h *PlexHandler) GetPlexStatus(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	response := PlexStatusResponse{
-		Connected:    true,
-		Username:     username,
-		FriendlyName: friendlyNameStr,
-		Email:        email,
-		Thumb:        thumb,
-		ServerCount:  serverCount,
-		ConnectedAt:  createdAt.Format(time.RFC3339),
+		Connected:     true,
+		Username:      username,
+		FriendlyName:  friendlyNameStr,
+		Email:         email,
+		Thumb:         thumb,
+		ServerCount:   serverCount,
+		ConnectedAt:   createdAt.Format(time.RFC3339),
+		HomeUserUUID:  homeUserUUID.String,
+		HomeUserTitle: homeUserTitle.String,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -299,3 +325,313 @@ func (h *PlexHandler) DisconnectPlex(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
 }
+
+// GetHomeUsers lists the Plex Home profiles on the connected account and which one is selected
+func (h *PlexHandler) GetHomeUsers(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	var token string
+	var selectedUUID sql.NullString
+	err = h.db.QueryRow(`
+		SELECT plex_token, plex_home_user_uuid FROM user_plex_tokens WHERE user_id = ?
+	`, user.ID).Scan(&token, &selectedUUID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Plex not connected", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get Plex token", http.StatusInternalServerError)
+		return
+	}
+
+	homeUsers, err := h.plexClient.GetHomeUsers(r.Context(), token)
+	if err != nil {
+		http.Error(w, "Failed to get Plex home users", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"homeUsers":    homeUsers,
+		"selectedUuid": selectedUUID.String,
+	})
+}
+
+// SelectHomeUser selects which Plex Home profile is used for syncing
+func (h *PlexHandler) SelectHomeUser(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req SelectHomeUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	var token string
+	err = h.db.QueryRow(`SELECT plex_token FROM user_plex_tokens WHERE user_id = ?`, user.ID).Scan(&token)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Plex not connected", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get Plex token", http.StatusInternalServerError)
+		return
+	}
+
+	// Empty UUID resets to the account owner
+	if req.UUID == "" {
+		_, err = h.db.Exec(`
+			UPDATE user_plex_tokens
+			SET plex_home_user_uuid = NULL, plex_home_user_title = NULL, plex_profile_token = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = ?
+		`, user.ID)
+		if err != nil {
+			http.Error(w, "Failed to update Plex profile", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "selectedUuid": ""})
+		return
+	}
+
+	// Switch to the profile to obtain a token scoped to its servers and libraries
+	profile, err := h.plexClient.SwitchHomeUser(r.Context(), token, req.UUID, req.PIN)
+	if err != nil {
+		http.Error(w, "Failed to switch Plex profile (check the PIN)", http.StatusBadRequest)
+		return
+	}
+
+	title := profile.Title
+	if title == "" {
+		title = profile.Username
+	}
+
+	_, err = h.db.Exec(`
+		UPDATE user_plex_tokens
+		SET plex_home_user_uuid = ?, plex_home_user_title = ?, plex_profile_token = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ?
+	`, req.UUID, title, profile.AuthToken, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to update Plex profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"selectedUuid":  req.UUID,
+		"homeUserTitle": title,
+	})
+}
+
+// GetNowPlaying returns what the user is currently watching on their Plex servers
+func (h *PlexHandler) GetNowPlaying(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	items, err := h.nowPlaying.GetNowPlaying(r.Context(), int64(user.ID))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Plex not connected", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get now playing", "user_id", user.ID, "error", err)
+		http.Error(w, "Failed to get now playing", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"now_playing": items,
+	})
+}
+
+// GetPlexHistory returns the user's recorded Plex plays, most recent first
+func (h *PlexHandler) GetPlexHistory(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	page := utils.GetQueryParamInt(r, "page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := utils.GetQueryParamInt(r, "limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	var totalCount int
+	err = h.db.QueryRow("SELECT COUNT(*) FROM plex_watch_history WHERE user_id = ?", user.ID).Scan(&totalCount)
+	if err != nil {
+		http.Error(w, "Failed to count Plex history", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT h.id, h.title, h.year, h.tmdb_id, m.poster_url, h.view_count, h.watched_at
+		FROM plex_watch_history h
+		LEFT JOIN movies m ON m.tmdb_id = h.tmdb_id
+		WHERE h.user_id = ?
+		ORDER BY h.watched_at DESC
+		LIMIT ? OFFSET ?
+	`, user.ID, limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to get Plex history", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := []map[string]interface{}{}
+	for rows.Next() {
+		var id, viewCount int
+		var title string
+		var year, tmdbID *int
+		var posterURL *string
+		var watchedAt time.Time
+
+		if err := rows.Scan(&id, &title, &year, &tmdbID, &posterURL, &viewCount, &watchedAt); err != nil {
+			continue
+		}
+
+		history = append(history, map[string]interface{}{
+			"id":         id,
+			"title":      title,
+			"year":       year,
+			"tmdb_id":    tmdbID,
+			"poster_url": posterURL,
+			"view_count": viewCount,
+			"watched_at": watchedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"history":      history,
+		"total":        totalCount,
+		"total_pages":  (totalCount + limit - 1) / limit,
+		"current_page": page,
+		"per_page":     limit,
+	})
+}
+
+// GetContinueWatching returns matched movies the user started on Plex but hasn't finished,
+// most recently played first
+func (h *PlexHandler) GetContinueWatching(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	limit := utils.GetQueryParamInt(r, "limit", 20)
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	// The same movie can be in progress on several Plex items; show it once
+	rows, err := h.db.Query(`
+		SELECT cw.id, cw.tmdb_id, cw.title, cw.year, cw.poster_url, cw.view_offset_ms, cw.duration_ms, cw.updated_at
+		FROM (
+			SELECT m.id, m.tmdb_id, m.title, m.year, m.poster_url, p.view_offset_ms, p.duration_ms, p.updated_at,
+			       ROW_NUMBER() OVER (PARTITION BY m.id ORDER BY p.updated_at DESC) as rank
+			FROM user_movie_progress p
+			JOIN movies m ON m.tmdb_id = (
+				SELECT pli.tmdb_id FROM plex_library_items pli
+				WHERE pli.plex_guid = p.plex_guid AND pli.tmdb_id IS NOT NULL
+				LIMIT 1
+			)
+			WHERE p.user_id = ? AND p.duration_ms > 0
+			  AND p.view_offset_ms * 100 >= p.duration_ms * ?
+			  AND p.view_offset_ms * 100 <= p.duration_ms * ?
+		) AS cw
+		WHERE cw.rank = 1
+		ORDER BY cw.updated_at DESC
+		LIMIT ?
+	`, user.ID, services.ContinueWatchingMinPercent, services.ContinueWatchingMaxPercent, limit)
+	if err != nil {
+		http.Error(w, "Failed to get continue watching", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	movies := []map[string]interface{}{}
+	for rows.Next() {
+		var id, tmdbID, viewOffset, duration int
+		var title string
+		var year *int
+		var posterURL *string
+		var updatedAt time.Time
+
+		if err := rows.Scan(&id, &tmdbID, &title, &year, &posterURL, &viewOffset, &duration, &updatedAt); err != nil {
+			continue
+		}
+
+		movies = append(movies, map[string]interface{}{
+			"id":             id,
+			"tmdb_id":        tmdbID,
+			"title":          title,
+			"year":           year,
+			"poster_url":     posterURL,
+			"view_offset_ms": viewOffset,
+			"duration_ms":    duration,
+			"progress":       viewOffset * 100 / duration,
+			"updated_at":     updatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"movies": movies,
+	})
+}
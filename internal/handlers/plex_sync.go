@@ -4,25 +4,31 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 
 	"moviedb/internal/auth"
 	"moviedb/internal/database"
 	"moviedb/internal/services"
+	"moviedb/internal/utils"
 )
 
 type PlexSyncHandler struct {
 	db         *sql.DB
+	tmdbClient *services.TMDBClient
 	plexClient *services.PlexClient
 	mapper     *services.PlexTMDBMapper
+	logger     *slog.Logger
 }
 
 func NewPlexSyncHandler(db *sql.DB, tmdbClient *services.TMDBClient) *PlexSyncHandler {
 	return &PlexSyncHandler{
 		db:         db,
+		tmdbClient: tmdbClient,
 		plexClient: services.NewPlexClient(),
 		mapper:     services.NewPlexTMDBMapper(db, tmdbClient),
+		logger:     slog.Default(),
 	}
 }
 
@@ -41,10 +47,7 @@ func (h *PlexSyncHandler) SyncPlexLibrary(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	var plexToken string
-	err = h.db.QueryRow(`
-		SELECT plex_token FROM user_plex_tokens WHERE user_id = ?
-	`, user.ID).Scan(&plexToken)
+	plexToken, err := services.GetUserPlexToken(h.db, int64(user.ID))
 
 	if err == sql.ErrNoRows {
 		http.Error(w, "Plex not connected", http.StatusBadRequest)
@@ -56,7 +59,7 @@ func (h *PlexSyncHandler) SyncPlexLibrary(w http.ResponseWriter, r *http.Request
 	}
 
 	// Get user's Plex servers
-	servers, err := h.plexClient.GetServers(plexToken)
+	servers, err := h.plexClient.GetServers(r.Context(), plexToken)
 	if err != nil {
 		http.Error(w, "Failed to get Plex servers", http.StatusInternalServerError)
 		return
@@ -88,8 +91,7 @@ func (h *PlexSyncHandler) SyncPlexLibrary(w http.ResponseWriter, r *http.Request
 			}
 		}
 		
-		fmt.Printf("DEBUG: Processing Plex server: %s\n", serverName)
-		fmt.Printf("DEBUG: Selected server URL: '%s'\n", serverURL)
+		h.logger.Debug("Processing Plex server", "server", serverName, "url", serverURL)
 		
 		debugInfo = append(debugInfo, fmt.Sprintf("Processing server: %s", serverName))
 		debugInfo = append(debugInfo, fmt.Sprintf("  Selected URL: '%s'", serverURL))
@@ -103,7 +105,7 @@ func (h *PlexSyncHandler) SyncPlexLibrary(w http.ResponseWriter, r *http.Request
 		owned, _ := server["owned"].(bool)
 		
 		// Get libraries for this server
-		libraries, err := h.plexClient.GetLibraries(plexToken, serverURL)
+		libraries, err := h.plexClient.GetLibraries(r.Context(), plexToken, serverURL)
 		if err != nil {
 			if !owned {
 				debugInfo = append(debugInfo, fmt.Sprintf("Cannot access libraries on shared server %s (not owner): %v", serverName, err))
@@ -166,7 +168,7 @@ func (h *PlexSyncHandler) SyncPlexLibrary(w http.ResponseWriter, r *http.Request
 			libTitle, _ := library["title"].(string)
 			
 			// Get all movies in this library
-			movies, err := h.plexClient.GetLibraryContent(plexToken, serverURL, libKey)
+			movies, err := h.plexClient.GetLibraryContent(r.Context(), plexToken, serverURL, libKey)
 			if err != nil {
 				totalErrors++
 				continue
@@ -291,6 +293,121 @@ func (h *PlexSyncHandler) SearchPlexMappings(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(response)
 }
 
+// UpdatePlexMapping overrides the TMDB movie a Plex item is mapped to, for when matching
+// picked the wrong one (e.g. a remake). Later syncs keep the override.
+func (h *PlexSyncHandler) UpdatePlexMapping(w http.ResponseWriter, r *http.Request) {
+	mapping, ok := h.userMapping(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TMDBID int `json:"tmdbId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !services.IsPlausibleTMDBID(req.TMDBID) {
+		http.Error(w, "Invalid TMDB ID", http.StatusBadRequest)
+		return
+	}
+
+	// The mapped movie has to be in the movies table
+	if _, err := services.EnsureMovieCached(h.db, h.tmdbClient, req.TMDBID); err != nil {
+		writeTMDBError(w, err, "Movie not found", "Failed to get movie")
+		return
+	}
+
+	updated, err := h.mapper.OverrideMapping(mapping.ID, req.TMDBID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Mapping not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to override Plex mapping", "mapping_id", mapping.ID, "error", err)
+		http.Error(w, "Failed to update mapping", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeletePlexMapping removes a mapping, including an override, so the Plex item is matched
+// again on the next sync
+func (h *PlexSyncHandler) DeletePlexMapping(w http.ResponseWriter, r *http.Request) {
+	mapping, ok := h.userMapping(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.mapper.DeleteMapping(mapping.ID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Mapping not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete Plex mapping", "mapping_id", mapping.ID, "error", err)
+		http.Error(w, "Failed to delete mapping", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Mapping deleted, the item will be matched again on the next sync",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// userMapping loads the mapping in the id path parameter, writing an error unless it exists
+// and maps an item in one of the user's Plex libraries
+func (h *PlexSyncHandler) userMapping(w http.ResponseWriter, r *http.Request) (*services.PlexTMDBMapping, bool) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	mappingID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid mapping ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	mapping, err := h.mapper.GetMappingByID(mappingID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Mapping not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to get mapping", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	// Mappings are shared, so only users with the item in their libraries may change one
+	canAccess, err := h.mapper.UserCanAccessGUID(user.ID, mapping.PlexGUID)
+	if err != nil {
+		http.Error(w, "Failed to verify access", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !canAccess {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return mapping, true
+}
+
 // trySharedUserSync attempts to sync movies for shared users using alternative endpoints
 func (h *PlexSyncHandler) trySharedUserSync(token, serverURL, serverName string) ([]services.PlexLibraryItem, error) {
 	// For shared users, we can't access the full library endpoints
@@ -3,7 +3,9 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,12 +15,18 @@ import (
 	"moviedb/internal/auth"
 	"moviedb/internal/database"
 	"moviedb/internal/services"
+	"moviedb/internal/utils"
 )
 
+// jobEventsKeepAlive is how often an idle job event stream gets a comment, so proxies don't
+// close it
+const jobEventsKeepAlive = 30 * time.Second
+
 // PlexSyncEnhancedHandler handles enhanced Plex sync operations
 type PlexSyncEnhancedHandler struct {
 	syncService    *services.PlexSyncService
 	authMiddleware *jwtmiddleware.JWTMiddleware
+	logger         *slog.Logger
 }
 
 // NewPlexSyncEnhancedHandler creates a new enhanced Plex sync handler
@@ -26,6 +34,7 @@ func NewPlexSyncEnhancedHandler(syncService *services.PlexSyncService, authMiddl
 	return &PlexSyncEnhancedHandler{
 		syncService:    syncService,
 		authMiddleware: authMiddleware,
+		logger:         slog.Default(),
 	}
 }
 
@@ -70,6 +79,8 @@ type JobStatusResponse struct {
 	ProcessedItems  int                    `json:"processed_items"`
 	SuccessfulItems int                    `json:"successful_items"`
 	FailedItems     int                    `json:"failed_items"`
+	Attempts        int                    `json:"attempts"`
+	MaxAttempts     int                    `json:"max_attempts"`
 	ErrorMessage    string                 `json:"error_message,omitempty"`
 	StartedAt       *time.Time             `json:"started_at,omitempty"`
 	CompletedAt     *time.Time             `json:"completed_at,omitempty"`
@@ -77,6 +88,28 @@ type JobStatusResponse struct {
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// newJobStatusResponse converts a job for the job status endpoints
+func newJobStatusResponse(job *services.Job) JobStatusResponse {
+	return JobStatusResponse{
+		JobID:           job.ID,
+		Type:            string(job.Type),
+		Status:          string(job.Status),
+		Progress:        job.Progress,
+		CurrentStep:     job.CurrentStep,
+		TotalItems:      job.TotalItems,
+		ProcessedItems:  job.ProcessedItems,
+		SuccessfulItems: job.SuccessfulItems,
+		FailedItems:     job.FailedItems,
+		Attempts:        job.Attempts,
+		MaxAttempts:     job.MaxAttempts,
+		ErrorMessage:    job.ErrorMessage,
+		StartedAt:       job.StartedAt,
+		CompletedAt:     job.CompletedAt,
+		CreatedAt:       job.CreatedAt,
+		Metadata:        job.Metadata,
+	}
+}
+
 // UserJobsResponse represents the response for user job history
 type UserJobsResponse struct {
 	Jobs []JobStatusResponse `json:"jobs"`
@@ -84,18 +117,61 @@ type UserJobsResponse struct {
 
 // LibraryInfo represents library information
 type LibraryInfo struct {
-	ID         int64  `json:"id"`
-	Title      string `json:"title"`
-	Type       string `json:"type"`
-	ItemCount  int    `json:"item_count"`
-	ServerName string `json:"server_name"`
-	LastSynced string `json:"last_synced"`
-	HasAccess  bool   `json:"has_access"`
+	ID             int64  `json:"id"`
+	Title          string `json:"title"`
+	Type           string `json:"type"`
+	ItemCount      int    `json:"item_count"`
+	ServerName     string `json:"server_name"`
+	LastSynced     string `json:"last_synced"`
+	HasAccess      bool   `json:"has_access"`
+	ConnectionType string `json:"connection_type,omitempty"` // local, remote or relay on the last sync
 }
 
 // UserLibrariesResponse represents the response for user libraries
 type UserLibrariesResponse struct {
-	Libraries []LibraryInfo `json:"libraries"`
+	Libraries       []LibraryInfo `json:"libraries"`
+	AvailableMovies int           `json:"available_movies"` // Distinct matched movies across all libraries
+}
+
+// LibrarySearchLocation is a single copy of a movie in one of the user's libraries
+type LibrarySearchLocation struct {
+	ServerName   string `json:"server_name"`
+	LibraryTitle string `json:"library_title"`
+	RatingKey    string `json:"rating_key"`
+	Owned        bool   `json:"owned"`
+}
+
+// LibrarySearchResult is a matched movie found in the user's synced libraries
+type LibrarySearchResult struct {
+	TMDBID    int                     `json:"tmdb_id"`
+	Title     string                  `json:"title"`
+	Year      *int                    `json:"year,omitempty"`
+	PosterURL *string                 `json:"poster_url,omitempty"`
+	Synopsis  *string                 `json:"synopsis,omitempty"`
+	Available bool                    `json:"available"`
+	Locations []LibrarySearchLocation `json:"locations"`
+}
+
+// PlexMovie is a matched movie the user can play from one of their Plex libraries
+type PlexMovie struct {
+	TMDBID      int      `json:"tmdb_id"`
+	Title       string   `json:"title"`
+	Year        *int     `json:"year,omitempty"`
+	PosterURL   *string  `json:"poster_url,omitempty"`
+	Synopsis    *string  `json:"synopsis,omitempty"`
+	Runtime     *int     `json:"runtime,omitempty"`
+	Genres      []string `json:"genres"`
+	CopyCount   int      `json:"copy_count"`         // Copies across the user's libraries
+	ServerCount int      `json:"server_count"`       // Servers carrying a copy
+	AddedAt     *string  `json:"added_at,omitempty"` // When the first copy was added to Plex
+}
+
+// plexMovieOrders are the sort options of GetPlexMovies and their ORDER BY clauses. Unknown
+// dates and years go last, which Postgres doesn't do by itself for DESC.
+var plexMovieOrders = map[string]string{
+	"added": "uam.first_added_at IS NULL, uam.first_added_at DESC, m.title",
+	"title": "m.title, m.year",
+	"year":  "m.year IS NULL, m.year DESC, m.title",
 }
 
 // TriggerFullSync triggers a full Plex sync for the authenticated user
@@ -108,7 +184,7 @@ func (h *PlexSyncEnhancedHandler) TriggerFullSync(w http.ResponseWriter, r *http
 
 	job, err := h.syncService.TriggerFullSync(userID)
 	if err != nil {
-		fmt.Printf("Failed to trigger full sync for user %d: %v\n", userID, err)
+		h.logger.Error("Failed to trigger full sync", "user_id", userID, "error", err)
 		http.Error(w, fmt.Sprintf("Failed to trigger sync: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -124,6 +200,115 @@ func (h *PlexSyncEnhancedHandler) TriggerFullSync(w http.ResponseWriter, r *http
 	json.NewEncoder(w).Encode(response)
 }
 
+// TriggerTMDBMatching starts a match-only sync that matches the user's already synced
+// library items with TMDB without re-enumerating their Plex libraries
+func (h *PlexSyncEnhancedHandler) TriggerTMDBMatching(w http.ResponseWriter, r *http.Request) {
+	h.triggerMatching(w, r, h.syncService.TriggerTMDBMatching)
+}
+
+// TriggerRematch starts a match-only sync that also retries items that repeatedly failed to
+// match, e.g. after their titles were fixed in Plex
+func (h *PlexSyncEnhancedHandler) TriggerRematch(w http.ResponseWriter, r *http.Request) {
+	h.triggerMatching(w, r, h.syncService.TriggerRematch)
+}
+
+func (h *PlexSyncEnhancedHandler) triggerMatching(w http.ResponseWriter, r *http.Request, trigger func(userID int64) (*services.Job, error)) {
+	userID := h.getUserID(r)
+	if userID == 0 {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := trigger(userID)
+	if errors.Is(err, services.ErrSyncInProgress) {
+		http.Error(w, "A sync is already in progress", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to trigger TMDB matching", "user_id", userID, "error", err)
+		http.Error(w, "Failed to trigger matching", http.StatusInternalServerError)
+		return
+	}
+
+	response := TriggerFullSyncResponse{
+		JobID:     job.ID,
+		Status:    string(job.Status),
+		Message:   "Matching job created successfully",
+		CreatedAt: job.CreatedAt.Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// TriggerLibrarySync starts a sync of a single library, for re-scanning one library without
+// a full sync of every server
+func (h *PlexSyncEnhancedHandler) TriggerLibrarySync(w http.ResponseWriter, r *http.Request) {
+	userID := h.getUserID(r)
+	if userID == 0 {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	libraryID, err := strconv.ParseInt(utils.GetPathParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid library ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.syncService.TriggerLibrarySync(userID, libraryID)
+	if errors.Is(err, services.ErrLibraryNotFound) {
+		http.Error(w, "Library not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrSyncInProgress) {
+		http.Error(w, "A sync is already in progress", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to trigger library sync", "library_id", libraryID, "user_id", userID, "error", err)
+		http.Error(w, "Failed to trigger library sync", http.StatusInternalServerError)
+		return
+	}
+
+	response := TriggerFullSyncResponse{
+		JobID:     job.ID,
+		Status:    string(job.Status),
+		Message:   "Library sync job created successfully",
+		CreatedAt: job.CreatedAt.Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// defaultSyncAllStagger spaces out the syncs started by SyncAllUsers
+const defaultSyncAllStagger = 30 * time.Second
+
+// SyncAllUsers enqueues a full sync for every user with Plex connected (admin only).
+// The delay between syncs can be set with ?stagger_seconds=.
+func (h *PlexSyncEnhancedHandler) SyncAllUsers(w http.ResponseWriter, r *http.Request) {
+	stagger := defaultSyncAllStagger
+	if seconds := utils.GetQueryParamInt(r, "stagger_seconds", -1); seconds >= 0 {
+		stagger = time.Duration(seconds) * time.Second
+	}
+
+	result, err := h.syncService.SyncAllConnectedUsers(stagger)
+	if err != nil {
+		h.logger.Error("Failed to sync all users", "error", err)
+		http.Error(w, "Failed to trigger syncs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"connected_users": result.ConnectedUsers,
+		"enqueued":        result.Enqueued,
+		"skipped":         result.Skipped,
+		"stagger_seconds": int(stagger.Seconds()),
+	})
+}
+
 // GetJobStatus returns the status of a specific job
 func (h *PlexSyncEnhancedHandler) GetJobStatus(w http.ResponseWriter, r *http.Request) {
 	userID := h.getUserID(r)
@@ -159,27 +344,90 @@ func (h *PlexSyncEnhancedHandler) GetJobStatus(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	response := JobStatusResponse{
-		JobID:           job.ID,
-		Type:            string(job.Type),
-		Status:          string(job.Status),
-		Progress:        job.Progress,
-		CurrentStep:     job.CurrentStep,
-		TotalItems:      job.TotalItems,
-		ProcessedItems:  job.ProcessedItems,
-		SuccessfulItems: job.SuccessfulItems,
-		FailedItems:     job.FailedItems,
-		ErrorMessage:    job.ErrorMessage,
-		StartedAt:       job.StartedAt,
-		CompletedAt:     job.CompletedAt,
-		CreatedAt:       job.CreatedAt,
-		Metadata:        job.Metadata,
-	}
+	response := newJobStatusResponse(job)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// StreamJobEvents streams a job's status as Server-Sent Events. The current status is sent
+// right away and again on every change, until the job finishes or the client disconnects.
+func (h *PlexSyncEnhancedHandler) StreamJobEvents(w http.ResponseWriter, r *http.Request) {
+	userID := h.getUserID(r)
+	if userID == 0 {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	jobIDStr := r.PathValue("jobId")
+	if err := validateInput(jobIDStr, 20, "job ID"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jobID, err := strconv.ParseInt(jobIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid job ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validateUserJobAccess(userID, jobID); err != nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before the first read so no change in between is missed
+	jobManager := h.syncService.JobManager()
+	changes, unsubscribe := jobManager.SubscribeJob(jobID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+
+	keepAlive := time.NewTicker(jobEventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		job, err := jobManager.GetJob(jobID)
+		if err != nil {
+			h.logger.Error("Failed to get job for event stream", "job_id", jobID, "error", err)
+			return
+		}
+
+		data, err := json.Marshal(newJobStatusResponse(job))
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+		flusher.Flush()
+
+		if job.Status == services.JobStatusCompleted || job.Status == services.JobStatusFailed || job.Status == services.JobStatusCancelled {
+			return
+		}
+
+	wait:
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+				flusher.Flush()
+			case <-changes:
+				break wait
+			}
+		}
+	}
+}
+
 // GetUserJobs returns the job history for the authenticated user
 func (h *PlexSyncEnhancedHandler) GetUserJobs(w http.ResponseWriter, r *http.Request) {
 	userID := h.getUserID(r)
@@ -207,30 +455,14 @@ func (h *PlexSyncEnhancedHandler) GetUserJobs(w http.ResponseWriter, r *http.Req
 
 	jobs, err := h.syncService.JobManager().GetUserJobs(userID, limit)
 	if err != nil {
-		fmt.Printf("Failed to get user jobs for user %d: %v\n", userID, err)
+		h.logger.Error("Failed to get user jobs", "user_id", userID, "error", err)
 		http.Error(w, "Failed to get jobs", http.StatusInternalServerError)
 		return
 	}
 
 	var jobResponses []JobStatusResponse
 	for _, job := range jobs {
-		jobResponse := JobStatusResponse{
-			JobID:           job.ID,
-			Type:            string(job.Type),
-			Status:          string(job.Status),
-			Progress:        job.Progress,
-			CurrentStep:     job.CurrentStep,
-			TotalItems:      job.TotalItems,
-			ProcessedItems:  job.ProcessedItems,
-			SuccessfulItems: job.SuccessfulItems,
-			FailedItems:     job.FailedItems,
-			ErrorMessage:    job.ErrorMessage,
-			StartedAt:       job.StartedAt,
-			CompletedAt:     job.CompletedAt,
-			CreatedAt:       job.CreatedAt,
-			Metadata:        job.Metadata,
-		}
-		jobResponses = append(jobResponses, jobResponse)
+		jobResponses = append(jobResponses, newJobStatusResponse(job))
 	}
 
 	response := UserJobsResponse{
@@ -251,19 +483,202 @@ func (h *PlexSyncEnhancedHandler) GetUserLibraries(w http.ResponseWriter, r *htt
 
 	libraries, err := h.getUserLibraries(userID)
 	if err != nil {
-		fmt.Printf("Failed to get user libraries for user %d: %v\n", userID, err)
+		h.logger.Error("Failed to get user libraries", "user_id", userID, "error", err)
 		http.Error(w, "Failed to get libraries", http.StatusInternalServerError)
 		return
 	}
 
+	availableMovies, err := h.syncService.CountAvailableMovies(userID)
+	if err != nil {
+		h.logger.Error("Failed to count available movies", "user_id", userID, "error", err)
+	}
+
 	response := UserLibrariesResponse{
-		Libraries: libraries,
+		Libraries:       libraries,
+		AvailableMovies: availableMovies,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// SearchLibrary searches the user's synced Plex libraries for matched movies.
+// Unlike the live Plex search this only uses data from the last sync.
+func (h *PlexSyncEnhancedHandler) SearchLibrary(w http.ResponseWriter, r *http.Request) {
+	userID := h.getUserID(r)
+	if userID == 0 {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+		return
+	}
+
+	// Titles can contain words validateInput rejects, so only the length is checked
+	if len(query) > 200 {
+		http.Error(w, "query exceeds maximum length of 200 characters", http.StatusBadRequest)
+		return
+	}
+
+	limit := utils.GetQueryParamInt(r, "limit", 20)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	results, err := h.searchUserLibrary(userID, query, limit)
+	if err != nil {
+		h.logger.Error("Failed to search libraries", "user_id", userID, "error", err)
+		http.Error(w, "Failed to search libraries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   query,
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+// GetPlexMovies returns a page of the matched movies the user can play from their Plex
+// libraries, with their movie details. Unmatched items are listed by GetUnmatchedItems.
+func (h *PlexSyncEnhancedHandler) GetPlexMovies(w http.ResponseWriter, r *http.Request) {
+	userID := h.getUserID(r)
+	if userID == 0 {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if matched := r.URL.Query().Get("matched"); matched != "" && matched != "true" {
+		http.Error(w, "Only matched movies are listed; see /api/plex/unmatched for the rest", http.StatusBadRequest)
+		return
+	}
+
+	sort := utils.GetQueryParam(r, "sort", "added")
+	if _, ok := plexMovieOrders[sort]; !ok {
+		http.Error(w, "Invalid sort parameter (must be added, title or year)", http.StatusBadRequest)
+		return
+	}
+
+	page := utils.GetQueryParamInt(r, "page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := utils.GetQueryParamInt(r, "limit", 50)
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	total, err := h.syncService.CountAvailableMovies(userID)
+	if err != nil {
+		h.logger.Error("Failed to count Plex movies", "user_id", userID, "error", err)
+		http.Error(w, "Failed to get Plex movies", http.StatusInternalServerError)
+		return
+	}
+
+	movies, err := h.userPlexMovies(userID, sort, limit, (page-1)*limit)
+	if err != nil {
+		h.logger.Error("Failed to get Plex movies", "user_id", userID, "error", err)
+		http.Error(w, "Failed to get Plex movies", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"movies":       movies,
+		"count":        len(movies),
+		"total":        total,
+		"total_pages":  (total + limit - 1) / limit,
+		"current_page": page,
+		"per_page":     limit,
+	})
+}
+
+// GetUnmatchedItems returns the user's Plex movies that couldn't be matched to TMDB, for
+// reviewing and matching them by hand
+func (h *PlexSyncEnhancedHandler) GetUnmatchedItems(w http.ResponseWriter, r *http.Request) {
+	userID := h.getUserID(r)
+	if userID == 0 {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	page := utils.GetQueryParamInt(r, "page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := utils.GetQueryParamInt(r, "limit", 50)
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	items, total, err := h.syncService.UnmatchedItems(userID, limit, (page-1)*limit)
+	if err != nil {
+		h.logger.Error("Failed to get unmatched items", "user_id", userID, "error", err)
+		http.Error(w, "Failed to get unmatched items", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":        items,
+		"count":        len(items),
+		"total":        total,
+		"total_pages":  (total + limit - 1) / limit,
+		"current_page": page,
+		"per_page":     limit,
+	})
+}
+
+// MatchUnmatchedItem matches one of the user's Plex movies to the TMDB movie they chose.
+// The match is kept as an override, so later syncs don't undo it.
+func (h *PlexSyncEnhancedHandler) MatchUnmatchedItem(w http.ResponseWriter, r *http.Request) {
+	userID := h.getUserID(r)
+	if userID == 0 {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	itemID, err := strconv.ParseInt(utils.GetPathParam(r, "itemId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		TMDBID int `json:"tmdb_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !services.IsPlausibleTMDBID(req.TMDBID) {
+		http.Error(w, "Invalid TMDB ID", http.StatusBadRequest)
+		return
+	}
+
+	mapping, err := h.syncService.MatchItemManually(userID, itemID, req.TMDBID)
+	if errors.Is(err, services.ErrItemNotFound) {
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrTMDBNotFound) {
+		http.Error(w, "Movie not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to match item", "item_id", itemID, "user_id", userID, "error", err)
+		http.Error(w, "Failed to match item", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapping)
+}
+
 // CancelJob cancels a running job
 func (h *PlexSyncEnhancedHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
 	userID := h.getUserID(r)
@@ -296,7 +711,7 @@ func (h *PlexSyncEnhancedHandler) CancelJob(w http.ResponseWriter, r *http.Reque
 	// Cancel the job
 	err = h.syncService.JobManager().CancelJob(jobID)
 	if err != nil {
-		fmt.Printf("Failed to cancel job %d: %v\n", jobID, err)
+		h.logger.Error("Failed to cancel job", "job_id", jobID, "error", err)
 		http.Error(w, "Failed to cancel job", http.StatusInternalServerError)
 		return
 	}
@@ -305,11 +720,60 @@ func (h *PlexSyncEnhancedHandler) CancelJob(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
 }
 
+// userPlexMovies retrieves a page of the matched movies in the user's libraries, in the
+// order of one of plexMovieOrders
+func (h *PlexSyncEnhancedHandler) userPlexMovies(userID int64, sort string, limit, offset int) ([]PlexMovie, error) {
+	rows, err := h.syncService.DB().Query(`
+		SELECT m.tmdb_id, m.title, m.year, m.poster_url, m.synopsis, m.runtime, m.genres,
+			   uam.copy_count, uam.server_count, uam.first_added_at
+		FROM user_available_movies uam
+		JOIN movies m ON uam.tmdb_id = m.tmdb_id
+		WHERE uam.user_id = ?
+		ORDER BY `+plexMovieOrders[sort]+`, m.tmdb_id
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []PlexMovie{}
+	for rows.Next() {
+		var movie PlexMovie
+		var genres *string
+
+		err := rows.Scan(
+			&movie.TMDBID,
+			&movie.Title,
+			&movie.Year,
+			&movie.PosterURL,
+			&movie.Synopsis,
+			&movie.Runtime,
+			&genres,
+			&movie.CopyCount,
+			&movie.ServerCount,
+			&movie.AddedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		movie.Genres = []string{}
+		if genres != nil {
+			json.Unmarshal([]byte(*genres), &movie.Genres)
+		}
+
+		movies = append(movies, movie)
+	}
+
+	return movies, rows.Err()
+}
+
 // getUserLibraries retrieves libraries accessible to a user
 func (h *PlexSyncEnhancedHandler) getUserLibraries(userID int64) ([]LibraryInfo, error) {
 	query := `
 		SELECT pl.id, pl.title, pl.type, pl.item_count, ps.name as server_name, 
-			   pl.last_synced_at, upa.is_active
+			   pl.last_synced_at, upa.is_active, pl.connection_type
 		FROM plex_libraries pl
 		JOIN plex_servers ps ON pl.server_id = ps.id
 		JOIN user_plex_access upa ON pl.id = upa.library_id
@@ -326,7 +790,7 @@ func (h *PlexSyncEnhancedHandler) getUserLibraries(userID int64) ([]LibraryInfo,
 	var libraries []LibraryInfo
 	for rows.Next() {
 		var library LibraryInfo
-		var lastSynced *string
+		var lastSynced, connectionType *string
 
 		err := rows.Scan(
 			&library.ID,
@@ -336,6 +800,7 @@ func (h *PlexSyncEnhancedHandler) getUserLibraries(userID int64) ([]LibraryInfo,
 			&library.ServerName,
 			&lastSynced,
 			&library.HasAccess,
+			&connectionType,
 		)
 		if err != nil {
 			continue
@@ -344,6 +809,9 @@ func (h *PlexSyncEnhancedHandler) getUserLibraries(userID int64) ([]LibraryInfo,
 		if lastSynced != nil {
 			library.LastSynced = *lastSynced
 		}
+		if connectionType != nil {
+			library.ConnectionType = *connectionType
+		}
 
 		libraries = append(libraries, library)
 	}
@@ -351,6 +819,74 @@ func (h *PlexSyncEnhancedHandler) getUserLibraries(userID int64) ([]LibraryInfo,
 	return libraries, nil
 }
 
+// searchUserLibrary finds matched movies in the user's active libraries whose title,
+// synopsis or genres match the query, grouping every copy under its movie.
+// The limit applies to distinct movies, not to copies.
+func (h *PlexSyncEnhancedHandler) searchUserLibrary(userID int64, query string, limit int) ([]LibrarySearchResult, error) {
+	pattern := "%" + query + "%"
+
+	rows, err := h.syncService.DB().Query(`
+		WITH matches AS (
+			SELECT uam.tmdb_id, CASE WHEN m.title LIKE ? THEN 0 ELSE 1 END as title_rank
+			FROM user_available_movies uam
+			JOIN movies m ON uam.tmdb_id = m.tmdb_id
+			WHERE uam.user_id = ? AND (m.title LIKE ? OR m.synopsis LIKE ? OR m.genres LIKE ?)
+			ORDER BY title_rank, m.title
+			LIMIT ?
+		)
+		SELECT m.tmdb_id, m.title, m.year, m.poster_url, m.synopsis,
+			   ps.name, pl.title, pli.plex_rating_key,
+			   CASE WHEN ps.owner_user_id = upa.user_id THEN 1 ELSE 0 END as owned
+		FROM matches
+		JOIN movies m ON matches.tmdb_id = m.tmdb_id
+		JOIN plex_library_items pli ON pli.tmdb_id = m.tmdb_id
+		JOIN plex_libraries pl ON pli.library_id = pl.id
+		JOIN plex_servers ps ON pl.server_id = ps.id
+		JOIN user_plex_access upa ON pl.id = upa.library_id
+		WHERE upa.user_id = ? AND upa.is_active = 1 AND pli.is_active = 1
+		ORDER BY matches.title_rank, m.title, owned DESC, ps.name, pl.title
+	`, pattern, userID, pattern, pattern, pattern, limit, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []LibrarySearchResult{}
+	indexByTMDBID := make(map[int]int)
+
+	for rows.Next() {
+		var movie LibrarySearchResult
+		var location LibrarySearchLocation
+
+		err := rows.Scan(
+			&movie.TMDBID,
+			&movie.Title,
+			&movie.Year,
+			&movie.PosterURL,
+			&movie.Synopsis,
+			&location.ServerName,
+			&location.LibraryTitle,
+			&location.RatingKey,
+			&location.Owned,
+		)
+		if err != nil {
+			continue
+		}
+
+		if i, ok := indexByTMDBID[movie.TMDBID]; ok {
+			results[i].Locations = append(results[i].Locations, location)
+			continue
+		}
+
+		movie.Available = true
+		movie.Locations = []LibrarySearchLocation{location}
+		indexByTMDBID[movie.TMDBID] = len(results)
+		results = append(results, movie)
+	}
+
+	return results, rows.Err()
+}
+
 // validateUserJobAccess validates that the user owns the specified job
 func (h *PlexSyncEnhancedHandler) validateUserJobAccess(userID int64, jobID int64) error {
 	var jobUserID sql.NullInt64
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
+	"moviedb/internal/services"
+	"moviedb/internal/utils"
+)
+
+// maxWebhookSize bounds Plex webhook requests, which include a thumbnail for play events
+const maxWebhookSize = 5 << 20
+
+// ReceiveWebhook accepts Plex's multipart webhook requests at /api/plex/webhook/{token}. The
+// token identifies the user; Plex can't send our auth headers.
+func (h *PlexHandler) ReceiveWebhook(w http.ResponseWriter, r *http.Request) {
+	token := utils.GetPathParam(r, "token")
+	if token == "" {
+		http.Error(w, "Unknown webhook", http.StatusNotFound)
+		return
+	}
+
+	var userID int64
+	err := h.db.QueryRow("SELECT user_id FROM user_plex_tokens WHERE webhook_token = ?", token).Scan(&userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Unknown webhook", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to look up webhook", http.StatusInternalServerError)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebhookSize)
+	if err := r.ParseMultipartForm(maxWebhookSize); err != nil {
+		http.Error(w, "Invalid webhook: expected a multipart Plex payload", http.StatusBadRequest)
+		return
+	}
+
+	var payload services.PlexWebhookPayload
+	if err := json.Unmarshal([]byte(r.FormValue("payload")), &payload); err != nil {
+		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
+		return
+	}
+
+	handled, err := h.nowPlaying.HandleWebhook(userID, &payload)
+	if err != nil {
+		h.logger.Error("Failed to handle Plex webhook", "event", payload.Event, "user_id", userID, "error", err)
+		http.Error(w, "Failed to handle webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"handled": handled})
+}
+
+// GetWebhookURL returns the URL to paste into Plex's webhook settings, creating it on first use
+func (h *PlexHandler) GetWebhookURL(w http.ResponseWriter, r *http.Request) {
+	h.serveWebhookURL(w, r, false)
+}
+
+// RegenerateWebhookURL replaces the user's webhook URL, e.g. after it leaked. The old URL
+// stops working.
+func (h *PlexHandler) RegenerateWebhookURL(w http.ResponseWriter, r *http.Request) {
+	h.serveWebhookURL(w, r, true)
+}
+
+func (h *PlexHandler) serveWebhookURL(w http.ResponseWriter, r *http.Request, regenerate bool) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	var token sql.NullString
+	err = h.db.QueryRow("SELECT webhook_token FROM user_plex_tokens WHERE user_id = ?", user.ID).Scan(&token)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Plex not connected", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get webhook", http.StatusInternalServerError)
+		return
+	}
+
+	if !token.Valid || regenerate {
+		token.String, err = newWebhookToken()
+		if err != nil {
+			http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+			return
+		}
+		if _, err := h.db.Exec("UPDATE user_plex_tokens SET webhook_token = ? WHERE user_id = ?", token.String, user.ID); err != nil {
+			http.Error(w, "Failed to save webhook", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhookUrl": fmt.Sprintf("%s/api/plex/webhook/%s", requestBaseURL(r), token.String),
+	})
+}
+
+// newWebhookToken returns a random 256-bit token
+func newWebhookToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// requestBaseURL returns the scheme and host the request reached us on, honoring a TLS
+// terminating proxy
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
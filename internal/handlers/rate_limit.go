@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// userRateLimiter is a simple per-user sliding window limiter used to protect
+// upstream budgets (e.g. TMDB) from a single user or scraper
+type userRateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+}
+
+func newUserRateLimiter(limit int, window time.Duration) *userRateLimiter {
+	return &userRateLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow records a hit for key and reports whether it is within the limit.
+// When the limit is exceeded it also returns how long until the next slot frees up.
+func (l *userRateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+
+	// Drop hits that fell out of the window
+	hits := l.hits[key]
+	kept := hits[:0]
+	for _, t := range hits {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+
+	if len(kept) >= l.limit {
+		l.hits[key] = kept
+		return false, kept[0].Sub(cutoff)
+	}
+
+	l.hits[key] = append(kept, now)
+
+	// Keep the map from growing without bound
+	if len(l.hits) > 10000 {
+		for k, v := range l.hits {
+			if len(v) == 0 || !v[len(v)-1].After(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+	}
+
+	return true, 0
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
+	"moviedb/internal/services"
+	"moviedb/internal/types"
+)
+
+// ratingsImportInlineLimit is the largest import processed within the request;
+// bigger files are handed to a background job
+const ratingsImportInlineLimit = 50
+
+// ratingDateFormats are the watched date formats accepted in imports
+var ratingDateFormats = []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05", "01/02/2006"}
+
+// RatingsImportHandler handles importing ratings exported from other services
+type RatingsImportHandler struct {
+	db       *sql.DB
+	importer *services.RatingsImporter
+	logger   *slog.Logger
+}
+
+// NewRatingsImportHandler creates a new ratings import handler
+func NewRatingsImportHandler(db *sql.DB, importer *services.RatingsImporter) *RatingsImportHandler {
+	return &RatingsImportHandler{db: db, importer: importer, logger: slog.Default()}
+}
+
+// ImportRatings imports a CSV of ratings uploaded as the multipart field "file". Letterboxd
+// (Name, Year, Rating, Watched Date) and IMDb (Const, Title, Year, Your Rating, Date Rated)
+// exports are understood, as is a plain title,year,rating,watched_date file. Small files are
+// imported right away with per-row results; larger ones return a job to poll for progress.
+func (h *RatingsImportHandler) ImportRatings(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		http.Error(w, "Invalid upload: expected a multipart CSV file up to 5 MB", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	rows, err := parseRatingsCSV(file)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid ratings CSV: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+	userID := int64(user.ID)
+
+	if len(rows) > ratingsImportInlineLimit {
+		job, err := h.importer.StartImportJob(userID, rows)
+		if err != nil {
+			h.logger.Error("Failed to start ratings import", "user_id", user.ID, "error", err)
+			http.Error(w, "Failed to start import", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"job_id":     job.ID,
+			"status":     string(job.Status),
+			"total_rows": len(rows),
+			"status_url": fmt.Sprintf("/api/plex/sync/status/%d", job.ID),
+		})
+		return
+	}
+
+	results := h.importer.ImportRatings(r.Context(), userID, rows)
+
+	response := services.SummarizeRatingImport(results)
+	response["total_rows"] = len(rows)
+	response["results"] = results
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseRatingsCSV reads a ratings CSV, finding columns by header name
+func parseRatingsCSV(reader io.Reader) ([]services.RatingImportRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, exists := columns[name]; !exists {
+			columns[name] = i
+		}
+	}
+
+	find := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	titleCol := find("title", "name")
+	yearCol := find("year")
+	imdbCol := find("const", "imdb_id", "imdb id", "imdbid")
+	dateCol := find("watched date", "watched_date", "date rated", "date")
+	ratingCol := find("rating", "your rating")
+	if titleCol < 0 && imdbCol < 0 {
+		return nil, fmt.Errorf("no title or IMDb id column found")
+	}
+
+	// IMDb and plain files rate out of 10 like we do; Letterboxd uses 0.5-5 stars
+	fivePointScale := find("letterboxd uri") >= 0
+
+	field := func(record []string, col int) string {
+		if col < 0 || col >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[col])
+	}
+
+	var rows []services.RatingImportRow
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := services.RatingImportRow{
+			Title:  field(record, titleCol),
+			IMDbID: field(record, imdbCol),
+		}
+		if row.Title == "" && row.IMDbID == "" {
+			continue
+		}
+
+		row.Year, _ = strconv.Atoi(field(record, yearCol))
+
+		if value, err := strconv.ParseFloat(field(record, ratingCol), 64); err == nil && value > 0 {
+			rating := normalizeImportedRating(value, fivePointScale)
+			row.Rating = &rating
+		}
+
+		if value := field(record, dateCol); value != "" {
+			for _, format := range ratingDateFormats {
+				if date, err := time.Parse(format, value); err == nil {
+					row.WatchedDate = &date
+					break
+				}
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no ratings found")
+	}
+
+	return rows, nil
+}
+
+// normalizeImportedRating converts a rating to our 1-10 scale, rounded to the nearest half step
+func normalizeImportedRating(value float64, fivePointScale bool) float64 {
+	if fivePointScale {
+		value = value * 2
+	}
+
+	return types.RoundRating(value)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"moviedb/internal/services"
+)
+
+func TestNormalizeImportedRating(t *testing.T) {
+	tests := []struct {
+		value          float64
+		fivePointScale bool
+		want           float64
+	}{
+		{3.5, true, 7}, // Letterboxd half stars double onto our scale
+		{0.5, true, 1},
+		{4, true, 8},
+		{5, true, 10},
+		{8, false, 8}, // IMDb and plain files already rate out of 10
+		{1, false, 1},
+		{7.6, false, 7.5}, // Half steps are kept, anything finer is rounded
+		{7.5, false, 7.5},
+		{4.3, true, 8.5},
+		{12, false, 10},
+		{0.2, false, 1},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeImportedRating(tt.value, tt.fivePointScale); got != tt.want {
+			t.Errorf("normalizeImportedRating(%v, %v) = %v, want %v", tt.value, tt.fivePointScale, got, tt.want)
+		}
+	}
+}
+
+func TestParseRatingsCSV(t *testing.T) {
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+	rating := func(r float64) *float64 { return &r }
+
+	tests := []struct {
+		name string
+		csv  string
+		want []services.RatingImportRow
+	}{
+		{
+			name: "letterboxd",
+			csv: "Date,Name,Year,Letterboxd URI,Rating\n" +
+				"2024-01-05,Dune,2021,https://boxd.it/a,3.5\n" +
+				"2024-01-06,Heat,1995,https://boxd.it/b,5\n" +
+				"2024-01-07,Arrival,2016,https://boxd.it/c,\n",
+			want: []services.RatingImportRow{
+				{Title: "Dune", Year: 2021, Rating: rating(7), WatchedDate: date(2024, 1, 5)},
+				{Title: "Heat", Year: 1995, Rating: rating(10), WatchedDate: date(2024, 1, 6)},
+				{Title: "Arrival", Year: 2016, WatchedDate: date(2024, 1, 7)},
+			},
+		},
+		{
+			name: "imdb",
+			csv: "\ufeffConst,Your Rating,Date Rated,Title,URL,Title Type,IMDb Rating,Year\n" +
+				"tt1160419,8,2023-11-02,Dune,https://www.imdb.com/title/tt1160419/,Movie,8.0,2021\n" +
+				"tt0113277,10,2023-11-03,Heat,https://www.imdb.com/title/tt0113277/,Movie,8.3,1995\n",
+			want: []services.RatingImportRow{
+				{Title: "Dune", Year: 2021, IMDbID: "tt1160419", Rating: rating(8), WatchedDate: date(2023, 11, 2)},
+				{Title: "Heat", Year: 1995, IMDbID: "tt0113277", Rating: rating(10), WatchedDate: date(2023, 11, 3)},
+			},
+		},
+		{
+			name: "plain file",
+			csv: "title,year,rating,watched_date\n" +
+				"Dune,2021,7,2024-02-01\n" +
+				"\"Crouching Tiger, Hidden Dragon\",2000,9,03/04/2024\n" +
+				",,,\n" +
+				"Heat,,6,\n",
+			want: []services.RatingImportRow{
+				{Title: "Dune", Year: 2021, Rating: rating(7), WatchedDate: date(2024, 2, 1)},
+				{Title: "Crouching Tiger, Hidden Dragon", Year: 2000, Rating: rating(9), WatchedDate: date(2024, 3, 4)},
+				{Title: "Heat", Rating: rating(6)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseRatingsCSV(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatalf("parseRatingsCSV() error = %v", err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i, row := range rows {
+				want := tt.want[i]
+				if row.Title != want.Title || row.Year != want.Year || row.IMDbID != want.IMDbID {
+					t.Errorf("row %d = %q (%d) %q, want %q (%d) %q", i+1, row.Title, row.Year, row.IMDbID, want.Title, want.Year, want.IMDbID)
+				}
+				if (row.Rating == nil) != (want.Rating == nil) || (row.Rating != nil && *row.Rating != *want.Rating) {
+					t.Errorf("row %d rating = %v, want %v", i+1, formatRating(row.Rating), formatRating(want.Rating))
+				}
+				if (row.WatchedDate == nil) != (want.WatchedDate == nil) || (row.WatchedDate != nil && !row.WatchedDate.Equal(*want.WatchedDate)) {
+					t.Errorf("row %d watched date = %v, want %v", i+1, row.WatchedDate, want.WatchedDate)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRatingsCSVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"empty file", ""},
+		{"no title or IMDb id column", "year,rating\n2021,8\n"},
+		{"no rows", "title,year,rating\n"},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseRatingsCSV(strings.NewReader(tt.csv)); err == nil {
+			t.Errorf("%s: parseRatingsCSV() succeeded, want an error", tt.name)
+		}
+	}
+}
+
+// formatRating prints an optional rating for test failures
+func formatRating(rating *float64) string {
+	if rating == nil {
+		return "none"
+	}
+	return strconv.FormatFloat(*rating, 'f', -1, 64)
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
+	"moviedb/internal/services"
+)
+
+// Which of the user's movies recommendations are based on and how many are returned
+const (
+	recommendationMinRating  = 7
+	recommendationSeedLimit  = 5
+	recommendationGenreLimit = 2
+	maxRecommendations       = 20
+)
+
+// How much a candidate scores for turning up in a seed's similar movies (scaled by the seed's
+// rating), in a favorite genre's discover page, and for sharing the user's favorite genres
+const (
+	recommendationDiscoverWeight = 0.5
+	recommendationGenreWeight    = 0.25
+)
+
+type recommendationCandidate struct {
+	movie     services.TMDBMovie
+	score     float64
+	becauseOf []string
+}
+
+// GetMyRecommendations suggests movies based on what the user rated highly: movies similar
+// to their top rated ones and popular movies in their favorite genres, leaving out anything
+// already in their movies or lists. Movies that turn up for several seeds or share more of
+// the user's favorite genres rank higher.
+func (h *MovieHandler) GetMyRecommendations(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	seeds, err := database.GetRecommendationSeeds(h.db, user.ID, recommendationMinRating, recommendationSeedLimit)
+	if err != nil {
+		h.logger.Error("Failed to get recommendation seeds", "user_id", user.ID, "error", err)
+		http.Error(w, "Failed to get recommendations", http.StatusInternalServerError)
+		return
+	}
+
+	known, err := database.GetUserKnownTMDBIDs(h.db, user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get known movies", "user_id", user.ID, "error", err)
+		http.Error(w, "Failed to get recommendations", http.StatusInternalServerError)
+		return
+	}
+
+	// Weigh genres by the ratings of the seeds they appear in
+	genreWeights := make(map[int]float64)
+	maxGenreWeight := 0.0
+	for _, seed := range seeds {
+		for _, name := range seed.Genres {
+			if id, ok := services.TMDBMovieGenreID(name); ok {
+				genreWeights[id] += seed.Rating
+				maxGenreWeight = math.Max(maxGenreWeight, genreWeights[id])
+			}
+		}
+	}
+
+	candidates := make(map[int]*recommendationCandidate)
+	addCandidates := func(results []services.TMDBMovie, weight float64, becauseOf string) {
+		for _, movie := range results {
+			if known[movie.ID] || movie.Adult {
+				continue
+			}
+			candidate, ok := candidates[movie.ID]
+			if !ok {
+				candidate = &recommendationCandidate{movie: movie, becauseOf: []string{}}
+				candidates[movie.ID] = candidate
+			}
+			candidate.score += weight
+			if becauseOf != "" {
+				candidate.becauseOf = append(candidate.becauseOf, becauseOf)
+			}
+		}
+	}
+
+	fetched, failed := 0, 0
+	for _, seed := range seeds {
+		seed := seed
+		searchResp, err := h.cachedTMDBPage(int64(user.ID), fmt.Sprintf("similar:%d:1", seed.TMDBID), func() (*services.TMDBSearchResponse, error) {
+			return h.tmdbClient.GetSimilarMovies(seed.TMDBID, 1)
+		})
+		if err != nil {
+			h.logger.Warn("Recommendations: failed to get similar movies", "tmdb_id", seed.TMDBID, "error", err)
+			failed++
+			continue
+		}
+		fetched++
+		addCandidates(searchResp.Results, seed.Rating/10, seed.Title)
+	}
+
+	for _, genreID := range topGenreIDs(genreWeights, recommendationGenreLimit) {
+		genreID := genreID
+		searchResp, err := h.cachedTMDBPage(int64(user.ID), fmt.Sprintf("discover-genre:%d", genreID), func() (*services.TMDBSearchResponse, error) {
+			return h.tmdbClient.DiscoverMovies(services.DiscoverParams{
+				GenreIDs:     []int{genreID},
+				MinVoteCount: 200,
+				SortBy:       "popularity.desc",
+			})
+		})
+		if err != nil {
+			h.logger.Warn("Recommendations: failed to discover movies in genre", "genre_id", genreID, "error", err)
+			failed++
+			continue
+		}
+		fetched++
+		addCandidates(searchResp.Results, recommendationDiscoverWeight, "")
+	}
+
+	if fetched == 0 && failed > 0 {
+		http.Error(w, "Failed to get recommendations", http.StatusBadGateway)
+		return
+	}
+
+	ranked := make([]*recommendationCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		for _, id := range candidate.movie.GenreIDs {
+			if maxGenreWeight > 0 {
+				candidate.score += recommendationGenreWeight * genreWeights[id] / maxGenreWeight
+			}
+		}
+		ranked = append(ranked, candidate)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		if ranked[i].movie.Popularity != ranked[j].movie.Popularity {
+			return ranked[i].movie.Popularity > ranked[j].movie.Popularity
+		}
+		return ranked[i].movie.ID < ranked[j].movie.ID
+	})
+	if len(ranked) > maxRecommendations {
+		ranked = ranked[:maxRecommendations]
+	}
+
+	results := make([]map[string]interface{}, len(ranked))
+	for i, candidate := range ranked {
+		results[i] = map[string]interface{}{
+			"id":         candidate.movie.ID,
+			"tmdb_id":    candidate.movie.ID,
+			"title":      candidate.movie.Title,
+			"year":       services.ExtractYear(candidate.movie.ReleaseDate),
+			"poster_url": h.tmdbClient.GetPosterURL(candidate.movie.PosterPath, "w500"),
+			"synopsis":   candidate.movie.Overview,
+			"vote_avg":   candidate.movie.VoteAverage,
+			"score":      math.Round(candidate.score*100) / 100,
+			"because_of": candidate.becauseOf,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results":  results,
+		"based_on": seeds,
+	})
+}
+
+// cachedTMDBPage returns a cached TMDB page, fetching it through the rate limiter on a miss
+func (h *MovieHandler) cachedTMDBPage(userID int64, cacheKey string, fetch func() (*services.TMDBSearchResponse, error)) (*services.TMDBSearchResponse, error) {
+	if searchResp, ok := h.relatedCache.Get(cacheKey); ok {
+		return searchResp, nil
+	}
+
+	var searchResp *services.TMDBSearchResponse
+	err := h.rateLimiter.ExecuteWithRateLimit(func() error {
+		var err error
+		searchResp, err = fetch()
+		return err
+	}, 2, userID) // Priority 2 - user is waiting on their recommendations
+	if err != nil {
+		return nil, err
+	}
+
+	h.relatedCache.Set(cacheKey, searchResp)
+	return searchResp, nil
+}
+
+// topGenreIDs returns up to limit genre ids with the highest weights
+func topGenreIDs(weights map[int]float64, limit int) []int {
+	ids := make([]int, 0, len(weights))
+	for id := range weights {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if weights[ids[i]] != weights[ids[j]] {
+			return weights[ids[i]] > weights[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids
+}
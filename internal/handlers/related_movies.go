@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"moviedb/internal/services"
+	"moviedb/internal/utils"
+)
+
+// relatedMoviesCacheTTL is how long similar and recommended movie pages are reused
+const relatedMoviesCacheTTL = 30 * time.Minute
+
+// relatedMoviesCache keeps recent similar/recommendations pages in memory so the
+// "more like this" row doesn't call TMDB on every detail page view
+type relatedMoviesCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]relatedMoviesEntry
+}
+
+type relatedMoviesEntry struct {
+	response  *services.TMDBSearchResponse
+	expiresAt time.Time
+}
+
+func newRelatedMoviesCache(ttl time.Duration) *relatedMoviesCache {
+	return &relatedMoviesCache{
+		ttl:     ttl,
+		entries: make(map[string]relatedMoviesEntry),
+	}
+}
+
+// Get returns a cached page if it hasn't expired
+func (c *relatedMoviesCache) Get(key string) (*services.TMDBSearchResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.response, true
+}
+
+// Set stores a page, dropping expired entries once the cache grows large
+func (c *relatedMoviesCache) Set(key string, response *services.TMDBSearchResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) > 1000 {
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+
+	c.entries[key] = relatedMoviesEntry{response: response, expiresAt: now.Add(c.ttl)}
+}
+
+// GetSimilarMovies returns movies similar to a movie (shared genres and keywords)
+func (h *MovieHandler) GetSimilarMovies(w http.ResponseWriter, r *http.Request) {
+	h.serveRelatedMovies(w, r, "similar", h.tmdbClient.GetSimilarMovies)
+}
+
+// GetRecommendedMovies returns TMDB's recommendations for a movie. These are based on
+// what users who liked it also liked, so they differ from the similar movies.
+func (h *MovieHandler) GetRecommendedMovies(w http.ResponseWriter, r *http.Request) {
+	h.serveRelatedMovies(w, r, "recommendations", h.tmdbClient.GetMovieRecommendations)
+}
+
+// serveRelatedMovies serves a page of related movies in the search results format,
+// from the cache when possible and otherwise through the TMDB rate limiter
+func (h *MovieHandler) serveRelatedMovies(w http.ResponseWriter, r *http.Request, kind string, fetch func(tmdbID int, page int) (*services.TMDBSearchResponse, error)) {
+	movieID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Short-circuit ids that cannot exist on TMDB
+	if !services.IsPlausibleTMDBID(movieID) {
+		http.Error(w, "Movie not found", http.StatusNotFound)
+		return
+	}
+
+	page := utils.GetQueryParamInt(r, "page", 1)
+	if page < 1 {
+		page = 1
+	}
+
+	cacheKey := fmt.Sprintf("%s:%d:%d", kind, movieID, page)
+	searchResp, ok := h.relatedCache.Get(cacheKey)
+	if !ok {
+		// Cache miss - limit how often a single user can make us call TMDB
+		if !h.allowColdFetch(w, r) {
+			return
+		}
+
+		err = h.rateLimiter.ExecuteWithRateLimit(func() error {
+			var err error
+			searchResp, err = fetch(movieID, page)
+			return err
+		}, 2, h.requestUserID(r)) // Priority 2 - user is waiting on the detail page
+		if err != nil {
+			h.logger.Error("Failed to get related movies", "kind", kind, "tmdb_id", movieID, "error", err)
+			http.Error(w, fmt.Sprintf("Failed to get %s movies", kind), http.StatusBadGateway)
+			return
+		}
+
+		h.relatedCache.Set(cacheKey, searchResp)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.searchResultsResponse(searchResp))
+}
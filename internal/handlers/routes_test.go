@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// serverMainPath is the file the production routes are registered in
+const serverMainPath = "../../cmd/server/main.go"
+
+// knownStubs are the handlers that still answer 501 Not Implemented. Remove a handler from
+// the list when it gets implemented.
+var knownStubs = map[string]bool{
+	"UserHandler.SetupUser": true,
+}
+
+// route is a production route and the handler method serving it
+type route struct {
+	pattern string
+	handler string // Type.Method, empty when the route isn't served by a handler method
+}
+
+// productionRoutes returns the routes cmd/server registers on its mux, with the handler
+// method of each, found from the handler variables assigned from handlers.New... calls
+func productionRoutes(t *testing.T) []route {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), serverMainPath, nil, 0)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", serverMainPath, err)
+	}
+
+	handlerTypes := make(map[string]string)
+	ast.Inspect(file, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			return true
+		}
+		name, ok := assign.Lhs[0].(*ast.Ident)
+		call, isCall := assign.Rhs[0].(*ast.CallExpr)
+		if !ok || !isCall {
+			return true
+		}
+		if fn, ok := call.Fun.(*ast.SelectorExpr); ok && isIdent(fn.X, "handlers") && strings.HasPrefix(fn.Sel.Name, "New") {
+			handlerTypes[name.Name] = strings.TrimPrefix(fn.Sel.Name, "New")
+		}
+		return true
+	})
+
+	var routes []route
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		fn, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || !isIdent(fn.X, "mux") || (fn.Sel.Name != "HandleFunc" && fn.Sel.Name != "Handle") {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true // Patterns built at runtime, i.e. the SPA routes
+		}
+		pattern, _ := strconv.Unquote(lit.Value)
+
+		r := route{pattern: pattern}
+		ast.Inspect(call.Args[1], func(n ast.Node) bool {
+			if r.handler != "" {
+				return false
+			}
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && handlerTypes[x.Name] != "" {
+					r.handler = handlerTypes[x.Name] + "." + sel.Sel.Name
+				}
+			}
+			return true
+		})
+		routes = append(routes, r)
+		return true
+	})
+
+	if len(routes) == 0 {
+		t.Fatalf("no routes found in %s", serverMainPath)
+	}
+	return routes
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// TestProductionRoutesRegister registers every production pattern on a fresh mux, which
+// panics on patterns that conflict
+func TestProductionRoutesRegister(t *testing.T) {
+	mux := http.NewServeMux()
+	registered := make(map[string]bool)
+
+	for _, r := range productionRoutes(t) {
+		// The same pattern twice is the static file server, registered in either branch of
+		// an if
+		if registered[r.pattern] {
+			continue
+		}
+		registered[r.pattern] = true
+
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					t.Errorf("route %q can't be registered: %v", r.pattern, p)
+				}
+			}()
+			mux.HandleFunc(r.pattern, func(http.ResponseWriter, *http.Request) {})
+		}()
+	}
+}
+
+// handlerMethods returns every method of the handlers package, as Type.Method, and whether
+// it answers 501 Not Implemented itself or through a function or method of the package
+// that it calls
+func handlerMethods(t *testing.T) map[string]bool {
+	t.Helper()
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the handlers package: %v", err)
+	}
+
+	bodies := make(map[string]*ast.BlockStmt)
+	for _, file := range pkgs["handlers"].Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				bodies[funcName(fn)] = fn.Body
+			}
+		}
+	}
+
+	memo := make(map[string]bool)
+	visiting := make(map[string]bool)
+	var answers501 func(name string) bool
+	answers501 = func(name string) bool {
+		if result, ok := memo[name]; ok {
+			return result
+		}
+		if visiting[name] {
+			return false
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		typeName, _, isMethod := strings.Cut(name, ".")
+		found := false
+		ast.Inspect(bodies[name], func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if isIdent(n.X, "http") && n.Sel.Name == "StatusNotImplemented" {
+					found = true
+				} else if callee := typeName + "." + n.Sel.Name; isMethod && bodies[callee] != nil {
+					found = answers501(callee) // h.helper(...) on the same handler
+				}
+			case *ast.BasicLit:
+				found = n.Kind == token.INT && n.Value == "501"
+			case *ast.Ident:
+				if bodies[n.Name] != nil && n.Name != name {
+					found = answers501(n.Name) // A function of the package
+				}
+			}
+			return !found
+		})
+
+		memo[name] = found
+		return found
+	}
+
+	methods := make(map[string]bool)
+	for name := range bodies {
+		if strings.Contains(name, ".") {
+			methods[name] = answers501(name)
+		}
+	}
+	return methods
+}
+
+// funcName returns Type.Method for methods and the name of plain functions
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// TestNoRouteIsNotImplemented fails when the handler of a production route answers 501
+// Not Implemented, so a route can't ship as a stub. Known stubs are skipped until they
+// are implemented.
+func TestNoRouteIsNotImplemented(t *testing.T) {
+	methods := handlerMethods(t)
+
+	for _, r := range productionRoutes(t) {
+		if r.handler == "" {
+			continue
+		}
+
+		stub, ok := methods[r.handler]
+		switch {
+		case !ok:
+			t.Errorf("route %q: handler %s not found in the handlers package", r.pattern, r.handler)
+		case stub && !knownStubs[r.handler]:
+			t.Errorf("route %q: %s answers 501 Not Implemented", r.pattern, r.handler)
+		case !stub && knownStubs[r.handler]:
+			t.Errorf("route %q: %s is implemented now, remove it from knownStubs", r.pattern, r.handler)
+		}
+	}
+}
@@ -3,11 +3,16 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"moviedb/internal/auth"
 	"moviedb/internal/database"
+	"moviedb/internal/services"
 	"moviedb/internal/types"
 	"moviedb/internal/utils"
 )
@@ -38,9 +43,59 @@ func (h *UserHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+const maxDisplayNameLength = 100
+
+// usernamePattern allows 3-30 letters, digits and underscores
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
 func (h *UserHandler) UpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement user update
-	w.WriteHeader(http.StatusNotImplemented)
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req types.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate name
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxDisplayNameLength {
+			http.Error(w, fmt.Sprintf("Name must be 1-%d characters", maxDisplayNameLength), http.StatusBadRequest)
+			return
+		}
+		req.Name = &name
+	}
+
+	// Validate username
+	if req.Username != nil && !usernamePattern.MatchString(*req.Username) {
+		http.Error(w, "Username must be 3-30 characters using only letters, numbers and underscores", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := database.UpdateUserProfile(h.db, user.ID, req.Name, req.Username)
+	if err == database.ErrUsernameTaken {
+		http.Error(w, "Username already taken", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
 }
 
 func (h *UserHandler) SetupUser(w http.ResponseWriter, r *http.Request) {
@@ -89,7 +144,7 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 			       COUNT(DISTINCT l.id) as list_count,
 			       COUNT(DISTINCT lm.movie_id) as movie_count
 			FROM users u
-			LEFT JOIN lists l ON u.id = l.user_id AND l.is_public = 1
+			LEFT JOIN lists l ON u.id = l.user_id AND l.is_public = 1 AND l.deleted_at IS NULL
 			LEFT JOIN list_movies lm ON l.id = lm.list_id
 			WHERE (u.name LIKE ? OR u.username LIKE ?) 
 			GROUP BY u.id, u.auth0_id, u.email, u.name, u.username, u.avatar_url, u.created_at
@@ -106,7 +161,7 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 			       COUNT(DISTINCT l.id) as list_count,
 			       COUNT(DISTINCT lm.movie_id) as movie_count
 			FROM users u
-			LEFT JOIN lists l ON u.id = l.user_id AND l.is_public = 1
+			LEFT JOIN lists l ON u.id = l.user_id AND l.is_public = 1 AND l.deleted_at IS NULL
 			LEFT JOIN list_movies lm ON l.id = lm.list_id
 			GROUP BY u.id, u.auth0_id, u.email, u.name, u.username, u.avatar_url, u.created_at
 			ORDER BY u.created_at DESC 
@@ -197,13 +252,58 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// resolveUserID maps a {id} path parameter to a numeric user id. It accepts "me" (or empty),
+// a numeric users.id, or an Auth0 id for backward compatibility. Returns sql.ErrNoRows if
+// no user matches.
+func resolveUserID(db *sql.DB, idParam string, currentUser *types.User) (int, error) {
+	if idParam == "me" || idParam == "" {
+		if currentUser == nil {
+			return 0, sql.ErrNoRows
+		}
+		return currentUser.ID, nil
+	}
+
+	var userID int
+	if numericID, err := strconv.Atoi(idParam); err == nil {
+		err = db.QueryRow("SELECT id FROM users WHERE id = ?", numericID).Scan(&userID)
+		return userID, err
+	}
+
+	// Legacy Auth0 id
+	err := db.QueryRow("SELECT id FROM users WHERE auth0_id = ?", idParam).Scan(&userID)
+	return userID, err
+}
+
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	// Get path parameter
 	userIDStr := utils.GetPathParam(r, "id")
-	
-	// Get user by Auth0 ID
+
+	// Get or create current user in database
+	currentUser, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get current user", http.StatusInternalServerError)
+		return
+	}
+
+	// Resolve "me", numeric id, or legacy Auth0 id
+	userID, err := resolveUserID(h.db, userIDStr, currentUser)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
 	var user types.User
-	err := h.db.QueryRow("SELECT id, auth0_id, email, name, username, avatar_url, created_at FROM users WHERE auth0_id = ?", userIDStr).Scan(
+	err = h.db.QueryRow("SELECT id, auth0_id, email, name, username, avatar_url, created_at FROM users WHERE id = ?", userID).Scan(
 		&user.ID, &user.Auth0ID, &user.Email, &user.Name, &user.Username, &user.AvatarURL, &user.Created)
 	if err == sql.ErrNoRows {
 		http.Error(w, "User not found", http.StatusNotFound)
@@ -248,25 +348,15 @@ func (h *UserHandler) GetUserLists(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Determine target user ID
-	var targetUserID int
-	if userIDStr == "me" || userIDStr == "" {
-		targetUserID = currentUser.ID
-	} else {
-		// For now, treat userID as Auth0 ID - in a real app you might want numeric IDs
-		// Get user by Auth0 ID
-		var targetUser types.User
-		err = h.db.QueryRow("SELECT id, auth0_id, email, name, username, created_at FROM users WHERE auth0_id = ?", userIDStr).Scan(
-			&targetUser.ID, &targetUser.Auth0ID, &targetUser.Email, &targetUser.Name, &targetUser.Username, &targetUser.Created)
-		if err == sql.ErrNoRows {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-		if err != nil {
-			http.Error(w, "Failed to get target user", http.StatusInternalServerError)
-			return
-		}
-		targetUserID = targetUser.ID
+	// Determine target user ID ("me", numeric id, or legacy Auth0 id)
+	targetUserID, err := resolveUserID(h.db, userIDStr, currentUser)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get target user", http.StatusInternalServerError)
+		return
 	}
 
 	isOwnProfile := targetUserID == currentUser.ID
@@ -280,7 +370,7 @@ func (h *UserHandler) GetUserLists(w http.ResponseWriter, r *http.Request) {
 			       COUNT(lm.movie_id) as movie_count
 			FROM lists l
 			LEFT JOIN list_movies lm ON l.id = lm.list_id
-			WHERE l.user_id = ?
+			WHERE l.user_id = ? AND l.deleted_at IS NULL
 			GROUP BY l.id, l.name, l.description, l.is_public, l.created_at
 			ORDER BY l.created_at DESC
 		`
@@ -291,7 +381,7 @@ func (h *UserHandler) GetUserLists(w http.ResponseWriter, r *http.Request) {
 			       COUNT(lm.movie_id) as movie_count
 			FROM lists l
 			LEFT JOIN list_movies lm ON l.id = lm.list_id
-			WHERE l.user_id = ? AND l.is_public = 1
+			WHERE l.user_id = ? AND l.is_public = 1 AND l.deleted_at IS NULL
 			GROUP BY l.id, l.name, l.description, l.is_public, l.created_at
 			ORDER BY l.created_at DESC
 		`
@@ -337,14 +427,154 @@ func (h *UserHandler) GetUserLists(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// AddFriend sends a friend request to the target user (or accepts theirs if they already asked)
 func (h *UserHandler) AddFriend(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement add friend
-	w.WriteHeader(http.StatusNotImplemented)
+	h.updateFriendship(w, r, true)
 }
 
+// RemoveFriend ends a friendship or withdraws a pending request
 func (h *UserHandler) RemoveFriend(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement remove friend
-	w.WriteHeader(http.StatusNotImplemented)
+	h.updateFriendship(w, r, false)
+}
+
+func (h *UserHandler) updateFriendship(w http.ResponseWriter, r *http.Request, add bool) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create current user in database
+	currentUser, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get current user", http.StatusInternalServerError)
+		return
+	}
+
+	// Target user is identified by numeric id (or legacy Auth0 id)
+	targetUserID, err := resolveUserID(h.db, utils.GetPathParam(r, "id"), currentUser)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get target user", http.StatusInternalServerError)
+		return
+	}
+
+	if targetUserID == currentUser.ID {
+		http.Error(w, "You cannot friend yourself", http.StatusBadRequest)
+		return
+	}
+
+	status := ""
+	if add {
+		status, err = database.AddFriend(h.db, currentUser.ID, targetUserID)
+	} else {
+		err = database.RemoveFriend(h.db, currentUser.ID, targetUserID)
+	}
+	if err != nil {
+		http.Error(w, "Failed to update friend", http.StatusInternalServerError)
+		return
+	}
+
+	friendCount, err := database.GetFriendCount(h.db, currentUser.ID)
+	if err != nil {
+		http.Error(w, "Failed to count friends", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       status, // pending, accepted, or empty once removed
+		"friend_count": friendCount,
+	})
+}
+
+// GetFriendRequests lists pending friend requests sent to the current user
+func (h *UserHandler) GetFriendRequests(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	requests, err := database.GetPendingFriendRequests(h.db, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get friend requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"requests": requests,
+		"count":    len(requests),
+	})
+}
+
+// AcceptFriendRequest accepts a pending friend request, making the friendship mutual
+func (h *UserHandler) AcceptFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.respondToFriendRequest(w, r, true)
+}
+
+// DeclineFriendRequest declines (deletes) a pending friend request
+func (h *UserHandler) DeclineFriendRequest(w http.ResponseWriter, r *http.Request) {
+	h.respondToFriendRequest(w, r, false)
+}
+
+func (h *UserHandler) respondToFriendRequest(w http.ResponseWriter, r *http.Request, accept bool) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	requestID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	err = database.RespondToFriendRequest(h.db, user.ID, requestID, accept)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Friend request not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update friend request", http.StatusInternalServerError)
+		return
+	}
+
+	friendCount, err := database.GetFriendCount(h.db, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to count friends", http.StatusInternalServerError)
+		return
+	}
+
+	status := "declined"
+	if accept {
+		status = types.FriendStatusAccepted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       status,
+		"friend_count": friendCount,
+	})
 }
 
 func (h *UserHandler) GetUserPreferences(w http.ResponseWriter, r *http.Request) {
@@ -370,7 +600,13 @@ func (h *UserHandler) GetUserPreferences(w http.ResponseWriter, r *http.Request)
 
 	// Return preferences in the format expected by frontend
 	response := map[string]interface{}{
-		"darkMode": prefs.DarkMode,
+		"darkMode":              prefs.DarkMode,
+		"defaultListVisibility": prefs.DefaultListVisibility,
+		"preferredRegion":       prefs.PreferredRegion,
+		"preferredProviders":    prefs.PreferredProviders,
+		"shareNowPlaying":       prefs.ShareNowPlaying,
+		"plexAutoSync":          prefs.PlexAutoSync,
+		"shareActivity":         prefs.ShareActivity,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -399,14 +635,61 @@ func (h *UserHandler) UpdateUserPreferences(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Ensure preferences exist first
-	_, err = database.GetUserPreferences(h.db, user.ID)
+	prefs, err := database.GetUserPreferences(h.db, user.ID)
 	if err != nil {
 		http.Error(w, "Failed to get preferences", http.StatusInternalServerError)
 		return
 	}
 
+	// Apply only the fields that were sent
+	if req.DarkMode != nil {
+		prefs.DarkMode = *req.DarkMode
+	}
+	if req.DefaultListVisibility != nil {
+		visibility := *req.DefaultListVisibility
+		if visibility != types.ListVisibilityPrivate && visibility != types.ListVisibilityPublic {
+			http.Error(w, "defaultListVisibility must be private or public", http.StatusBadRequest)
+			return
+		}
+		prefs.DefaultListVisibility = visibility
+	}
+	if req.PreferredRegion != nil {
+		if *req.PreferredRegion == "" {
+			prefs.PreferredRegion = nil
+		} else {
+			region, ok := services.NormalizeRegion(*req.PreferredRegion)
+			if !ok {
+				http.Error(w, "preferredRegion must be a two-letter country code", http.StatusBadRequest)
+				return
+			}
+			prefs.PreferredRegion = &region
+		}
+	}
+	if req.PreferredProviders != nil {
+		providers, ok := normalizeProviderIDs(*req.PreferredProviders)
+		if !ok {
+			http.Error(w, "preferredProviders must be TMDB provider ids", http.StatusBadRequest)
+			return
+		}
+		prefs.PreferredProviders = providers
+	}
+	if req.ShareNowPlaying != nil {
+		prefs.ShareNowPlaying = *req.ShareNowPlaying
+	}
+	if req.PlexAutoSync != nil {
+		interval := *req.PlexAutoSync
+		if interval != types.PlexAutoSyncOff && interval != types.PlexAutoSyncDaily && interval != types.PlexAutoSyncWeekly {
+			http.Error(w, "plexAutoSync must be off, daily or weekly", http.StatusBadRequest)
+			return
+		}
+		prefs.PlexAutoSync = interval
+	}
+	if req.ShareActivity != nil {
+		prefs.ShareActivity = *req.ShareActivity
+	}
+
 	// Update preferences
-	err = database.UpdateUserPreferences(h.db, user.ID, req.DarkMode)
+	err = database.UpdateUserPreferences(h.db, user.ID, prefs)
 	if err != nil {
 		http.Error(w, "Failed to update preferences", http.StatusInternalServerError)
 		return
@@ -414,14 +697,105 @@ func (h *UserHandler) UpdateUserPreferences(w http.ResponseWriter, r *http.Reque
 
 	// Return success
 	response := map[string]interface{}{
-		"success":  true,
-		"darkMode": req.DarkMode,
+		"success":               true,
+		"darkMode":              prefs.DarkMode,
+		"defaultListVisibility": prefs.DefaultListVisibility,
+		"preferredRegion":       prefs.PreferredRegion,
+		"preferredProviders":    prefs.PreferredProviders,
+		"shareNowPlaying":       prefs.ShareNowPlaying,
+		"plexAutoSync":          prefs.PlexAutoSync,
+		"shareActivity":         prefs.ShareActivity,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// maxPreferredProviders caps how many subscriptions a user can save
+const maxPreferredProviders = 50
+
+// normalizeProviderIDs drops duplicates, rejecting non-positive ids and overlong lists
+func normalizeProviderIDs(ids []int) ([]int, bool) {
+	seen := make(map[int]bool, len(ids))
+	providers := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			return nil, false
+		}
+		if !seen[id] {
+			seen[id] = true
+			providers = append(providers, id)
+		}
+	}
+	return providers, len(providers) <= maxPreferredProviders
+}
+
+// AddPreferredProvider marks a TMDB watch provider as one the user subscribes to
+func (h *UserHandler) AddPreferredProvider(w http.ResponseWriter, r *http.Request) {
+	h.updatePreferredProvider(w, r, true)
+}
+
+// RemovePreferredProvider removes a TMDB watch provider from the user's subscriptions
+func (h *UserHandler) RemovePreferredProvider(w http.ResponseWriter, r *http.Request) {
+	h.updatePreferredProvider(w, r, false)
+}
+
+// updatePreferredProvider adds or removes the {providerId} path value in the user's
+// preferred providers and returns the resulting list
+func (h *UserHandler) updatePreferredProvider(w http.ResponseWriter, r *http.Request, add bool) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	providerID, err := strconv.Atoi(utils.GetPathParam(r, "providerId"))
+	if err != nil || providerID <= 0 {
+		http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	prefs, err := database.GetUserPreferences(h.db, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get preferences", http.StatusInternalServerError)
+		return
+	}
+
+	providers := make([]int, 0, len(prefs.PreferredProviders)+1)
+	for _, id := range prefs.PreferredProviders {
+		if id != providerID {
+			providers = append(providers, id)
+		}
+	}
+	if add {
+		providers = append(providers, providerID)
+	}
+
+	providers, ok := normalizeProviderIDs(providers)
+	if !ok {
+		http.Error(w, "Too many preferred providers", http.StatusBadRequest)
+		return
+	}
+	prefs.PreferredProviders = providers
+
+	if err := database.UpdateUserPreferences(h.db, user.ID, prefs); err != nil {
+		http.Error(w, "Failed to update preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"preferredProviders": prefs.PreferredProviders,
+	})
+}
+
 func (h *UserHandler) GetUserMovies(w http.ResponseWriter, r *http.Request) {
 	authUser, err := auth.GetUserFromContext(r.Context())
 	if err != nil {
@@ -461,24 +835,15 @@ func (h *UserHandler) GetUserMovies(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Determine target user ID
-	var targetUserID int
-	if userIDStr == "me" || userIDStr == "" {
-		targetUserID = currentUser.ID
-	} else {
-		// Get user by Auth0 ID
-		var targetUser types.User
-		err = h.db.QueryRow("SELECT id, auth0_id, email, name, username, avatar_url, created_at FROM users WHERE auth0_id = ?", userIDStr).Scan(
-			&targetUser.ID, &targetUser.Auth0ID, &targetUser.Email, &targetUser.Name, &targetUser.Username, &targetUser.AvatarURL, &targetUser.Created)
-		if err == sql.ErrNoRows {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-		if err != nil {
-			http.Error(w, "Failed to get target user", http.StatusInternalServerError)
-			return
-		}
-		targetUserID = targetUser.ID
+	// Determine target user ID ("me", numeric id, or legacy Auth0 id)
+	targetUserID, err := resolveUserID(h.db, userIDStr, currentUser)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get target user", http.StatusInternalServerError)
+		return
 	}
 
 	isOwnProfile := targetUserID == currentUser.ID
@@ -491,7 +856,7 @@ func (h *UserHandler) GetUserMovies(w http.ResponseWriter, r *http.Request) {
 			FROM list_movies lm
 			JOIN movies m ON lm.movie_id = m.id
 			JOIN lists l ON lm.list_id = l.id
-			WHERE l.user_id = ?
+			WHERE l.user_id = ? AND l.deleted_at IS NULL
 		`
 	} else {
 		countQuery = `
@@ -499,7 +864,7 @@ func (h *UserHandler) GetUserMovies(w http.ResponseWriter, r *http.Request) {
 			FROM list_movies lm
 			JOIN movies m ON lm.movie_id = m.id
 			JOIN lists l ON lm.list_id = l.id
-			WHERE l.user_id = ? AND l.is_public = 1
+			WHERE l.user_id = ? AND l.is_public = 1 AND l.deleted_at IS NULL
 		`
 	}
 	
@@ -522,7 +887,7 @@ func (h *UserHandler) GetUserMovies(w http.ResponseWriter, r *http.Request) {
 			FROM list_movies lm
 			JOIN movies m ON lm.movie_id = m.id
 			JOIN lists l ON lm.list_id = l.id
-			WHERE l.user_id = ?
+			WHERE l.user_id = ? AND l.deleted_at IS NULL
 			GROUP BY m.id, m.tmdb_id, m.title, m.year, m.poster_url, m.synopsis
 			ORDER BY MAX(lm.added_at) DESC
 			LIMIT ? OFFSET ?
@@ -535,7 +900,7 @@ func (h *UserHandler) GetUserMovies(w http.ResponseWriter, r *http.Request) {
 			FROM list_movies lm
 			JOIN movies m ON lm.movie_id = m.id
 			JOIN lists l ON lm.list_id = l.id
-			WHERE l.user_id = ? AND l.is_public = 1
+			WHERE l.user_id = ? AND l.is_public = 1 AND l.deleted_at IS NULL
 			GROUP BY m.id, m.tmdb_id, m.title, m.year, m.poster_url, m.synopsis
 			ORDER BY MAX(lm.added_at) DESC
 			LIMIT ? OFFSET ?
@@ -589,4 +954,110 @@ func (h *UserHandler) GetUserMovies(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+}
+
+func (h *UserHandler) GetUserRatingHistogram(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Get path parameter
+	userIDStr := utils.GetPathParam(r, "id")
+
+	// Get current user for authentication
+	currentUser, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get current user", http.StatusInternalServerError)
+		return
+	}
+
+	// Determine target user ID ("me", numeric id, or legacy Auth0 id)
+	targetUserID, err := resolveUserID(h.db, userIDStr, currentUser)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get target user", http.StatusInternalServerError)
+		return
+	}
+
+	isOwnProfile := targetUserID == currentUser.ID
+
+	// Count ratings per bucket (with privacy filtering)
+	var query string
+	if isOwnProfile {
+		// Own profile: include every rating
+		query = `
+			SELECT um.rating, COUNT(*)
+			FROM user_movies um
+			WHERE um.user_id = ? AND um.rating IS NOT NULL
+			GROUP BY um.rating
+		`
+	} else {
+		// Other's profile: only ratings for movies that appear in their public lists
+		query = `
+			SELECT um.rating, COUNT(*)
+			FROM user_movies um
+			WHERE um.user_id = ? AND um.rating IS NOT NULL
+			  AND EXISTS (
+				SELECT 1 FROM list_movies lm
+				JOIN lists l ON lm.list_id = l.id
+				WHERE l.user_id = um.user_id AND l.is_public = 1 AND l.deleted_at IS NULL AND lm.movie_id = um.movie_id
+			  )
+			GROUP BY um.rating
+		`
+	}
+
+	rows, err := h.db.Query(query, targetUserID)
+	if err != nil {
+		http.Error(w, "Failed to get rating histogram", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// One bucket per step of the rating scale
+	buckets := make(map[float64]int)
+	total := 0
+	sum := 0.0
+	for rows.Next() {
+		var rating float64
+		var count int
+		if err := rows.Scan(&rating, &count); err != nil {
+			continue
+		}
+		if rating < types.MinRating || rating > types.MaxRating {
+			continue
+		}
+		buckets[types.RoundRating(rating)] += count
+		total += count
+		sum += rating * float64(count)
+	}
+
+	values := types.RatingValues()
+	histogram := make([]map[string]interface{}, 0, len(values))
+	for _, rating := range values {
+		histogram = append(histogram, map[string]interface{}{
+			"rating": rating,
+			"count":  buckets[rating],
+		})
+	}
+
+	var average *float64
+	if total > 0 {
+		avg := sum / float64(total)
+		average = &avg
+	}
+
+	response := map[string]interface{}{
+		"histogram":      histogram,
+		"total":          total,
+		"average_rating": average,
+		"rating_scale":   types.UserRatingScale,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
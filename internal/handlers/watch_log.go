@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"moviedb/internal/auth"
+	"moviedb/internal/database"
+	"moviedb/internal/services"
+	"moviedb/internal/types"
+	"moviedb/internal/utils"
+)
+
+// GetMovieWatchLog returns every time the user watched a movie, most recent first
+func (h *MovieHandler) GetMovieWatchLog(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	entries := []types.WatchLogEntry{}
+	movieID, err := database.GetMovieIDByTMDBID(h.db, tmdbID)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Failed to get movie", http.StatusInternalServerError)
+		return
+	}
+	// A movie that isn't in the database can't have been watched yet
+	if err == nil {
+		entries, err = database.GetMovieWatchLog(h.db, user.ID, movieID)
+		if err != nil {
+			http.Error(w, "Failed to get watch log", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tmdb_id": tmdbID,
+		"watches": entries,
+	})
+}
+
+// AddMovieWatch logs a watch of a movie (now, unless watched_at is given) and marks it
+// watched. The movie's watched_date is its most recent watch.
+func (h *MovieHandler) AddMovieWatch(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional: an empty one logs a watch now without a rating
+	var req types.WatchLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	watchedAt, ok := validateWatchLogRequest(w, req)
+	if !ok {
+		return
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	// Resolve the local movie row, caching it from TMDB if needed
+	movieID, err := services.EnsureMovieCached(h.db, h.tmdbClient, tmdbID)
+	if err != nil {
+		writeTMDBError(w, err, "Movie not found", "Failed to get movie")
+		return
+	}
+
+	entry, err := database.AddWatchLogEntry(h.db, user.ID, movieID, watchedAt, req.Rating)
+	if err != nil {
+		h.logger.Error("Failed to add watch", "movie_id", movieID, "user_id", user.ID, "error", err)
+		http.Error(w, "Failed to add watch", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// UpdateWatch changes when one of the user's watches happened and its rating
+func (h *MovieHandler) UpdateWatch(w http.ResponseWriter, r *http.Request) {
+	var req types.WatchLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.WatchedAt == nil {
+		http.Error(w, "watched_at is required", http.StatusBadRequest)
+		return
+	}
+
+	watchedAt, ok := validateWatchLogRequest(w, req)
+	if !ok {
+		return
+	}
+
+	entry, ok := h.loadOwnWatch(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := database.UpdateWatchLogEntry(h.db, entry, watchedAt, req.Rating)
+	if err != nil {
+		h.logger.Error("Failed to update watch", "watch_id", entry.ID, "error", err)
+		http.Error(w, "Failed to update watch", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteWatch removes one of the user's watches
+func (h *MovieHandler) DeleteWatch(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.loadOwnWatch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := database.DeleteWatchLogEntry(h.db, entry); err != nil {
+		h.logger.Error("Failed to delete watch", "watch_id", entry.ID, "error", err)
+		http.Error(w, "Failed to delete watch", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMyWatchLog returns a page of the user's watches across all movies, most recent first
+func (h *MovieHandler) GetMyWatchLog(w http.ResponseWriter, r *http.Request) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page := utils.GetQueryParamInt(r, "page", 1)
+	limit := utils.GetQueryParamInt(r, "limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	items, err := database.GetUserWatchLog(h.db, user.ID, limit, (page-1)*limit)
+	if err != nil {
+		h.logger.Error("Failed to get watch log", "user_id", user.ID, "error", err)
+		http.Error(w, "Failed to get watch log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"watches": items,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// loadOwnWatch loads the watch named by the {id} path parameter. Watches of other users
+// are reported as not found. On failure it writes the error response and reports false.
+func (h *MovieHandler) loadOwnWatch(w http.ResponseWriter, r *http.Request) (*types.WatchLogEntry, bool) {
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	entryID, err := strconv.Atoi(utils.GetPathParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid watch ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Get or create user in database
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	entry, err := database.GetWatchLogEntry(h.db, entryID)
+	if err == sql.ErrNoRows || (err == nil && entry.UserID != user.ID) {
+		http.Error(w, "Watch not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to get watch", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// validateWatchLogRequest checks the watch's rating and date, returning when it happened.
+// On failure it writes the error response and reports false.
+func validateWatchLogRequest(w http.ResponseWriter, req types.WatchLogRequest) (time.Time, bool) {
+	if req.Rating != nil {
+		if err := types.ValidateRating(*req.Rating); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return time.Time{}, false
+		}
+	}
+
+	now := time.Now()
+	if req.WatchedAt == nil {
+		return now, true
+	}
+	if req.WatchedAt.After(now) {
+		http.Error(w, "watched_at cannot be in the future", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+	return *req.WatchedAt, true
+}
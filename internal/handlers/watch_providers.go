@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"moviedb/internal/auth"
 	"moviedb/internal/database"
 	"moviedb/internal/services"
 )
 
+// maxWatchProviderRegions limits how many countries one request can ask for
+const maxWatchProviderRegions = 10
+
 type WatchProvidersHandler struct {
 	service *services.WatchProvidersService
 	db      *sql.DB
@@ -23,7 +27,11 @@ func NewWatchProvidersHandler(db *sql.DB, tmdbClient *services.TMDBClient, plexC
 	}
 }
 
-// GetMovieWatchProviders returns watch provider information for a movie
+// GetMovieWatchProviders returns watch provider information for a movie. The region comes
+// from ?region=, then the user's preferred region, then the default. ?regions=NO,SE,US
+// returns several countries at once, grouped by region. ?subscribed=only drops
+// subscription services the user hasn't listed in their preferred providers, and
+// ?subscribed=group moves them to otherProviders instead.
 func (h *WatchProvidersHandler) GetMovieWatchProviders(w http.ResponseWriter, r *http.Request) {
 	// Get TMDB ID from URL path
 	tmdbIDStr := r.PathValue("id")
@@ -38,12 +46,6 @@ func (h *WatchProvidersHandler) GetMovieWatchProviders(w http.ResponseWriter, r
 		return
 	}
 
-	// Get region from query params (default to NO for Norway)
-	region := r.URL.Query().Get("region")
-	if region == "" {
-		region = "NO"
-	}
-
 	// Get user ID (authentication is required for this endpoint)
 	authUser, err := auth.GetUserFromContext(r.Context())
 	if err != nil {
@@ -59,17 +61,145 @@ func (h *WatchProvidersHandler) GetMovieWatchProviders(w http.ResponseWriter, r
 	}
 	userID := &user.ID
 
+	subscribed, group, ok := h.subscriptionFilter(r, user.ID)
+	if !ok {
+		http.Error(w, "subscribed must be 'only' or 'group'", http.StatusBadRequest)
+		return
+	}
+
+	if value := r.URL.Query().Get("regions"); value != "" {
+		var regions []string
+		seen := make(map[string]bool)
+		for _, part := range strings.Split(value, ",") {
+			region, ok := services.NormalizeRegion(part)
+			if !ok {
+				http.Error(w, "Invalid region code", http.StatusBadRequest)
+				return
+			}
+			if !seen[region] {
+				seen[region] = true
+				regions = append(regions, region)
+			}
+		}
+		if len(regions) > maxWatchProviderRegions {
+			http.Error(w, "Too many regions", http.StatusBadRequest)
+			return
+		}
+
+		byRegion, err := h.service.GetWatchProvidersForRegions(tmdbID, regions, userID)
+		if err != nil {
+			http.Error(w, "Failed to get watch providers", http.StatusInternalServerError)
+			return
+		}
+		if subscribed != nil {
+			for _, providers := range byRegion {
+				services.FilterSubscribedProviders(providers, subscribed, group)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"tmdbId":  tmdbID,
+			"regions": byRegion,
+		})
+		return
+	}
+
+	region, ok := h.resolveRegion(r, user.ID)
+	if !ok {
+		http.Error(w, "Invalid region code", http.StatusBadRequest)
+		return
+	}
+
 	// Get watch providers
 	providers, err := h.service.GetWatchProviders(tmdbID, region, userID)
 	if err != nil {
 		http.Error(w, "Failed to get watch providers", http.StatusInternalServerError)
 		return
 	}
+	if subscribed != nil {
+		services.FilterSubscribedProviders(providers, subscribed, group)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(providers)
 }
 
+// subscriptionFilter reads ?subscribed= and returns the user's preferred providers to
+// filter by, or nil when no filtering applies (not requested, or no subscriptions saved).
+// It reports false for an unknown mode.
+func (h *WatchProvidersHandler) subscriptionFilter(r *http.Request, userID int) ([]int, bool, bool) {
+	var group bool
+	switch r.URL.Query().Get("subscribed") {
+	case "":
+		return nil, false, true
+	case "only":
+		group = false
+	case "group":
+		group = true
+	default:
+		return nil, false, false
+	}
+
+	prefs, err := database.GetUserPreferences(h.db, userID)
+	if err != nil || len(prefs.PreferredProviders) == 0 {
+		return nil, false, true
+	}
+
+	return prefs.PreferredProviders, group, true
+}
+
+// resolveRegion picks the watch provider region for a request: ?region=, then the user's
+// preferred region, then the default. It reports false for an invalid ?region=.
+func (h *WatchProvidersHandler) resolveRegion(r *http.Request, userID int) (string, bool) {
+	if value := r.URL.Query().Get("region"); value != "" {
+		return services.NormalizeRegion(value)
+	}
+
+	prefs, err := database.GetUserPreferences(h.db, userID)
+	if err == nil && prefs.PreferredRegion != nil {
+		if region, ok := services.NormalizeRegion(*prefs.PreferredRegion); ok {
+			return region, true
+		}
+	}
+
+	return services.DefaultWatchRegion, true
+}
+
+// GetMoviePlexLocations returns each of the user's Plex servers and libraries that have the movie
+func (h *WatchProvidersHandler) GetMoviePlexLocations(w http.ResponseWriter, r *http.Request) {
+	tmdbID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		return
+	}
+
+	authUser, err := auth.GetUserFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := database.GetOrCreateUser(h.db, authUser.Auth0ID, authUser.Email, authUser.Name, authUser.AvatarURL)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+
+	locations, err := h.service.GetPlexLocations(tmdbID, user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get Plex locations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tmdbId":    tmdbID,
+		"locations": locations,
+		"count":     len(locations),
+	})
+}
+
 // ClearExpiredCache clears expired cache entries (admin endpoint)
 func (h *WatchProvidersHandler) ClearExpiredCache(w http.ResponseWriter, r *http.Request) {
 	// This could be protected with admin auth in the future
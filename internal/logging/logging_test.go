@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// loggingPackages are the packages whose output LOG_LEVEL has to control
+var loggingPackages = []string{"../database", "../handlers", "../services"}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"DEBUG", slog.LevelDebug, false},
+		{" info ", slog.LevelInfo, false},
+		{"", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"warning", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", slog.LevelInfo, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLevel(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNewFiltersByLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		want  []string // Messages written, of debug, info, warn and error
+	}{
+		{"debug", []string{"debug", "info", "warn", "error"}},
+		{"info", []string{"info", "warn", "error"}},
+		{"warn", []string{"warn", "error"}},
+		{"error", []string{"error"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			level, err := ParseLevel(tt.level)
+			if err != nil {
+				t.Fatalf("ParseLevel(%q) error = %v", tt.level, err)
+			}
+
+			var buf bytes.Buffer
+			logger := New(&buf, level)
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error")
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if _, msg, ok := strings.Cut(line, "msg="); ok {
+					got = append(got, msg)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("LOG_LEVEL=%s wrote %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPackagesLogThroughSlog checks that nothing logs by printing or through the log
+// package, which LOG_LEVEL can't filter by severity
+func TestPackagesLogThroughSlog(t *testing.T) {
+	fset := token.NewFileSet()
+	for _, dir := range loggingPackages {
+		pkgs, err := parser.ParseDir(fset, dir, func(info fs.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, 0)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", dir, err)
+		}
+
+		for _, pkg := range pkgs {
+			for _, file := range pkg.Files {
+				ast.Inspect(file, func(n ast.Node) bool {
+					sel, ok := n.(*ast.SelectorExpr)
+					if !ok {
+						return true
+					}
+					pkgName, ok := sel.X.(*ast.Ident)
+					if !ok {
+						return true
+					}
+					name := sel.Sel.Name
+					if (pkgName.Name == "fmt" && strings.HasPrefix(name, "Print")) ||
+						(pkgName.Name == "log" && (strings.HasPrefix(name, "Print") || strings.HasPrefix(name, "Fatal") || strings.HasPrefix(name, "Panic"))) {
+						t.Errorf("%s: %s.%s bypasses the slog logger", fset.Position(sel.Pos()), pkgName.Name, name)
+					}
+					return true
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import "sync"
+
+// jobEvents notifies subscribers when a job's row changes. Notifications carry no data;
+// subscribers re-read the job, so a slow subscriber only ever has one pending notification.
+type jobEvents struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan struct{}]bool // Keyed by job id
+}
+
+func newJobEvents() *jobEvents {
+	return &jobEvents{subscribers: make(map[int64]map[chan struct{}]bool)}
+}
+
+// subscribe returns a channel signalled on each change of the job and a function that
+// unsubscribes and must be called when the subscriber goes away
+func (e *jobEvents) subscribe(jobID int64) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	e.mu.Lock()
+	if e.subscribers[jobID] == nil {
+		e.subscribers[jobID] = make(map[chan struct{}]bool)
+	}
+	e.subscribers[jobID][ch] = true
+	e.mu.Unlock()
+
+	return ch, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.subscribers[jobID], ch)
+		if len(e.subscribers[jobID]) == 0 {
+			delete(e.subscribers, jobID)
+		}
+	}
+}
+
+// publish signals the job's subscribers without blocking on any of them
+func (e *jobEvents) publish(jobID int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch := range e.subscribers[jobID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// Already has an unread notification
+		}
+	}
+}
+
+// SubscribeJob returns a channel signalled whenever the job's progress or status changes. The
+// returned function unsubscribes; call it when the subscriber disconnects.
+func (jm *JobManager) SubscribeJob(jobID int64) (<-chan struct{}, func()) {
+	return jm.events.subscribe(jobID)
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -13,10 +14,13 @@ import (
 type JobType string
 
 const (
-	JobTypeFullSync     JobType = "full_sync"
-	JobTypeLibrarySync  JobType = "library_sync"
-	JobTypeTMDBMatching JobType = "tmdb_matching"
-	JobTypeCleanup      JobType = "cleanup"
+	JobTypeFullSync      JobType = "full_sync"
+	JobTypeLibrarySync   JobType = "library_sync"
+	JobTypeTMDBMatching  JobType = "tmdb_matching"
+	JobTypeCleanup       JobType = "cleanup"
+	JobTypeRatingsImport JobType = "ratings_import"
+	JobTypeListImport    JobType = "list_import"
+	JobTypeMovieCache    JobType = "movie_cache"
 )
 
 // JobStatus represents the current status of a job
@@ -43,6 +47,8 @@ type Job struct {
 	ProcessedItems   int               `json:"processed_items"`
 	SuccessfulItems  int               `json:"successful_items"`
 	FailedItems      int               `json:"failed_items"`
+	Attempts         int               `json:"attempts"`     // Runs so far, including the current one
+	MaxAttempts      int               `json:"max_attempts"` // Failed runs are retried until this many
 	ErrorMessage     string            `json:"error_message,omitempty"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 	StartedAt        *time.Time        `json:"started_at,omitempty"`
@@ -67,10 +73,16 @@ type JobManager struct {
 	wg         sync.WaitGroup
 	mutex      sync.RWMutex
 	isRunning  bool
+	events     *jobEvents
+	running    map[int64]context.CancelFunc // Cancels the context of jobs being processed
+	ctx        context.Context              // Parent of job contexts, cancelled on shutdown
+	cancel     context.CancelFunc
+	logger     *slog.Logger
 }
 
 // NewJobManager creates a new job manager
 func NewJobManager(db *sql.DB, workers int) *JobManager {
+	ctx, cancel := context.WithCancel(context.Background())
 	manager := &JobManager{
 		db:         db,
 		processors: make(map[JobType]JobProcessor),
@@ -78,16 +90,33 @@ func NewJobManager(db *sql.DB, workers int) *JobManager {
 		workerPool: make(chan chan *Job, workers),
 		jobQueue:   make(chan *Job, 100), // Buffer up to 100 jobs
 		quit:       make(chan bool),
+		events:     newJobEvents(),
+		running:    make(map[int64]context.CancelFunc),
+		ctx:        ctx,
+		cancel:     cancel,
+		logger:     slog.Default(),
 	}
 	
 	return manager
 }
 
+// SetLogger replaces the logger, which defaults to slog's default logger at construction
+func (jm *JobManager) SetLogger(logger *slog.Logger) {
+	jm.logger = logger
+}
+
 // DB returns the database connection for validation purposes
 func (jm *JobManager) DB() *sql.DB {
 	return jm.db
 }
 
+// IsRunning reports whether the job manager has been started and not stopped
+func (jm *JobManager) IsRunning() bool {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	return jm.isRunning
+}
+
 // RegisterProcessor registers a job processor for a specific job type
 func (jm *JobManager) RegisterProcessor(processor JobProcessor) {
 	jm.mutex.Lock()
@@ -118,10 +147,11 @@ func (jm *JobManager) Start() {
 	// Resume any jobs that were running when the system shut down
 	go jm.resumePendingJobs()
 	
-	fmt.Printf("Job manager started with %d workers\n", jm.workers)
+	jm.logger.Info("Job manager started", "workers", jm.workers)
 }
 
-// Stop gracefully stops the job manager
+// Stop gracefully stops the job manager. Jobs being processed are interrupted and put back
+// to pending, so they are resumed on the next start.
 func (jm *JobManager) Stop() {
 	jm.mutex.Lock()
 	if !jm.isRunning {
@@ -131,15 +161,16 @@ func (jm *JobManager) Stop() {
 	jm.isRunning = false
 	jm.mutex.Unlock()
 	
-	fmt.Println("Stopping job manager...")
+	jm.logger.Info("Stopping job manager")
 	
-	// Stop accepting new jobs
+	// Interrupt running jobs and stop accepting new ones
+	jm.interruptJobs()
 	close(jm.quit)
 	
 	// Wait for all workers to finish
 	jm.wg.Wait()
 	
-	fmt.Println("Job manager stopped")
+	jm.logger.Info("Job manager stopped")
 }
 
 // CreateJob creates a new job in the database
@@ -170,7 +201,7 @@ func (jm *JobManager) CreateJob(jobType JobType, userID *int64, libraryID *int64
 	// Queue the job for processing
 	select {
 	case jm.jobQueue <- job:
-		fmt.Printf("Job %d (%s) queued for processing\n", job.ID, job.Type)
+		jm.logger.Debug("Job queued", "job_id", job.ID, "type", job.Type)
 	default:
 		// Job queue is full, mark job as failed
 		jm.updateJobStatus(job.ID, JobStatusFailed, "Job queue is full")
@@ -191,13 +222,14 @@ func (jm *JobManager) GetJob(jobID int64) (*Job, error) {
 	err := jm.db.QueryRow(`
 		SELECT id, type, user_id, library_id, status, progress, current_step,
 			   total_items, processed_items, successful_items, failed_items,
-			   error_message, metadata_json, started_at, completed_at, created_at
+			   error_message, metadata_json, started_at, completed_at, created_at,
+			   attempts, max_attempts
 		FROM sync_jobs WHERE id = ?
 	`, jobID).Scan(
 		&job.ID, &job.Type, &userID, &libraryID, &job.Status, &job.Progress,
 		&currentStep, &job.TotalItems, &job.ProcessedItems, &job.SuccessfulItems,
 		&job.FailedItems, &errorMessage, &metadataJSON, &startedAt, &completedAt,
-		&job.CreatedAt,
+		&job.CreatedAt, &job.Attempts, &job.MaxAttempts,
 	)
 	
 	if err != nil {
@@ -241,7 +273,8 @@ func (jm *JobManager) GetUserJobs(userID int64, limit int) ([]*Job, error) {
 	rows, err := jm.db.Query(`
 		SELECT id, type, user_id, library_id, status, progress, current_step,
 			   total_items, processed_items, successful_items, failed_items,
-			   error_message, metadata_json, started_at, completed_at, created_at
+			   error_message, metadata_json, started_at, completed_at, created_at,
+			   attempts, max_attempts
 		FROM sync_jobs 
 		WHERE user_id = ? 
 		ORDER BY created_at DESC 
@@ -265,7 +298,7 @@ func (jm *JobManager) GetUserJobs(userID int64, limit int) ([]*Job, error) {
 			&job.ID, &job.Type, &userID, &libraryID, &job.Status, &job.Progress,
 			&currentStep, &job.TotalItems, &job.ProcessedItems, &job.SuccessfulItems,
 			&job.FailedItems, &errorMessage, &metadataJSON, &startedAt, &completedAt,
-			&job.CreatedAt,
+			&job.CreatedAt, &job.Attempts, &job.MaxAttempts,
 		)
 		
 		if err != nil {
@@ -315,10 +348,38 @@ func (jm *JobManager) UpdateJobProgress(jobID int64, progress int, currentStep s
 			successful_items = ?, failed_items = ?
 		WHERE id = ?
 	`, progress, currentStep, processedItems, successfulItems, failedItems, jobID)
+	if err == nil {
+		jm.events.publish(jobID)
+	}
 	
 	return err
 }
 
+// MergeJobMetadata merges values into a job's metadata, overwriting existing keys
+func (jm *JobManager) MergeJobMetadata(jobID int64, values map[string]interface{}) error {
+	var metadataJSON string
+	err := jm.db.QueryRow(`SELECT metadata_json FROM sync_jobs WHERE id = ?`, jobID).Scan(&metadataJSON)
+	if err != nil {
+		return fmt.Errorf("failed to load job metadata: %w", err)
+	}
+
+	metadata := make(map[string]interface{})
+	if metadataJSON != "" {
+		json.Unmarshal([]byte(metadataJSON), &metadata)
+	}
+	for key, value := range values {
+		metadata[key] = value
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode job metadata: %w", err)
+	}
+
+	_, err = jm.db.Exec(`UPDATE sync_jobs SET metadata_json = ? WHERE id = ?`, string(data), jobID)
+	return err
+}
+
 // updateJobStatus updates job status and error message
 func (jm *JobManager) updateJobStatus(jobID int64, status JobStatus, errorMessage string) error {
 	now := time.Now()
@@ -333,26 +394,28 @@ func (jm *JobManager) updateJobStatus(jobID int64, status JobStatus, errorMessag
 		SET status = ?, error_message = ?, completed_at = ?
 		WHERE id = ?
 	`, status, errorMessage, completedAt, jobID)
+	if err == nil {
+		jm.events.publish(jobID)
+	}
 	
 	return err
 }
 
 // dispatch continuously dispatches jobs to available workers
 func (jm *JobManager) dispatch() {
-	fmt.Println("Job dispatcher started")
+	jm.logger.Debug("Job dispatcher started")
 	for {
 		select {
 		case job := <-jm.jobQueue:
-			fmt.Printf("Dispatcher: Received job %d (%s) from queue\n", job.ID, job.Type)
+			jm.logger.Debug("Dispatcher received job", "job_id", job.ID, "type", job.Type)
 			// Wait for an available worker
 			go func(job *Job) {
-				fmt.Printf("Dispatcher: Waiting for available worker for job %d\n", job.ID)
 				worker := <-jm.workerPool
-				fmt.Printf("Dispatcher: Dispatching job %d to worker\n", job.ID)
+				jm.logger.Debug("Dispatching job to worker", "job_id", job.ID)
 				worker <- job
 			}(job)
 		case <-jm.quit:
-			fmt.Println("Job dispatcher stopping")
+			jm.logger.Debug("Job dispatcher stopping")
 			return
 		}
 	}
@@ -360,7 +423,7 @@ func (jm *JobManager) dispatch() {
 
 // resumePendingJobs finds jobs that were running when system shut down and requeues them
 func (jm *JobManager) resumePendingJobs() {
-	fmt.Println("Checking for pending jobs to resume...")
+	jm.logger.Debug("Checking for pending jobs to resume")
 	rows, err := jm.db.Query(`
 		SELECT id FROM sync_jobs 
 		WHERE status IN (?, ?) 
@@ -368,7 +431,7 @@ func (jm *JobManager) resumePendingJobs() {
 	`, JobStatusPending, JobStatusRunning)
 	
 	if err != nil {
-		fmt.Printf("Failed to query pending jobs: %v\n", err)
+		jm.logger.Error("Failed to query pending jobs", "error", err)
 		return
 	}
 	defer rows.Close()
@@ -380,41 +443,93 @@ func (jm *JobManager) resumePendingJobs() {
 			continue
 		}
 		
-		fmt.Printf("Found pending job %d, resetting status\n", jobID)
-		
 		// Reset status to pending
 		if err := jm.updateJobStatus(jobID, JobStatusPending, ""); err != nil {
-			fmt.Printf("Failed to reset job %d status: %v\n", jobID, err)
+			jm.logger.Error("Failed to reset job status", "job_id", jobID, "error", err)
 			continue
 		}
 		
 		// Load and requeue the job
 		if job, err := jm.GetJob(jobID); err == nil {
-			fmt.Printf("Requeuing job %d (%s)\n", jobID, job.Type)
 			select {
 			case jm.jobQueue <- job:
 				resumedCount++
-				fmt.Printf("Successfully requeued job %d\n", jobID)
+				jm.logger.Info("Requeued interrupted job", "job_id", jobID, "type", job.Type)
 			default:
 				// Queue full, leave as pending
-				fmt.Printf("Job queue full, leaving job %d as pending\n", jobID)
+				jm.logger.Warn("Job queue full, leaving job pending", "job_id", jobID)
 				break
 			}
 		} else {
-			fmt.Printf("Failed to load job %d: %v\n", jobID, err)
+			jm.logger.Error("Failed to load job", "job_id", jobID, "error", err)
 		}
 	}
 	
 	if resumedCount > 0 {
-		fmt.Printf("Resumed %d pending jobs\n", resumedCount)
+		jm.logger.Info("Resumed pending jobs", "count", resumedCount)
 	} else {
-		fmt.Println("No pending jobs to resume")
+		jm.logger.Debug("No pending jobs to resume")
 	}
 }
 
 // CancelJob cancels a running or pending job
 func (jm *JobManager) CancelJob(jobID int64) error {
-	return jm.updateJobStatus(jobID, JobStatusCancelled, "Job cancelled by user")
+	if err := jm.updateJobStatus(jobID, JobStatusCancelled, "Job cancelled by user"); err != nil {
+		return err
+	}
+
+	// Stop the job if a worker is processing it; queued jobs are skipped when they come up
+	jm.mutex.RLock()
+	cancel, ok := jm.running[jobID]
+	jm.mutex.RUnlock()
+	if ok {
+		cancel()
+	}
+
+	return nil
+}
+
+// interruptJobs cancels the context of every job being processed for shutdown. Workers see
+// the cancelled context and put their job back to pending; it is safe to call more than once.
+func (jm *JobManager) interruptJobs() {
+	jm.cancel()
+}
+
+// isShuttingDown reports whether jobs are being interrupted for shutdown
+func (jm *JobManager) isShuttingDown() bool {
+	return jm.ctx.Err() != nil
+}
+
+// requeueInterrupted puts a job interrupted by shutdown back to pending, so it is resumed on
+// the next start, and doesn't count the interrupted run as an attempt. Jobs cancelled by
+// their user while running stay cancelled.
+func (jm *JobManager) requeueInterrupted(job *Job) error {
+	result, err := jm.db.Exec(`
+		UPDATE sync_jobs
+		SET status = ?, error_message = ?, attempts = CASE WHEN attempts > 0 THEN attempts - 1 ELSE 0 END, completed_at = NULL
+		WHERE id = ? AND status = ?
+	`, JobStatusPending, "Interrupted by server shutdown, will resume on restart", job.ID, JobStatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to requeue interrupted job: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+		jm.events.publish(job.ID)
+	}
+	return nil
+}
+
+// trackRunning registers the cancel function of a job a worker has started processing
+func (jm *JobManager) trackRunning(jobID int64, cancel context.CancelFunc) {
+	jm.mutex.Lock()
+	jm.running[jobID] = cancel
+	jm.mutex.Unlock()
+}
+
+// untrackRunning forgets a job once its worker is done with it
+func (jm *JobManager) untrackRunning(jobID int64) {
+	jm.mutex.Lock()
+	delete(jm.running, jobID)
+	jm.mutex.Unlock()
 }
 
 // CleanupOldJobs removes old completed jobs (older than specified days)
@@ -422,14 +537,14 @@ func (jm *JobManager) CleanupOldJobs(daysOld int) error {
 	result, err := jm.db.Exec(`
 		DELETE FROM sync_jobs 
 		WHERE status IN (?, ?, ?) 
-		AND created_at < datetime('now', '-' || ? || ' days')
-	`, JobStatusCompleted, JobStatusFailed, JobStatusCancelled, daysOld)
+		AND created_at < ?
+	`, JobStatusCompleted, JobStatusFailed, JobStatusCancelled, time.Now().UTC().AddDate(0, 0, -daysOld))
 	
 	if err != nil {
 		return err
 	}
 	
 	rowsAffected, _ := result.RowsAffected()
-	fmt.Printf("Cleaned up %d old jobs\n", rowsAffected)
+	jm.logger.Info("Cleaned up old jobs", "count", rowsAffected)
 	return nil
 }
\ No newline at end of file
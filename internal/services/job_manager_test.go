@@ -0,0 +1,65 @@
+package services
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"moviedb/internal/logging"
+)
+
+// syncBuffer is a bytes.Buffer safe to log to from several goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestJobManagerLogLevel starts and stops a job manager with each log level, checking its
+// debug chatter only shows up at debug level and nothing below the level is written
+func TestJobManagerLogLevel(t *testing.T) {
+	tests := []struct {
+		level     slog.Level
+		wantInfo  bool
+		wantDebug bool
+	}{
+		{slog.LevelDebug, true, true},
+		{slog.LevelInfo, true, false},
+		{slog.LevelWarn, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			var out syncBuffer
+			jm := NewJobManager(newTestDB(t), 1)
+			jm.SetLogger(logging.New(&out, tt.level))
+
+			jm.Start()
+			jm.Stop()
+
+			logged := out.String()
+			if got := strings.Contains(logged, `msg="Job manager started"`); got != tt.wantInfo {
+				t.Errorf("info message written = %v, want %v:\n%s", got, tt.wantInfo, logged)
+			}
+			if got := strings.Contains(logged, "level=DEBUG"); got != tt.wantDebug {
+				t.Errorf("debug messages written = %v, want %v:\n%s", got, tt.wantDebug, logged)
+			}
+			if tt.level >= slog.LevelWarn && logged != "" {
+				t.Errorf("wrote below the warn level:\n%s", logged)
+			}
+		})
+	}
+}
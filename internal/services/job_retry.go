@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/LukeHagar/plexgo/models/sdkerrors"
+)
+
+// Failed attempts are retried after jobRetryBaseDelay, doubling up to jobRetryMaxDelay
+const (
+	jobRetryBaseDelay = 30 * time.Second
+	jobRetryMaxDelay  = 10 * time.Minute
+)
+
+// jobRetryDelay returns how long to wait before retrying after the given failed attempt
+func jobRetryDelay(attempt int) time.Duration {
+	delay := jobRetryBaseDelay
+	for i := 1; i < attempt && delay < jobRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > jobRetryMaxDelay {
+		return jobRetryMaxDelay
+	}
+	return delay
+}
+
+// IsRetryableJobError reports whether a job failed for a reason that may go away by itself:
+// network errors and timeouts, rate limiting and 5xx responses from TMDB or Plex. Anything
+// else, e.g. a missing Plex connection or invalid input, fails the job right away.
+func IsRetryableJobError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimiterStopped) {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrTMDBRateLimited) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var tmdbErr *TMDBError
+	if errors.As(err, &tmdbErr) {
+		return tmdbErr.StatusCode >= 500
+	}
+
+	var plexErr *sdkerrors.SDKError
+	if errors.As(err, &plexErr) {
+		return plexErr.StatusCode >= 500 || plexErr.StatusCode == 429
+	}
+
+	return false
+}
+
+// startAttempt counts a new run of a job
+func (jm *JobManager) startAttempt(job *Job) {
+	job.Attempts++
+	if _, err := jm.db.Exec("UPDATE sync_jobs SET attempts = ? WHERE id = ?", job.Attempts, job.ID); err != nil {
+		jm.logger.Warn("Failed to record job attempt", "job_id", job.ID, "error", err)
+	} else {
+		jm.events.publish(job.ID)
+	}
+}
+
+// scheduleRetry puts a failed job back to pending and re-queues it after a backoff delay. It
+// reports false when the error isn't transient or the job is out of attempts, leaving the
+// caller to fail the job.
+func (jm *JobManager) scheduleRetry(job *Job, jobErr error) bool {
+	if job.Attempts >= job.MaxAttempts || !IsRetryableJobError(jobErr) {
+		return false
+	}
+
+	delay := jobRetryDelay(job.Attempts)
+	errMsg := fmt.Sprintf("Attempt %d of %d failed, retrying in %v: %v", job.Attempts, job.MaxAttempts, delay, jobErr)
+	if err := jm.updateJobStatus(job.ID, JobStatusPending, errMsg); err != nil {
+		return false
+	}
+
+	go func() {
+		select {
+		case <-time.After(delay):
+		case <-jm.quit:
+			// Still pending, so it is resumed on the next start
+			return
+		}
+
+		// The job may have been cancelled while waiting
+		retry, err := jm.GetJob(job.ID)
+		if err != nil || retry.Status != JobStatusPending {
+			return
+		}
+
+		select {
+		case jm.jobQueue <- retry:
+			jm.logger.Info("Job re-queued", "job_id", retry.ID, "type", retry.Type, "attempt", retry.Attempts+1)
+		default:
+			jm.updateJobStatus(job.ID, JobStatusFailed, "Job queue is full")
+		}
+	}()
+
+	return true
+}
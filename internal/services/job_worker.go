@@ -33,15 +33,13 @@ func (w *Worker) Start() {
 		
 		for {
 			// Register worker in the worker pool
-			fmt.Printf("Worker %d: Registering for work\n", w.id)
 			w.workerPool <- w.jobChannel
 			
 			select {
 			case job := <-w.jobChannel:
-				fmt.Printf("Worker %d: Received job %d (%s)\n", w.id, job.ID, job.Type)
 				w.processJob(job)
 			case <-w.quit:
-				fmt.Printf("Worker %d stopping\n", w.id)
+				w.manager.logger.Debug("Worker stopping", "worker", w.id)
 				return
 			}
 		}
@@ -50,18 +48,25 @@ func (w *Worker) Start() {
 
 // processJob processes a single job
 func (w *Worker) processJob(job *Job) {
-	fmt.Printf("Worker %d processing job %d (%s)\n", w.id, job.ID, job.Type)
+	w.manager.logger.Debug("Worker processing job", "worker", w.id, "job_id", job.ID, "type", job.Type)
+	
+	// Jobs cancelled while queued are dropped
+	if current, err := w.manager.GetJob(job.ID); err == nil && current.Status == JobStatusCancelled {
+		w.manager.logger.Debug("Worker skipping cancelled job", "worker", w.id, "job_id", job.ID)
+		return
+	}
 	
 	// Mark job as running
 	w.manager.updateJobStatus(job.ID, JobStatusRunning, "")
 	
 	// Update started_at timestamp
 	_, err := w.manager.db.Exec(`
-		UPDATE sync_jobs SET started_at = datetime('now') WHERE id = ?
+		UPDATE sync_jobs SET started_at = CURRENT_TIMESTAMP WHERE id = ?
 	`, job.ID)
 	if err != nil {
-		fmt.Printf("Failed to update job start time: %v\n", err)
+		w.manager.logger.Warn("Failed to update job start time", "job_id", job.ID, "error", err)
 	}
+	w.manager.startAttempt(job)
 	
 	// Find processor for this job type
 	w.manager.mutex.RLock()
@@ -70,36 +75,59 @@ func (w *Worker) processJob(job *Job) {
 	
 	if !exists {
 		errMsg := fmt.Sprintf("No processor registered for job type: %s", job.Type)
-		fmt.Printf("Worker %d: %s\n", w.id, errMsg)
+		w.manager.logger.Error("No processor registered for job", "worker", w.id, "job_id", job.ID, "type", job.Type)
 		w.manager.updateJobStatus(job.ID, JobStatusFailed, errMsg)
 		return
 	}
 	
-	// Create context with timeout (jobs shouldn't run longer than 2 hours)
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	// Create context with timeout (jobs shouldn't run longer than 2 hours), cancelled on shutdown
+	ctx, cancel := context.WithTimeout(w.manager.ctx, 2*time.Hour)
 	defer cancel()
 	
+	// CancelJob cancels the context, stopping the processor at its next check
+	w.manager.trackRunning(job.ID, cancel)
+	defer w.manager.untrackRunning(job.ID)
+	
 	// Process the job
 	startTime := time.Now()
 	err = processor.ProcessJob(ctx, job)
 	duration := time.Since(startTime)
 	
-	if err != nil {
+	outcome := "completed"
+	if ctx.Err() == context.Canceled && w.manager.isShuttingDown() {
+		// Shutdown interrupted the job; it picks up again after the restart
+		outcome = "interrupted"
+		w.manager.logger.Info("Job interrupted by shutdown", "worker", w.id, "job_id", job.ID, "duration", duration)
+		if err := w.manager.requeueInterrupted(job); err != nil {
+			w.manager.logger.Error("Failed to requeue interrupted job", "worker", w.id, "job_id", job.ID, "error", err)
+		}
+	} else if ctx.Err() == context.Canceled {
+		// CancelJob already marked the job cancelled
+		outcome = "cancelled"
+		w.manager.logger.Info("Job cancelled", "worker", w.id, "job_id", job.ID, "duration", duration)
+	} else if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
+			outcome = "timed_out"
 			errMsg := "Job timed out after 2 hours"
-			fmt.Printf("Worker %d: Job %d timed out\n", w.id, job.ID)
+			w.manager.logger.Warn("Job timed out", "worker", w.id, "job_id", job.ID)
 			w.manager.updateJobStatus(job.ID, JobStatusFailed, errMsg)
+		} else if w.manager.scheduleRetry(job, err) {
+			outcome = "retried"
+			w.manager.logger.Warn("Job attempt failed, will retry", "worker", w.id, "job_id", job.ID, "attempt", job.Attempts, "error", err)
 		} else {
+			outcome = "failed"
 			errMsg := fmt.Sprintf("Job failed: %v", err)
-			fmt.Printf("Worker %d: Job %d failed: %v\n", w.id, job.ID, err)
+			w.manager.logger.Error("Job failed", "worker", w.id, "job_id", job.ID, "error", err)
 			w.manager.updateJobStatus(job.ID, JobStatusFailed, errMsg)
 		}
 	} else {
 		// Job completed successfully
-		fmt.Printf("Worker %d: Job %d completed successfully in %v\n", w.id, job.ID, duration)
+		w.manager.logger.Info("Job completed", "worker", w.id, "job_id", job.ID, "type", job.Type, "duration", duration)
 		w.manager.updateJobStatus(job.ID, JobStatusCompleted, "")
 		
 		// Set progress to 100% if not already set
 		w.manager.UpdateJobProgress(job.ID, 100, "Completed", 0, 0, 0)
 	}
+	
+	jobDuration.WithLabelValues(string(job.Type), outcome).Observe(duration.Seconds())
 }
\ No newline at end of file
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"moviedb/internal/database"
+)
+
+// ListImportFilm is one film of an imported list
+type ListImportFilm struct {
+	Title string `json:"title"`
+	Year  int    `json:"year,omitempty"`
+}
+
+// ListImportRequest is a list to create from an import, with the films to match to TMDB
+type ListImportRequest struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	IsPublic    bool             `json:"is_public"`
+	Films       []ListImportFilm `json:"films"`
+}
+
+// ListImportResult is the outcome of a list import
+type ListImportResult struct {
+	ListID          int      `json:"list_id"`
+	Name            string   `json:"name"`
+	Imported        int      `json:"imported"`
+	Unmatched       int      `json:"unmatched"`
+	UnmatchedTitles []string `json:"unmatched_titles"`
+}
+
+// ListImporter matches imported films to TMDB movies and creates a list of them
+type ListImporter struct {
+	db          *sql.DB
+	tmdbClient  *TMDBClient
+	rateLimiter *TMDBRateLimiter
+	jobManager  *JobManager
+	logger      *slog.Logger
+}
+
+// ListImportJobProcessor implements JobProcessor for list imports too large to run inline
+type ListImportJobProcessor struct {
+	importer *ListImporter
+}
+
+// NewListImporter creates a list importer and registers its job processor
+func NewListImporter(db *sql.DB, tmdbClient *TMDBClient, rateLimiter *TMDBRateLimiter, jobManager *JobManager) *ListImporter {
+	importer := &ListImporter{
+		db:          db,
+		tmdbClient:  tmdbClient,
+		rateLimiter: rateLimiter,
+		jobManager:  jobManager,
+		logger:      slog.Default(),
+	}
+
+	jobManager.RegisterProcessor(&ListImportJobProcessor{importer: importer})
+
+	return importer
+}
+
+// GetJobType returns the job type this processor handles
+func (p *ListImportJobProcessor) GetJobType() JobType {
+	return JobTypeListImport
+}
+
+// ProcessJob imports the list stored in the job metadata
+func (p *ListImportJobProcessor) ProcessJob(ctx context.Context, job *Job) error {
+	if job.UserID == nil {
+		return fmt.Errorf("user ID is required for list import job")
+	}
+
+	// Metadata round-trips through JSON, so decode the request back into its struct
+	data, err := json.Marshal(job.Metadata["request"])
+	if err != nil {
+		return fmt.Errorf("failed to read import request: %w", err)
+	}
+	var req ListImportRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to read import request: %w", err)
+	}
+
+	result, err := p.importer.importList(ctx, *job.UserID, req, job.ID)
+	if err != nil {
+		return err
+	}
+
+	// Keep the result (and drop the uploaded films) so it can be read from the job status
+	return p.importer.jobManager.MergeJobMetadata(job.ID, map[string]interface{}{
+		"request":          nil,
+		"list_id":          result.ListID,
+		"imported":         result.Imported,
+		"unmatched":        result.Unmatched,
+		"unmatched_titles": result.UnmatchedTitles,
+	})
+}
+
+// ImportList matches the films and creates the list right away
+func (i *ListImporter) ImportList(ctx context.Context, userID int64, req ListImportRequest) (*ListImportResult, error) {
+	return i.importList(ctx, userID, req, 0)
+}
+
+// StartImportJob queues a background job importing the list. The result is stored in the
+// job metadata once it finishes.
+func (i *ListImporter) StartImportJob(userID int64, req ListImportRequest) (*Job, error) {
+	metadata := map[string]interface{}{
+		"user_id":     userID,
+		"total_films": len(req.Films),
+		"request":     req,
+	}
+
+	job, err := i.jobManager.CreateJob(JobTypeListImport, &userID, nil, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	return job, nil
+}
+
+// importList matches every film, then creates the list with the matched movies in one
+// transaction, so a cancelled or failed import leaves no partial list behind. Progress is
+// reported on jobID when it is non-zero.
+func (i *ListImporter) importList(ctx context.Context, userID int64, req ListImportRequest, jobID int64) (*ListImportResult, error) {
+	result := &ListImportResult{Name: req.Name, UnmatchedTitles: []string{}}
+	var movieIDs []int
+	added := make(map[int]bool)
+	successful, failed := 0, 0
+
+	for n, film := range req.Films {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		movieID, err := i.matchFilm(userID, film)
+		if err != nil {
+			i.logger.Warn("List import failed to match film", "title", film.Title, "year", film.Year, "error", err)
+		}
+		if movieID == 0 {
+			result.UnmatchedTitles = append(result.UnmatchedTitles, formatImportTitle(film))
+			failed++
+		} else {
+			// The same film can appear twice in an export
+			if !added[movieID] {
+				added[movieID] = true
+				movieIDs = append(movieIDs, movieID)
+			}
+			successful++
+		}
+
+		if jobID != 0 {
+			progress := (n + 1) * 100 / len(req.Films)
+			i.jobManager.UpdateJobProgress(jobID, progress, fmt.Sprintf("Matched %d of %d films", n+1, len(req.Films)), n+1, successful, failed)
+		}
+	}
+
+	listID, err := database.CreateListWithMovies(i.db, int(userID), req.Name, req.Description, req.IsPublic, movieIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ListID = listID
+	result.Imported = len(movieIDs)
+	result.Unmatched = len(result.UnmatchedTitles)
+	return result, nil
+}
+
+// matchFilm searches TMDB for a film and caches the first result with its full details,
+// returning its movies.id. It returns 0 without an error when TMDB has no match. Both the
+// search and the details fetch go through the shared rate limiter.
+func (i *ListImporter) matchFilm(userID int64, film ListImportFilm) (int, error) {
+	var searchResp *TMDBSearchResponse
+	err := i.rateLimiter.ExecuteWithRateLimit(func() error {
+		var err error
+		searchResp, err = i.tmdbClient.SearchMovies(film.Title, film.Year)
+		return err
+	}, 1, userID) // Priority 1 - user request, but may be a long batch
+	if err != nil {
+		return 0, fmt.Errorf("TMDB search failed: %w", err)
+	}
+	if len(searchResp.Results) == 0 {
+		return 0, nil
+	}
+
+	var movieID int
+	err = i.rateLimiter.ExecuteWithRateLimit(func() error {
+		var err error
+		movieID, err = EnsureMovieCached(i.db, i.tmdbClient, searchResp.Results[0].ID)
+		return err
+	}, 1, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return movieID, nil
+}
+
+// formatImportTitle formats a film as "Title (Year)" for the unmatched report
+func formatImportTitle(film ListImportFilm) string {
+	if film.Year > 0 {
+		return fmt.Sprintf("%s (%d)", film.Title, film.Year)
+	}
+	return film.Title
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"moviedb/internal/database"
+)
+
+// ListRestoreWindow is how long a deleted list can be restored before it is purged
+const ListRestoreWindow = 30 * 24 * time.Hour
+
+// ListPurgeInterval is how often deleted lists past the restore window are purged
+const ListPurgeInterval = 6 * time.Hour
+
+// ScheduleListPurge purges deleted lists past the restore window now and then every interval
+// until ctx is done
+func ScheduleListPurge(ctx context.Context, db *sql.DB, interval time.Duration) {
+	purgeDeletedLists(db)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("List purge scheduler stopping")
+			return
+		case <-ticker.C:
+			purgeDeletedLists(db)
+		}
+	}
+}
+
+func purgeDeletedLists(db *sql.DB) {
+	purged, err := database.PurgeDeletedLists(db, time.Now().Add(-ListRestoreWindow))
+	if err != nil {
+		slog.Error("List purge failed", "error", err)
+	} else if purged > 0 {
+		slog.Info("List purge removed deleted lists", "lists", purged)
+	}
+}
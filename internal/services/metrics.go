@@ -0,0 +1,138 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const metricsNamespace = "moviedb"
+
+// tmdbFailedRequests counts TMDB requests that failed after their retries
+var tmdbFailedRequests = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "tmdb_failed_requests_total",
+	Help:      "TMDB requests that failed after retries.",
+})
+
+// jobDuration records how long each run of a background job took, by job type and outcome
+// (completed, failed, retried, cancelled, timed_out or interrupted). Sync jobs can run for an hour or more.
+var jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Name:      "job_duration_seconds",
+	Help:      "Duration of background job runs.",
+	Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200},
+}, []string{"type", "outcome"})
+
+var (
+	rateLimiterTokensDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "tmdb_rate_limiter", "available_tokens"),
+		"TMDB requests that can be made right now without waiting.", nil, nil)
+	rateLimiterMaxTokensDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "tmdb_rate_limiter", "max_tokens"),
+		"TMDB requests allowed per rate limit window.", nil, nil)
+	rateLimiterQueueDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "tmdb_rate_limiter", "queue_depth"),
+		"TMDB requests waiting for a rate limit token.", nil, nil)
+	tmdbRequestsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "tmdb", "requests_total"),
+		"TMDB requests made through the rate limiter.", nil, nil)
+	jobManagerRunningDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "job_manager", "running"),
+		"Whether the background job manager is running (1) or not (0).", nil, nil)
+	jobsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "jobs"),
+		"Background jobs by status.", []string{"status"}, nil)
+)
+
+// jobStatuses are always exported, so a status without jobs reads 0 rather than disappearing
+var jobStatuses = []JobStatus{JobStatusPending, JobStatusRunning, JobStatusCompleted, JobStatusFailed, JobStatusCancelled}
+
+// integrationCollector reads the rate limiter and job queue state when metrics are scraped
+type integrationCollector struct {
+	db          *sql.DB
+	rateLimiter *TMDBRateLimiter
+	jobManager  *JobManager
+}
+
+func (c *integrationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rateLimiterTokensDesc
+	ch <- rateLimiterMaxTokensDesc
+	ch <- rateLimiterQueueDesc
+	ch <- tmdbRequestsDesc
+	ch <- jobManagerRunningDesc
+	ch <- jobsDesc
+}
+
+func (c *integrationCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(rateLimiterTokensDesc, prometheus.GaugeValue, float64(c.rateLimiter.AvailableTokens()))
+	ch <- prometheus.MustNewConstMetric(rateLimiterMaxTokensDesc, prometheus.GaugeValue, float64(c.rateLimiter.maxRequests))
+	ch <- prometheus.MustNewConstMetric(rateLimiterQueueDesc, prometheus.GaugeValue, float64(c.rateLimiter.QueueDepth()))
+
+	running := 0.0
+	if c.jobManager.IsRunning() {
+		running = 1
+	}
+	ch <- prometheus.MustNewConstMetric(jobManagerRunningDesc, prometheus.GaugeValue, running)
+
+	var totalRequests int
+	err := c.db.QueryRow("SELECT COALESCE(SUM(requests_count), 0) FROM tmdb_rate_limits").Scan(&totalRequests)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(tmdbRequestsDesc, fmt.Errorf("failed to count TMDB requests: %w", err))
+	} else {
+		ch <- prometheus.MustNewConstMetric(tmdbRequestsDesc, prometheus.CounterValue, float64(totalRequests))
+	}
+
+	counts, err := c.jobCounts()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(jobsDesc, err)
+		return
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(jobsDesc, prometheus.GaugeValue, float64(count), string(status))
+	}
+}
+
+// jobCounts returns how many jobs have each status
+func (c *integrationCollector) jobCounts() (map[JobStatus]int, error) {
+	counts := make(map[JobStatus]int, len(jobStatuses))
+	for _, status := range jobStatuses {
+		counts[status] = 0
+	}
+
+	rows, err := c.db.Query("SELECT status, COUNT(*) FROM sync_jobs GROUP BY status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to read job count: %w", err)
+		}
+		counts[JobStatus(status)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read job counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// MetricsRegistry returns a Prometheus registry with the rate limiter, job queue and job
+// duration metrics, along with the standard Go runtime and process metrics
+func (m *PlexIntegrationManager) MetricsRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		&integrationCollector{db: m.db, rateLimiter: m.rateLimiter, jobManager: m.jobManager},
+		tmdbFailedRequests,
+		jobDuration,
+	)
+	return registry
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EnsureMovieCached returns the local movies.id for a TMDB movie, fetching
+// details from TMDB and inserting the row when it is not cached yet. A placeholder still
+// waiting on the movie cache queue is filled in right away. The error matches
+// ErrTMDBNotFound only when TMDB has no such movie.
+func EnsureMovieCached(db *sql.DB, tmdbClient *TMDBClient, tmdbID int) (int, error) {
+	if !IsPlausibleTMDBID(tmdbID) {
+		return 0, fmt.Errorf("invalid TMDB id %d: %w", tmdbID, ErrTMDBNotFound)
+	}
+
+	var movieID int
+	err := db.QueryRow("SELECT id FROM movies WHERE tmdb_id = ? AND caching = 0", tmdbID).Scan(&movieID)
+	if err == nil {
+		return movieID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to look up movie: %w", err)
+	}
+
+	details, err := tmdbClient.GetMovieDetails(tmdbID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch movie %d from TMDB: %w", tmdbID, err)
+	}
+
+	genreNames := make([]string, len(details.Genres))
+	for i, genre := range details.Genres {
+		genreNames[i] = genre.Name
+	}
+	genresJSON, _ := json.Marshal(genreNames)
+
+	var posterURL *string
+	if url := tmdbClient.GetPosterURL(details.PosterPath, "w500"); url != "" {
+		posterURL = &url
+	}
+
+	// Another request may have cached the movie in the meantime
+	_, err = db.Exec(`
+		INSERT INTO movies (tmdb_id, title, year, poster_url, synopsis, runtime, genres, imdb_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(tmdb_id) DO UPDATE SET
+			title = excluded.title,
+			year = excluded.year,
+			poster_url = excluded.poster_url,
+			synopsis = excluded.synopsis,
+			runtime = excluded.runtime,
+			genres = excluded.genres,
+			imdb_id = excluded.imdb_id,
+			caching = 0
+		WHERE movies.caching = 1
+	`, details.ID, details.Title, ExtractYear(details.ReleaseDate), posterURL, details.Overview,
+		details.Runtime, string(genresJSON), details.IMDbID, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to cache movie: %w", err)
+	}
+
+	if err := db.QueryRow("SELECT id FROM movies WHERE tmdb_id = ?", tmdbID).Scan(&movieID); err != nil {
+		return 0, fmt.Errorf("failed to look up cached movie: %w", err)
+	}
+
+	return movieID, nil
+}
+
+// CacheSearchResult returns the local movies.id for a TMDB search result, inserting the row
+// from the search data when it is not cached yet. Unlike EnsureMovieCached this makes no
+// further TMDB requests, so runtime, genres and IMDb id are filled in later by a details fetch.
+func CacheSearchResult(db *sql.DB, tmdbClient *TMDBClient, movie TMDBMovie) (int, error) {
+	var posterURL *string
+	if url := tmdbClient.GetPosterURL(movie.PosterPath, "w500"); url != "" {
+		posterURL = &url
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO movies (tmdb_id, title, year, poster_url, synopsis, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(tmdb_id) DO NOTHING
+	`, movie.ID, movie.Title, ExtractYear(movie.ReleaseDate), posterURL, movie.Overview, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to cache movie: %w", err)
+	}
+
+	var movieID int
+	if err := db.QueryRow("SELECT id FROM movies WHERE tmdb_id = ?", movie.ID).Scan(&movieID); err != nil {
+		return 0, fmt.Errorf("failed to look up cached movie: %w", err)
+	}
+
+	return movieID, nil
+}
+
+// CacheSearchResults inserts the basic fields of a page of search results in one transaction
+// so they can be added to lists straight away. Movies that are already cached are left as
+// they are; the rest get runtime and genres when their details are first fetched.
+func CacheSearchResults(db *sql.DB, tmdbClient *TMDBClient, movies []TMDBMovie) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO movies (tmdb_id, title, year, poster_url, synopsis, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(tmdb_id) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare movie insert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, movie := range movies {
+		if !IsPlausibleTMDBID(movie.ID) || movie.Title == "" {
+			continue
+		}
+
+		var posterURL *string
+		if url := tmdbClient.GetPosterURL(movie.PosterPath, "w500"); url != "" {
+			posterURL = &url
+		}
+
+		if _, err := stmt.Exec(movie.ID, movie.Title, ExtractYear(movie.ReleaseDate), posterURL, movie.Overview, now); err != nil {
+			return fmt.Errorf("failed to cache movie %d: %w", movie.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
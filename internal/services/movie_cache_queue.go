@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"moviedb/internal/database"
+)
+
+// MovieCacheQueue fetches the details of movies added to lists in the background, so adding
+// a movie that isn't cached yet doesn't wait on TMDB
+type MovieCacheQueue struct {
+	db          *sql.DB
+	tmdbClient  *TMDBClient
+	rateLimiter *TMDBRateLimiter
+	jobManager  *JobManager
+	logger      *slog.Logger
+}
+
+// MovieCacheJobProcessor implements JobProcessor for background movie caching
+type MovieCacheJobProcessor struct {
+	queue *MovieCacheQueue
+}
+
+// NewMovieCacheQueue creates a movie cache queue and registers its job processor
+func NewMovieCacheQueue(db *sql.DB, tmdbClient *TMDBClient, rateLimiter *TMDBRateLimiter, jobManager *JobManager) *MovieCacheQueue {
+	queue := &MovieCacheQueue{
+		db:          db,
+		tmdbClient:  tmdbClient,
+		rateLimiter: rateLimiter,
+		jobManager:  jobManager,
+		logger:      slog.Default(),
+	}
+
+	jobManager.RegisterProcessor(&MovieCacheJobProcessor{queue: queue})
+
+	return queue
+}
+
+// GetJobType returns the job type this processor handles
+func (p *MovieCacheJobProcessor) GetJobType() JobType {
+	return JobTypeMovieCache
+}
+
+// ProcessJob fetches the movie named by the tmdb_id in the job metadata
+func (p *MovieCacheJobProcessor) ProcessJob(ctx context.Context, job *Job) error {
+	// Numbers come back from the JSON metadata as float64
+	tmdbID, ok := job.Metadata["tmdb_id"].(float64)
+	if !ok {
+		return fmt.Errorf("tmdb_id is required for movie cache job")
+	}
+
+	return p.queue.cacheMovie(int(tmdbID))
+}
+
+// Enqueue returns the local movies.id for a TMDB movie right away. A movie that isn't cached
+// yet gets a placeholder row marked as caching and a background job that fills it in; caching
+// reports whether that is still pending.
+func (q *MovieCacheQueue) Enqueue(tmdbID int) (movieID int, caching bool, err error) {
+	if !IsPlausibleTMDBID(tmdbID) {
+		return 0, false, fmt.Errorf("invalid TMDB id %d", tmdbID)
+	}
+
+	err = q.db.QueryRow("SELECT id, caching FROM movies WHERE tmdb_id = ?", tmdbID).Scan(&movieID, &caching)
+	if err == sql.ErrNoRows {
+		// The synopsis is read as a string by list queries, so it can't be NULL
+		_, err = q.db.Exec(`
+			INSERT INTO movies (tmdb_id, title, synopsis, caching) VALUES (?, '', '', 1)
+			ON CONFLICT(tmdb_id) DO NOTHING
+		`, tmdbID)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to add movie placeholder: %w", err)
+		}
+		err = q.db.QueryRow("SELECT id, caching FROM movies WHERE tmdb_id = ?", tmdbID).Scan(&movieID, &caching)
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to look up movie: %w", err)
+	}
+	if !caching {
+		return movieID, false, nil
+	}
+
+	// A placeholder left by a failed job gets a new one
+	var existingJobID int64
+	err = q.db.QueryRow(`
+		SELECT id FROM sync_jobs
+		WHERE type = ? AND status IN (?, ?) AND `+database.DialectOf(q.db).JSONField("metadata_json", "tmdb_id")+` = ?
+		LIMIT 1
+	`, JobTypeMovieCache, JobStatusPending, JobStatusRunning, tmdbID).Scan(&existingJobID)
+	if err == nil {
+		return movieID, true, nil
+	}
+
+	if _, err := q.jobManager.CreateJob(JobTypeMovieCache, nil, nil, map[string]interface{}{"tmdb_id": tmdbID}); err != nil {
+		// Queue full - fetch it now instead
+		q.logger.Warn("Movie cache: failed to enqueue movie, fetching now", "tmdb_id", tmdbID, "error", err)
+		if err := q.cacheMovie(tmdbID); err != nil {
+			return 0, false, err
+		}
+		return movieID, false, nil
+	}
+
+	return movieID, true, nil
+}
+
+// cacheMovie fills in a placeholder movie from TMDB. A movie TMDB doesn't know is removed
+// again, along with the list entries that referenced it.
+func (q *MovieCacheQueue) cacheMovie(tmdbID int) error {
+	var details *TMDBMovieDetails
+	err := q.rateLimiter.ExecuteWithRateLimit(func() error {
+		var err error
+		details, err = q.tmdbClient.GetMovieDetails(tmdbID)
+		return err
+	}, 1, 0) // Priority 1 - shows up on a list the user is looking at
+	if errors.Is(err, ErrTMDBNotFound) {
+		if err := q.removePlaceholder(tmdbID); err != nil {
+			return err
+		}
+		return fmt.Errorf("movie %d not found on TMDB", tmdbID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch movie %d from TMDB: %w", tmdbID, err)
+	}
+
+	genreNames := make([]string, len(details.Genres))
+	for i, genre := range details.Genres {
+		genreNames[i] = genre.Name
+	}
+	genresJSON, _ := json.Marshal(genreNames)
+
+	var posterURL *string
+	if url := q.tmdbClient.GetPosterURL(details.PosterPath, "w500"); url != "" {
+		posterURL = &url
+	}
+
+	_, err = q.db.Exec(`
+		UPDATE movies
+		SET title = ?, year = ?, poster_url = ?, synopsis = ?, runtime = ?, genres = ?, imdb_id = ?, caching = 0
+		WHERE tmdb_id = ?
+	`, details.Title, ExtractYear(details.ReleaseDate), posterURL, details.Overview, details.Runtime,
+		string(genresJSON), details.IMDbID, tmdbID)
+	if err != nil {
+		return fmt.Errorf("failed to cache movie %d: %w", tmdbID, err)
+	}
+
+	return nil
+}
+
+// removePlaceholder deletes a movie that is still waiting for its details and its list entries
+func (q *MovieCacheQueue) removePlaceholder(tmdbID int) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var movieID int
+	err = tx.QueryRow("SELECT id FROM movies WHERE tmdb_id = ? AND caching = 1", tmdbID).Scan(&movieID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up placeholder: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM list_movies WHERE movie_id = ?", movieID); err != nil {
+		return fmt.Errorf("failed to remove list entries of movie %d: %w", tmdbID, err)
+	}
+	if _, err := tx.Exec("DELETE FROM movies WHERE id = ?", movieID); err != nil {
+		return fmt.Errorf("failed to remove placeholder of movie %d: %w", tmdbID, err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestEnsureMovieCachedErrors checks that only movies TMDB doesn't have report
+// ErrTMDBNotFound, so handlers can tell them apart from TMDB being unavailable
+func TestEnsureMovieCachedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/movie/404":
+			http.Error(w, `{"status_code":34}`, http.StatusNotFound)
+		default:
+			http.Error(w, "upstream failure", http.StatusInternalServerError)
+		}
+	}))
+
+	client := NewTMDBClient("test-api-key-for-movie-cache")
+	client.BaseURL = server.URL
+	db := newTestDB(t)
+
+	if _, err := EnsureMovieCached(db, client, 404); !errors.Is(err, ErrTMDBNotFound) {
+		t.Errorf("missing movie: error = %v, want ErrTMDBNotFound", err)
+	}
+	if _, err := EnsureMovieCached(db, client, 0); !errors.Is(err, ErrTMDBNotFound) {
+		t.Errorf("invalid id: error = %v, want ErrTMDBNotFound", err)
+	}
+
+	_, err := EnsureMovieCached(db, client, 500)
+	if err == nil || errors.Is(err, ErrTMDBNotFound) {
+		t.Errorf("TMDB server error: error = %v, want a non-not-found error", err)
+	}
+
+	// A transport failure isn't a not-found either
+	server.Close()
+	_, err = EnsureMovieCached(db, client, 501)
+	if err == nil || errors.Is(err, ErrTMDBNotFound) {
+		t.Errorf("unreachable TMDB: error = %v, want a non-not-found error", err)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// Credits are trimmed to the top-billed cast and cached for a week; they rarely change
+const (
+	maxCreditsCast  = 10
+	creditsCacheTTL = 7 * 24 * time.Hour
+)
+
+// CastMember is a top-billed actor as shown on the movie details page
+type CastMember struct {
+	Name       string `json:"name"`
+	Character  string `json:"character"`
+	ProfileURL string `json:"profile_url,omitempty"`
+}
+
+// CrewMember is a director or writer as shown on the movie details page
+type CrewMember struct {
+	Name       string `json:"name"`
+	Job        string `json:"job"`
+	ProfileURL string `json:"profile_url,omitempty"`
+}
+
+// MovieCredits is the trimmed cast and key crew of a movie
+type MovieCredits struct {
+	Cast      []CastMember `json:"cast"`
+	Directors []CrewMember `json:"directors"`
+	Writers   []CrewMember `json:"writers"`
+}
+
+// GetMovieCredits returns the trimmed credits of a movie, served from movie_credits_cache
+// while fresh and fetched from TMDB otherwise
+func GetMovieCredits(db *sql.DB, tmdbClient *TMDBClient, tmdbID int) (*MovieCredits, error) {
+	var data string
+	var expiresAt time.Time
+	err := db.QueryRow(`
+		SELECT credits_data, expires_at FROM movie_credits_cache WHERE tmdb_id = ?
+	`, tmdbID).Scan(&data, &expiresAt)
+	if err == nil && expiresAt.After(time.Now()) {
+		var credits MovieCredits
+		if err := json.Unmarshal([]byte(data), &credits); err == nil {
+			return &credits, nil
+		}
+	} else if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to read credits cache: %w", err)
+	}
+
+	tmdbCredits, err := tmdbClient.GetMovieCredits(tmdbID)
+	if err != nil {
+		return nil, err
+	}
+
+	credits := trimCredits(tmdbClient, tmdbCredits)
+
+	encoded, err := json.Marshal(credits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode credits: %w", err)
+	}
+
+	now := time.Now()
+	_, err = db.Exec(`
+		INSERT INTO movie_credits_cache (tmdb_id, credits_data, cached_at, expires_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(tmdb_id) DO UPDATE SET
+			credits_data = excluded.credits_data,
+			cached_at = excluded.cached_at,
+			expires_at = excluded.expires_at
+	`, tmdbID, string(encoded), now, now.Add(creditsCacheTTL))
+	if err != nil {
+		// Still return the fresh credits; the next view will try caching again
+		slog.Warn("Failed to cache movie credits", "tmdb_id", tmdbID, "error", err)
+	}
+
+	return credits, nil
+}
+
+// trimCredits keeps the top-billed cast and the directors and writers from the crew
+func trimCredits(tmdbClient *TMDBClient, tmdbCredits *TMDBCredits) *MovieCredits {
+	credits := &MovieCredits{
+		Cast:      []CastMember{},
+		Directors: []CrewMember{},
+		Writers:   []CrewMember{},
+	}
+
+	cast := append([]TMDBCastMember(nil), tmdbCredits.Cast...)
+	sort.SliceStable(cast, func(i, j int) bool { return cast[i].Order < cast[j].Order })
+	if len(cast) > maxCreditsCast {
+		cast = cast[:maxCreditsCast]
+	}
+	for _, member := range cast {
+		credits.Cast = append(credits.Cast, CastMember{
+			Name:       member.Name,
+			Character:  member.Character,
+			ProfileURL: tmdbClient.GetPosterURL(member.ProfilePath, "w185"),
+		})
+	}
+
+	// A person can be credited for several writing jobs; list them once
+	seenWriters := make(map[int]bool)
+	for _, member := range tmdbCredits.Crew {
+		crew := CrewMember{
+			Name:       member.Name,
+			Job:        member.Job,
+			ProfileURL: tmdbClient.GetPosterURL(member.ProfilePath, "w185"),
+		}
+
+		switch {
+		case member.Job == "Director":
+			credits.Directors = append(credits.Directors, crew)
+		case member.Department == "Writing" && !seenWriters[member.ID]:
+			seenWriters[member.ID] = true
+			credits.Writers = append(credits.Writers, crew)
+		}
+	}
+
+	return credits
+}
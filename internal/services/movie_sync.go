@@ -4,15 +4,25 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync"
 	"time"
 )
 
+// Runtime backfill limits - bounded per run to protect the TMDB budget
+const (
+	runtimeBackfillBatchSize = 50
+	runtimeBackfillDelay     = 250 * time.Millisecond
+	runtimeRecheckInterval   = 7 * 24 * time.Hour
+)
+
 type MovieSyncService struct {
 	db         *sql.DB
 	tmdbClient *TMDBClient
 	ticker     *time.Ticker
 	stopChan   chan bool
+	stopOnce   sync.Once
+	logger     *slog.Logger
 }
 
 type SyncStatus struct {
@@ -26,24 +36,25 @@ func NewMovieSyncService(db *sql.DB, tmdbClient *TMDBClient) *MovieSyncService {
 		db:         db,
 		tmdbClient: tmdbClient,
 		stopChan:   make(chan bool),
+		logger:     slog.Default(),
 	}
 }
 
 // StartSyncScheduler starts the automatic daily sync scheduler
 func (s *MovieSyncService) StartSyncScheduler() {
-	log.Println("Starting movie sync scheduler...")
+	s.logger.Info("Starting movie sync scheduler")
 
 	// Check if we need to sync immediately (empty table)
 	movieCount, err := s.getMovieCount()
 	if err != nil {
-		log.Printf("Error checking movie count: %v", err)
+		s.logger.Error("Failed to check movie count", "error", err)
 	} else if movieCount == 0 {
-		log.Println("Movies table is empty, starting initial sync...")
+		s.logger.Info("Movies table is empty, starting initial sync")
 		go s.performSync()
 	} else {
-		log.Printf("Movies table has %d movies, checking last sync...", movieCount)
+		s.logger.Debug("Checking last movie sync", "movies", movieCount)
 		if s.shouldSync() {
-			log.Println("Starting sync (last sync was more than 24 hours ago)...")
+			s.logger.Info("Starting movie sync, last sync was more than 24 hours ago")
 			go s.performSync()
 		}
 	}
@@ -55,19 +66,30 @@ func (s *MovieSyncService) StartSyncScheduler() {
 		for {
 			select {
 			case <-s.ticker.C:
-				log.Println("Daily sync triggered...")
+				s.logger.Info("Daily movie sync triggered")
 				s.performSync()
 			case <-s.stopChan:
-				log.Println("Movie sync scheduler stopped")
+				s.logger.Info("Movie sync scheduler stopped")
 				return
 			}
 		}
 	}()
 }
 
+// StopSyncScheduler stops the daily sync scheduler. A sync already in progress isn't
+// interrupted; it is safe to call more than once.
+func (s *MovieSyncService) StopSyncScheduler() {
+	s.stopOnce.Do(func() {
+		if s.ticker != nil {
+			s.ticker.Stop()
+		}
+		close(s.stopChan)
+	})
+}
+
 // ManualSync triggers a manual sync (can be called from API)
 func (s *MovieSyncService) ManualSync() error {
-	log.Println("Manual sync triggered...")
+	s.logger.Info("Manual movie sync triggered")
 	return s.performSync()
 }
 
@@ -91,36 +113,41 @@ func (s *MovieSyncService) GetSyncStatus() (*SyncStatus, error) {
 }
 
 func (s *MovieSyncService) performSync() error {
-	log.Println("Starting movie sync with TMDB...")
+	s.logger.Info("Starting movie sync with TMDB")
 	start := time.Now()
 
 	// Sync popular movies (first 5 pages = ~100 movies)
 	if err := s.syncPopularMovies(5); err != nil {
-		log.Printf("Error syncing popular movies: %v", err)
+		s.logger.Error("Failed to sync popular movies", "error", err)
 		return err
 	}
 
 	// Sync trending movies for this week
 	if err := s.syncTrendingMovies(); err != nil {
-		log.Printf("Error syncing trending movies: %v", err)
+		s.logger.Error("Failed to sync trending movies", "error", err)
 		return err
 	}
 
+	// Fill in runtimes that earlier details calls failed to fetch
+	if err := s.backfillMissingRuntimes(runtimeBackfillBatchSize); err != nil {
+		s.logger.Error("Failed to backfill movie runtimes", "error", err)
+	}
+
 	// Update last sync time
 	if err := s.updateLastSyncTime(); err != nil {
-		log.Printf("Error updating last sync time: %v", err)
+		s.logger.Error("Failed to update last movie sync time", "error", err)
 	}
 
 	duration := time.Since(start)
 	movieCount, _ := s.getMovieCount()
-	log.Printf("Movie sync completed in %v. Total movies: %d", duration, movieCount)
+	s.logger.Info("Movie sync completed", "duration", duration, "movies", movieCount)
 
 	return nil
 }
 
 func (s *MovieSyncService) syncPopularMovies(maxPages int) error {
 	for page := 1; page <= maxPages; page++ {
-		log.Printf("Syncing popular movies page %d/%d...", page, maxPages)
+		s.logger.Debug("Syncing popular movies", "page", page, "max_pages", maxPages)
 
 		resp, err := s.tmdbClient.GetPopularMovies(page)
 		if err != nil {
@@ -129,7 +156,7 @@ func (s *MovieSyncService) syncPopularMovies(maxPages int) error {
 
 		for _, tmdbMovie := range resp.Results {
 			if err := s.syncMovie(tmdbMovie); err != nil {
-				log.Printf("Error syncing movie %s (ID: %d): %v", tmdbMovie.Title, tmdbMovie.ID, err)
+				s.logger.Warn("Failed to sync movie", "title", tmdbMovie.Title, "tmdb_id", tmdbMovie.ID, "error", err)
 				continue
 			}
 		}
@@ -142,20 +169,85 @@ func (s *MovieSyncService) syncPopularMovies(maxPages int) error {
 }
 
 func (s *MovieSyncService) syncTrendingMovies() error {
-	log.Println("Syncing trending movies...")
+	s.logger.Debug("Syncing trending movies")
 
-	resp, err := s.tmdbClient.GetTrendingMovies("week")
+	resp, err := s.tmdbClient.GetTrendingMovies("week", 1)
 	if err != nil {
 		return fmt.Errorf("failed to get trending movies: %w", err)
 	}
 
 	for _, tmdbMovie := range resp.Results {
 		if err := s.syncMovie(tmdbMovie); err != nil {
-			log.Printf("Error syncing trending movie %s (ID: %d): %v", tmdbMovie.Title, tmdbMovie.ID, err)
+			s.logger.Warn("Failed to sync trending movie", "title", tmdbMovie.Title, "tmdb_id", tmdbMovie.ID, "error", err)
+			continue
+		}
+	}
+
+	return nil
+}
+
+// backfillMissingRuntimes fetches details for movies with a NULL/zero runtime.
+// Movies checked recently are skipped so titles TMDB has no runtime for
+// don't use up every run's budget.
+func (s *MovieSyncService) backfillMissingRuntimes(limit int) error {
+	recheckBefore := time.Now().Add(-runtimeRecheckInterval)
+
+	rows, err := s.db.Query(`
+		SELECT tmdb_id
+		FROM movies
+		WHERE (runtime IS NULL OR runtime = 0)
+		  AND (runtime_checked_at IS NULL OR runtime_checked_at < ?)
+		ORDER BY runtime_checked_at IS NOT NULL, runtime_checked_at, id
+		LIMIT ?
+	`, recheckBefore, limit)
+	if err != nil {
+		return fmt.Errorf("failed to find movies missing runtime: %w", err)
+	}
+
+	var tmdbIDs []int
+	for rows.Next() {
+		var tmdbID int
+		if err := rows.Scan(&tmdbID); err != nil {
 			continue
 		}
+		tmdbIDs = append(tmdbIDs, tmdbID)
+	}
+	rows.Close()
+
+	if len(tmdbIDs) == 0 {
+		return nil
+	}
+
+	s.logger.Info("Backfilling movie runtimes", "movies", len(tmdbIDs))
+
+	filled := 0
+	for _, tmdbID := range tmdbIDs {
+		details, err := s.tmdbClient.GetMovieDetails(tmdbID)
+		if err != nil {
+			s.logger.Warn("Failed to get movie details for runtime backfill", "tmdb_id", tmdbID, "error", err)
+		}
+
+		var runtime interface{}
+		if err == nil && details.Runtime > 0 {
+			runtime = details.Runtime
+			filled++
+		}
+
+		_, err = s.db.Exec(`
+			UPDATE movies
+			SET runtime = COALESCE(?, runtime), runtime_checked_at = ?
+			WHERE tmdb_id = ?
+		`, runtime, time.Now(), tmdbID)
+		if err != nil {
+			s.logger.Warn("Failed to update movie runtime", "tmdb_id", tmdbID, "error", err)
+		}
+
+		// Small delay to be nice to TMDB API
+		time.Sleep(runtimeBackfillDelay)
 	}
 
+	s.logger.Info("Runtime backfill completed", "updated", filled, "movies", len(tmdbIDs))
+
 	return nil
 }
 
@@ -188,14 +280,14 @@ func (s *MovieSyncService) insertMovie(tmdbMovie TMDBMovie) error {
 	// Get detailed movie info for runtime and genres
 	details, err := s.tmdbClient.GetMovieDetails(tmdbMovie.ID)
 	if err != nil {
-		log.Printf("Warning: Could not get details for movie %d, using basic info", tmdbMovie.ID)
+		s.logger.Warn("Failed to get movie details, using basic info", "tmdb_id", tmdbMovie.ID, "error", err)
 		details = &TMDBMovieDetails{TMDBMovie: tmdbMovie}
 	}
 
 	// Convert genres to JSON
 	genresJSON, err := s.convertGenresToJSON(details.Genres)
 	if err != nil {
-		log.Printf("Warning: Could not convert genres for movie %d: %v", tmdbMovie.ID, err)
+		s.logger.Warn("Failed to convert movie genres", "tmdb_id", tmdbMovie.ID, "error", err)
 		genresJSON = "[]"
 	}
 
@@ -227,14 +319,14 @@ func (s *MovieSyncService) updateMovie(tmdbMovie TMDBMovie) error {
 	// Get detailed movie info
 	details, err := s.tmdbClient.GetMovieDetails(tmdbMovie.ID)
 	if err != nil {
-		log.Printf("Warning: Could not get details for movie %d during update", tmdbMovie.ID)
+		s.logger.Warn("Failed to get movie details for update", "tmdb_id", tmdbMovie.ID, "error", err)
 		return nil // Skip update if we can't get details
 	}
 
 	// Convert genres to JSON
 	genresJSON, err := s.convertGenresToJSON(details.Genres)
 	if err != nil {
-		log.Printf("Warning: Could not convert genres for movie %d: %v", tmdbMovie.ID, err)
+		s.logger.Warn("Failed to convert movie genres", "tmdb_id", tmdbMovie.ID, "error", err)
 		genresJSON = "[]"
 	}
 
@@ -304,7 +396,7 @@ func (s *MovieSyncService) getLastSyncTime() (time.Time, error) {
 		CREATE TABLE IF NOT EXISTS app_settings (
 			key TEXT PRIMARY KEY,
 			value TEXT NOT NULL,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)
 	`)
 	if err != nil {
@@ -335,7 +427,7 @@ func (s *MovieSyncService) updateLastSyncTime() error {
 		CREATE TABLE IF NOT EXISTS app_settings (
 			key TEXT PRIMARY KEY,
 			value TEXT NOT NULL,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)
 	`)
 	if err != nil {
@@ -344,8 +436,9 @@ func (s *MovieSyncService) updateLastSyncTime() error {
 
 	now := time.Now()
 	_, err = s.db.Exec(`
-		INSERT OR REPLACE INTO app_settings (key, value, updated_at)
+		INSERT INTO app_settings (key, value, updated_at)
 		VALUES ('last_movie_sync', ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
 	`, now.Format(time.RFC3339), now)
 
 	return err
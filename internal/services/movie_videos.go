@@ -0,0 +1,91 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// MovieVideo is a trailer or teaser as shown on the movie details page
+type MovieVideo struct {
+	Key  string `json:"key"`
+	Site string `json:"site"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// GetMovieVideos returns the YouTube trailers and teasers of a cached movie, official
+// trailers first. They are fetched from TMDB once and stored on the movie row.
+func GetMovieVideos(db *sql.DB, tmdbClient *TMDBClient, tmdbID int) ([]MovieVideo, error) {
+	var stored sql.NullString
+	err := db.QueryRow("SELECT videos FROM movies WHERE tmdb_id = ?", tmdbID).Scan(&stored)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to look up movie videos: %w", err)
+	}
+
+	if stored.Valid {
+		var videos []MovieVideo
+		if err := json.Unmarshal([]byte(stored.String), &videos); err == nil {
+			return videos, nil
+		}
+	}
+
+	response, err := tmdbClient.GetMovieVideos(tmdbID)
+	if err != nil {
+		return nil, err
+	}
+
+	videos := selectTrailers(response.Results)
+
+	// Only cached movies have a row to store them on; others are fetched again next time
+	encoded, _ := json.Marshal(videos)
+	if _, err := db.Exec("UPDATE movies SET videos = ? WHERE tmdb_id = ?", string(encoded), tmdbID); err != nil {
+		slog.Warn("Failed to store movie videos", "tmdb_id", tmdbID, "error", err)
+	}
+
+	return videos, nil
+}
+
+// selectTrailers keeps YouTube trailers and teasers, ordering official trailers first,
+// then other trailers, then teasers
+func selectTrailers(tmdbVideos []TMDBVideo) []MovieVideo {
+	rank := func(video TMDBVideo) int {
+		switch {
+		case video.Type == "Trailer" && video.Official:
+			return 0
+		case video.Type == "Trailer":
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	var candidates []TMDBVideo
+	for _, video := range tmdbVideos {
+		if video.Site != "YouTube" || video.Key == "" {
+			continue
+		}
+		if video.Type != "Trailer" && video.Type != "Teaser" {
+			continue
+		}
+		candidates = append(candidates, video)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return rank(candidates[i]) < rank(candidates[j])
+	})
+
+	videos := make([]MovieVideo, 0, len(candidates))
+	for _, video := range candidates {
+		videos = append(videos, MovieVideo{
+			Key:  video.Key,
+			Site: video.Site,
+			Type: video.Type,
+			Name: video.Name,
+		})
+	}
+
+	return videos
+}
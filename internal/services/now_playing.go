@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nowPlayingCacheTTL keeps Plex servers from being asked for sessions on every feed refresh
+const nowPlayingCacheTTL = 30 * time.Second
+
+// NowPlayingItem is something a user is currently watching on Plex, with its TMDB mapping
+// when the item has been matched by a library sync
+type NowPlayingItem struct {
+	Type          string `json:"type"` // movie or episode
+	Title         string `json:"title"`
+	ShowTitle     string `json:"show_title,omitempty"`
+	SeasonNumber  *int   `json:"season_number,omitempty"`
+	EpisodeNumber *int   `json:"episode_number,omitempty"`
+	TMDBID        *int   `json:"tmdb_id,omitempty"`    // Movies
+	TMDBTVID      *int   `json:"tmdb_tv_id,omitempty"` // Episodes (the show's id)
+	PosterURL     string `json:"poster_url,omitempty"`
+	Progress      int    `json:"progress"` // Percent watched
+	State         string `json:"state"`    // playing, paused or buffering
+	Player        string `json:"player,omitempty"`
+	ServerName    string `json:"server_name"`
+}
+
+// serverSession is a playback session with the name of the server it's on
+type serverSession struct {
+	session    PlexSession
+	serverName string
+}
+
+type nowPlayingEntry struct {
+	items     []NowPlayingItem
+	expiresAt time.Time
+}
+
+// NowPlayingService looks up what users are playing on the Plex servers their accounts reach
+type NowPlayingService struct {
+	db           *sql.DB
+	plexgoClient *PlexgoClient
+
+	mu    sync.Mutex
+	cache map[int64]nowPlayingEntry // Keyed by user id
+
+	logger *slog.Logger
+}
+
+func NewNowPlayingService(db *sql.DB, plexgoClient *PlexgoClient) *NowPlayingService {
+	return &NowPlayingService{
+		db:           db,
+		plexgoClient: plexgoClient,
+		cache:        make(map[int64]nowPlayingEntry),
+		logger:       slog.Default(),
+	}
+}
+
+// GetNowPlaying returns the user's own playback sessions across their Plex servers. Sessions
+// of other accounts on the same servers are left out. Servers that can't be reached or refuse
+// the request are skipped. Returns sql.ErrNoRows when the user hasn't connected Plex.
+func (s *NowPlayingService) GetNowPlaying(ctx context.Context, userID int64) ([]NowPlayingItem, error) {
+	if items, ok := s.cached(userID); ok {
+		return items, nil
+	}
+
+	plexToken, err := GetUserPlexToken(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accountNames, err := s.plexAccountNames(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := s.plexgoClient.GetServers(ctx, plexToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get servers: %w", err)
+	}
+
+	var playing []serverSession
+	for _, server := range servers {
+		connection, _ := s.plexgoClient.SelectConnection(ctx, server)
+		if connection == nil {
+			continue
+		}
+
+		sessions, err := s.plexgoClient.GetSessions(ctx, server.AccessToken, s.plexgoClient.BuildServerURL(*connection))
+		if err != nil {
+			s.logger.Warn("Now playing: skipping Plex server", "server", server.Name, "user_id", userID, "error", err)
+			continue
+		}
+
+		for _, session := range sessions {
+			if !accountNames[strings.ToLower(session.UserTitle)] {
+				continue
+			}
+			playing = append(playing, serverSession{session: session, serverName: server.Name})
+
+			if session.Type == "movie" {
+				if err := SaveProgress(s.db, userID, session.GUID, session.ViewOffset, session.Duration); err != nil {
+					s.logger.Warn("Now playing: failed to save progress", "user_id", userID, "error", err)
+				}
+			}
+		}
+	}
+
+	items := s.toNowPlayingItems(playing)
+
+	s.mu.Lock()
+	s.cache[userID] = nowPlayingEntry{items: items, expiresAt: time.Now().Add(nowPlayingCacheTTL)}
+	s.mu.Unlock()
+
+	return items, nil
+}
+
+// cached returns a user's recent sessions if they haven't expired
+func (s *NowPlayingService) cached(userID int64) ([]NowPlayingItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, entry := range s.cache {
+		if now.After(entry.expiresAt) {
+			delete(s.cache, id)
+		}
+	}
+
+	entry, ok := s.cache[userID]
+	return entry.items, ok
+}
+
+// plexAccountNames returns the lowercased names a user's sessions are reported under. With a
+// Plex Home profile selected only that profile counts as the user.
+func (s *NowPlayingService) plexAccountNames(userID int64) (map[string]bool, error) {
+	var username, friendlyName, homeUserTitle sql.NullString
+	err := s.db.QueryRow(`
+		SELECT plex_username, plex_friendly_name, plex_home_user_title
+		FROM user_plex_tokens WHERE user_id = ?
+	`, userID).Scan(&username, &friendlyName, &homeUserTitle)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	candidates := []sql.NullString{username, friendlyName}
+	if homeUserTitle.String != "" {
+		candidates = []sql.NullString{homeUserTitle}
+	}
+	for _, name := range candidates {
+		if name.String != "" {
+			names[strings.ToLower(name.String)] = true
+		}
+	}
+
+	return names, nil
+}
+
+// toNowPlayingItems converts sessions, looking up the TMDB matches of their movies and shows
+// by Plex GUID in synced libraries. All sessions are resolved with one query for the matches
+// and one each for movie and show posters.
+func (s *NowPlayingService) toNowPlayingItems(sessions []serverSession) []NowPlayingItem {
+	items := make([]NowPlayingItem, len(sessions))
+	var guids []string
+	seen := make(map[string]bool)
+	for i, ss := range sessions {
+		items[i] = toNowPlayingItem(ss.session, ss.serverName)
+		if guid := matchGUID(ss.session); guid != "" && !seen[guid] {
+			seen[guid] = true
+			guids = append(guids, guid)
+		}
+	}
+	if len(guids) == 0 {
+		return items
+	}
+
+	movieIDs, showIDs, err := s.lookupTMDBMatches(guids)
+	if err != nil {
+		s.logger.Error("Now playing: failed to look up TMDB matches", "error", err)
+		return items
+	}
+	moviePosters, err := s.lookupPosters("movies", movieIDs)
+	if err != nil {
+		s.logger.Error("Now playing: failed to look up movie posters", "error", err)
+	}
+	showPosters, err := s.lookupPosters("tv_shows", showIDs)
+	if err != nil {
+		s.logger.Error("Now playing: failed to look up show posters", "error", err)
+	}
+
+	for i, ss := range sessions {
+		guid := matchGUID(ss.session)
+		if ss.session.Type == "episode" {
+			if id, ok := showIDs[guid]; ok {
+				items[i].TMDBTVID = &id
+				items[i].PosterURL = showPosters[id]
+			}
+		} else if id, ok := movieIDs[guid]; ok {
+			items[i].TMDBID = &id
+			items[i].PosterURL = moviePosters[id]
+		}
+	}
+
+	return items
+}
+
+// matchGUID returns the Plex GUID a session's TMDB match is stored under: the show's for
+// episodes, the item's own otherwise
+func matchGUID(session PlexSession) string {
+	if session.Type == "episode" {
+		return session.GrandparentGUID
+	}
+	return session.GUID
+}
+
+// lookupTMDBMatches maps Plex GUIDs to matched TMDB movie and show ids
+func (s *NowPlayingService) lookupTMDBMatches(guids []string) (map[string]int, map[string]int, error) {
+	placeholders := make([]string, len(guids))
+	args := make([]interface{}, len(guids))
+	for i, guid := range guids {
+		placeholders[i] = "?"
+		args[i] = guid
+	}
+
+	rows, err := s.db.Query(`
+		SELECT plex_guid, tmdb_id, tmdb_tv_id
+		FROM plex_library_items
+		WHERE plex_guid IN (`+strings.Join(placeholders, ", ")+`)
+			AND (tmdb_id IS NOT NULL OR tmdb_tv_id IS NOT NULL)
+	`, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	// An item in several libraries may only be matched in some of them
+	movieIDs := make(map[string]int)
+	showIDs := make(map[string]int)
+	for rows.Next() {
+		var guid string
+		var tmdbID, tmdbTVID sql.NullInt64
+		if err := rows.Scan(&guid, &tmdbID, &tmdbTVID); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := movieIDs[guid]; !ok && tmdbID.Valid {
+			movieIDs[guid] = int(tmdbID.Int64)
+		}
+		if _, ok := showIDs[guid]; !ok && tmdbTVID.Valid {
+			showIDs[guid] = int(tmdbTVID.Int64)
+		}
+	}
+
+	return movieIDs, showIDs, rows.Err()
+}
+
+// lookupPosters returns the poster URLs of the given TMDB ids from the movies or tv_shows table
+func (s *NowPlayingService) lookupPosters(table string, ids map[string]int) (map[int]string, error) {
+	posters := make(map[int]string)
+	if len(ids) == 0 {
+		return posters, nil
+	}
+
+	var placeholders []string
+	var args []interface{}
+	for _, id := range ids {
+		placeholders = append(placeholders, "?")
+		args = append(args, id)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT tmdb_id, poster_url FROM `+table+`
+		WHERE tmdb_id IN (`+strings.Join(placeholders, ", ")+`)
+	`, args...)
+	if err != nil {
+		return posters, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tmdbID int
+		var posterURL sql.NullString
+		if err := rows.Scan(&tmdbID, &posterURL); err != nil {
+			return posters, err
+		}
+		posters[tmdbID] = posterURL.String
+	}
+
+	return posters, rows.Err()
+}
+
+// toNowPlayingItem converts a session without its TMDB match
+func toNowPlayingItem(session PlexSession, serverName string) NowPlayingItem {
+	item := NowPlayingItem{
+		Type:       session.Type,
+		Title:      session.Title,
+		State:      session.State,
+		Player:     session.Player,
+		ServerName: serverName,
+	}
+	if session.Duration > 0 {
+		item.Progress = min(session.ViewOffset*100/session.Duration, 100)
+	}
+	if session.Type == "episode" {
+		item.ShowTitle = session.GrandparentTitle
+		item.SeasonNumber = session.SeasonNumber
+		item.EpisodeNumber = session.EpisodeNumber
+	}
+
+	return item
+}
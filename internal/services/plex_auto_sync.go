@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// autoSyncCheckInterval is how often users are checked for a due automatic sync
+const autoSyncCheckInterval = time.Hour
+
+// SyncDueUsers enqueues a full sync for every user with Plex connected whose plex_auto_sync
+// preference is daily or weekly and whose latest full sync was created longer ago than that.
+// Users with a sync already pending or running are skipped. Returns how many were enqueued.
+func (s *PlexSyncService) SyncDueUsers() (int, error) {
+	now := time.Now().UTC()
+	rows, err := s.db.Query(`
+		SELECT t.user_id
+		FROM user_plex_tokens t
+		JOIN user_preferences p ON p.user_id = t.user_id
+		WHERE t.plex_token != ''
+			AND p.plex_auto_sync IN ('daily', 'weekly')
+			AND NOT EXISTS (
+				SELECT 1 FROM sync_jobs j
+				WHERE j.user_id = t.user_id AND j.type = ?
+					AND j.created_at > CASE p.plex_auto_sync WHEN 'daily' THEN ? ELSE ? END
+			)
+		ORDER BY t.user_id
+	`, JobTypeFullSync, now.AddDate(0, 0, -1), now.AddDate(0, 0, -7))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get users due for auto-sync: %w", err)
+	}
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read user due for auto-sync: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+
+	enqueued := 0
+	for _, userID := range userIDs {
+		if _, ok := s.activeFullSyncJob(userID); ok {
+			continue
+		}
+		if _, err := s.TriggerFullSync(userID); err != nil {
+			s.logger.Warn("Auto-sync: failed to trigger sync", "user_id", userID, "error", err)
+			continue
+		}
+		enqueued++
+	}
+
+	return enqueued, nil
+}
+
+// ScheduleAutoSync checks for users due for an automatic sync every interval until ctx is done
+func (s *PlexSyncService) ScheduleAutoSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Debug("Auto-sync scheduler stopping")
+			return
+		case <-ticker.C:
+			enqueued, err := s.SyncDueUsers()
+			if err != nil {
+				s.logger.Error("Auto-sync failed", "error", err)
+			} else if enqueued > 0 {
+				s.logger.Info("Auto-sync: enqueued syncs", "count", enqueued)
+			}
+		}
+	}
+}
@@ -4,24 +4,27 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 )
 
 // PlexCleanupService handles cleanup and maintenance for Plex data
 type PlexCleanupService struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
 }
 
 // NewPlexCleanupService creates a new cleanup service
 func NewPlexCleanupService(db *sql.DB) *PlexCleanupService {
 	return &PlexCleanupService{
-		db: db,
+		db:     db,
+		logger: slog.Default(),
 	}
 }
 
 // CleanupOrphanedItems removes library items that no longer have any users with access
 func (s *PlexCleanupService) CleanupOrphanedItems(ctx context.Context) error {
-	fmt.Println("Starting cleanup of orphaned Plex library items")
+	s.logger.Debug("Starting cleanup of orphaned Plex library items")
 
 	// Remove items from libraries that have no active user access
 	result, err := s.db.ExecContext(ctx, `
@@ -38,56 +41,56 @@ func (s *PlexCleanupService) CleanupOrphanedItems(ctx context.Context) error {
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	fmt.Printf("Cleaned up %d orphaned library items\n", rowsAffected)
+	s.logger.Info("Cleaned up orphaned Plex library items", "count", rowsAffected)
 
 	return nil
 }
 
 // CleanupInactiveUserAccess removes user access records for users who haven't synced in a long time
 func (s *PlexCleanupService) CleanupInactiveUserAccess(ctx context.Context, daysInactive int) error {
-	fmt.Printf("Starting cleanup of inactive user access (older than %d days)\n", daysInactive)
+	s.logger.Debug("Starting cleanup of inactive user access", "days_inactive", daysInactive)
 
 	// Mark user access as inactive if not verified recently
 	result, err := s.db.ExecContext(ctx, `
 		UPDATE user_plex_access 
 		SET is_active = 0 
-		WHERE last_verified_at < datetime('now', '-' || ? || ' days')
+		WHERE last_verified_at < ?
 		AND is_active = 1
-	`, daysInactive)
+	`, time.Now().UTC().AddDate(0, 0, -daysInactive))
 
 	if err != nil {
 		return fmt.Errorf("failed to cleanup inactive user access: %w", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	fmt.Printf("Marked %d user access records as inactive\n", rowsAffected)
+	s.logger.Info("Marked user access records inactive", "count", rowsAffected)
 
 	return nil
 }
 
 // CleanupOldSyncJobs removes old completed sync jobs
 func (s *PlexCleanupService) CleanupOldSyncJobs(ctx context.Context, daysOld int) error {
-	fmt.Printf("Starting cleanup of old sync jobs (older than %d days)\n", daysOld)
+	s.logger.Debug("Starting cleanup of old sync jobs", "days_old", daysOld)
 
 	result, err := s.db.ExecContext(ctx, `
 		DELETE FROM sync_jobs 
 		WHERE status IN ('completed', 'failed', 'cancelled') 
-		AND created_at < datetime('now', '-' || ? || ' days')
-	`, daysOld)
+		AND created_at < ?
+	`, time.Now().UTC().AddDate(0, 0, -daysOld))
 
 	if err != nil {
 		return fmt.Errorf("failed to cleanup old sync jobs: %w", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	fmt.Printf("Cleaned up %d old sync jobs\n", rowsAffected)
+	s.logger.Info("Cleaned up old sync jobs", "count", rowsAffected)
 
 	return nil
 }
 
 // CleanupUnmatchedItems removes items that failed to match with TMDB after multiple attempts
 func (s *PlexCleanupService) CleanupUnmatchedItems(ctx context.Context, maxAttempts int) error {
-	fmt.Printf("Starting cleanup of unmatched items (more than %d attempts)\n", maxAttempts)
+	s.logger.Debug("Starting cleanup of unmatched items", "max_attempts", maxAttempts)
 
 	// Mark items as inactive if they failed to match multiple times
 	result, err := s.db.ExecContext(ctx, `
@@ -103,18 +106,19 @@ func (s *PlexCleanupService) CleanupUnmatchedItems(ctx context.Context, maxAttem
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	fmt.Printf("Marked %d unmatched items as inactive\n", rowsAffected)
+	s.logger.Info("Marked unmatched items inactive", "count", rowsAffected)
 
 	return nil
 }
 
-// CleanupOrphanedMappings removes TMDB mappings that no longer have corresponding library items
+// CleanupOrphanedMappings removes TMDB mappings that no longer have corresponding library items.
+// Mappings overridden by hand are kept, in case the item comes back.
 func (s *PlexCleanupService) CleanupOrphanedMappings(ctx context.Context) error {
-	fmt.Println("Starting cleanup of orphaned TMDB mappings")
+	s.logger.Debug("Starting cleanup of orphaned TMDB mappings")
 
 	result, err := s.db.ExecContext(ctx, `
 		DELETE FROM plex_tmdb_mappings 
-		WHERE plex_guid NOT IN (
+		WHERE is_override = 0 AND plex_guid NOT IN (
 			SELECT DISTINCT plex_guid 
 			FROM plex_library_items 
 			WHERE is_active = 1
@@ -126,14 +130,14 @@ func (s *PlexCleanupService) CleanupOrphanedMappings(ctx context.Context) error
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	fmt.Printf("Cleaned up %d orphaned TMDB mappings\n", rowsAffected)
+	s.logger.Info("Cleaned up orphaned TMDB mappings", "count", rowsAffected)
 
 	return nil
 }
 
 // UpdateLibraryItemCounts updates the cached item counts for all libraries
 func (s *PlexCleanupService) UpdateLibraryItemCounts(ctx context.Context) error {
-	fmt.Println("Updating library item counts")
+	s.logger.Debug("Updating library item counts")
 
 	_, err := s.db.ExecContext(ctx, `
 		UPDATE plex_libraries 
@@ -149,13 +153,13 @@ func (s *PlexCleanupService) UpdateLibraryItemCounts(ctx context.Context) error
 		return fmt.Errorf("failed to update library item counts: %w", err)
 	}
 
-	fmt.Println("Library item counts updated")
+	s.logger.Debug("Library item counts updated")
 	return nil
 }
 
 // RunFullCleanup runs all cleanup operations
 func (s *PlexCleanupService) RunFullCleanup(ctx context.Context) error {
-	fmt.Println("Starting full Plex cleanup")
+	s.logger.Info("Starting full Plex cleanup")
 
 	// Run cleanup operations in order
 	cleanupOps := []struct {
@@ -177,14 +181,14 @@ func (s *PlexCleanupService) RunFullCleanup(ctx context.Context) error {
 	}
 
 	for _, op := range cleanupOps {
-		fmt.Printf("Running: %s\n", op.name)
+		s.logger.Debug("Running cleanup operation", "operation", op.name)
 		if err := op.fn(ctx); err != nil {
-			fmt.Printf("Cleanup operation failed: %s - %v\n", op.name, err)
+			s.logger.Error("Cleanup operation failed", "operation", op.name, "error", err)
 			// Continue with other operations even if one fails
 		}
 	}
 
-	fmt.Println("Full cleanup completed")
+	s.logger.Info("Full Plex cleanup completed")
 	return nil
 }
 
@@ -196,12 +200,11 @@ func (s *PlexCleanupService) ScheduleCleanup(ctx context.Context, interval time.
 	for {
 		select {
 		case <-ctx.Done():
-			fmt.Println("Cleanup scheduler stopping")
+			s.logger.Debug("Cleanup scheduler stopping")
 			return
 		case <-ticker.C:
-			fmt.Println("Running scheduled cleanup")
 			if err := s.RunFullCleanup(ctx); err != nil {
-				fmt.Printf("Scheduled cleanup failed: %v\n", err)
+				s.logger.Error("Scheduled cleanup failed", "error", err)
 			}
 		}
 	}
@@ -2,12 +2,47 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 )
 
+// Default per-call timeouts for Plex requests (see SetPlexTimeouts)
+const (
+	DefaultPlexRequestTimeout     = 30 * time.Second
+	DefaultPlexLibraryPageTimeout = 2 * time.Minute
+)
+
+// Per-call timeouts for Plex requests. Callers pass the request or job context so
+// cancellation and shutdown abort outstanding calls; these only bound a single call.
+var (
+	plexRequestTimeout     = DefaultPlexRequestTimeout
+	plexLibraryPageTimeout = DefaultPlexLibraryPageTimeout
+)
+
+// SetPlexTimeouts configures how long a single Plex call may take, for every Plex client:
+// request for API calls and libraryPage for fetching a page of a library, which can be slow
+// on large libraries. Zero keeps the current value. Call it before any Plex requests are made.
+func SetPlexTimeouts(request, libraryPage time.Duration) {
+	if request > 0 {
+		plexRequestTimeout = request
+	}
+	if libraryPage > 0 {
+		plexLibraryPageTimeout = libraryPage
+	}
+}
+
+// withPlexTimeout derives a per-call context from the caller's context
+func withPlexTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 type PlexClient struct {
 	clientID string
 	product  string
@@ -43,6 +78,20 @@ type PlexUser struct {
 	Country      string `json:"country"`
 }
 
+// PlexHomeUser represents a Plex Home (managed or regular) user profile
+type PlexHomeUser struct {
+	ID          int    `json:"id"`
+	UUID        string `json:"uuid"`
+	Title       string `json:"title"`
+	Username    string `json:"username"`
+	Thumb       string `json:"thumb"`
+	Admin       bool   `json:"admin"`
+	Guest       bool   `json:"guest"`
+	Restricted  bool   `json:"restricted"`
+	Protected   bool   `json:"protected"`
+	HasPassword bool   `json:"hasPassword"`
+}
+
 func NewPlexClient() *PlexClient {
 	return &PlexClient{
 		clientID: "moviedb-app",
@@ -53,10 +102,13 @@ func NewPlexClient() *PlexClient {
 }
 
 // RequestPin starts the Plex PIN authentication flow
-func (p *PlexClient) RequestPin() (*PlexPinResponse, error) {
+func (p *PlexClient) RequestPin(ctx context.Context) (*PlexPinResponse, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
 	headers := p.getHeaders("")
 
-	resp, err := p.MakeRequest("POST", "https://plex.tv/api/v2/pins", headers, nil)
+	resp, err := p.MakeRequest(ctx, "POST", "https://plex.tv/api/v2/pins", headers, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to request PIN: %w", err)
 	}
@@ -75,11 +127,14 @@ func (p *PlexClient) RequestPin() (*PlexPinResponse, error) {
 }
 
 // CheckPin polls Plex to see if the PIN has been authorized
-func (p *PlexClient) CheckPin(pinID int) (*PlexPinResponse, error) {
+func (p *PlexClient) CheckPin(ctx context.Context, pinID int) (*PlexPinResponse, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
 	headers := p.getHeaders("")
 
 	pinURL := fmt.Sprintf("https://plex.tv/api/v2/pins/%d", pinID)
-	resp, err := p.MakeRequest("GET", pinURL, headers, nil)
+	resp, err := p.MakeRequest(ctx, "GET", pinURL, headers, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check PIN: %w", err)
 	}
@@ -98,10 +153,13 @@ func (p *PlexClient) CheckPin(pinID int) (*PlexPinResponse, error) {
 }
 
 // GetUser gets the authenticated user's information
-func (p *PlexClient) GetUser(token string) (*PlexUser, error) {
+func (p *PlexClient) GetUser(ctx context.Context, token string) (*PlexUser, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
 	headers := p.getHeaders(token)
 
-	resp, err := p.MakeRequest("GET", "https://plex.tv/api/v2/user", headers, nil)
+	resp, err := p.MakeRequest(ctx, "GET", "https://plex.tv/api/v2/user", headers, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -119,11 +177,76 @@ func (p *PlexClient) GetUser(token string) (*PlexUser, error) {
 	return &user, nil
 }
 
+// GetHomeUsers lists the Plex Home users (profiles) on the authenticated account
+func (p *PlexClient) GetHomeUsers(ctx context.Context, token string) ([]PlexHomeUser, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
+	headers := p.getHeaders(token)
+
+	resp, err := p.MakeRequest(ctx, "GET", "https://plex.tv/api/v2/home/users", headers, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home users: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get home users failed with status: %d", resp.StatusCode)
+	}
+
+	var homeResp struct {
+		Users []PlexHomeUser `json:"users"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&homeResp); err != nil {
+		return nil, fmt.Errorf("failed to decode home users response: %w", err)
+	}
+
+	return homeResp.Users, nil
+}
+
+// SwitchHomeUser switches to a Plex Home user profile and returns that profile's user (with its own auth token).
+// The PIN is only required for protected profiles.
+func (p *PlexClient) SwitchHomeUser(ctx context.Context, token, uuid, pin string) (*PlexUser, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
+	headers := p.getHeaders(token)
+
+	switchURL := fmt.Sprintf("https://plex.tv/api/v2/home/users/%s/switch", url.PathEscape(uuid))
+	if pin != "" {
+		switchURL += "?pin=" + url.QueryEscape(pin)
+	}
+
+	resp, err := p.MakeRequest(ctx, "POST", switchURL, headers, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to switch home user: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("switch home user failed with status: %d", resp.StatusCode)
+	}
+
+	var user PlexUser
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode switch user response: %w", err)
+	}
+
+	if user.AuthToken == "" {
+		return nil, fmt.Errorf("switch home user returned no auth token")
+	}
+
+	return &user, nil
+}
+
 // GetServers gets the user's available Plex servers
-func (p *PlexClient) GetServers(token string) ([]map[string]interface{}, error) {
+func (p *PlexClient) GetServers(ctx context.Context, token string) ([]map[string]interface{}, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
 	headers := p.getHeaders(token)
 
-	resp, err := p.MakeRequest("GET", "https://plex.tv/api/v2/resources?includeHttps=1&includeRelay=1", headers, nil)
+	resp, err := p.MakeRequest(ctx, "GET", "https://plex.tv/api/v2/resources?includeHttps=1&includeRelay=1", headers, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get servers: %w", err)
 	}
@@ -156,11 +279,14 @@ type PlexLibraryItem struct {
 }
 
 // GetLibraries gets all libraries from a Plex server
-func (p *PlexClient) GetLibraries(token, serverURL string) ([]map[string]interface{}, error) {
+func (p *PlexClient) GetLibraries(ctx context.Context, token, serverURL string) ([]map[string]interface{}, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
 	headers := p.getHeaders(token)
 
 	url := fmt.Sprintf("%s/library/sections", serverURL)
-	resp, err := p.MakeRequest("GET", url, headers, nil)
+	resp, err := p.MakeRequest(ctx, "GET", url, headers, nil)
 	if err != nil {
 		return nil, fmt.Errorf("is itfailed to get libraries: %w", err)
 	}
@@ -184,11 +310,14 @@ func (p *PlexClient) GetLibraries(token, serverURL string) ([]map[string]interfa
 }
 
 // GetLibraryContent gets all movies from a specific library
-func (p *PlexClient) GetLibraryContent(token, serverURL, libraryKey string) ([]PlexLibraryItem, error) {
+func (p *PlexClient) GetLibraryContent(ctx context.Context, token, serverURL, libraryKey string) ([]PlexLibraryItem, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexLibraryPageTimeout)
+	defer cancel()
+
 	headers := p.getHeaders(token)
 
 	url := fmt.Sprintf("%s/library/sections/%s/all", serverURL, libraryKey)
-	resp, err := p.MakeRequest("GET", url, headers, nil)
+	resp, err := p.MakeRequest(ctx, "GET", url, headers, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get library content: %w", err)
 	}
@@ -231,14 +360,14 @@ func (p *PlexClient) getHeaders(token string) map[string]string {
 	return headers
 }
 
-func (p *PlexClient) MakeRequest(method, url string, headers map[string]string, body *bytes.Buffer) (*http.Response, error) {
+func (p *PlexClient) MakeRequest(ctx context.Context, method, url string, headers map[string]string, body *bytes.Buffer) (*http.Response, error) {
 	var req *http.Request
 	var err error
 
 	if body != nil {
-		req, err = http.NewRequest(method, url, body)
+		req, err = http.NewRequestWithContext(ctx, method, url, body)
 	} else {
-		req, err = http.NewRequest(method, url, nil)
+		req, err = http.NewRequestWithContext(ctx, method, url, nil)
 	}
 
 	if err != nil {
@@ -249,6 +378,6 @@ func (p *PlexClient) MakeRequest(method, url string, headers map[string]string,
 		req.Header.Set(key, value)
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	return client.Do(req)
+	// Timeouts come from ctx (see withPlexTimeout)
+	return http.DefaultClient.Do(req)
 }
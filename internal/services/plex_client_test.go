@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newSlowPlexServer answers every request only after delay, or gives up when the client does
+func newSlowPlexServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"MediaContainer":{}}`))
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// setTestPlexTimeouts shortens the Plex call timeouts for the rest of the test
+func setTestPlexTimeouts(t *testing.T, timeout time.Duration) {
+	t.Helper()
+
+	request, libraryPage := plexRequestTimeout, plexLibraryPageTimeout
+	t.Cleanup(func() {
+		plexRequestTimeout, plexLibraryPageTimeout = request, libraryPage
+	})
+	SetPlexTimeouts(timeout, timeout)
+}
+
+func TestPlexCallsTimeOut(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	setTestPlexTimeouts(t, timeout)
+	server := newSlowPlexServer(t, 5*time.Second)
+
+	plexClient := NewPlexClient()
+	plexgoClient := NewPlexgoClient()
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{"PlexClient.GetLibraries", func(ctx context.Context) error {
+			_, err := plexClient.GetLibraries(ctx, "token", server.URL)
+			return err
+		}},
+		{"PlexClient.GetLibraryContent", func(ctx context.Context) error {
+			_, err := plexClient.GetLibraryContent(ctx, "token", server.URL, "1")
+			return err
+		}},
+		{"PlexgoClient.GetLibraries", func(ctx context.Context) error {
+			_, err := plexgoClient.GetLibraries(ctx, "token", server.URL)
+			return err
+		}},
+		{"PlexgoClient.SearchAllLibraries", func(ctx context.Context) error {
+			_, err := plexgoClient.SearchAllLibraries(ctx, "token", server.URL, "Dune")
+			return err
+		}},
+		{"PlexgoClient.GetShowsInLibrary", func(ctx context.Context) error {
+			_, err := plexgoClient.GetShowsInLibrary(ctx, "token", server.URL, 1)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := tt.call(context.Background())
+			elapsed := time.Since(start)
+
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("error = %v, want a wrapped context.DeadlineExceeded", err)
+			}
+			if elapsed > timeout+time.Second {
+				t.Errorf("call returned after %s, want about %s", elapsed, timeout)
+			}
+		})
+	}
+}
+
+func TestPlexCallsStopWhenCallerCancels(t *testing.T) {
+	setTestPlexTimeouts(t, time.Minute)
+	server := newSlowPlexServer(t, 5*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewPlexgoClient().GetLibraries(ctx, "token", server.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the caller's deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("call returned after %s, want it bounded by the caller's context", elapsed)
+	}
+}
+
+func TestWithPlexTimeoutNilContext(t *testing.T) {
+	ctx, cancel := withPlexTimeout(nil, time.Minute)
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("context has no deadline")
+	}
+}
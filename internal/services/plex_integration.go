@@ -3,7 +3,7 @@ package services
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"log/slog"
 	"time"
 )
 
@@ -16,6 +16,12 @@ type PlexIntegrationManager struct {
 	jobManager     *JobManager
 	syncService    *PlexSyncService
 	cleanupService *PlexCleanupService
+
+	ratingsImporter *RatingsImporter
+	listImporter    *ListImporter
+	movieCacheQueue *MovieCacheQueue
+
+	logger *slog.Logger
 }
 
 // NewPlexIntegrationManager creates a new Plex integration manager
@@ -31,6 +37,11 @@ func NewPlexIntegrationManager(db *sql.DB, tmdbClient *TMDBClient) *PlexIntegrat
 	// Initialize cleanup service
 	cleanupService := NewPlexCleanupService(db)
 
+	// Imports and movie caching share the job queue and TMDB rate limit with Plex syncs
+	ratingsImporter := NewRatingsImporter(db, tmdbClient, rateLimiter, jobManager)
+	listImporter := NewListImporter(db, tmdbClient, rateLimiter, jobManager)
+	movieCacheQueue := NewMovieCacheQueue(db, tmdbClient, rateLimiter, jobManager)
+
 	manager := &PlexIntegrationManager{
 		db:             db,
 		plexgoClient:   plexgoClient,
@@ -39,6 +50,12 @@ func NewPlexIntegrationManager(db *sql.DB, tmdbClient *TMDBClient) *PlexIntegrat
 		jobManager:     jobManager,
 		syncService:    syncService,
 		cleanupService: cleanupService,
+
+		ratingsImporter: ratingsImporter,
+		listImporter:    listImporter,
+		movieCacheQueue: movieCacheQueue,
+
+		logger: slog.Default(),
 	}
 
 	return manager
@@ -49,9 +66,39 @@ func (m *PlexIntegrationManager) SyncService() *PlexSyncService {
 	return m.syncService
 }
 
+// PlexgoClient returns the shared Plex server client
+func (m *PlexIntegrationManager) PlexgoClient() *PlexgoClient {
+	return m.plexgoClient
+}
+
+// RatingsImporter returns the ratings import service
+func (m *PlexIntegrationManager) RatingsImporter() *RatingsImporter {
+	return m.ratingsImporter
+}
+
+// ListImporter returns the list import service
+func (m *PlexIntegrationManager) ListImporter() *ListImporter {
+	return m.listImporter
+}
+
+// MovieCacheQueue returns the background movie cache queue
+func (m *PlexIntegrationManager) MovieCacheQueue() *MovieCacheQueue {
+	return m.movieCacheQueue
+}
+
+// JobManager returns the background job manager
+func (m *PlexIntegrationManager) JobManager() *JobManager {
+	return m.jobManager
+}
+
+// RateLimiter returns the shared TMDB rate limiter
+func (m *PlexIntegrationManager) RateLimiter() *TMDBRateLimiter {
+	return m.rateLimiter
+}
+
 // Start starts all background services
 func (m *PlexIntegrationManager) Start(ctx context.Context) error {
-	fmt.Println("Starting Plex integration services...")
+	m.logger.Info("Starting Plex integration services")
 
 	// Start job manager
 	m.jobManager.Start()
@@ -59,21 +106,28 @@ func (m *PlexIntegrationManager) Start(ctx context.Context) error {
 	// Start periodic cleanup (every 6 hours)
 	go m.cleanupService.ScheduleCleanup(ctx, 6*time.Hour)
 
-	fmt.Println("Plex integration services started successfully")
+	// Sync libraries of users who turned on auto-sync
+	go m.syncService.ScheduleAutoSync(ctx, autoSyncCheckInterval)
+
+	m.logger.Info("Plex integration services started")
 	return nil
 }
 
 // Stop stops all background services
 func (m *PlexIntegrationManager) Stop() error {
-	fmt.Println("Stopping Plex integration services...")
+	m.logger.Info("Stopping Plex integration services")
+
+	// Interrupt running jobs before stopping the rate limiter, so the errors they get from it
+	// are seen as the shutdown rather than failures
+	m.jobManager.interruptJobs()
 
 	// Stop rate limiter
 	m.rateLimiter.Stop()
 
-	// Stop job manager
+	// Stop job manager, waiting for workers to put their jobs back to pending
 	m.jobManager.Stop()
 
-	fmt.Println("Plex integration services stopped")
+	m.logger.Info("Plex integration services stopped")
 	return nil
 }
 
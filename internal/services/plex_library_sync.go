@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrLibraryNotFound is returned when a library doesn't exist or the user has no access to it
+var ErrLibraryNotFound = errors.New("library not found")
+
+// LibrarySyncJobProcessor implements JobProcessor for syncs of a single library
+type LibrarySyncJobProcessor struct {
+	syncService *PlexSyncService
+}
+
+// GetJobType returns the job type this processor handles
+func (p *LibrarySyncJobProcessor) GetJobType() JobType {
+	return JobTypeLibrarySync
+}
+
+// ProcessJob processes a library sync job
+func (p *LibrarySyncJobProcessor) ProcessJob(ctx context.Context, job *Job) error {
+	if job.UserID == nil || job.LibraryID == nil {
+		return fmt.Errorf("user ID and library ID are required for library sync job")
+	}
+
+	p.syncService.logger.Debug("Processing Plex library sync", "library_id", *job.LibraryID, "user_id", *job.UserID, "job_id", job.ID)
+	return p.syncService.PerformLibrarySync(ctx, *job.UserID, *job.LibraryID, job.ID)
+}
+
+// TriggerLibrarySync creates a job that syncs one of the user's libraries
+func (s *PlexSyncService) TriggerLibrarySync(userID, libraryID int64) (*Job, error) {
+	if _, _, err := s.accessibleLibrary(userID, libraryID); err != nil {
+		return nil, err
+	}
+
+	// A full sync covers the library already
+	if existingJobID, ok := s.activeFullSyncJob(userID); ok {
+		return nil, fmt.Errorf("%w for user %d (job %d)", ErrSyncInProgress, userID, existingJobID)
+	}
+
+	var existingJobID int64
+	err := s.db.QueryRow(`
+		SELECT id FROM sync_jobs
+		WHERE user_id = ? AND library_id = ? AND type = ? AND status IN (?, ?)
+		ORDER BY created_at DESC LIMIT 1
+	`, userID, libraryID, JobTypeLibrarySync, JobStatusPending, JobStatusRunning).Scan(&existingJobID)
+	if err == nil {
+		return nil, fmt.Errorf("%w for library %d (job %d)", ErrSyncInProgress, libraryID, existingJobID)
+	}
+
+	metadata := map[string]interface{}{
+		"sync_type":  "library",
+		"user_id":    userID,
+		"library_id": libraryID,
+	}
+
+	job, err := s.jobManager.CreateJob(JobTypeLibrarySync, &userID, &libraryID, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create library sync job: %w", err)
+	}
+
+	return job, nil
+}
+
+// accessibleLibrary returns a stored movie or show library the user currently has access to,
+// and the machine id of its server. The connection details are left for the caller to fill in.
+func (s *PlexSyncService) accessibleLibrary(userID, libraryID int64) (*PlexLibrary, string, error) {
+	library := &PlexLibrary{ID: libraryID}
+	var machineID string
+	err := s.db.QueryRow(`
+		SELECT pl.section_key, pl.title, pl.type, pl.server_id, ps.machine_id
+		FROM plex_libraries pl
+		JOIN plex_servers ps ON ps.id = pl.server_id
+		JOIN user_plex_access upa ON upa.library_id = pl.id
+		WHERE pl.id = ? AND upa.user_id = ? AND upa.is_active = 1 AND pl.type IN ('movie', 'show')
+	`, libraryID, userID).Scan(&library.Key, &library.Title, &library.Type, &library.ServerID, &machineID)
+	if err == sql.ErrNoRows {
+		return nil, "", ErrLibraryNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get library: %w", err)
+	}
+
+	return library, machineID, nil
+}
+
+// PerformLibrarySync syncs a single library: its items, their TMDB matches and the user's
+// plays and progress in it. Libraries the user hasn't synced before need a full sync first.
+func (s *PlexSyncService) PerformLibrarySync(ctx context.Context, userID, libraryID, jobID int64) error {
+	library, machineID, err := s.accessibleLibrary(userID, libraryID)
+	if err != nil {
+		return err
+	}
+
+	plexToken, err := GetUserPlexToken(s.db, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get Plex token: %w", err)
+	}
+
+	s.jobManager.UpdateJobProgress(jobID, 5, "Connecting to Plex server", 0, 0, 0)
+
+	servers, err := s.plexgoClient.GetServers(ctx, plexToken)
+	if err != nil {
+		return fmt.Errorf("failed to get servers: %w", err)
+	}
+
+	var server *PlexServer
+	for i := range servers {
+		if servers[i].MachineID == machineID {
+			server = &servers[i]
+			break
+		}
+	}
+	if server == nil {
+		return fmt.Errorf("server of library %s is no longer available", library.Title)
+	}
+
+	connection, latency := s.plexgoClient.SelectConnection(ctx, *server)
+	if connection == nil {
+		return fmt.Errorf("no accessible connection for server %s", server.Name)
+	}
+	library.ServerURL = s.plexgoClient.BuildServerURL(*connection)
+	library.AccessToken = server.AccessToken
+	library.ConnectionType = ConnectionType(*connection)
+	s.recordServerConnection(library.ServerID, library.ConnectionType, latency)
+
+	s.jobManager.UpdateJobProgress(jobID, 10, fmt.Sprintf("Syncing library: %s", library.Title), 0, 0, 0)
+
+	items, err := s.syncLibraryItems(ctx, library.AccessToken, *library, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to sync library %s: %w", library.Title, err)
+	}
+
+	s.jobManager.UpdateJobProgress(jobID, 50, "Matching items with TMDB", len(items), len(items), 0)
+
+	matchedItems, err := s.performTMDBMatching(ctx, userID, libraryID, jobID, 50, 45)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if err != nil {
+		s.logger.Warn("TMDB matching failed", "user_id", userID, "library", library.Title, "error", err)
+	}
+
+	var warnings []string
+	if library.Type == "movie" {
+		if err := s.recordWatchHistory(userID, watchedItems(library.ID, items)); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to record watch history: %v", err))
+		}
+		if err := s.recordProgress(userID, inProgressItems(library.ID, items), false); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to record progress: %v", err))
+		}
+	}
+
+	completedStep := fmt.Sprintf("Synced library: %s", library.Title)
+	if len(warnings) > 0 {
+		s.recordSyncWarnings(jobID, warnings)
+		completedStep = fmt.Sprintf("Synced library %s with %d warnings", library.Title, len(warnings))
+	}
+	s.jobManager.UpdateJobProgress(jobID, 100, completedStep, len(items), len(items), 0)
+
+	s.logger.Info("Plex library sync completed", "user_id", userID, "library", library.Title, "items", len(items), "matched", matchedItems)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Progress between these percentages counts as "continue watching"; below it was barely
+// started, above it the credits are rolling
+const (
+	ContinueWatchingMinPercent = 5
+	ContinueWatchingMaxPercent = 95
+)
+
+// upsertProgressSQL saves a user's offset into a Plex item
+const upsertProgressSQL = `
+	INSERT INTO user_movie_progress (user_id, plex_guid, view_offset_ms, duration_ms, updated_at)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(user_id, plex_guid) DO UPDATE SET
+		view_offset_ms = excluded.view_offset_ms,
+		duration_ms = excluded.duration_ms,
+		updated_at = excluded.updated_at
+`
+
+// inProgressItems picks the items of a synced library that the token owner has partly played
+func inProgressItems(libraryID int64, items []PlexSearchResult) []userLibraryItem {
+	var inProgress []userLibraryItem
+	for _, item := range items {
+		if item.ViewOffset > 0 && item.Duration > 0 && item.GUID != "" {
+			inProgress = append(inProgress, userLibraryItem{libraryID: libraryID, item: item})
+		}
+	}
+	return inProgress
+}
+
+// recordProgress saves the partial plays a sync found. A full sync replaces all of the user's
+// progress (replaceAll); a sync of one library only adds to it. A movie on several servers
+// keeps the furthest offset.
+func (s *PlexSyncService) recordProgress(userID int64, inProgress []userLibraryItem, replaceAll bool) error {
+	furthest := make(map[string]PlexSearchResult)
+	for _, p := range inProgress {
+		if current, ok := furthest[p.item.GUID]; !ok || p.item.ViewOffset > current.ViewOffset {
+			furthest[p.item.GUID] = p.item
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if replaceAll {
+		if _, err := tx.Exec("DELETE FROM user_movie_progress WHERE user_id = ?", userID); err != nil {
+			return fmt.Errorf("failed to clear progress: %w", err)
+		}
+	}
+
+	now := time.Now()
+	for guid, item := range furthest {
+		if _, err := tx.Exec(upsertProgressSQL, userID, guid, item.ViewOffset, item.Duration, now); err != nil {
+			return fmt.Errorf("failed to save progress of %s: %w", item.Title, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SaveProgress records how far a user is into a Plex item, e.g. from a playback session
+func SaveProgress(db *sql.DB, userID int64, plexGUID string, viewOffsetMs, durationMs int) error {
+	if plexGUID == "" || durationMs <= 0 {
+		return nil
+	}
+
+	if _, err := db.Exec(upsertProgressSQL, userID, plexGUID, viewOffsetMs, durationMs, time.Now()); err != nil {
+		return fmt.Errorf("failed to save progress: %w", err)
+	}
+	return nil
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // PlexSyncService handles comprehensive Plex library synchronization
@@ -16,6 +19,7 @@ type PlexSyncService struct {
 	tmdbClient   *TMDBClient
 	rateLimiter  *TMDBRateLimiter
 	jobManager   *JobManager
+	logger       *slog.Logger
 }
 
 // PlexSyncJobProcessor implements JobProcessor for Plex sync operations
@@ -31,15 +35,23 @@ func NewPlexSyncService(db *sql.DB, plexgoClient *PlexgoClient, tmdbClient *TMDB
 		tmdbClient:   tmdbClient,
 		rateLimiter:  rateLimiter,
 		jobManager:   jobManager,
+		logger:       slog.Default(),
 	}
 
-	// Register job processor
+	// Register job processors
 	processor := &PlexSyncJobProcessor{syncService: service}
 	jobManager.RegisterProcessor(processor)
+	jobManager.RegisterProcessor(&TMDBMatchingJobProcessor{syncService: service})
+	jobManager.RegisterProcessor(&LibrarySyncJobProcessor{syncService: service})
 
 	return service
 }
 
+// SetLogger replaces the logger, which defaults to slog's default logger at construction
+func (s *PlexSyncService) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // DB returns the database connection for validation purposes
 func (s *PlexSyncService) DB() *sql.DB {
 	return s.db
@@ -57,37 +69,74 @@ func (p *PlexSyncJobProcessor) GetJobType() JobType {
 
 // ProcessJob processes a full sync job
 func (p *PlexSyncJobProcessor) ProcessJob(ctx context.Context, job *Job) error {
-	fmt.Printf("PlexSyncJobProcessor: Starting to process job %d\n", job.ID)
-
 	if job.UserID == nil {
-		fmt.Printf("PlexSyncJobProcessor: Job %d missing user ID\n", job.ID)
 		return fmt.Errorf("user ID is required for sync job")
 	}
 
-	fmt.Printf("PlexSyncJobProcessor: Processing full sync for user %d, job %d\n", *job.UserID, job.ID)
-	err := p.syncService.PerformFullSync(ctx, *job.UserID, job.ID)
+	// The worker logs how the job ended
+	p.syncService.logger.Debug("Processing full Plex sync", "user_id", *job.UserID, "job_id", job.ID)
+	return p.syncService.PerformFullSync(ctx, *job.UserID, job.ID)
+}
 
-	if err != nil {
-		fmt.Printf("PlexSyncJobProcessor: Job %d failed: %v\n", job.ID, err)
-	} else {
-		fmt.Printf("PlexSyncJobProcessor: Job %d completed successfully\n", job.ID)
+// TMDBMatchingJobProcessor implements JobProcessor for match-only syncs, which match
+// already stored library items with TMDB without enumerating Plex again
+type TMDBMatchingJobProcessor struct {
+	syncService *PlexSyncService
+}
+
+// GetJobType returns the job type this processor handles
+func (p *TMDBMatchingJobProcessor) GetJobType() JobType {
+	return JobTypeTMDBMatching
+}
+
+// ProcessJob processes a match-only sync job
+func (p *TMDBMatchingJobProcessor) ProcessJob(ctx context.Context, job *Job) error {
+	if job.UserID == nil {
+		return fmt.Errorf("user ID is required for TMDB matching job")
 	}
 
-	return err
+	if retryFailed, _ := job.Metadata["retry_failed"].(bool); retryFailed {
+		if err := p.syncService.resetMatchingAttempts(*job.UserID); err != nil {
+			return fmt.Errorf("failed to reset matching attempts: %w", err)
+		}
+	}
+
+	p.syncService.logger.Debug("Matching stored Plex items", "user_id", *job.UserID, "job_id", job.ID)
+	return p.syncService.PerformTMDBMatchingSync(ctx, *job.UserID, job.ID)
 }
 
-// TriggerFullSync creates a new full sync job for a user
-func (s *PlexSyncService) TriggerFullSync(userID int64) (*Job, error) {
-	// Check if there's already a running sync for this user
+// ErrSyncInProgress is returned when a user already has a pending or running full sync
+var ErrSyncInProgress = errors.New("sync already in progress")
+
+// SyncAllResult summarizes a sync-all request
+type SyncAllResult struct {
+	ConnectedUsers int `json:"connected_users"`
+	Enqueued       int `json:"enqueued"`
+	Skipped        int `json:"skipped"` // Already had a sync pending or running
+}
+
+// activeFullSyncJob returns the user's pending or running full sync job, if any
+func (s *PlexSyncService) activeFullSyncJob(userID int64) (int64, bool) {
+	return s.activeSyncJob(userID, JobTypeFullSync)
+}
+
+// activeSyncJob returns the user's pending or running job of the given type, if any
+func (s *PlexSyncService) activeSyncJob(userID int64, jobType JobType) (int64, bool) {
 	var existingJobID int64
 	err := s.db.QueryRow(`
 		SELECT id FROM sync_jobs 
 		WHERE user_id = ? AND type = ? AND status IN (?, ?)
 		ORDER BY created_at DESC LIMIT 1
-	`, userID, JobTypeFullSync, JobStatusPending, JobStatusRunning).Scan(&existingJobID)
+	`, userID, jobType, JobStatusPending, JobStatusRunning).Scan(&existingJobID)
+
+	return existingJobID, err == nil
+}
 
-	if err == nil {
-		return nil, fmt.Errorf("sync already in progress for user %d (job %d)", userID, existingJobID)
+// TriggerFullSync creates a new full sync job for a user
+func (s *PlexSyncService) TriggerFullSync(userID int64) (*Job, error) {
+	// Check if there's already a running sync for this user
+	if existingJobID, ok := s.activeFullSyncJob(userID); ok {
+		return nil, fmt.Errorf("%w for user %d (job %d)", ErrSyncInProgress, userID, existingJobID)
 	}
 
 	// Create new sync job
@@ -104,13 +153,109 @@ func (s *PlexSyncService) TriggerFullSync(userID int64) (*Job, error) {
 	return job, nil
 }
 
+// TriggerTMDBMatching creates a match-only sync job for a user. Only items already stored
+// by a previous sync are matched, so Plex isn't contacted.
+func (s *PlexSyncService) TriggerTMDBMatching(userID int64) (*Job, error) {
+	return s.triggerMatching(userID, "match_only", false)
+}
+
+// TriggerRematch creates a match-only sync job that also retries items which failed to match
+// too often to be tried again by regular syncs, e.g. after their titles were fixed in Plex
+func (s *PlexSyncService) TriggerRematch(userID int64) (*Job, error) {
+	return s.triggerMatching(userID, "rematch", true)
+}
+
+// triggerMatching creates a TMDB matching job unless a sync is already running for the user
+func (s *PlexSyncService) triggerMatching(userID int64, syncType string, retryFailed bool) (*Job, error) {
+	// A full sync runs the matching phase itself
+	for _, jobType := range []JobType{JobTypeFullSync, JobTypeTMDBMatching} {
+		if existingJobID, ok := s.activeSyncJob(userID, jobType); ok {
+			return nil, fmt.Errorf("%w for user %d (job %d)", ErrSyncInProgress, userID, existingJobID)
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"sync_type":    syncType,
+		"user_id":      userID,
+		"retry_failed": retryFailed,
+	}
+
+	job, err := s.jobManager.CreateJob(JobTypeTMDBMatching, &userID, nil, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create matching job: %w", err)
+	}
+
+	return job, nil
+}
+
+// SyncAllConnectedUsers enqueues a full sync for every user with Plex connected. Users with a
+// sync already pending or running are skipped; the rest are enqueued in the background, one
+// every stagger, so the TMDB rate limit and the job queue aren't flooded.
+func (s *PlexSyncService) SyncAllConnectedUsers(stagger time.Duration) (*SyncAllResult, error) {
+	rows, err := s.db.Query("SELECT user_id FROM user_plex_tokens ORDER BY user_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connected users: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	result := &SyncAllResult{ConnectedUsers: len(userIDs)}
+	var toSync []int64
+	for _, userID := range userIDs {
+		if _, ok := s.activeFullSyncJob(userID); ok {
+			result.Skipped++
+			continue
+		}
+		toSync = append(toSync, userID)
+	}
+	result.Enqueued = len(toSync)
+
+	go func() {
+		for i, userID := range toSync {
+			if i > 0 {
+				time.Sleep(stagger)
+			}
+
+			// TriggerFullSync re-checks for duplicates in case the user started a sync meanwhile
+			if _, err := s.TriggerFullSync(userID); err != nil {
+				s.logger.Warn("Sync all: failed to trigger sync", "user_id", userID, "error", err)
+			}
+		}
+		s.logger.Info("Sync all: finished enqueuing syncs", "count", len(toSync))
+	}()
+
+	return result, nil
+}
+
+// GetUserPlexToken returns the Plex token to use for a user's server and library access.
+// When a Plex Home profile has been selected its token is used instead of the account owner's.
+func GetUserPlexToken(db *sql.DB, userID int64) (string, error) {
+	var plexToken string
+	err := db.QueryRow(`
+		SELECT COALESCE(NULLIF(plex_profile_token, ''), plex_token)
+		FROM user_plex_tokens WHERE user_id = ?
+	`, userID).Scan(&plexToken)
+	if err != nil {
+		return "", err
+	}
+
+	return plexToken, nil
+}
+
 // PerformFullSync performs a complete sync for a user
 func (s *PlexSyncService) PerformFullSync(ctx context.Context, userID int64, jobID int64) error {
-	fmt.Printf("Starting full Plex sync for user %d\n", userID)
+	s.logger.Info("Starting full Plex sync", "user_id", userID, "job_id", jobID)
 
-	// Get user's Plex token
-	var plexToken string
-	err := s.db.QueryRow(`SELECT plex_token FROM user_plex_tokens WHERE user_id = ?`, userID).Scan(&plexToken)
+	// Get user's Plex token (scoped to the selected Plex Home profile, if any)
+	plexToken, err := GetUserPlexToken(s.db, userID)
 	if err != nil {
 		return fmt.Errorf("failed to get Plex token: %w", err)
 	}
@@ -118,17 +263,20 @@ func (s *PlexSyncService) PerformFullSync(ctx context.Context, userID int64, job
 	// Phase 1: Server and Library Discovery
 	s.jobManager.UpdateJobProgress(jobID, 10, "Discovering Plex servers and libraries", 0, 0, 0)
 
-	serverLibraries, err := s.discoverUserLibraries(ctx, plexToken, userID)
+	serverLibraries, warnings, err := s.discoverUserLibraries(ctx, plexToken, userID)
 	if err != nil {
 		return fmt.Errorf("failed to discover libraries: %w", err)
 	}
 
-	fmt.Printf("DEBUG: [PerformFullSync] Found %d libraries from discovery\n", len(serverLibraries))
-	for i, lib := range serverLibraries {
-		fmt.Printf("DEBUG: [PerformFullSync] Library %d: %s (Type: %s)\n", i, lib.Title, lib.Type)
+	s.logger.Debug("Discovered Plex libraries", "user_id", userID, "count", len(serverLibraries))
+	for _, lib := range serverLibraries {
+		s.logger.Debug("Discovered Plex library", "title", lib.Title, "type", lib.Type)
 	}
 
+	s.recordConnectionStats(jobID, serverLibraries)
+
 	if len(serverLibraries) == 0 {
+		s.recordSyncWarnings(jobID, warnings)
 		s.jobManager.UpdateJobProgress(jobID, 100, "No accessible libraries found", 0, 0, 0)
 		return nil
 	}
@@ -140,22 +288,29 @@ func (s *PlexSyncService) PerformFullSync(ctx context.Context, userID int64, job
 	processedItems := 0
 	successfulItems := 0
 	failedItems := 0
+	attemptedLibraries := 0
+	var libraryErrors []string
+	var watched, inProgress []userLibraryItem
 
 	for _, library := range serverLibraries {
-		fmt.Printf("DEBUG: [PerformFullSync] Found library: %s (Type: %s)\n", library.Title, library.Type)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-		// Only sync movie libraries for now
-		if library.Type != "movie" {
-			fmt.Printf("DEBUG: [PerformFullSync] Skipping non-movie library: %s\n", library.Title)
+		// Only movie and show libraries can be matched with TMDB
+		if library.Type != "movie" && library.Type != "show" {
+			s.logger.Debug("Skipping unsupported Plex library", "title", library.Title, "type", library.Type)
 			continue
 		}
 
-		fmt.Printf("Syncing library: %s (%s)\n", library.Title, library.Type)
+		s.logger.Debug("Syncing Plex library", "title", library.Title, "type", library.Type)
+		attemptedLibraries++
 
 		// Sync this library using its server-specific access token
 		items, err := s.syncLibraryItems(ctx, library.AccessToken, library, jobID)
 		if err != nil {
-			fmt.Printf("Failed to sync library %s: %v\n", library.Title, err)
+			s.logger.Warn("Failed to sync Plex library", "library", library.Title, "error", err)
+			libraryErrors = append(libraryErrors, fmt.Sprintf("library %s: %v", library.Title, err))
 			failedItems++
 			continue
 		}
@@ -164,73 +319,132 @@ func (s *PlexSyncService) PerformFullSync(ctx context.Context, userID int64, job
 		processedItems += len(items)
 		successfulItems += len(items)
 
+		if library.Type == "movie" {
+			watched = append(watched, watchedItems(library.ID, items)...)
+			inProgress = append(inProgress, inProgressItems(library.ID, items)...)
+		}
+
 		// Update progress
 		progress := 20 + (processedItems * 60 / max(totalItems, 1))
 		s.jobManager.UpdateJobProgress(jobID, progress, fmt.Sprintf("Synced library: %s", library.Title), processedItems, successfulItems, failedItems)
 	}
 
-	fmt.Printf("DEBUG: [PerformFullSync] Library sync completed, starting TMDB matching phase\n")
+	warnings = append(warnings, libraryErrors...)
+
+	// If every library failed there is nothing useful to match - fail the job
+	if attemptedLibraries > 0 && len(libraryErrors) == attemptedLibraries {
+		return fmt.Errorf("all %d libraries failed to sync: %s", attemptedLibraries, strings.Join(warnings, "; "))
+	}
 
 	// Phase 3: TMDB Matching
 	s.jobManager.UpdateJobProgress(jobID, 80, "Matching items with TMDB", processedItems, successfulItems, failedItems)
 
-	fmt.Printf("DEBUG: [PerformFullSync] About to call performTMDBMatching for user %d\n", userID)
-	matchedItems, err := s.performTMDBMatching(ctx, userID, jobID)
+	matchedItems, err := s.performTMDBMatching(ctx, userID, 0, jobID, 80, 15)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
 	if err != nil {
-		fmt.Printf("TMDB matching failed: %v\n", err)
+		s.logger.Warn("TMDB matching failed", "user_id", userID, "error", err)
 		// Don't fail the entire sync for TMDB matching issues
 	}
-	fmt.Printf("DEBUG: [PerformFullSync] TMDB matching returned %d matched items\n", matchedItems)
+	s.logger.Debug("TMDB matching finished", "user_id", userID, "matched", matchedItems)
+
+	// Record plays finished since the last sync, now that new items have TMDB ids
+	if err := s.recordWatchHistory(userID, watched); err != nil {
+		s.logger.Warn("Recording Plex watch history failed", "user_id", userID, "error", err)
+		warnings = append(warnings, fmt.Sprintf("failed to record watch history: %v", err))
+	}
+	if err := s.recordProgress(userID, inProgress, true); err != nil {
+		s.logger.Warn("Recording Plex progress failed", "user_id", userID, "error", err)
+		warnings = append(warnings, fmt.Sprintf("failed to record progress: %v", err))
+	}
+
+	// Final progress update
+	completedStep := "Sync completed"
+	if len(warnings) > 0 {
+		s.recordSyncWarnings(jobID, warnings)
+		completedStep = fmt.Sprintf("Sync completed with %d warnings", len(warnings))
+	}
+	s.jobManager.UpdateJobProgress(jobID, 100, completedStep, processedItems, successfulItems, failedItems)
+
+	s.logger.Info("Full Plex sync completed", "user_id", userID, "processed", processedItems,
+		"successful", successfulItems, "failed", failedItems, "matched", matchedItems)
+
+	return nil
+}
 
-	// Phase 4: Cleanup
-	s.jobManager.UpdateJobProgress(jobID, 95, "Cleaning up removed items", processedItems, successfulItems, failedItems)
+// PerformTMDBMatchingSync runs only the TMDB matching phase of a full sync over the
+// user's stored library items, skipping server discovery and library enumeration
+func (s *PlexSyncService) PerformTMDBMatchingSync(ctx context.Context, userID int64, jobID int64) error {
+	s.logger.Info("Starting match-only Plex sync", "user_id", userID, "job_id", jobID)
 
-	err = s.cleanupRemovedItems(ctx, userID)
+	s.jobManager.UpdateJobProgress(jobID, 5, "Matching stored items with TMDB", 0, 0, 0)
+
+	matchedItems, err := s.performTMDBMatching(ctx, userID, 0, jobID, 5, 90)
 	if err != nil {
-		fmt.Printf("Cleanup failed: %v\n", err)
-		// Don't fail the entire sync for cleanup issues
+		return fmt.Errorf("TMDB matching failed: %w", err)
 	}
 
-	// Final progress update
-	s.jobManager.UpdateJobProgress(jobID, 100, "Sync completed", processedItems, successfulItems, failedItems)
+	s.jobManager.UpdateJobProgress(jobID, 100, fmt.Sprintf("Matched %d items with TMDB", matchedItems), matchedItems, matchedItems, 0)
 
-	fmt.Printf("Full sync completed for user %d: %d items processed, %d successful, %d failed, %d TMDB matched\n",
-		userID, processedItems, successfulItems, failedItems, matchedItems)
+	s.logger.Info("Match-only Plex sync completed", "user_id", userID, "matched", matchedItems)
 
 	return nil
 }
 
-// discoverUserLibraries discovers all servers and libraries accessible to a user
-func (s *PlexSyncService) discoverUserLibraries(ctx context.Context, plexToken string, userID int64) ([]PlexLibrary, error) {
+// resetMatchingAttempts makes the user's unmatched items eligible for matching again
+func (s *PlexSyncService) resetMatchingAttempts(userID int64) error {
+	_, err := s.db.Exec(`
+		UPDATE plex_library_items
+		SET matching_attempts = 0, last_matched_at = NULL
+		WHERE ((type = 'movie' AND tmdb_id IS NULL) OR (type = 'show' AND tmdb_tv_id IS NULL))
+		AND library_id IN (
+			SELECT library_id FROM user_plex_access WHERE user_id = ? AND is_active = 1
+		)
+	`, userID)
+	return err
+}
+
+// discoverUserLibraries discovers all servers and libraries accessible to a user.
+// Per-server and per-library problems are returned as warnings; an error is only
+// returned when server discovery fails outright or every server failed.
+func (s *PlexSyncService) discoverUserLibraries(ctx context.Context, plexToken string, userID int64) ([]PlexLibrary, []string, error) {
 	// Get user's accessible servers
 	servers, err := s.plexgoClient.GetServers(ctx, plexToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get servers: %w", err)
+		return nil, nil, fmt.Errorf("failed to get servers: %w", err)
 	}
 
 	var allLibraries []PlexLibrary
+	var serverErrors []string
+	var warnings []string
 
 	for _, server := range servers {
 		// Store or update server in database
-		serverID, err := s.storeServer(server)
+		serverID, err := s.storeServer(server, userID)
 		if err != nil {
-			fmt.Printf("Failed to store server %s: %v\n", server.Name, err)
+			s.logger.Warn("Failed to store Plex server", "server", server.Name, "error", err)
+			serverErrors = append(serverErrors, fmt.Sprintf("server %s: failed to store server: %v", server.Name, err))
 			continue
 		}
 
-		// Get best connection for this server
-		bestConnection := s.plexgoClient.GetBestConnection(server)
+		// Pick the fastest responding connection, avoiding relays where possible
+		bestConnection, latency := s.plexgoClient.SelectConnection(ctx, server)
 		if bestConnection == nil {
-			fmt.Printf("No accessible connection for server %s\n", server.Name)
+			s.logger.Warn("No accessible connection for Plex server", "server", server.Name)
+			serverErrors = append(serverErrors, fmt.Sprintf("server %s: no accessible connection", server.Name))
 			continue
 		}
 
 		serverURL := s.plexgoClient.BuildServerURL(*bestConnection)
+		connectionType := ConnectionType(*bestConnection)
+		s.recordServerConnection(serverID, connectionType, latency)
 
 		// Get libraries for this server using the server-specific access token
 		libraries, err := s.plexgoClient.GetLibraries(ctx, server.AccessToken, serverURL)
 		if err != nil {
-			fmt.Printf("Failed to get libraries for server %s: %v\n", server.Name, err)
+			s.logger.Warn("Failed to get Plex libraries", "server", server.Name, "error", err)
+			serverErrors = append(serverErrors, fmt.Sprintf("server %s: failed to get libraries: %v", server.Name, err))
 			continue
 		}
 
@@ -239,18 +453,21 @@ func (s *PlexSyncService) discoverUserLibraries(ctx context.Context, plexToken s
 			library.ServerID = serverID
 			library.ServerURL = serverURL
 			library.AccessToken = server.AccessToken // Store server-specific token
+			library.ConnectionType = connectionType
 
 			// Store library in database
 			libraryID, err := s.storeLibrary(library)
 			if err != nil {
-				fmt.Printf("Failed to store library %s: %v\n", library.Title, err)
+				s.logger.Warn("Failed to store Plex library", "library", library.Title, "server", server.Name, "error", err)
+				warnings = append(warnings, fmt.Sprintf("library %s on %s: failed to store library: %v", library.Title, server.Name, err))
 				continue
 			}
 
 			// Record user access to this library
 			err = s.recordUserAccess(userID, libraryID)
 			if err != nil {
-				fmt.Printf("Failed to record user access to library %s: %v\n", library.Title, err)
+				s.logger.Warn("Failed to record user access to Plex library", "library", library.Title, "user_id", userID, "error", err)
+				warnings = append(warnings, fmt.Sprintf("library %s on %s: failed to record access: %v", library.Title, server.Name, err))
 			}
 
 			library.ID = libraryID
@@ -258,12 +475,57 @@ func (s *PlexSyncService) discoverUserLibraries(ctx context.Context, plexToken s
 		}
 	}
 
-	return allLibraries, nil
+	// Every server failed - surface that as an error instead of an empty "successful" sync
+	if len(servers) > 0 && len(serverErrors) == len(servers) {
+		return nil, nil, fmt.Errorf("all %d Plex servers failed: %s", len(servers), strings.Join(serverErrors, "; "))
+	}
+
+	// When every server answered, drop access to libraries the token (e.g. a newly
+	// selected Plex Home profile) can no longer see
+	if len(serverErrors) == 0 {
+		if err := s.deactivateMissingAccess(userID, allLibraries); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to update library access: %v", err))
+		}
+	}
+
+	return allLibraries, append(serverErrors, warnings...), nil
+}
+
+// recordConnectionStats stores how many libraries were reached over each connection type in the job metadata
+func (s *PlexSyncService) recordConnectionStats(jobID int64, libraries []PlexLibrary) {
+	if len(libraries) == 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, library := range libraries {
+		counts[library.ConnectionType]++
+	}
+
+	if err := s.jobManager.MergeJobMetadata(jobID, map[string]interface{}{"connection_types": counts}); err != nil {
+		s.logger.Warn("Failed to record connection stats", "job_id", jobID, "error", err)
+	}
+}
+
+// recordSyncWarnings stores partial-failure warnings in the job metadata so they are visible to the user
+func (s *PlexSyncService) recordSyncWarnings(jobID int64, warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+
+	if err := s.jobManager.MergeJobMetadata(jobID, map[string]interface{}{"warnings": warnings}); err != nil {
+		s.logger.Warn("Failed to record sync warnings", "job_id", jobID, "error", err)
+	}
 }
 
 // storeServer stores or updates a Plex server in the database
-func (s *PlexSyncService) storeServer(server PlexServer) (int64, error) {
+// When the syncing user owns the server it is recorded as the server's owner.
+func (s *PlexSyncService) storeServer(server PlexServer, userID int64) (int64, error) {
 	var serverID int64
+	var ownerUserID interface{}
+	if server.Owned {
+		ownerUserID = userID
+	}
 
 	// Try to get existing server
 	err := s.db.QueryRow(`
@@ -273,10 +535,10 @@ func (s *PlexSyncService) storeServer(server PlexServer) (int64, error) {
 	if err == sql.ErrNoRows {
 		// Create new server
 		err = s.db.QueryRow(`
-			INSERT INTO plex_servers (machine_id, name, platform, version, last_synced_at, updated_at)
-			VALUES (?, ?, ?, ?, datetime('now'), datetime('now'))
+			INSERT INTO plex_servers (machine_id, name, owner_user_id, platform, version, last_synced_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 			RETURNING id
-		`, server.MachineID, server.Name, server.Platform, server.ProductVersion).Scan(&serverID)
+		`, server.MachineID, server.Name, ownerUserID, server.Platform, server.ProductVersion).Scan(&serverID)
 
 		if err != nil {
 			return 0, fmt.Errorf("failed to create server: %w", err)
@@ -287,9 +549,9 @@ func (s *PlexSyncService) storeServer(server PlexServer) (int64, error) {
 		// Update existing server
 		_, err = s.db.Exec(`
 			UPDATE plex_servers 
-			SET name = ?, platform = ?, version = ?, last_synced_at = datetime('now'), updated_at = datetime('now')
+			SET name = ?, owner_user_id = COALESCE(?, owner_user_id), platform = ?, version = ?, last_synced_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
 			WHERE id = ?
-		`, server.Name, server.Platform, server.ProductVersion, serverID)
+		`, server.Name, ownerUserID, server.Platform, server.ProductVersion, serverID)
 
 		if err != nil {
 			return 0, fmt.Errorf("failed to update server: %w", err)
@@ -299,6 +561,21 @@ func (s *PlexSyncService) storeServer(server PlexServer) (int64, error) {
 	return serverID, nil
 }
 
+// recordServerConnection stores which connection type was used for a server and its measured latency
+func (s *PlexSyncService) recordServerConnection(serverID int64, connectionType string, latency time.Duration) {
+	var latencyMs interface{}
+	if latency > 0 {
+		latencyMs = latency.Milliseconds()
+	}
+
+	_, err := s.db.Exec(`
+		UPDATE plex_servers SET connection_type = ?, connection_latency_ms = ? WHERE id = ?
+	`, connectionType, latencyMs, serverID)
+	if err != nil {
+		s.logger.Warn("Failed to record Plex server connection", "server_id", serverID, "error", err)
+	}
+}
+
 // storeLibrary stores or updates a Plex library in the database
 func (s *PlexSyncService) storeLibrary(library PlexLibrary) (int64, error) {
 	var libraryID int64
@@ -311,10 +588,10 @@ func (s *PlexSyncService) storeLibrary(library PlexLibrary) (int64, error) {
 	if err == sql.ErrNoRows {
 		// Create new library
 		err = s.db.QueryRow(`
-			INSERT INTO plex_libraries (server_id, section_key, title, type, agent, scanner, language, uuid, last_synced_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+			INSERT INTO plex_libraries (server_id, section_key, title, type, agent, scanner, language, uuid, connection_type, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 			RETURNING id
-		`, library.ServerID, library.Key, library.Title, library.Type, library.Agent, library.Scanner, library.Language, library.UUID).Scan(&libraryID)
+		`, library.ServerID, library.Key, library.Title, library.Type, library.Agent, library.Scanner, library.Language, library.UUID, library.ConnectionType).Scan(&libraryID)
 
 		if err != nil {
 			return 0, fmt.Errorf("failed to create library: %w", err)
@@ -325,9 +602,9 @@ func (s *PlexSyncService) storeLibrary(library PlexLibrary) (int64, error) {
 		// Update existing library
 		_, err = s.db.Exec(`
 			UPDATE plex_libraries 
-			SET title = ?, type = ?, agent = ?, scanner = ?, language = ?, uuid = ?, last_synced_at = datetime('now'), updated_at = datetime('now')
+			SET title = ?, type = ?, agent = ?, scanner = ?, language = ?, uuid = ?, connection_type = ?, updated_at = CURRENT_TIMESTAMP
 			WHERE id = ?
-		`, library.Title, library.Type, library.Agent, library.Scanner, library.Language, library.UUID, libraryID)
+		`, library.Title, library.Type, library.Agent, library.Scanner, library.Language, library.UUID, library.ConnectionType, libraryID)
 
 		if err != nil {
 			return 0, fmt.Errorf("failed to update library: %w", err)
@@ -341,44 +618,143 @@ func (s *PlexSyncService) storeLibrary(library PlexLibrary) (int64, error) {
 func (s *PlexSyncService) recordUserAccess(userID, libraryID int64) error {
 	_, err := s.db.Exec(`
 		INSERT INTO user_plex_access (user_id, library_id, access_level, last_verified_at)
-		VALUES (?, ?, 'read', datetime('now'))
+		VALUES (?, ?, 'read', CURRENT_TIMESTAMP)
 		ON CONFLICT(user_id, library_id) DO UPDATE SET
-			last_verified_at = datetime('now'),
+			last_verified_at = CURRENT_TIMESTAMP,
 			is_active = 1
 	`, userID, libraryID)
 
 	return err
 }
 
-// syncLibraryItems syncs all items in a library
+// deactivateMissingAccess marks the user's access inactive for libraries that were not discovered
+func (s *PlexSyncService) deactivateMissingAccess(userID int64, libraries []PlexLibrary) error {
+	query := `UPDATE user_plex_access SET is_active = 0 WHERE user_id = ? AND is_active = 1`
+	args := []interface{}{userID}
+
+	if len(libraries) > 0 {
+		placeholders := make([]string, len(libraries))
+		for i, library := range libraries {
+			placeholders[i] = "?"
+			args = append(args, library.ID)
+		}
+		query += ` AND library_id NOT IN (` + strings.Join(placeholders, ", ") + `)`
+	}
+
+	_, err := s.db.Exec(query, args...)
+	return err
+}
+
+// syncLibraryItems syncs the items of a movie or show library. Every item is listed so
+// removals and view state are picked up, but only items Plex changed since they were stored
+// are written. Returns all listed items.
 func (s *PlexSyncService) syncLibraryItems(ctx context.Context, plexToken string, library PlexLibrary, jobID int64) ([]PlexSearchResult, error) {
-	items, err := s.plexgoClient.GetMoviesInLibrary(ctx, plexToken, library.ServerURL, library.Key)
+	var items []PlexSearchResult
+	var err error
+	if library.Type == "show" {
+		items, err = s.plexgoClient.GetShowsInLibrary(ctx, plexToken, library.ServerURL, library.Key)
+	} else {
+		items, err = s.plexgoClient.GetMoviesInLibrary(ctx, plexToken, library.ServerURL, library.Key)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get library items: %w", err)
 	}
 
+	stored, err := s.storedLibraryItems(library.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stored items: %w", err)
+	}
+
+	changed := 0
+	listed := make(map[string]bool, len(items))
 	for _, item := range items {
+		listed[item.RatingKey] = true
+		if existing, ok := stored[item.RatingKey]; ok && existing.unchanged(item) {
+			continue
+		}
+
 		// Store item in database
 		err = s.storeLibraryItem(library.ID, item)
 		if err != nil {
-			fmt.Printf("Failed to store item %s: %v\n", item.Title, err)
+			s.logger.Warn("Failed to store Plex item", "title", item.Title, "library", library.Title, "error", err)
 			continue
 		}
+		changed++
 	}
 
-	// Update library item count
+	// An empty listing is more likely a failed fallback than an emptied library
+	removed := 0
+	if len(items) > 0 {
+		for ratingKey, existing := range stored {
+			if !existing.active || listed[ratingKey] {
+				continue
+			}
+			_, err := s.db.Exec(`
+				UPDATE plex_library_items SET is_active = 0, updated_at = CURRENT_TIMESTAMP
+				WHERE library_id = ? AND plex_rating_key = ?
+			`, library.ID, ratingKey)
+			if err != nil {
+				s.logger.Warn("Failed to deactivate removed Plex item", "rating_key", ratingKey, "library", library.Title, "error", err)
+				continue
+			}
+			removed++
+		}
+	}
+
+	s.logger.Info("Synced Plex library", "library", library.Title, "items", len(items), "changed", changed, "removed", removed)
+
+	// Update library item count and sync time
 	_, err = s.db.Exec(`
-		UPDATE plex_libraries SET item_count = ? WHERE id = ?
+		UPDATE plex_libraries SET item_count = ?, last_synced_at = CURRENT_TIMESTAMP WHERE id = ?
 	`, len(items), library.ID)
 
 	if err != nil {
-		fmt.Printf("Failed to update library item count: %v\n", err)
+		s.logger.Warn("Failed to update Plex library item count", "library", library.Title, "error", err)
 	}
 
 	return items, nil
 }
 
-// storeLibraryItem stores or updates a library item
+// storedLibraryItem is what a previous sync stored about a library item
+type storedLibraryItem struct {
+	guid          string
+	updatedAtPlex sql.NullTime
+	active        bool
+}
+
+// unchanged reports whether a listed item matches what is stored. Items without an update
+// time are always treated as changed.
+func (stored storedLibraryItem) unchanged(item PlexSearchResult) bool {
+	return stored.active && stored.guid == item.GUID &&
+		stored.updatedAtPlex.Valid && item.UpdatedAt != nil && stored.updatedAtPlex.Time.Equal(*item.UpdatedAt)
+}
+
+// storedLibraryItems returns a library's stored items keyed by rating key
+func (s *PlexSyncService) storedLibraryItems(libraryID int64) (map[string]storedLibraryItem, error) {
+	rows, err := s.db.Query(`
+		SELECT plex_rating_key, plex_guid, updated_at_plex, COALESCE(is_active, 0)
+		FROM plex_library_items WHERE library_id = ?
+	`, libraryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[string]storedLibraryItem)
+	for rows.Next() {
+		var ratingKey string
+		var item storedLibraryItem
+		if err := rows.Scan(&ratingKey, &item.guid, &item.updatedAtPlex, &item.active); err != nil {
+			return nil, err
+		}
+		stored[ratingKey] = item
+	}
+
+	return stored, rows.Err()
+}
+
+// storeLibraryItem stores or updates a library item. An item whose Plex GUID changed (e.g. it
+// was re-matched in Plex) loses its TMDB match so it is matched again.
 func (s *PlexSyncService) storeLibraryItem(libraryID int64, item PlexSearchResult) error {
 	// Convert item to JSON for metadata storage
 	metadata, _ := json.Marshal(item)
@@ -387,52 +763,65 @@ func (s *PlexSyncService) storeLibraryItem(libraryID int64, item PlexSearchResul
 	ratingKey := item.RatingKey
 
 	_, err := s.db.Exec(`
-		INSERT INTO plex_library_items (library_id, plex_rating_key, plex_guid, title, year, type, metadata_json, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'))
+		INSERT INTO plex_library_items (library_id, plex_rating_key, plex_guid, title, year, type, metadata_json, updated_at_plex, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 		ON CONFLICT(library_id, plex_rating_key) DO UPDATE SET
+			plex_guid = excluded.plex_guid,
 			title = excluded.title,
 			year = excluded.year,
 			type = excluded.type,
 			metadata_json = excluded.metadata_json,
-			updated_at = datetime('now'),
+			updated_at_plex = excluded.updated_at_plex,
+			tmdb_id = CASE WHEN plex_library_items.plex_guid = excluded.plex_guid THEN plex_library_items.tmdb_id END,
+			tmdb_tv_id = CASE WHEN plex_library_items.plex_guid = excluded.plex_guid THEN plex_library_items.tmdb_tv_id END,
+			matching_attempts = CASE WHEN plex_library_items.plex_guid = excluded.plex_guid THEN plex_library_items.matching_attempts ELSE 0 END,
+			last_matched_at = CASE WHEN plex_library_items.plex_guid = excluded.plex_guid THEN plex_library_items.last_matched_at END,
+			updated_at = CURRENT_TIMESTAMP,
 			is_active = 1
-	`, libraryID, ratingKey, item.GUID, item.Title, item.Year, item.Type, string(metadata))
+	`, libraryID, ratingKey, item.GUID, item.Title, item.Year, item.Type, string(metadata), item.UpdatedAt)
 
 	return err
 }
 
-// performTMDBMatching matches Plex items with TMDB using rate limiting
-func (s *PlexSyncService) performTMDBMatching(ctx context.Context, userID int64, jobID int64) (int, error) {
-	fmt.Printf("DEBUG: [performTMDBMatching] Starting TMDB matching for user %d\n", userID)
-
-	// Debug: Check total items in database
-	var totalItems int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM plex_library_items WHERE is_active = 1`).Scan(&totalItems)
+// CountAvailableMovies returns how many distinct matched movies the user can watch.
+// A film carried by several servers or libraries is counted once.
+func (s *PlexSyncService) CountAvailableMovies(userID int64) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM user_available_movies WHERE user_id = ?`, userID).Scan(&count)
 	if err != nil {
-		fmt.Printf("DEBUG: [performTMDBMatching] Error counting total items: %v\n", err)
-	} else {
-		fmt.Printf("DEBUG: [performTMDBMatching] Total active items in database: %d\n", totalItems)
+		return 0, fmt.Errorf("failed to count available movies: %w", err)
 	}
+	return count, nil
+}
 
-	// Debug: Check user access entries
-	var userAccessCount int
-	err = s.db.QueryRow(`SELECT COUNT(*) FROM user_plex_access WHERE user_id = ? AND is_active = 1`, userID).Scan(&userAccessCount)
-	if err != nil {
-		fmt.Printf("DEBUG: [performTMDBMatching] Error counting user access: %v\n", err)
-	} else {
-		fmt.Printf("DEBUG: [performTMDBMatching] User %d has access to %d libraries\n", userID, userAccessCount)
+// performTMDBMatching matches the user's unmatched Plex items with TMDB using rate limiting,
+// only those of one library when libraryID isn't 0. Job progress moves from progressStart
+// to progressStart+progressSpan as items are matched.
+func (s *PlexSyncService) performTMDBMatching(ctx context.Context, userID, libraryID int64, jobID int64, progressStart, progressSpan int) (int, error) {
+	// The counts are only worth querying when they will be logged
+	if s.logger.Enabled(ctx, slog.LevelDebug) {
+		var totalItems, userAccessCount int
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM plex_library_items WHERE is_active = 1`).Scan(&totalItems); err != nil {
+			s.logger.Debug("Failed to count active Plex items", "error", err)
+		}
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM user_plex_access WHERE user_id = ? AND is_active = 1`, userID).Scan(&userAccessCount); err != nil {
+			s.logger.Debug("Failed to count user Plex access", "user_id", userID, "error", err)
+		}
+		s.logger.Debug("Starting TMDB matching", "user_id", userID, "library_id", libraryID,
+			"active_items", totalItems, "accessible_libraries", userAccessCount)
 	}
 
-	// Get unmatched items
+	// Get unmatched movies and shows
 	rows, err := s.db.Query(`
-		SELECT pli.id, pli.title, pli.year, pli.plex_guid
+		SELECT pli.id, pli.title, pli.year, pli.plex_guid, pli.type
 		FROM plex_library_items pli
 		JOIN plex_libraries pl ON pli.library_id = pl.id
 		JOIN user_plex_access upa ON pl.id = upa.library_id
-		WHERE upa.user_id = ? AND pli.tmdb_id IS NULL AND pli.is_active = 1
+		WHERE upa.user_id = ? AND pli.is_active = 1 AND (? = 0 OR pli.library_id = ?)
+		AND ((pli.type = 'movie' AND pli.tmdb_id IS NULL) OR (pli.type = 'show' AND pli.tmdb_tv_id IS NULL))
 		AND (pli.last_matched_at IS NULL OR pli.matching_attempts < 3)
 		ORDER BY pli.created_at DESC
-	`, userID)
+	`, userID, libraryID, libraryID)
 
 	if err != nil {
 		return 0, fmt.Errorf("failed to query unmatched items: %w", err)
@@ -444,6 +833,7 @@ func (s *PlexSyncService) performTMDBMatching(ctx context.Context, userID int64,
 		Title    string
 		Year     *int
 		PlexGUID string
+		Type     string
 	}
 
 	for rows.Next() {
@@ -452,9 +842,10 @@ func (s *PlexSyncService) performTMDBMatching(ctx context.Context, userID int64,
 			Title    string
 			Year     *int
 			PlexGUID string
+			Type     string
 		}
 
-		err := rows.Scan(&item.ID, &item.Title, &item.Year, &item.PlexGUID)
+		err := rows.Scan(&item.ID, &item.Title, &item.Year, &item.PlexGUID, &item.Type)
 		if err != nil {
 			continue
 		}
@@ -462,26 +853,33 @@ func (s *PlexSyncService) performTMDBMatching(ctx context.Context, userID int64,
 		unmatchedItems = append(unmatchedItems, item)
 	}
 
-	fmt.Printf("DEBUG: [performTMDBMatching] Found %d unmatched items for user %d\n", len(unmatchedItems), userID)
+	s.logger.Debug("Found unmatched Plex items", "user_id", userID, "count", len(unmatchedItems))
 
 	matchedCount := 0
 
 	for i, item := range unmatchedItems {
+		if err := ctx.Err(); err != nil {
+			return matchedCount, err
+		}
+
 		// Update progress
-		progress := 80 + (i * 15 / max(len(unmatchedItems), 1))
+		progress := progressStart + (i * progressSpan / max(len(unmatchedItems), 1))
 		s.jobManager.UpdateJobProgress(jobID, progress, fmt.Sprintf("Matching with TMDB: %s", item.Title), 0, 0, 0)
 
 		// Try to match with TMDB using rate limiting
 		err := s.rateLimiter.ExecuteWithRateLimit(func() error {
+			if item.Type == "show" {
+				return s.matchShowWithTMDB(item.ID, item.Title, item.Year, item.PlexGUID)
+			}
 			return s.matchItemWithTMDB(item.ID, item.Title, item.Year, item.PlexGUID)
-		}, 0) // Priority 0 for background sync
+		}, 0, userID) // Priority 0 for background sync
 
 		if err != nil {
-			fmt.Printf("Failed to match %s with TMDB: %v\n", item.Title, err)
+			s.logger.Debug("Failed to match Plex item with TMDB", "title", item.Title, "error", err)
 			// Update attempt count
 			s.db.Exec(`
 				UPDATE plex_library_items 
-				SET matching_attempts = matching_attempts + 1, last_matched_at = datetime('now')
+				SET matching_attempts = matching_attempts + 1, last_matched_at = CURRENT_TIMESTAMP
 				WHERE id = ?
 			`, item.ID)
 		} else {
@@ -494,6 +892,19 @@ func (s *PlexSyncService) performTMDBMatching(ctx context.Context, userID int64,
 
 // matchItemWithTMDB attempts to match a Plex item with TMDB
 func (s *PlexSyncService) matchItemWithTMDB(itemID int64, title string, year *int, plexGUID string) error {
+	// A mapping corrected by hand wins over anything derived from the item's metadata
+	if tmdbID, ok := overriddenTMDBID(s.db, plexGUID); ok {
+		_, err := s.db.Exec(`
+			UPDATE plex_library_items 
+			SET tmdb_id = ?, last_matched_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, tmdbID, itemID)
+		if err != nil {
+			return fmt.Errorf("failed to update item with TMDB ID: %w", err)
+		}
+		return nil
+	}
+
 	// Try to extract TMDB ID from Plex GUID first
 	if tmdbID := extractTMDBFromGUID(plexGUID); tmdbID > 0 {
 		// Verify the movie exists in TMDB
@@ -502,7 +913,7 @@ func (s *PlexSyncService) matchItemWithTMDB(itemID int64, title string, year *in
 			// Update the item with TMDB ID
 			_, err = s.db.Exec(`
 				UPDATE plex_library_items 
-				SET tmdb_id = ?, last_matched_at = datetime('now')
+				SET tmdb_id = ?, last_matched_at = CURRENT_TIMESTAMP
 				WHERE id = ?
 			`, tmdbID, itemID)
 
@@ -529,8 +940,12 @@ func (s *PlexSyncService) matchItemWithTMDB(itemID int64, title string, year *in
 		return fmt.Errorf("no TMDB matches found for %s (%d)", title, yearInt)
 	}
 
-	// Use the first match (most relevant)
-	bestMatch := searchResp.Results[0]
+	// Leave the item unmatched rather than guess when no result is a confident match
+	match, score := bestTitleMatch(title, year, searchResp.Results)
+	if match == nil {
+		return fmt.Errorf("no confident TMDB match for %s (%d), best score %.2f", title, yearInt, score)
+	}
+	bestMatch := *match
 
 	// Store movie in movies table first (to satisfy foreign key constraint)
 	err = s.storeMovieFromTMDB(bestMatch)
@@ -541,7 +956,7 @@ func (s *PlexSyncService) matchItemWithTMDB(itemID int64, title string, year *in
 	// Update the item with TMDB ID
 	_, err = s.db.Exec(`
 		UPDATE plex_library_items 
-		SET tmdb_id = ?, last_matched_at = datetime('now')
+		SET tmdb_id = ?, last_matched_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`, bestMatch.ID, itemID)
 
@@ -552,6 +967,106 @@ func (s *PlexSyncService) matchItemWithTMDB(itemID int64, title string, year *in
 	return nil
 }
 
+// matchShowWithTMDB attempts to match a Plex show with a TMDB TV show
+func (s *PlexSyncService) matchShowWithTMDB(itemID int64, title string, year *int, plexGUID string) error {
+	var show *TMDBTVDetails
+
+	// Try to extract TMDB ID from Plex GUID first, verifying it is a TV show
+	if tmdbID := extractTMDBFromGUID(plexGUID); tmdbID > 0 {
+		if details, err := s.tmdbClient.GetTVDetails(tmdbID); err == nil {
+			show = details
+		}
+	}
+
+	if show == nil {
+		// Fallback to search by title and first air year
+		yearInt := 0
+		if year != nil {
+			yearInt = *year
+		}
+
+		searchResp, err := s.tmdbClient.SearchTV(title, yearInt)
+		if err != nil {
+			return fmt.Errorf("TMDB TV search failed: %w", err)
+		}
+
+		if len(searchResp.Results) == 0 {
+			return fmt.Errorf("no TMDB TV matches found for %s (%d)", title, yearInt)
+		}
+
+		// Use the first match (most relevant)
+		show = &TMDBTVDetails{TMDBTVShow: searchResp.Results[0]}
+	}
+
+	// Store show first (to satisfy foreign key constraint)
+	if err := s.storeTVShowFromTMDB(show); err != nil {
+		return fmt.Errorf("failed to store show from TMDB: %w", err)
+	}
+
+	_, err := s.db.Exec(`
+		UPDATE plex_library_items 
+		SET tmdb_tv_id = ?, last_matched_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, show.ID, itemID)
+
+	if err != nil {
+		return fmt.Errorf("failed to update item with TMDB TV ID: %w", err)
+	}
+
+	return nil
+}
+
+// storeTVShowFromTMDB stores a TV show from TMDB API response. Search results carry no
+// genre names or season count, so those are only overwritten when known.
+func (s *PlexSyncService) storeTVShowFromTMDB(show *TMDBTVDetails) error {
+	var posterURL string
+	if show.PosterPath != nil && *show.PosterPath != "" {
+		posterURL = "https://image.tmdb.org/t/p/w500" + *show.PosterPath
+	}
+
+	var year *int
+	if len(show.FirstAirDate) >= 4 {
+		if parsedYear, err := strconv.Atoi(show.FirstAirDate[:4]); err == nil {
+			year = &parsedYear
+		}
+	}
+
+	var genresJSON *string
+	if len(show.Genres) > 0 {
+		genreNames := make([]string, 0, len(show.Genres))
+		for _, genre := range show.Genres {
+			genreNames = append(genreNames, genre.Name)
+		}
+		if genresBytes, err := json.Marshal(genreNames); err == nil {
+			genres := string(genresBytes)
+			genresJSON = &genres
+		}
+	}
+
+	var seasons *int
+	if show.NumberOfSeasons > 0 {
+		seasons = &show.NumberOfSeasons
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO tv_shows (tmdb_id, title, year, poster_url, synopsis, genres, number_of_seasons, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(tmdb_id) DO UPDATE SET
+			title = excluded.title,
+			year = excluded.year,
+			poster_url = excluded.poster_url,
+			synopsis = excluded.synopsis,
+			genres = COALESCE(excluded.genres, tv_shows.genres),
+			number_of_seasons = COALESCE(excluded.number_of_seasons, tv_shows.number_of_seasons)
+	`, show.ID, show.Name, year, posterURL, show.Overview, genresJSON, seasons)
+
+	if err != nil {
+		return fmt.Errorf("failed to store show in database: %w", err)
+	}
+
+	return nil
+}
+
 // storeMovieFromTMDB stores a movie from TMDB API response
 func (s *PlexSyncService) storeMovieFromTMDB(movie interface{}) error {
 	// Handle both TMDBMovie and TMDBMovieDetails types
@@ -610,7 +1125,7 @@ func (s *PlexSyncService) storeMovieFromTMDB(movie interface{}) error {
 	// Insert or update movie in database
 	_, err := s.db.Exec(`
 		INSERT INTO movies (tmdb_id, title, year, poster_url, synopsis, runtime, genres, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'))
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 		ON CONFLICT(tmdb_id) DO UPDATE SET
 			title = excluded.title,
 			year = excluded.year,
@@ -627,25 +1142,6 @@ func (s *PlexSyncService) storeMovieFromTMDB(movie interface{}) error {
 	return nil
 }
 
-// cleanupRemovedItems removes items that are no longer in Plex
-func (s *PlexSyncService) cleanupRemovedItems(ctx context.Context, userID int64) error {
-	// Mark items as inactive if they weren't updated in the last sync
-	// This is a simplified approach - in a real implementation, you'd want to
-	// actually check if the item still exists in Plex
-
-	_, err := s.db.Exec(`
-		UPDATE plex_library_items 
-		SET is_active = 0
-		WHERE library_id IN (
-			SELECT pl.id FROM plex_libraries pl
-			JOIN user_plex_access upa ON pl.id = upa.library_id
-			WHERE upa.user_id = ? AND upa.is_active = 1
-		) AND updated_at < datetime('now', '-1 hour')
-	`, userID)
-
-	return err
-}
-
 // Helper functions
 func extractTMDBFromGUID(plexGUID string) int {
 	// Extract TMDB ID from Plex GUID formats like:
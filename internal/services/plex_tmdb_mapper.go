@@ -3,6 +3,7 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"regexp"
 	"strconv"
 	"strings"
@@ -11,6 +12,7 @@ import (
 type PlexTMDBMapper struct {
 	db         *sql.DB
 	tmdbClient *TMDBClient
+	logger     *slog.Logger
 }
 
 type PlexTMDBMapping struct {
@@ -20,12 +22,18 @@ type PlexTMDBMapping struct {
 	Title     string `json:"title"`
 	Year      *int   `json:"year,omitempty"`
 	RatingKey string `json:"ratingKey,omitempty"`
+	Override  bool   `json:"override"` // Set by hand; not re-derived or cleaned up
 	CreatedAt string `json:"createdAt"`
 	UpdatedAt string `json:"updatedAt"`
 }
 
 func NewPlexTMDBMapper(db *sql.DB, tmdbClient *TMDBClient) *PlexTMDBMapper {
-	return &PlexTMDBMapper{db: db, tmdbClient: tmdbClient}
+	return &PlexTMDBMapper{db: db, tmdbClient: tmdbClient, logger: slog.Default()}
+}
+
+// SetLogger replaces the logger, which defaults to slog's default logger at construction
+func (m *PlexTMDBMapper) SetLogger(logger *slog.Logger) {
+	m.logger = logger
 }
 
 // ExternalIDInfo represents extracted external ID information from Plex GUID
@@ -91,7 +99,7 @@ func (m *PlexTMDBMapper) ExtractExternalIDFromGUID(guid string) (*ExternalIDInfo
 
 // GetOrCreateMapping gets existing mapping or creates new one using TMDB API for external ID lookups
 func (m *PlexTMDBMapper) GetOrCreateMapping(plexGUID, title string, year *int, ratingKey string) (*PlexTMDBMapping, error) {
-	// First, try to get existing mapping
+	// First, try to get existing mapping; one overridden by hand is never re-derived
 	existing, err := m.GetMappingByPlexGUID(plexGUID)
 	if err == nil {
 		return existing, nil
@@ -100,11 +108,11 @@ func (m *PlexTMDBMapper) GetOrCreateMapping(plexGUID, title string, year *int, r
 	// Extract external ID from GUID
 	extID, err := m.ExtractExternalIDFromGUID(plexGUID)
 	if err != nil {
-		fmt.Printf("DEBUG: Failed to extract external ID from GUID %s: %v\n", plexGUID, err)
+		m.logger.Debug("Failed to extract external ID from Plex GUID", "guid", plexGUID, "error", err)
 		return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 	}
 
-	fmt.Printf("DEBUG: Extracted external ID - Type: %s, Value: %s from GUID: %s\n", extID.Type, extID.Value, plexGUID)
+	m.logger.Debug("Extracted external ID from Plex GUID", "guid", plexGUID, "type", extID.Type, "value", extID.Value)
 
 	var tmdbID int
 
@@ -114,61 +122,61 @@ func (m *PlexTMDBMapper) GetOrCreateMapping(plexGUID, title string, year *int, r
 		// Direct TMDB ID - convert to int
 		tmdbID, err = strconv.Atoi(extID.Value)
 		if err != nil {
-			fmt.Printf("DEBUG: Failed to convert TMDB ID %s to int: %v\n", extID.Value, err)
+			m.logger.Debug("Invalid TMDB ID in Plex GUID", "guid", plexGUID, "value", extID.Value, "error", err)
 			return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 		}
 
 	case "imdb":
 		// Use TMDB find API to lookup by IMDb ID
 		if m.tmdbClient == nil {
-			fmt.Printf("DEBUG: No TMDB client available for external ID lookup, failing for IMDb ID: %s\n", extID.Value)
+			m.logger.Debug("No TMDB client for external ID lookup", "imdb_id", extID.Value)
 			return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 		}
 
 		findResp, err := m.tmdbClient.FindByExternalID(extID.Value, "imdb_id")
 		if err != nil {
-			fmt.Printf("DEBUG: TMDB find API failed for IMDb ID %s: %v\n", extID.Value, err)
+			m.logger.Debug("TMDB find failed", "imdb_id", extID.Value, "error", err)
 			return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 		}
 
 		if len(findResp.MovieResults) == 0 {
-			fmt.Printf("DEBUG: No TMDB movies found for IMDb ID %s\n", extID.Value)
+			m.logger.Debug("No TMDB movies found", "imdb_id", extID.Value)
 			return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 		}
 
 		// Take the first result (should be the best match)
 		tmdbID = findResp.MovieResults[0].ID
-		fmt.Printf("DEBUG: Found TMDB ID %d via IMDb ID %s\n", tmdbID, extID.Value)
+		m.logger.Debug("Found TMDB ID", "imdb_id", extID.Value, "tmdb_id", tmdbID)
 
 	case "tvdb":
 		// Use TMDB find API to lookup by TVDB ID
 		if m.tmdbClient == nil {
-			fmt.Printf("DEBUG: No TMDB client available for external ID lookup, failing for TVDB ID: %s\n", extID.Value)
+			m.logger.Debug("No TMDB client for external ID lookup", "tvdb_id", extID.Value)
 			return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 		}
 
 		findResp, err := m.tmdbClient.FindByExternalID(extID.Value, "tvdb_id")
 		if err != nil {
-			fmt.Printf("DEBUG: TMDB find API failed for TVDB ID %s: %v\n", extID.Value, err)
+			m.logger.Debug("TMDB find failed", "tvdb_id", extID.Value, "error", err)
 			return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 		}
 
 		if len(findResp.MovieResults) == 0 {
-			fmt.Printf("DEBUG: No TMDB movies found for TVDB ID %s\n", extID.Value)
+			m.logger.Debug("No TMDB movies found", "tvdb_id", extID.Value)
 			return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 		}
 
 		// Take the first result (should be the best match)
 		tmdbID = findResp.MovieResults[0].ID
-		fmt.Printf("DEBUG: Found TMDB ID %d via TVDB ID %s\n", tmdbID, extID.Value)
+		m.logger.Debug("Found TMDB ID", "tvdb_id", extID.Value, "tmdb_id", tmdbID)
 
 	case "plex":
 		// Plex's own format can't be directly converted to TMDB
-		fmt.Printf("DEBUG: Cannot convert Plex internal ID %s to TMDB ID, trying fallback\n", extID.Value)
+		m.logger.Debug("Plex internal IDs can't be converted to TMDB IDs, trying fallback", "plex_id", extID.Value)
 		return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 
 	default:
-		fmt.Printf("DEBUG: Unsupported external ID type %s for value %s\n", extID.Type, extID.Value)
+		m.logger.Debug("Unsupported external ID type", "type", extID.Type, "value", extID.Value)
 		return m.tryFallbackMapping(plexGUID, title, year, ratingKey)
 	}
 
@@ -176,16 +184,16 @@ func (m *PlexTMDBMapper) GetOrCreateMapping(plexGUID, title string, year *int, r
 	var existsInMovies bool
 	err = m.db.QueryRow("SELECT 1 FROM movies WHERE tmdb_id = ?", tmdbID).Scan(&existsInMovies)
 	if err == sql.ErrNoRows {
-		fmt.Printf("DEBUG: TMDB movie %d not found in local database\n", tmdbID)
+		m.logger.Debug("TMDB movie not found in local database", "tmdb_id", tmdbID)
 		return nil, fmt.Errorf("TMDB movie %d not found in local database", tmdbID)
 	}
 	if err != nil {
-		fmt.Printf("DEBUG: Error checking movie existence for TMDB ID %d: %v\n", tmdbID, err)
+		m.logger.Debug("Failed to check movie exists", "tmdb_id", tmdbID, "error", err)
 		return nil, fmt.Errorf("error checking movie existence: %w", err)
 	}
 
 	// Create new mapping
-	fmt.Printf("DEBUG: Creating mapping - Plex GUID: %s -> TMDB ID: %d\n", plexGUID, tmdbID)
+	m.logger.Debug("Creating Plex TMDB mapping", "guid", plexGUID, "tmdb_id", tmdbID)
 	return m.CreateMapping(plexGUID, tmdbID, title, year, ratingKey)
 }
 
@@ -195,53 +203,44 @@ func (m *PlexTMDBMapper) tryFallbackMapping(plexGUID, title string, year *int, r
 		return nil, fmt.Errorf("no TMDB client available for fallback search and no direct ID mapping found")
 	}
 
-	// Search TMDB by title
-	fmt.Printf("DEBUG: Attempting fallback search for title: %s, year: %v\n", title, year)
-	searchResp, err := m.tmdbClient.SearchMovies(title, 1)
+	// Search TMDB by title; the year is scored rather than filtered on, as release years
+	// can differ by one between Plex and TMDB
+	m.logger.Debug("Trying TMDB title search fallback", "title", title, "year", year)
+	searchResp, err := m.tmdbClient.SearchMovies(title, 0)
 	if err != nil {
-		fmt.Printf("DEBUG: TMDB search failed for title %s: %v\n", title, err)
+		m.logger.Debug("TMDB title search failed", "title", title, "error", err)
 		return nil, fmt.Errorf("failed to search TMDB for title %s: %w", title, err)
 	}
 
 	if len(searchResp.Results) == 0 {
-		fmt.Printf("DEBUG: No TMDB search results for title: %s\n", title)
+		m.logger.Debug("No TMDB title search results", "title", title)
 		return nil, fmt.Errorf("no TMDB results found for title: %s", title)
 	}
 
-	// Try to find best match by year if provided
-	var bestMatch *TMDBMovie
-	if year != nil {
-		for _, movie := range searchResp.Results {
-			movieYear := ExtractYear(movie.ReleaseDate)
-			if movieYear != nil && *movieYear == *year {
-				bestMatch = &movie
-				fmt.Printf("DEBUG: Found exact year match - TMDB ID: %d, Title: %s, Year: %d\n", movie.ID, movie.Title, *movieYear)
-				break
-			}
-		}
-	}
-
-	// If no year match, take the first (most popular) result
+	// Only take a result that matches the title and year well enough; guessing would
+	// map the item to the wrong movie
+	bestMatch, score := bestTitleMatch(title, year, searchResp.Results)
 	if bestMatch == nil {
-		bestMatch = &searchResp.Results[0]
-		movieYear := ExtractYear(bestMatch.ReleaseDate)
-		fmt.Printf("DEBUG: Using first search result - TMDB ID: %d, Title: %s, Year: %v\n", bestMatch.ID, bestMatch.Title, movieYear)
+		m.logger.Debug("No confident TMDB title match", "title", title, "year", year, "best_score", score)
+		return nil, fmt.Errorf("no confident TMDB match for title: %s", title)
 	}
+	m.logger.Debug("Found TMDB title match", "tmdb_id", bestMatch.ID, "title", bestMatch.Title,
+		"year", ExtractYear(bestMatch.ReleaseDate), "score", score)
 
 	// Check if the TMDB movie exists in our database
 	var existsInMovies bool
 	err = m.db.QueryRow("SELECT 1 FROM movies WHERE tmdb_id = ?", bestMatch.ID).Scan(&existsInMovies)
 	if err == sql.ErrNoRows {
-		fmt.Printf("DEBUG: TMDB movie %d from fallback search not found in local database\n", bestMatch.ID)
+		m.logger.Debug("TMDB movie from title search not found in local database", "tmdb_id", bestMatch.ID)
 		return nil, fmt.Errorf("TMDB movie %d not found in local database", bestMatch.ID)
 	}
 	if err != nil {
-		fmt.Printf("DEBUG: Error checking movie existence for TMDB ID %d from fallback: %v\n", bestMatch.ID, err)
+		m.logger.Debug("Failed to check movie from title search exists", "tmdb_id", bestMatch.ID, "error", err)
 		return nil, fmt.Errorf("error checking movie existence: %w", err)
 	}
 
 	// Create new mapping
-	fmt.Printf("DEBUG: Creating fallback mapping - Plex GUID: %s -> TMDB ID: %d (via search)\n", plexGUID, bestMatch.ID)
+	m.logger.Debug("Creating Plex TMDB mapping from title search", "guid", plexGUID, "tmdb_id", bestMatch.ID)
 	return m.CreateMapping(plexGUID, bestMatch.ID, title, year, ratingKey)
 }
 
@@ -255,13 +254,13 @@ func (m *PlexTMDBMapper) CreateMapping(plexGUID string, tmdbID int, title string
 			year = excluded.year,
 			plex_rating_key = excluded.plex_rating_key,
 			updated_at = CURRENT_TIMESTAMP
-		RETURNING id, plex_guid, tmdb_id, title, year, plex_rating_key, created_at, updated_at
+		RETURNING id, plex_guid, tmdb_id, title, year, plex_rating_key, is_override, created_at, updated_at
 	`
 
 	var mapping PlexTMDBMapping
 	err := m.db.QueryRow(query, plexGUID, tmdbID, title, year, ratingKey).Scan(
 		&mapping.ID, &mapping.PlexGUID, &mapping.TMDBID, &mapping.Title,
-		&mapping.Year, &mapping.RatingKey, &mapping.CreatedAt, &mapping.UpdatedAt,
+		&mapping.Year, &mapping.RatingKey, &mapping.Override, &mapping.CreatedAt, &mapping.UpdatedAt,
 	)
 
 	if err != nil {
@@ -274,7 +273,7 @@ func (m *PlexTMDBMapper) CreateMapping(plexGUID string, tmdbID int, title string
 // GetMappingByPlexGUID gets mapping by Plex GUID
 func (m *PlexTMDBMapper) GetMappingByPlexGUID(plexGUID string) (*PlexTMDBMapping, error) {
 	query := `
-		SELECT id, plex_guid, tmdb_id, title, year, plex_rating_key, created_at, updated_at
+		SELECT id, plex_guid, tmdb_id, title, year, plex_rating_key, is_override, created_at, updated_at
 		FROM plex_tmdb_mappings 
 		WHERE plex_guid = ?
 	`
@@ -282,7 +281,28 @@ func (m *PlexTMDBMapper) GetMappingByPlexGUID(plexGUID string) (*PlexTMDBMapping
 	var mapping PlexTMDBMapping
 	err := m.db.QueryRow(query, plexGUID).Scan(
 		&mapping.ID, &mapping.PlexGUID, &mapping.TMDBID, &mapping.Title,
-		&mapping.Year, &mapping.RatingKey, &mapping.CreatedAt, &mapping.UpdatedAt,
+		&mapping.Year, &mapping.RatingKey, &mapping.Override, &mapping.CreatedAt, &mapping.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &mapping, nil
+}
+
+// GetMappingByID gets a mapping by its id
+func (m *PlexTMDBMapper) GetMappingByID(id int) (*PlexTMDBMapping, error) {
+	query := `
+		SELECT id, plex_guid, tmdb_id, title, year, plex_rating_key, is_override, created_at, updated_at
+		FROM plex_tmdb_mappings 
+		WHERE id = ?
+	`
+
+	var mapping PlexTMDBMapping
+	err := m.db.QueryRow(query, id).Scan(
+		&mapping.ID, &mapping.PlexGUID, &mapping.TMDBID, &mapping.Title,
+		&mapping.Year, &mapping.RatingKey, &mapping.Override, &mapping.CreatedAt, &mapping.UpdatedAt,
 	)
 
 	if err != nil {
@@ -292,10 +312,155 @@ func (m *PlexTMDBMapper) GetMappingByPlexGUID(plexGUID string) (*PlexTMDBMapping
 	return &mapping, nil
 }
 
+// UserCanAccessGUID reports whether the user has access to a library with a Plex item of
+// the GUID, i.e. whether the mapping for it affects what they see
+func (m *PlexTMDBMapper) UserCanAccessGUID(userID int, plexGUID string) (bool, error) {
+	var exists bool
+	err := m.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM plex_library_items pli
+			JOIN user_plex_access upa ON pli.library_id = upa.library_id
+			WHERE upa.user_id = ? AND upa.is_active = 1 AND pli.plex_guid = ?
+		)
+	`, userID, plexGUID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check access to Plex item: %w", err)
+	}
+	return exists, nil
+}
+
+// OverrideMapping points a mapping at another TMDB movie, which must be in the movies table,
+// and marks it as overridden so syncs keep it. The Plex movies with its GUID are matched to
+// the new movie right away. Returns sql.ErrNoRows when there is no such mapping.
+func (m *PlexTMDBMapper) OverrideMapping(id, tmdbID int) (*PlexTMDBMapping, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var mapping PlexTMDBMapping
+	err = tx.QueryRow(`
+		UPDATE plex_tmdb_mappings
+		SET tmdb_id = ?, is_override = 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+		RETURNING id, plex_guid, tmdb_id, title, year, plex_rating_key, is_override, created_at, updated_at
+	`, tmdbID, id).Scan(
+		&mapping.ID, &mapping.PlexGUID, &mapping.TMDBID, &mapping.Title,
+		&mapping.Year, &mapping.RatingKey, &mapping.Override, &mapping.CreatedAt, &mapping.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := matchItemsByGUID(tx, mapping.PlexGUID, tmdbID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &mapping, nil
+}
+
+// OverrideGUID maps a Plex GUID to a TMDB movie by hand, which must be in the movies table,
+// creating the mapping when the GUID has none yet. Like OverrideMapping, the Plex movies
+// with the GUID are matched right away and syncs keep the mapping.
+func (m *PlexTMDBMapper) OverrideGUID(plexGUID, title string, year *int, ratingKey string, tmdbID int) (*PlexTMDBMapping, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var mapping PlexTMDBMapping
+	err = tx.QueryRow(`
+		INSERT INTO plex_tmdb_mappings (plex_guid, tmdb_id, title, year, plex_rating_key, is_override)
+		VALUES (?, ?, ?, ?, ?, 1)
+		ON CONFLICT (plex_guid) DO UPDATE SET
+			tmdb_id = excluded.tmdb_id,
+			is_override = 1,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, plex_guid, tmdb_id, title, year, plex_rating_key, is_override, created_at, updated_at
+	`, plexGUID, tmdbID, title, year, ratingKey).Scan(
+		&mapping.ID, &mapping.PlexGUID, &mapping.TMDBID, &mapping.Title,
+		&mapping.Year, &mapping.RatingKey, &mapping.Override, &mapping.CreatedAt, &mapping.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save mapping: %w", err)
+	}
+
+	if err := matchItemsByGUID(tx, plexGUID, tmdbID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &mapping, nil
+}
+
+// matchItemsByGUID matches the Plex movies with a GUID to a TMDB movie
+func matchItemsByGUID(tx *sql.Tx, plexGUID string, tmdbID int) error {
+	_, err := tx.Exec(`
+		UPDATE plex_library_items
+		SET tmdb_id = ?, last_matched_at = CURRENT_TIMESTAMP, matching_attempts = 0
+		WHERE plex_guid = ? AND type = 'movie'
+	`, tmdbID, plexGUID)
+	if err != nil {
+		return fmt.Errorf("failed to update Plex items: %w", err)
+	}
+	return nil
+}
+
+// DeleteMapping removes a mapping, overridden or not, and clears the match of the Plex movies
+// with its GUID so the next sync matches them again. Returns sql.ErrNoRows when there is no
+// such mapping.
+func (m *PlexTMDBMapper) DeleteMapping(id int) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var plexGUID string
+	err = tx.QueryRow("DELETE FROM plex_tmdb_mappings WHERE id = ? RETURNING plex_guid", id).Scan(&plexGUID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE plex_library_items
+		SET tmdb_id = NULL, last_matched_at = NULL, matching_attempts = 0
+		WHERE plex_guid = ? AND type = 'movie'
+	`, plexGUID)
+	if err != nil {
+		return fmt.Errorf("failed to clear Plex item matches: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// overriddenTMDBID returns the TMDB id a user mapped the Plex GUID to by hand, if any
+func overriddenTMDBID(db *sql.DB, plexGUID string) (int, bool) {
+	var tmdbID int
+	err := db.QueryRow(
+		"SELECT tmdb_id FROM plex_tmdb_mappings WHERE plex_guid = ? AND is_override = 1",
+		plexGUID,
+	).Scan(&tmdbID)
+	if err != nil {
+		return 0, false
+	}
+	return tmdbID, true
+}
+
 // SearchMappingsByTitle searches mappings by title (fuzzy)
 func (m *PlexTMDBMapper) SearchMappingsByTitle(title string) ([]*PlexTMDBMapping, error) {
 	query := `
-		SELECT id, plex_guid, tmdb_id, title, year, plex_rating_key, created_at, updated_at
+		SELECT id, plex_guid, tmdb_id, title, year, plex_rating_key, is_override, created_at, updated_at
 		FROM plex_tmdb_mappings 
 		WHERE title LIKE ? 
 		ORDER BY title
@@ -314,7 +479,7 @@ func (m *PlexTMDBMapper) SearchMappingsByTitle(title string) ([]*PlexTMDBMapping
 		var mapping PlexTMDBMapping
 		err := rows.Scan(
 			&mapping.ID, &mapping.PlexGUID, &mapping.TMDBID, &mapping.Title,
-			&mapping.Year, &mapping.RatingKey, &mapping.CreatedAt, &mapping.UpdatedAt,
+			&mapping.Year, &mapping.RatingKey, &mapping.Override, &mapping.CreatedAt, &mapping.UpdatedAt,
 		)
 		if err != nil {
 			continue
@@ -336,7 +501,7 @@ func (m *PlexTMDBMapper) GetAllMappings(limit, offset int) ([]*PlexTMDBMapping,
 
 	// Get mappings
 	query := `
-		SELECT id, plex_guid, tmdb_id, title, year, plex_rating_key, created_at, updated_at
+		SELECT id, plex_guid, tmdb_id, title, year, plex_rating_key, is_override, created_at, updated_at
 		FROM plex_tmdb_mappings 
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
@@ -353,7 +518,7 @@ func (m *PlexTMDBMapper) GetAllMappings(limit, offset int) ([]*PlexTMDBMapping,
 		var mapping PlexTMDBMapping
 		err := rows.Scan(
 			&mapping.ID, &mapping.PlexGUID, &mapping.TMDBID, &mapping.Title,
-			&mapping.Year, &mapping.RatingKey, &mapping.CreatedAt, &mapping.UpdatedAt,
+			&mapping.Year, &mapping.RatingKey, &mapping.Override, &mapping.CreatedAt, &mapping.UpdatedAt,
 		)
 		if err != nil {
 			continue
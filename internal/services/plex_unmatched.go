@@ -0,0 +1,95 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrItemNotFound is returned when a Plex item doesn't exist or the user has no access to it
+var ErrItemNotFound = errors.New("item not found")
+
+// UnmatchedItem is a Plex movie in one of the user's libraries that matching couldn't link
+// to a TMDB movie
+type UnmatchedItem struct {
+	ID            int64      `json:"id"`
+	Title         string     `json:"title"`
+	Year          *int       `json:"year,omitempty"`
+	PlexGUID      string     `json:"plex_guid"`
+	Attempts      int        `json:"attempts"`
+	LastMatchedAt *time.Time `json:"last_matched_at,omitempty"`
+	LibraryTitle  string     `json:"library_title"`
+	ServerName    string     `json:"server_name"`
+}
+
+// UnmatchedItems returns a page of the user's active, unmatched Plex movies, those tried the
+// most first, and how many there are in all
+func (s *PlexSyncService) UnmatchedItems(userID int64, limit, offset int) ([]UnmatchedItem, int, error) {
+	const unmatched = `
+		FROM plex_library_items pli
+		JOIN plex_libraries pl ON pli.library_id = pl.id
+		JOIN plex_servers ps ON pl.server_id = ps.id
+		JOIN user_plex_access upa ON pl.id = upa.library_id
+		WHERE upa.user_id = ? AND upa.is_active = 1 AND pli.is_active = 1
+		AND pli.type = 'movie' AND pli.tmdb_id IS NULL
+	`
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) `+unmatched, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count unmatched items: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT pli.id, pli.title, pli.year, pli.plex_guid, COALESCE(pli.matching_attempts, 0),
+			   pli.last_matched_at, pl.title, ps.name
+		`+unmatched+`
+		ORDER BY COALESCE(pli.matching_attempts, 0) DESC, pli.title, pli.id
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query unmatched items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []UnmatchedItem{}
+	for rows.Next() {
+		var item UnmatchedItem
+		err := rows.Scan(&item.ID, &item.Title, &item.Year, &item.PlexGUID, &item.Attempts,
+			&item.LastMatchedAt, &item.LibraryTitle, &item.ServerName)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan unmatched item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, total, rows.Err()
+}
+
+// MatchItemManually links one of the user's Plex movies to the TMDB movie they picked. The
+// choice is stored as an overridden mapping for the item's GUID, so every copy of the movie
+// is matched and later syncs and rematches keep it. Returns ErrItemNotFound when the user
+// has no such movie.
+func (s *PlexSyncService) MatchItemManually(userID, itemID int64, tmdbID int) (*PlexTMDBMapping, error) {
+	var plexGUID, title, ratingKey string
+	var year *int
+	err := s.db.QueryRow(`
+		SELECT pli.plex_guid, pli.title, pli.year, pli.plex_rating_key
+		FROM plex_library_items pli
+		JOIN user_plex_access upa ON pli.library_id = upa.library_id
+		WHERE pli.id = ? AND upa.user_id = ? AND upa.is_active = 1 AND pli.type = 'movie'
+	`, itemID, userID).Scan(&plexGUID, &title, &year, &ratingKey)
+	if err == sql.ErrNoRows {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	// The mapped movie has to be in the movies table
+	if _, err := EnsureMovieCached(s.db, s.tmdbClient, tmdbID); err != nil {
+		return nil, err
+	}
+
+	return NewPlexTMDBMapper(s.db, s.tmdbClient).OverrideGUID(plexGUID, title, year, ratingKey, tmdbID)
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// userLibraryItem is a library movie with the syncing user's view state
+type userLibraryItem struct {
+	libraryID int64
+	item      PlexSearchResult
+}
+
+// watchedItems picks the items of a synced library that the token owner has played
+func watchedItems(libraryID int64, items []PlexSearchResult) []userLibraryItem {
+	var watched []userLibraryItem
+	for _, item := range items {
+		if item.ViewCount > 0 && item.LastViewedAt != nil && item.GUID != "" {
+			watched = append(watched, userLibraryItem{libraryID: libraryID, item: item})
+		}
+	}
+	return watched
+}
+
+// recordWatchHistory records a play for each watched movie last viewed after the user's latest
+// recorded play of it, and marks matched movies as watched in user_movies. The first sync
+// records each movie's most recent play.
+func (s *PlexSyncService) recordWatchHistory(userID int64, watched []userLibraryItem) error {
+	// Plex keeps view state per server; a movie on several servers counts by its latest play
+	latest := make(map[string]userLibraryItem)
+	var order []string
+	for _, w := range watched {
+		current, ok := latest[w.item.GUID]
+		if !ok {
+			order = append(order, w.item.GUID)
+		}
+		if !ok || w.item.LastViewedAt.After(*current.item.LastViewedAt) {
+			latest[w.item.GUID] = w
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Earlier plays of items that have been matched since get their TMDB id and count as watched
+	now := time.Now()
+	rows, err := tx.Query(`
+		SELECT h.id, pli.tmdb_id, h.watched_at
+		FROM plex_watch_history h
+		JOIN plex_library_items pli ON pli.id = h.library_item_id
+		WHERE h.user_id = ? AND h.tmdb_id IS NULL AND pli.tmdb_id IS NOT NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find newly matched plays: %w", err)
+	}
+	type matchedPlay struct {
+		id        int64
+		tmdbID    int64
+		watchedAt time.Time
+	}
+	var matched []matchedPlay
+	for rows.Next() {
+		var play matchedPlay
+		if err := rows.Scan(&play.id, &play.tmdbID, &play.watchedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read newly matched play: %w", err)
+		}
+		matched = append(matched, play)
+	}
+	rows.Close()
+
+	for _, play := range matched {
+		if _, err := tx.Exec("UPDATE plex_watch_history SET tmdb_id = ? WHERE id = ?", play.tmdbID, play.id); err != nil {
+			return fmt.Errorf("failed to update watch history match: %w", err)
+		}
+		if err := markWatchedFromPlex(tx, userID, play.tmdbID, play.watchedAt, now); err != nil {
+			return err
+		}
+	}
+
+	recorded := 0
+	for _, guid := range order {
+		w := latest[guid]
+		watchedAt := *w.item.LastViewedAt
+
+		var lastWatchedAt time.Time
+		err := tx.QueryRow(`
+			SELECT watched_at FROM plex_watch_history
+			WHERE user_id = ? AND plex_guid = ?
+			ORDER BY watched_at DESC LIMIT 1
+		`, userID, guid).Scan(&lastWatchedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get last play of %s: %w", w.item.Title, err)
+		}
+		if err == nil && !watchedAt.After(lastWatchedAt) {
+			continue
+		}
+
+		var itemID sql.NullInt64
+		var tmdbID sql.NullInt64
+		err = tx.QueryRow(`
+			SELECT id, tmdb_id FROM plex_library_items WHERE library_id = ? AND plex_rating_key = ?
+		`, w.libraryID, w.item.RatingKey).Scan(&itemID, &tmdbID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to look up library item %s: %w", w.item.Title, err)
+		}
+
+		if err := recordPlay(tx, userID, itemID, tmdbID, guid, w.item.Title, w.item.Year, w.item.ViewCount, watchedAt, now); err != nil {
+			return err
+		}
+		recorded++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit watch history: %w", err)
+	}
+
+	s.logger.Debug("Recorded new Plex plays", "user_id", userID, "count", recorded)
+	return nil
+}
+
+// markWatchedFromPlex marks a movie finished on Plex as watched, keeping a watched date the
+// user already set
+func markWatchedFromPlex(tx *sql.Tx, userID, tmdbID int64, watchedAt, now time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO user_movies (user_id, movie_id, status, watched_date, created_at, updated_at)
+		SELECT ?, m.id, 'watched', ?, ?, ? FROM movies m WHERE m.tmdb_id = ?
+		ON CONFLICT(user_id, movie_id) DO UPDATE SET
+			status = 'watched',
+			watched_date = CASE
+				WHEN user_movies.status = 'watched' AND user_movies.watched_date IS NOT NULL THEN user_movies.watched_date
+				ELSE excluded.watched_date
+			END,
+			updated_at = excluded.updated_at
+	`, userID, watchedAt, now, now, tmdbID)
+	if err != nil {
+		return fmt.Errorf("failed to mark movie %d watched: %w", tmdbID, err)
+	}
+	return nil
+}
+
+// RecordPlexScrobble records a play Plex reported as finished (e.g. through a webhook), marks
+// the movie watched when it has been matched and clears its progress
+func RecordPlexScrobble(db *sql.DB, userID int64, plexGUID, title string, year *int, viewCount int, watchedAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Prefer a matched copy when the movie is in several libraries
+	var itemID, tmdbID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT id, tmdb_id FROM plex_library_items
+		WHERE plex_guid = ?
+		ORDER BY tmdb_id IS NULL
+		LIMIT 1
+	`, plexGUID).Scan(&itemID, &tmdbID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up library item %s: %w", title, err)
+	}
+
+	if err := recordPlay(tx, userID, itemID, tmdbID, plexGUID, title, year, viewCount, watchedAt, time.Now()); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_movie_progress WHERE user_id = ? AND plex_guid = ?", userID, plexGUID); err != nil {
+		return fmt.Errorf("failed to clear progress of %s: %w", title, err)
+	}
+
+	return tx.Commit()
+}
+
+// recordPlay inserts a play into the watch history and marks the movie watched when it has
+// been matched. A play already recorded for the same time is ignored.
+func recordPlay(tx *sql.Tx, userID int64, itemID, tmdbID sql.NullInt64, plexGUID, title string, year *int, viewCount int, watchedAt, now time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO plex_watch_history (user_id, library_item_id, plex_guid, title, year, tmdb_id, view_count, watched_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, plex_guid, watched_at) DO NOTHING
+	`, userID, itemID, plexGUID, title, year, tmdbID, viewCount, watchedAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to record play of %s: %w", title, err)
+	}
+
+	if !tmdbID.Valid {
+		return nil
+	}
+	return markWatchedFromPlex(tx, userID, tmdbID.Int64, watchedAt, now)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Plex webhook events that update playback state
+const (
+	PlexEventPlay     = "media.play"
+	PlexEventPause    = "media.pause"
+	PlexEventResume   = "media.resume"
+	PlexEventStop     = "media.stop"
+	PlexEventScrobble = "media.scrobble" // Sent once an item is more than 90% played
+)
+
+// PlexWebhookPayload is the JSON "payload" part of a Plex webhook request
+type PlexWebhookPayload struct {
+	Event    string              `json:"event"`
+	Account  PlexWebhookAccount  `json:"Account"`
+	Server   PlexWebhookServer   `json:"Server"`
+	Metadata PlexWebhookMetadata `json:"Metadata"`
+}
+
+// PlexWebhookAccount is the Plex account whose playback triggered the event
+type PlexWebhookAccount struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// PlexWebhookServer is the server the playback happened on
+type PlexWebhookServer struct {
+	Title string `json:"title"`
+	UUID  string `json:"uuid"`
+}
+
+// PlexWebhookMetadata is the item being played
+type PlexWebhookMetadata struct {
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	Year         *int   `json:"year"`
+	GUID         string `json:"guid"`
+	RatingKey    string `json:"ratingKey"`
+	ViewOffset   int    `json:"viewOffset"` // Milliseconds
+	Duration     int    `json:"duration"`   // Milliseconds
+	ViewCount    int    `json:"viewCount"`
+	LastViewedAt int64  `json:"lastViewedAt"` // Unix seconds
+}
+
+// HandleWebhook applies a Plex webhook event to a user's now-playing, progress and watch
+// history. Events for other accounts on the user's server and for anything but movies are
+// ignored; it reports whether the event was applied.
+func (s *NowPlayingService) HandleWebhook(userID int64, payload *PlexWebhookPayload) (bool, error) {
+	metadata := payload.Metadata
+	if metadata.Type != "movie" || metadata.GUID == "" {
+		return false, nil
+	}
+
+	// A server owner's webhook fires for everyone playing on their server
+	accountNames, err := s.plexAccountNames(userID)
+	if err != nil {
+		return false, err
+	}
+	if !accountNames[strings.ToLower(payload.Account.Title)] {
+		return false, nil
+	}
+
+	switch payload.Event {
+	case PlexEventPlay, PlexEventPause, PlexEventResume, PlexEventStop:
+		s.forget(userID)
+		if metadata.ViewOffset > 0 {
+			if err := SaveProgress(s.db, userID, metadata.GUID, metadata.ViewOffset, metadata.Duration); err != nil {
+				return false, err
+			}
+		}
+
+	case PlexEventScrobble:
+		s.forget(userID)
+
+		// Plex's own timestamp lets later syncs recognize the play as already recorded
+		watchedAt := time.Now().UTC().Truncate(time.Second)
+		if metadata.LastViewedAt > 0 {
+			watchedAt = time.Unix(metadata.LastViewedAt, 0).UTC()
+		}
+		viewCount := max(metadata.ViewCount, 1)
+
+		if err := RecordPlexScrobble(s.db, userID, metadata.GUID, metadata.Title, metadata.Year, viewCount, watchedAt); err != nil {
+			return false, fmt.Errorf("failed to record scrobble: %w", err)
+		}
+
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// forget drops a user's cached sessions so the next lookup reflects the event
+func (s *NowPlayingService) forget(userID int64) {
+	s.mu.Lock()
+	delete(s.cache, userID)
+	s.mu.Unlock()
+}
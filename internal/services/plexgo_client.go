@@ -3,8 +3,13 @@ package services
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/LukeHagar/plexgo"
 	"github.com/LukeHagar/plexgo/models/operations"
@@ -16,6 +21,41 @@ type PlexgoClient struct {
 	product  string
 	version  string
 	device   string
+
+	// avoidRelay deprioritizes relay connections, which are slow and bandwidth-limited
+	avoidRelay bool
+
+	// Library enumeration paging
+	pageSize        int
+	pageConcurrency int
+
+	latencyMu    sync.Mutex
+	latencyCache map[string]latencyProbe // Keyed by connection URL
+
+	logger *slog.Logger
+}
+
+// Connection types recorded in sync stats and library history
+const (
+	ConnectionTypeLocal  = "local"
+	ConnectionTypeRemote = "remote"
+	ConnectionTypeRelay  = "relay"
+)
+
+const (
+	connectionProbeTimeout    = 5 * time.Second
+	connectionProbeTTL        = time.Hour
+	connectionProbeFailureTTL = 30 * time.Second // A connection that was down may be back soon
+
+	defaultLibraryPageSize        = 100
+	defaultLibraryPageConcurrency = 3
+)
+
+// latencyProbe is a cached connection latency measurement
+type latencyProbe struct {
+	latency   time.Duration
+	err       error
+	checkedAt time.Time
 }
 
 // PlexServer represents a Plex server with connection info
@@ -44,17 +84,18 @@ type PlexConnection struct {
 
 // PlexLibrary represents a Plex library section
 type PlexLibrary struct {
-	ID          int64  // Database ID after storage
-	Key         int    // Plex section key
-	Title       string
-	Type        string
-	Agent       string
-	Scanner     string
-	Language    string
-	UUID        string
-	ServerID    int64  // Database server ID
-	ServerURL   string // Server URL for API calls
-	AccessToken string // Server-specific access token for API calls
+	ID             int64 // Database ID after storage
+	Key            int   // Plex section key
+	Title          string
+	Type           string
+	Agent          string
+	Scanner        string
+	Language       string
+	UUID           string
+	ServerID       int64  // Database server ID
+	ServerURL      string // Server URL for API calls
+	AccessToken    string // Server-specific access token for API calls
+	ConnectionType string // local, remote or relay
 }
 
 // PlexSearchResult represents a search result
@@ -63,20 +104,56 @@ type PlexSearchResult struct {
 	Year      *int
 	Type      string
 	GUID      string
-	RatingKey string // The numeric rating key from Plex API
+	RatingKey string     // The numeric rating key from Plex API
+	UpdatedAt *time.Time // When Plex last changed the item's metadata
+
+	// The token owner's view state; not stored in the shared item metadata
+	ViewCount    int        `json:"-"`
+	LastViewedAt *time.Time `json:"-"`
+	ViewOffset   int        `json:"-"` // Milliseconds into a partial play
+	Duration     int        `json:"-"` // Milliseconds
 }
 
 func NewPlexgoClient() *PlexgoClient {
 	return &PlexgoClient{
-		clientID: "moviedb-app",
-		product:  "MovieDB",
-		version:  "1.0.0",
-		device:   "Web",
+		clientID:        "moviedb-app",
+		product:         "MovieDB",
+		version:         "1.0.0",
+		device:          "Web",
+		avoidRelay:      true,
+		pageSize:        defaultLibraryPageSize,
+		pageConcurrency: defaultLibraryPageConcurrency,
+		latencyCache:    make(map[string]latencyProbe),
+		logger:          slog.Default(),
+	}
+}
+
+// SetLogger replaces the logger, which defaults to slog's default logger at construction
+func (p *PlexgoClient) SetLogger(logger *slog.Logger) {
+	p.logger = logger
+}
+
+// SetLibraryPaging configures the page size and how many pages are fetched concurrently
+// when enumerating a library. Keep concurrency small to avoid overloading Plex servers.
+func (p *PlexgoClient) SetLibraryPaging(pageSize, concurrency int) {
+	if pageSize > 0 {
+		p.pageSize = pageSize
+	}
+	if concurrency > 0 {
+		p.pageConcurrency = concurrency
 	}
 }
 
+// SetAvoidRelay controls whether relay connections are only used when nothing else responds
+func (p *PlexgoClient) SetAvoidRelay(avoid bool) {
+	p.avoidRelay = avoid
+}
+
 // GetServers gets all servers accessible to the user (automatically filtered by permissions)
 func (p *PlexgoClient) GetServers(ctx context.Context, token string) ([]PlexServer, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
 	client := plexgo.New(
 		plexgo.WithSecurity(token),
 	)
@@ -129,12 +206,55 @@ func (p *PlexgoClient) GetServers(ctx context.Context, token string) ([]PlexServ
 		}
 	}
 
-	fmt.Printf("DEBUG: [GetServers] Retrieved %d accessible servers using plexgo\n", len(servers))
+	sortServers(servers)
+
+	p.logger.Debug("Retrieved accessible Plex servers", "servers", len(servers))
 	return servers, nil
 }
 
+// sortServers orders servers deterministically (owned first, then by name) and each
+// server's connections by preference, so sync behaviour doesn't depend on Plex.tv's ordering
+func sortServers(servers []PlexServer) {
+	sort.SliceStable(servers, func(i, j int) bool {
+		if servers[i].Owned != servers[j].Owned {
+			return servers[i].Owned
+		}
+		if servers[i].Name != servers[j].Name {
+			return servers[i].Name < servers[j].Name
+		}
+		return servers[i].MachineID < servers[j].MachineID
+	})
+
+	for _, server := range servers {
+		conns := server.Connections
+		sort.SliceStable(conns, func(i, j int) bool {
+			ri, rj := connectionRank(conns[i]), connectionRank(conns[j])
+			if ri != rj {
+				return ri < rj
+			}
+			return conns[i].URI < conns[j].URI
+		})
+	}
+}
+
+// connectionRank orders connections by preference: local, which is fastest when reachable,
+// then remote direct, then relay
+func connectionRank(conn PlexConnection) int {
+	switch ConnectionType(conn) {
+	case ConnectionTypeLocal:
+		return 0
+	case ConnectionTypeRemote:
+		return 1
+	default:
+		return 2
+	}
+}
+
 // GetLibraries gets all libraries from a server (automatically filtered by user permissions)
 func (p *PlexgoClient) GetLibraries(ctx context.Context, token, serverURL string) ([]PlexLibrary, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
 	client := plexgo.New(
 		plexgo.WithSecurity(token),
 		plexgo.WithServerURL(serverURL),
@@ -172,6 +292,9 @@ func (p *PlexgoClient) GetLibraries(ctx context.Context, token, serverURL string
 
 // SearchAllLibraries searches across all accessible libraries for a query
 func (p *PlexgoClient) SearchAllLibraries(ctx context.Context, token, serverURL, query string) ([]PlexSearchResult, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
 	client := plexgo.New(
 		plexgo.WithSecurity(token),
 		plexgo.WithServerURL(serverURL),
@@ -189,7 +312,7 @@ func (p *PlexgoClient) SearchAllLibraries(ctx context.Context, token, serverURL,
 	
 	if res.Object != nil {
 		mediaContainer := res.Object.MediaContainer
-		fmt.Printf("DEBUG: [SearchAllLibraries] Found %d search results for query '%s'\n", len(mediaContainer.SearchResult), query)
+		p.logger.Debug("Plex library search returned results", "query", query, "results", len(mediaContainer.SearchResult))
 		
 		for _, searchResult := range mediaContainer.SearchResult {
 			// Check if this is a metadata result with a movie
@@ -210,18 +333,100 @@ func (p *PlexgoClient) SearchAllLibraries(ctx context.Context, token, serverURL,
 					}
 					
 					results = append(results, result)
-					fmt.Printf("DEBUG: [SearchAllLibraries] Found movie: '%s'\n", result.Title)
+					p.logger.Debug("Plex library search found movie", "query", query, "title", result.Title)
 				}
 			}
 		}
 	}
 
-	fmt.Printf("DEBUG: [SearchAllLibraries] Returning %d movie results for query '%s'\n", len(results), query)
+	p.logger.Debug("Plex library search done", "query", query, "movies", len(results))
 	return results, nil
 }
 
+// PlexSession is an item currently being played on a Plex server
+type PlexSession struct {
+	Type             string // movie or episode
+	Title            string
+	GUID             string
+	RatingKey        string
+	GrandparentTitle string // Show title for episodes
+	GrandparentGUID  string
+	SeasonNumber     *int
+	EpisodeNumber    *int
+	ViewOffset       int    // Milliseconds
+	Duration         int    // Milliseconds
+	State            string // playing, paused or buffering
+	Player           string
+	UserTitle        string // Plex account watching it
+}
+
+// GetSessions gets the playback sessions on a server. With the owner's token this includes
+// every user's sessions; servers shared with the user may refuse the request.
+func (p *PlexgoClient) GetSessions(ctx context.Context, token, serverURL string) ([]PlexSession, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
+	client := plexgo.New(
+		plexgo.WithSecurity(token),
+		plexgo.WithServerURL(serverURL),
+	)
+
+	res, err := client.Sessions.GetSessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	var sessions []PlexSession
+	if res.Object == nil || res.Object.MediaContainer == nil {
+		return sessions, nil
+	}
+
+	for _, metadata := range res.Object.MediaContainer.Metadata {
+		sessionType := getStringValue(metadata.Type)
+		if sessionType != "movie" && sessionType != "episode" {
+			continue // Music, photos, clips
+		}
+
+		session := PlexSession{
+			Type:             sessionType,
+			Title:            getStringValue(metadata.Title),
+			GUID:             getStringValue(metadata.GUID),
+			RatingKey:        getStringValue(metadata.RatingKey),
+			GrandparentTitle: getStringValue(metadata.GrandparentTitle),
+			GrandparentGUID:  getStringValue(metadata.GrandparentGUID),
+		}
+		if sessionType == "episode" {
+			session.SeasonNumber = metadata.ParentIndex
+			session.EpisodeNumber = metadata.Index
+		}
+		if metadata.ViewOffset != nil {
+			session.ViewOffset = *metadata.ViewOffset
+		}
+		if metadata.Duration != nil {
+			session.Duration = *metadata.Duration
+		}
+		if metadata.Player != nil {
+			session.State = getStringValue(metadata.Player.State)
+			session.Player = getStringValue(metadata.Player.Title)
+			if session.Player == "" {
+				session.Player = getStringValue(metadata.Player.Product)
+			}
+		}
+		if metadata.User != nil {
+			session.UserTitle = getStringValue(metadata.User.Title)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
 // PerformGlobalSearch performs a global search across the server
 func (p *PlexgoClient) PerformGlobalSearch(ctx context.Context, token, serverURL, query string) ([]PlexSearchResult, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexRequestTimeout)
+	defer cancel()
+
 	client := plexgo.New(
 		plexgo.WithSecurity(token),
 		plexgo.WithServerURL(serverURL),
@@ -236,12 +441,12 @@ func (p *PlexgoClient) PerformGlobalSearch(ctx context.Context, token, serverURL
 	
 	// PerformSearch appears to not return structured data in the response object
 	// The response may be in the raw HTTP response body
-	fmt.Printf("DEBUG: [PerformGlobalSearch] PerformSearch completed with status %d for query '%s'\n", res.StatusCode, query)
+	p.logger.Debug("Plex global search completed", "query", query, "status", res.StatusCode)
 	
 	// For now, return empty results as this method may need raw response parsing
 	// or we should prefer SearchAllLibraries method which has structured responses
 
-	fmt.Printf("DEBUG: [PerformGlobalSearch] Returning %d movie results for query '%s'\n", len(results), query)
+	p.logger.Debug("Plex global search done", "query", query, "movies", len(results))
 	return results, nil
 }
 
@@ -253,102 +458,183 @@ func (p *PlexgoClient) GetMoviesInLibrary(ctx context.Context, token, serverURL
 	)
 
 	// Try GetLibrarySectionsAll first - this works better for shared users
-	fmt.Printf("DEBUG: [GetMoviesInLibrary] Trying GetLibrarySectionsAll for library %d with pagination\n", libraryKey)
-	
-	var results []PlexSearchResult
-	pageSize := 100  // Increase page size for better performance
-	start := 0
-	
-	for {
-		sectionsReq := operations.GetLibrarySectionsAllRequest{
-			SectionKey: libraryKey,
-			Type:       operations.GetLibrarySectionsAllQueryParamTypeMovie,
-			XPlexContainerStart: &start,
-			XPlexContainerSize:  &pageSize,
-		}
-		
-		sectionsRes, err := client.Library.GetLibrarySectionsAll(ctx, sectionsReq)
-		if err != nil {
-			fmt.Printf("DEBUG: [GetMoviesInLibrary] GetLibrarySectionsAll failed: %v, trying GetLibraryItems\n", err)
-			// Fallback to GetLibraryItems
-			return p.getMoviesViaLibraryItems(ctx, client, libraryKey)
-		}
+	p.logger.Debug("Listing Plex library movies with pagination", "library", libraryKey)
 
-		pageResults := 0
-		if sectionsRes.Object != nil && sectionsRes.Object.MediaContainer != nil {
-			mediaContainer := sectionsRes.Object.MediaContainer
-			fmt.Printf("DEBUG: [GetMoviesInLibrary] GetLibrarySectionsAll page (start=%d, size=%d) found %d items in library %d\n", 
-				start, pageSize, len(mediaContainer.Metadata), libraryKey)
-			
-			for i, metadata := range mediaContainer.Metadata {
-				// Only include movies (type 1 = movie) - using string comparison as type is complex
-				if string(metadata.Type) == "1" || string(metadata.Type) == "movie" {
-					result := PlexSearchResult{
-						Title:     metadata.Title,
-						Type:      "movie",
-						GUID:      metadata.GUID,
-						RatingKey: metadata.RatingKey,
-					}
-					
-					// Convert year if available
-					if metadata.Year != nil {
-						result.Year = metadata.Year
-					}
-					
-					results = append(results, result)
-					pageResults++
-					if i < 3 { // Only show first 3 items per page for debugging
-						fmt.Printf("DEBUG: [GetMoviesInLibrary] Found movie: '%s'\n", result.Title)
-					}
-				}
-			}
-			
-			// Check if we got fewer items than requested - indicates last page
-			if len(mediaContainer.Metadata) < pageSize {
-				fmt.Printf("DEBUG: [GetMoviesInLibrary] Reached last page (got %d items, expected %d)\n", 
-					len(mediaContainer.Metadata), pageSize)
-				break
-			}
-		} else {
-			fmt.Printf("DEBUG: [GetMoviesInLibrary] No MediaContainer found in GetLibrarySectionsAll response\n")
-			break
-		}
-		
-		// If no movies found on this page, we're done
-		if pageResults == 0 {
-			fmt.Printf("DEBUG: [GetMoviesInLibrary] No movies found on this page, stopping pagination\n")
-			break
+	results, err := p.fetchAllLibraryPages(ctx, client, libraryKey, "movie")
+	if err != nil {
+		// Don't fall back when the caller cancelled or the job is shutting down
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
-		
-		// Move to next page
-		start += pageSize
-		fmt.Printf("DEBUG: [GetMoviesInLibrary] Moving to next page (start=%d), found %d movies so far\n", start, len(results))
+		p.logger.Debug("Paginated Plex library listing failed, trying library items", "library", libraryKey, "error", err)
+		return p.getMoviesViaLibraryItems(ctx, client, libraryKey)
 	}
 
 	// If we got 0 results, try the old GetLibraryItems method
 	if len(results) == 0 {
-		fmt.Printf("DEBUG: [GetMoviesInLibrary] No items found via GetLibrarySectionsAll, trying GetLibraryItems\n")
+		p.logger.Debug("Paginated Plex library listing was empty, trying library items", "library", libraryKey)
 		libraryResults, err := p.getMoviesViaLibraryItems(ctx, client, libraryKey)
 		if err != nil || len(libraryResults) == 0 {
-			fmt.Printf("DEBUG: [GetMoviesInLibrary] GetLibraryItems also failed/empty, trying global search fallback\n")
+			p.logger.Debug("Plex library items failed or empty, trying global search", "library", libraryKey)
 			return p.getMoviesViaGlobalSearch(ctx, token, serverURL, libraryKey)
 		}
 		return libraryResults, nil
 	}
 
-	fmt.Printf("DEBUG: [GetMoviesInLibrary] Retrieved %d movies from library %d via GetLibrarySectionsAll\n", len(results), libraryKey)
+	p.logger.Debug("Listed Plex library movies", "library", libraryKey, "movies", len(results))
+	return results, nil
+}
+
+// GetShowsInLibrary gets all TV shows in a show library
+func (p *PlexgoClient) GetShowsInLibrary(ctx context.Context, token, serverURL string, libraryKey int) ([]PlexSearchResult, error) {
+	client := plexgo.New(
+		plexgo.WithSecurity(token),
+		plexgo.WithServerURL(serverURL),
+	)
+
+	results, err := p.fetchAllLibraryPages(ctx, client, libraryKey, "show")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shows in library %d: %w", libraryKey, err)
+	}
+
+	p.logger.Debug("Listed Plex library shows", "library", libraryKey, "shows", len(results))
+	return results, nil
+}
+
+// fetchAllLibraryPages pages through a library via GetLibrarySectionsAll. After the first page
+// reveals the library size, the remaining pages are fetched with bounded concurrency and
+// assembled in order.
+func (p *PlexgoClient) fetchAllLibraryPages(ctx context.Context, client *plexgo.PlexAPI, libraryKey int, mediaType string) ([]PlexSearchResult, error) {
+	pageSize := p.pageSize
+
+	// The first page tells us the library size so the rest can be fetched concurrently
+	results, itemCount, totalSize, err := p.fetchLibraryPage(ctx, client, libraryKey, mediaType, 0, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if itemCount == pageSize && totalSize > pageSize {
+		// Fetch the remaining pages with bounded concurrency, keeping them in order
+		pages := (totalSize - 1) / pageSize
+		pageResults := make([][]PlexSearchResult, pages)
+		pageErrors := make([]error, pages)
+		sem := make(chan struct{}, p.pageConcurrency)
+		var wg sync.WaitGroup
+
+		for i := 0; i < pages; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				pageResults[i], _, _, pageErrors[i] = p.fetchLibraryPage(ctx, client, libraryKey, mediaType, (i+1)*pageSize, pageSize)
+			}(i)
+		}
+		wg.Wait()
+
+		for i := range pageResults {
+			if pageErrors[i] != nil {
+				return nil, fmt.Errorf("page %d: %w", i+1, pageErrors[i])
+			}
+			results = append(results, pageResults[i]...)
+		}
+	} else if itemCount == pageSize && totalSize == 0 {
+		// Server didn't report a total - page sequentially until a short page
+		for start := pageSize; ; start += pageSize {
+			page, count, _, err := p.fetchLibraryPage(ctx, client, libraryKey, mediaType, start, pageSize)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, page...)
+			if count < pageSize || len(page) == 0 {
+				break
+			}
+		}
+	}
+
 	return results, nil
 }
 
+// fetchLibraryPage fetches one page of a library via GetLibrarySectionsAll. It returns the items
+// of mediaType ("movie" or "show") on the page, the number of items the page contained and the
+// library's total size (0 if unknown).
+func (p *PlexgoClient) fetchLibraryPage(ctx context.Context, client *plexgo.PlexAPI, libraryKey int, mediaType string, start, size int) ([]PlexSearchResult, int, int, error) {
+	queryType, typeNumber := operations.GetLibrarySectionsAllQueryParamTypeMovie, "1"
+	if mediaType == "show" {
+		queryType, typeNumber = operations.GetLibrarySectionsAllQueryParamTypeTvShow, "2"
+	}
+
+	sectionsReq := operations.GetLibrarySectionsAllRequest{
+		SectionKey:          libraryKey,
+		Type:                queryType,
+		XPlexContainerStart: &start,
+		XPlexContainerSize:  &size,
+	}
+
+	// Each page gets its own timeout so large libraries aren't cut off
+	pageCtx, cancel := withPlexTimeout(ctx, plexLibraryPageTimeout)
+	defer cancel()
+
+	sectionsRes, err := client.Library.GetLibrarySectionsAll(pageCtx, sectionsReq)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if sectionsRes.Object == nil || sectionsRes.Object.MediaContainer == nil {
+		p.logger.Debug("Plex library page had no media container", "library", libraryKey, "start", start)
+		return nil, 0, 0, nil
+	}
+
+	mediaContainer := sectionsRes.Object.MediaContainer
+	p.logger.Debug("Fetched Plex library page", "library", libraryKey, "start", start, "size", size,
+		"items", len(mediaContainer.Metadata), "total", mediaContainer.TotalSize)
+
+	var results []PlexSearchResult
+	for _, metadata := range mediaContainer.Metadata {
+		// Only include the requested type (1 = movie, 2 = show) - using string comparison as type is complex
+		if string(metadata.Type) == typeNumber || string(metadata.Type) == mediaType {
+			result := PlexSearchResult{
+				Title:     metadata.Title,
+				Year:      metadata.Year,
+				Type:      mediaType,
+				GUID:      metadata.GUID,
+				RatingKey: metadata.RatingKey,
+				Duration:  metadata.Duration,
+			}
+			if metadata.UpdatedAt != nil {
+				updatedAt := time.Unix(*metadata.UpdatedAt, 0).UTC()
+				result.UpdatedAt = &updatedAt
+			}
+			if metadata.ViewCount != nil {
+				result.ViewCount = *metadata.ViewCount
+			}
+			if metadata.LastViewedAt != nil {
+				lastViewedAt := time.Unix(int64(*metadata.LastViewedAt), 0).UTC()
+				result.LastViewedAt = &lastViewedAt
+			}
+			if metadata.ViewOffset != nil {
+				result.ViewOffset = *metadata.ViewOffset
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results, len(mediaContainer.Metadata), mediaContainer.TotalSize, nil
+}
+
 // getMoviesViaLibraryItems gets movies using the GetLibraryItems endpoint
 func (p *PlexgoClient) getMoviesViaLibraryItems(ctx context.Context, client *plexgo.PlexAPI, libraryKey int) ([]PlexSearchResult, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexLibraryPageTimeout)
+	defer cancel()
+
 	libraryReq := operations.GetLibraryItemsRequest{
 		SectionKey: libraryKey,
 		Tag:        operations.Tag("all"), // Cast to Tag type
 	}
 	res, err := client.Library.GetLibraryItems(ctx, libraryReq)
 	if err != nil {
-		fmt.Printf("DEBUG: [getMoviesViaLibraryItems] GetLibraryItems failed: %v\n", err)
+		p.logger.Debug("Plex library items request failed", "library", libraryKey, "error", err)
 		// Return the error - we'll handle global search fallback at a higher level
 		return nil, err
 	}
@@ -357,10 +643,10 @@ func (p *PlexgoClient) getMoviesViaLibraryItems(ctx context.Context, client *ple
 	
 	if res.Object != nil && res.Object.MediaContainer != nil {
 		mediaContainer := res.Object.MediaContainer
-		fmt.Printf("DEBUG: [getMoviesViaLibraryItems] Found %d items in library %d\n", len(mediaContainer.Metadata), libraryKey)
+		p.logger.Debug("Fetched Plex library items", "library", libraryKey, "items", len(mediaContainer.Metadata))
 		
 		for i, metadata := range mediaContainer.Metadata {
-			fmt.Printf("DEBUG: [getMoviesViaLibraryItems] Item %d: Title='%s', Type='%v', GUID='%s'\n", i, metadata.Title, metadata.Type, metadata.GUID)
+			p.logger.Debug("Plex library item", "index", i, "title", metadata.Title, "type", metadata.Type, "guid", metadata.GUID)
 			
 			// Only include movies (type 1 = movie)
 			if metadata.Type == operations.GetLibraryItemsTypeMovie {
@@ -375,28 +661,35 @@ func (p *PlexgoClient) getMoviesViaLibraryItems(ctx context.Context, client *ple
 				if metadata.Year != nil {
 					result.Year = metadata.Year
 				}
+				if metadata.UpdatedAt != nil {
+					updatedAt := time.Unix(*metadata.UpdatedAt, 0).UTC()
+					result.UpdatedAt = &updatedAt
+				}
 				
 				results = append(results, result)
-				fmt.Printf("DEBUG: [getMoviesViaLibraryItems] Found movie: '%s'\n", result.Title)
+				p.logger.Debug("Plex library item is a movie", "title", result.Title)
 			} else {
-				fmt.Printf("DEBUG: [getMoviesViaLibraryItems] Skipping non-movie item: '%s' (type: %v)\n", metadata.Title, metadata.Type)
+				p.logger.Debug("Skipping Plex library item that is not a movie", "title", metadata.Title, "type", metadata.Type)
 			}
 		}
 	} else {
-		fmt.Printf("DEBUG: [getMoviesViaLibraryItems] No MediaContainer found in response\n")
+		p.logger.Debug("Plex library items response had no media container", "library", libraryKey)
 	}
 
 	// If we got 0 results, that's fine - return empty results
 	if len(results) == 0 {
-		fmt.Printf("DEBUG: [getMoviesViaLibraryItems] No items found via direct access\n")
+		p.logger.Debug("No movies found in Plex library items", "library", libraryKey)
 	}
 
-	fmt.Printf("DEBUG: [getMoviesViaLibraryItems] Retrieved %d movies from library %d\n", len(results), libraryKey)
+	p.logger.Debug("Listed Plex library movies from library items", "library", libraryKey, "movies", len(results))
 	return results, nil
 }
 
 // getMoviesViaGlobalSearch gets movies using global search as fallback for shared users
 func (p *PlexgoClient) getMoviesViaGlobalSearch(ctx context.Context, token, serverURL string, libraryKey int) ([]PlexSearchResult, error) {
+	ctx, cancel := withPlexTimeout(ctx, plexLibraryPageTimeout)
+	defer cancel()
+
 	client := plexgo.New(
 		plexgo.WithSecurity(token),
 		plexgo.WithServerURL(serverURL),
@@ -414,16 +707,15 @@ func (p *PlexgoClient) getMoviesViaGlobalSearch(ctx context.Context, token, serv
 	// Note: The raw response shows movies are in the Hub structure, but plexgo
 	// doesn't seem to parse this correctly. For now, we'll log what we can
 	// and return empty results. This is a limitation of the current plexgo SDK.
-	fmt.Printf("DEBUG: [getMoviesViaGlobalSearch] Global search response: status=%d, type=%T\n", res.StatusCode, res)
+	p.logger.Debug("Plex global search fallback completed", "library", libraryKey, "status", res.StatusCode)
 	
 	if res.StatusCode == 200 {
 		// Based on the raw JSON response, we know movies are available
 		// but we can't parse them with the current plexgo SDK structure
-		fmt.Printf("DEBUG: [getMoviesViaGlobalSearch] Global search succeeded but cannot parse movie data with current SDK\n")
-		fmt.Printf("DEBUG: [getMoviesViaGlobalSearch] Raw response indicates movies are available for library %d\n", libraryKey)
+		p.logger.Debug("Plex global search succeeded but its movie data can't be parsed with the current SDK", "library", libraryKey)
 	}
 
-	fmt.Printf("DEBUG: [getMoviesViaGlobalSearch] Retrieved %d movies from global search for library %d\n", len(results), libraryKey)
+	p.logger.Debug("Listed Plex library movies from global search", "library", libraryKey, "movies", len(results))
 	return results, nil
 }
 
@@ -435,34 +727,169 @@ func (p *PlexgoClient) BuildServerURL(connection PlexConnection) string {
 	return fmt.Sprintf("%s://%s:%d", connection.Protocol, connection.Address, connection.Port)
 }
 
-// GetBestConnection returns the best connection for a server (prefer external, then local)
+// GetBestConnection returns a server's most preferred connection without probing it:
+// local, then remote, then relay, in the same order as sortServers
 func (p *PlexgoClient) GetBestConnection(server PlexServer) *PlexConnection {
 	var bestConn *PlexConnection
-	
-	// Prefer external connections first
-	for _, conn := range server.Connections {
-		if !conn.Local && !conn.Relay {
-			bestConn = &conn
-			break
+	for i := range server.Connections {
+		conn := &server.Connections[i]
+		if bestConn == nil {
+			bestConn = conn
+			continue
+		}
+		if r, best := p.rankConnection(*conn), p.rankConnection(*bestConn); r < best || (r == best && conn.URI < bestConn.URI) {
+			bestConn = conn
 		}
 	}
-	
-	// Fall back to local connections
+
 	if bestConn == nil {
-		for _, conn := range server.Connections {
-			if conn.Local {
-				bestConn = &conn
-				break
+		return nil
+	}
+	conn := *bestConn
+	return &conn
+}
+
+// rankConnection is connectionRank, with relay connections ranked as remote ones when
+// relay avoidance is disabled
+func (p *PlexgoClient) rankConnection(conn PlexConnection) int {
+	if conn.Relay && !p.avoidRelay {
+		return connectionRank(PlexConnection{})
+	}
+	return connectionRank(conn)
+}
+
+// ConnectionType classifies a connection as local, remote or relay
+func ConnectionType(conn PlexConnection) string {
+	switch {
+	case conn.Relay:
+		return ConnectionTypeRelay
+	case conn.Local:
+		return ConnectionTypeLocal
+	default:
+		return ConnectionTypeRemote
+	}
+}
+
+// SelectConnection picks the best-ranked responding connection for a server: local, then
+// remote, then relay, the fastest within a rank. Relay connections rank with remote ones
+// when relay avoidance is disabled. All connections are probed at once and each latency is
+// cached, so selection takes at most one probe timeout; it returns as soon as no better
+// ranked probe is outstanding. Falls back to GetBestConnection when nothing responds,
+// returning a zero latency in that case.
+func (p *PlexgoClient) SelectConnection(ctx context.Context, server PlexServer) (*PlexConnection, time.Duration) {
+	candidates := server.Connections
+	if len(candidates) == 0 {
+		return nil, 0
+	}
+
+	rank := p.rankConnection
+
+	type probeResult struct {
+		index   int
+		latency time.Duration
+		err     error
+	}
+
+	// Buffered so probes still running after a connection is picked finish (and fill the
+	// cache) without blocking
+	results := make(chan probeResult, len(candidates))
+	pending := make(map[int]int) // Outstanding probes by rank
+	for i := range candidates {
+		pending[rank(candidates[i])]++
+		go func(i int) {
+			latency, err := p.probeConnection(ctx, candidates[i])
+			results <- probeResult{index: i, latency: latency, err: err}
+		}(i)
+	}
+
+	betterPending := func(r int) bool {
+		for pr, n := range pending {
+			if pr < r && n > 0 {
+				return true
 			}
 		}
+		return false
 	}
-	
-	// Last resort: any connection
-	if bestConn == nil && len(server.Connections) > 0 {
-		bestConn = &server.Connections[0]
+
+	best := -1
+	var bestLatency time.Duration
+	for range candidates {
+		result := <-results
+		r := rank(candidates[result.index])
+		pending[r]--
+
+		if result.err == nil {
+			if best < 0 || r < rank(candidates[best]) || (r == rank(candidates[best]) && result.latency < bestLatency) {
+				best = result.index
+				bestLatency = result.latency
+			}
+		}
+
+		// Uncached probes of the same rank answering later are slower, so only a better rank
+		// can still win
+		if best >= 0 && !betterPending(rank(candidates[best])) {
+			break
+		}
 	}
-	
-	return bestConn
+
+	if best >= 0 {
+		conn := candidates[best]
+		p.logger.Debug("Selected Plex connection", "server", server.Name, "type", ConnectionType(conn),
+			"url", p.BuildServerURL(conn), "latency", bestLatency)
+		return &conn, bestLatency
+	}
+
+	return p.GetBestConnection(server), 0
+}
+
+// probeConnection measures the round trip to a connection's identity endpoint, caching the
+// result. Failures are only cached briefly so a connection that comes back is used again.
+func (p *PlexgoClient) probeConnection(ctx context.Context, conn PlexConnection) (time.Duration, error) {
+	serverURL := p.BuildServerURL(conn)
+
+	p.latencyMu.Lock()
+	cached, ok := p.latencyCache[serverURL]
+	p.latencyMu.Unlock()
+	ttl := connectionProbeTTL
+	if cached.err != nil {
+		ttl = connectionProbeFailureTTL
+	}
+	if ok && time.Since(cached.checkedAt) < ttl {
+		return cached.latency, cached.err
+	}
+
+	ctx, cancel := withPlexTimeout(ctx, connectionProbeTimeout)
+	defer cancel()
+
+	probe := latencyProbe{checkedAt: time.Now()}
+	req, err := http.NewRequestWithContext(ctx, "GET", serverURL+"/identity", nil)
+	if err != nil {
+		probe.err = err
+	} else {
+		req.Header.Set("Accept", "application/json")
+		start := time.Now()
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			probe.err = err
+		} else {
+			resp.Body.Close()
+			probe.latency = time.Since(start)
+			if resp.StatusCode != http.StatusOK {
+				probe.err = fmt.Errorf("identity check failed with status: %d", resp.StatusCode)
+			}
+		}
+	}
+
+	// Don't cache results of a cancelled sync
+	if ctx.Err() == context.Canceled {
+		return probe.latency, probe.err
+	}
+
+	p.latencyMu.Lock()
+	p.latencyCache[serverURL] = probe
+	p.latencyMu.Unlock()
+
+	return probe.latency, probe.err
 }
 
 // getStringValue safely converts a pointer string to a string value
@@ -473,50 +900,56 @@ func getStringValue(ptr *string) string {
 	return *ptr
 }
 
-// SearchMovieByTitle searches for a specific movie title across accessible libraries
-func (p *PlexgoClient) SearchMovieByTitle(ctx context.Context, token, serverURL, movieTitle string) (bool, error) {
-	fmt.Printf("DEBUG: [SearchMovieByTitle] Starting search for '%s' on server %s\n", movieTitle, serverURL)
+// SearchMovieByTitle searches for a specific movie across accessible libraries. When year is
+// known a result only matches if it has the same year, so a remake with the same title
+// doesn't count.
+func (p *PlexgoClient) SearchMovieByTitle(ctx context.Context, token, serverURL, movieTitle string, year *int) (bool, error) {
+	p.logger.Debug("Searching Plex for movie", "title", movieTitle, "year", year, "server", serverURL)
 	
 	// First try global search across all libraries (faster and more comprehensive)
 	results, err := p.SearchAllLibraries(ctx, token, serverURL, movieTitle)
 	if err != nil {
-		fmt.Printf("DEBUG: [SearchMovieByTitle] SearchAllLibraries failed: %v, trying PerformGlobalSearch\n", err)
+		p.logger.Debug("Plex library search failed, trying global search", "title", movieTitle, "error", err)
 		
 		// Fallback to global search
 		results, err = p.PerformGlobalSearch(ctx, token, serverURL, movieTitle)
 		if err != nil {
-			fmt.Printf("DEBUG: [SearchMovieByTitle] Both search methods failed: %v\n", err)
+			p.logger.Debug("Plex global search failed too", "title", movieTitle, "error", err)
 			return false, fmt.Errorf("failed to search for movie: %w", err)
 		}
 	}
 	
-	// Check if any result matches our movie title
+	// Check if any movie result matches our movie's title and year
 	for _, result := range results {
-		if p.titleMatches(result.Title, movieTitle) {
-			fmt.Printf("DEBUG: [SearchMovieByTitle] Found matching movie: '%s'\n", result.Title)
-			return true, nil
+		if result.Type != "movie" || !p.titleMatches(result.Title, movieTitle) {
+			continue
 		}
+		if year != nil && result.Year != nil && *result.Year != *year {
+			p.logger.Debug("Skipping Plex movie with a different year", "title", result.Title, "year", *result.Year, "want_year", *year)
+			continue
+		}
+		p.logger.Debug("Found matching movie on Plex", "title", result.Title, "year", result.Year)
+		return true, nil
 	}
 	
-	fmt.Printf("DEBUG: [SearchMovieByTitle] Movie '%s' not found in %d search results\n", movieTitle, len(results))
+	p.logger.Debug("Movie not found on Plex", "title", movieTitle, "results", len(results))
 	return false, nil
 }
 
-// titleMatches checks if two movie titles are similar (case-insensitive, ignoring common variations)
+// titleMatches reports whether a Plex title is the searched title once both are normalized.
+// Only the whole title counts, so a sequel like "Dune: Part Two" isn't "Dune"; a year in
+// brackets after the title, as in "Dune (2021)", is ignored.
 func (p *PlexgoClient) titleMatches(plexTitle, searchTitle string) bool {
-	// Simple case-insensitive comparison
-	plexLower := strings.ToLower(strings.TrimSpace(plexTitle))
-	searchLower := strings.ToLower(strings.TrimSpace(searchTitle))
-	
-	// Exact match
-	if plexLower == searchLower {
-		return true
-	}
-	
-	// Contains match (for cases like "Movie Title" vs "Movie Title (2023)")
-	if strings.Contains(plexLower, searchLower) || strings.Contains(searchLower, plexLower) {
-		return true
+	return normalizeTitle(stripTitleYear(plexTitle)) == normalizeTitle(stripTitleYear(searchTitle))
+}
+
+// stripTitleYear removes a trailing year in brackets, "Dune (2021)" becoming "Dune"
+func stripTitleYear(title string) string {
+	title = strings.TrimSpace(title)
+	if n := len(title); n > 6 && title[n-6] == '(' && title[n-1] == ')' {
+		if _, err := strconv.Atoi(title[n-5 : n-1]); err == nil {
+			return strings.TrimSpace(title[:n-6])
+		}
 	}
-	
-	return false
-}
\ No newline at end of file
+	return title
+}
@@ -0,0 +1,602 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// plexSearchItem is a movie the fake Plex server returns from a library search
+type plexSearchItem struct {
+	Title string
+	Year  *int
+	Type  string // "movie" unless set
+}
+
+// newPlexSearchServer serves /library/search with the given items, whatever the query
+func newPlexSearchServer(t *testing.T, items []plexSearchItem) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/library/search" {
+			http.NotFound(w, r)
+			return
+		}
+
+		results := []map[string]interface{}{}
+		for i, item := range items {
+			key := strconv.Itoa(i + 1)
+			itemType := item.Type
+			if itemType == "" {
+				itemType = "movie"
+			}
+			metadata := map[string]interface{}{
+				"ratingKey": key,
+				"key":       "/library/metadata/" + key,
+				"guid":      "plex://movie/" + key,
+				"type":      itemType,
+				"title":     item.Title,
+			}
+			if item.Year != nil {
+				metadata["year"] = *item.Year
+			}
+			results = append(results, map[string]interface{}{"score": 1, "Metadata": metadata})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"MediaContainer": map[string]interface{}{
+				"size":         len(results),
+				"SearchResult": results,
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTitleMatches(t *testing.T) {
+	tests := []struct {
+		plexTitle   string
+		searchTitle string
+		want        bool
+	}{
+		{"Dune", "Dune", true},
+		{"dune ", "Dune", true},
+		{"Dune (2021)", "Dune", true},
+		{"Amélie", "Amelie", true},
+		{"Matrix, The", "The Matrix", true},
+		{"Dune: Part Two", "Dune", false}, // A sequel isn't the movie
+		{"Dune", "Dune: Part Two", false},
+		{"Arrival", "Dune", false},
+	}
+
+	p := NewPlexgoClient()
+	for _, tt := range tests {
+		if got := p.titleMatches(tt.plexTitle, tt.searchTitle); got != tt.want {
+			t.Errorf("titleMatches(%q, %q) = %v, want %v", tt.plexTitle, tt.searchTitle, got, tt.want)
+		}
+	}
+}
+
+func TestSearchMovieByTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []plexSearchItem
+		title string
+		year  *int
+		want  bool
+	}{
+		{
+			name:  "same title and year",
+			items: []plexSearchItem{{Title: "Dune", Year: intPtr(2021)}},
+			title: "Dune",
+			year:  intPtr(2021),
+			want:  true,
+		},
+		{
+			name:  "only the remake from another year",
+			items: []plexSearchItem{{Title: "Dune", Year: intPtr(1984)}},
+			title: "Dune",
+			year:  intPtr(2021),
+			want:  false,
+		},
+		{
+			name:  "both the original and the remake",
+			items: []plexSearchItem{{Title: "Dune", Year: intPtr(1984)}, {Title: "Dune", Year: intPtr(2021)}},
+			title: "Dune",
+			year:  intPtr(2021),
+			want:  true,
+		},
+		{
+			// The year has to be the same, so a release a year apart isn't the movie
+			name:  "year off by one",
+			items: []plexSearchItem{{Title: "Parasite", Year: intPtr(2019)}},
+			title: "Parasite",
+			year:  intPtr(2020),
+			want:  false,
+		},
+		{
+			name:  "no year wanted",
+			items: []plexSearchItem{{Title: "Dune", Year: intPtr(1984)}},
+			title: "Dune",
+			want:  true,
+		},
+		{
+			name:  "Plex item without a year",
+			items: []plexSearchItem{{Title: "Dune"}},
+			title: "Dune",
+			year:  intPtr(2021),
+			want:  true,
+		},
+		{
+			name:  "not a movie",
+			items: []plexSearchItem{{Title: "Dune", Year: intPtr(2021), Type: "show"}},
+			title: "Dune",
+			year:  intPtr(2021),
+			want:  false,
+		},
+		{
+			name:  "only the sequel",
+			items: []plexSearchItem{{Title: "Dune: Part Two", Year: intPtr(2024)}},
+			title: "Dune",
+			want:  false,
+		},
+		{
+			name:  "different title",
+			items: []plexSearchItem{{Title: "Arrival", Year: intPtr(2016)}},
+			title: "Dune",
+			year:  intPtr(2016),
+			want:  false,
+		},
+		{
+			name:  "no results",
+			title: "Dune",
+			year:  intPtr(2021),
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newPlexSearchServer(t, tt.items)
+
+			found, err := NewPlexgoClient().SearchMovieByTitle(context.Background(), "token", server.URL, tt.title, tt.year)
+			if err != nil {
+				t.Fatalf("SearchMovieByTitle() error = %v", err)
+			}
+			if found != tt.want {
+				t.Errorf("SearchMovieByTitle() = %v, want %v", found, tt.want)
+			}
+		})
+	}
+}
+
+// newPlexLibraryServer serves a movie library of total items, "Movie 0000" onwards, from
+// /library/sections/{key}/all, honouring the container start and size. Later pages answer
+// sooner, so pages fetched concurrently finish out of order.
+func newPlexLibraryServer(tb testing.TB, total int, delay time.Duration) *httptest.Server {
+	tb.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/library/sections/1/all" {
+			http.NotFound(w, r)
+			return
+		}
+
+		start, _ := strconv.Atoi(r.URL.Query().Get("X-Plex-Container-Start"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("X-Plex-Container-Size"))
+		if delay > 0 && total > 0 {
+			time.Sleep(delay * time.Duration(total-start) / time.Duration(total))
+		}
+
+		metadata := []map[string]interface{}{}
+		for i := start; i < start+size && i < total; i++ {
+			metadata = append(metadata, map[string]interface{}{
+				"ratingKey": strconv.Itoa(i + 1),
+				"guid":      fmt.Sprintf("plex://movie/%d", i+1),
+				"type":      "movie",
+				"title":     fmt.Sprintf("Movie %04d", i),
+				"year":      1950 + i%70,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"MediaContainer": map[string]interface{}{
+				"size":      len(metadata),
+				"totalSize": total,
+				"offset":    start,
+				"Metadata":  metadata,
+			},
+		})
+	}))
+	tb.Cleanup(server.Close)
+	return server
+}
+
+func TestGetMoviesInLibraryPageOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		pageSize int
+	}{
+		{"single short page", 42, 100},
+		{"exactly one page", 100, 100},
+		{"last page partial", 1050, 100},
+		{"many small pages", 3000, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newPlexLibraryServer(t, tt.total, 20*time.Millisecond)
+			client := NewPlexgoClient()
+			client.SetLibraryPaging(tt.pageSize, 4)
+
+			movies, err := client.GetMoviesInLibrary(context.Background(), "token", server.URL, 1)
+			if err != nil {
+				t.Fatalf("GetMoviesInLibrary() error = %v", err)
+			}
+			if len(movies) != tt.total {
+				t.Fatalf("got %d movies, want %d", len(movies), tt.total)
+			}
+			for i, movie := range movies {
+				if want := fmt.Sprintf("Movie %04d", i); movie.Title != want {
+					t.Fatalf("movie %d is %q, want %q", i, movie.Title, want)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetMoviesInLibrary(b *testing.B) {
+	server := newPlexLibraryServer(b, 5000, 0)
+	client := NewPlexgoClient()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		movies, err := client.GetMoviesInLibrary(context.Background(), "token", server.URL, 1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(movies) != 5000 {
+			b.Fatalf("got %d movies, want 5000", len(movies))
+		}
+	}
+}
+
+func TestConnectionRank(t *testing.T) {
+	local := connectionRank(PlexConnection{Local: true})
+	remote := connectionRank(PlexConnection{})
+	relay := connectionRank(PlexConnection{Relay: true})
+	localRelay := connectionRank(PlexConnection{Local: true, Relay: true})
+
+	if !(local < remote && remote < relay) {
+		t.Errorf("ranks local %d, remote %d, relay %d, want them in that order", local, remote, relay)
+	}
+	if localRelay != relay {
+		t.Errorf("a local relay ranks %d, want %d like any relay", localRelay, relay)
+	}
+}
+
+func TestSortServers(t *testing.T) {
+	servers := []PlexServer{
+		{Name: "Shared B", MachineID: "m4"},
+		{Name: "Home", MachineID: "m2", Owned: true, Connections: []PlexConnection{
+			{URI: "https://relay.plex.direct:8443", Relay: true},
+			{URI: "https://203-0-113-7.plex.direct:32400"},
+			{URI: "http://192.168.1.20:32400", Local: true, Protocol: "http"},
+			{URI: "https://192-168-1-20.plex.direct:32400", Local: true},
+			{URI: "http://192.168.1.20:32400", Local: true, Protocol: "https"},
+			{URI: "https://198-51-100-2.plex.direct:32400"},
+		}},
+		{Name: "Shared A", MachineID: "m3"},
+		{Name: "Home", MachineID: "m1", Owned: true},
+		{Name: "Shared A", MachineID: "m3", Product: "second copy"},
+	}
+
+	sortServers(servers)
+
+	var order []string
+	for _, server := range servers {
+		order = append(order, server.MachineID)
+	}
+	if want := []string{"m1", "m2", "m3", "m3", "m4"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("server order = %v, want %v", order, want)
+	}
+	// Servers that compare equal keep the order Plex.tv returned them in
+	if servers[2].Product != "" || servers[3].Product != "second copy" {
+		t.Error("servers comparing equal were reordered")
+	}
+
+	var conns []string
+	for _, conn := range servers[1].Connections {
+		conns = append(conns, ConnectionType(conn)+" "+conn.URI+" "+conn.Protocol)
+	}
+	want := []string{
+		"local http://192.168.1.20:32400 http", // Same rank and URI as the next: input order kept
+		"local http://192.168.1.20:32400 https",
+		"local https://192-168-1-20.plex.direct:32400 ",
+		"remote https://198-51-100-2.plex.direct:32400 ",
+		"remote https://203-0-113-7.plex.direct:32400 ",
+		"relay https://relay.plex.direct:8443 ",
+	}
+	if !reflect.DeepEqual(conns, want) {
+		t.Errorf("connection order:\n%s\nwant:\n%s", strings.Join(conns, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestSortServersIgnoresInputOrder sorts every rotation of the same servers, which must all
+// end up in one order
+func TestSortServersIgnoresInputOrder(t *testing.T) {
+	base := []PlexServer{
+		{Name: "Zeta", MachineID: "z", Owned: true},
+		{Name: "Alpha", MachineID: "a"},
+		{Name: "Beta", MachineID: "b", Owned: true},
+		{Name: "Alpha", MachineID: "c"},
+	}
+
+	var first []string
+	for shift := range base {
+		servers := append(append([]PlexServer{}, base[shift:]...), base[:shift]...)
+		sortServers(servers)
+
+		var order []string
+		for _, server := range servers {
+			order = append(order, server.MachineID)
+		}
+		if first == nil {
+			first = order
+		} else if !reflect.DeepEqual(order, first) {
+			t.Errorf("rotation %d sorted to %v, want %v", shift, order, first)
+		}
+	}
+	if want := []string{"b", "z", "a", "c"}; !reflect.DeepEqual(first, want) {
+		t.Errorf("order = %v, want %v", first, want)
+	}
+}
+
+// newIdentityServer answers /identity after delay with status
+func newIdentityServer(t *testing.T, delay time.Duration, status int) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.WriteHeader(status)
+		case <-r.Context().Done():
+		}
+	}))
+	// Probes still waiting on a slow server would hold up Close
+	t.Cleanup(func() {
+		server.CloseClientConnections()
+		server.Close()
+	})
+	return server.URL
+}
+
+// unreachableURL is the URL of a server that has been shut down
+func unreachableURL(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestSelectConnection(t *testing.T) {
+	fast := func(t *testing.T) string { return newIdentityServer(t, 0, http.StatusOK) }
+	slow := func(t *testing.T) string { return newIdentityServer(t, 300*time.Millisecond, http.StatusOK) }
+	failing := func(t *testing.T) string { return newIdentityServer(t, 0, http.StatusUnauthorized) }
+
+	type candidate struct {
+		name  string
+		url   func(t *testing.T) string
+		local bool
+		relay bool
+	}
+
+	tests := []struct {
+		name       string
+		candidates []candidate
+		allowRelay bool
+		want       string // Name of the selected candidate
+	}{
+		{
+			name: "slower local beats faster remote and relay",
+			candidates: []candidate{
+				{name: "relay", url: fast, relay: true},
+				{name: "remote", url: fast},
+				{name: "local", url: slow, local: true},
+			},
+			want: "local",
+		},
+		{
+			name: "remote when local is down",
+			candidates: []candidate{
+				{name: "local", url: unreachableURL, local: true},
+				{name: "relay", url: fast, relay: true},
+				{name: "remote", url: slow},
+			},
+			want: "remote",
+		},
+		{
+			name: "remote when local answers with an error",
+			candidates: []candidate{
+				{name: "local", url: failing, local: true},
+				{name: "remote", url: fast},
+			},
+			want: "remote",
+		},
+		{
+			name: "fastest of one rank",
+			candidates: []candidate{
+				{name: "slow remote", url: slow},
+				{name: "fast remote", url: fast},
+			},
+			want: "fast remote",
+		},
+		{
+			name: "relay as a last resort",
+			candidates: []candidate{
+				{name: "local", url: unreachableURL, local: true},
+				{name: "remote", url: failing},
+				{name: "relay", url: slow, relay: true},
+			},
+			want: "relay",
+		},
+		{
+			name: "relay competes with remote when allowed",
+			candidates: []candidate{
+				{name: "remote", url: slow},
+				{name: "relay", url: fast, relay: true},
+			},
+			allowRelay: true,
+			want:       "relay",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := PlexServer{Name: "Test"}
+			names := make(map[string]string)
+			for _, c := range tt.candidates {
+				url := c.url(t)
+				names[url] = c.name
+				server.Connections = append(server.Connections, PlexConnection{URI: url, Local: c.local, Relay: c.relay})
+			}
+
+			client := NewPlexgoClient()
+			client.SetAvoidRelay(!tt.allowRelay)
+			conn, _ := client.SelectConnection(context.Background(), server)
+			if conn == nil {
+				t.Fatalf("no connection selected, want %s", tt.want)
+			}
+			if got := names[conn.URI]; got != tt.want {
+				t.Errorf("selected %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectConnectionProbesConcurrently(t *testing.T) {
+	const delay = 300 * time.Millisecond
+	server := PlexServer{Name: "Test"}
+	for i := 0; i < 4; i++ {
+		server.Connections = append(server.Connections, PlexConnection{URI: newIdentityServer(t, delay, http.StatusOK)})
+	}
+
+	start := time.Now()
+	conn, _ := NewPlexgoClient().SelectConnection(context.Background(), server)
+	elapsed := time.Since(start)
+
+	if conn == nil {
+		t.Fatal("no connection selected")
+	}
+	if elapsed > 2*delay {
+		t.Errorf("selection took %s, want about one probe (%s) for 4 connections", elapsed, delay)
+	}
+}
+
+func TestSelectConnectionReturnsWithoutWaitingForWorseRanks(t *testing.T) {
+	server := PlexServer{Name: "Test", Connections: []PlexConnection{
+		{URI: newIdentityServer(t, 3*time.Second, http.StatusOK)},
+		{URI: newIdentityServer(t, 0, http.StatusOK), Local: true},
+	}}
+
+	start := time.Now()
+	conn, _ := NewPlexgoClient().SelectConnection(context.Background(), server)
+	if conn == nil || !conn.Local {
+		t.Fatalf("selected %+v, want the local connection", conn)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("selection took %s waiting for a worse ranked connection", elapsed)
+	}
+}
+
+func TestGetBestConnection(t *testing.T) {
+	relay := PlexConnection{URI: "https://relay.plex.direct", Relay: true}
+	remote := PlexConnection{URI: "https://remote.plex.direct"}
+	local := PlexConnection{URI: "http://192.168.1.10:32400", Local: true}
+
+	tests := []struct {
+		name        string
+		connections []PlexConnection
+		allowRelay  bool
+		want        string
+	}{
+		{"local first", []PlexConnection{relay, remote, local}, false, local.URI},
+		{"remote before relay", []PlexConnection{relay, remote}, false, remote.URI},
+		{"relay as a last resort", []PlexConnection{relay}, false, relay.URI},
+		{"relay ties with remote by URI when allowed", []PlexConnection{remote, relay}, true, relay.URI},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewPlexgoClient()
+			client.SetAvoidRelay(!tt.allowRelay)
+			conn := client.GetBestConnection(PlexServer{Connections: tt.connections})
+			if conn == nil || conn.URI != tt.want {
+				t.Errorf("GetBestConnection() = %+v, want %s", conn, tt.want)
+			}
+		})
+	}
+
+	if conn := NewPlexgoClient().GetBestConnection(PlexServer{}); conn != nil {
+		t.Errorf("GetBestConnection() with no connections = %+v, want nil", conn)
+	}
+}
+
+func TestProbeConnectionCachesFailuresBriefly(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewPlexgoClient()
+	conn := PlexConnection{URI: server.URL}
+	age := func(d time.Duration) {
+		client.latencyMu.Lock()
+		probe := client.latencyCache[server.URL]
+		probe.checkedAt = probe.checkedAt.Add(-d)
+		client.latencyCache[server.URL] = probe
+		client.latencyMu.Unlock()
+	}
+
+	if _, err := client.probeConnection(context.Background(), conn); err == nil {
+		t.Fatal("probe of a failing server succeeded")
+	}
+
+	// The failure is reused for a little while...
+	healthy.Store(true)
+	if _, err := client.probeConnection(context.Background(), conn); err == nil {
+		t.Error("failed probe wasn't cached")
+	}
+
+	// ...but not for as long as a success
+	age(connectionProbeFailureTTL)
+	if _, err := client.probeConnection(context.Background(), conn); err != nil {
+		t.Errorf("probe after the failure expired: %v", err)
+	}
+
+	healthy.Store(false)
+	age(connectionProbeFailureTTL)
+	if _, err := client.probeConnection(context.Background(), conn); err != nil {
+		t.Errorf("successful probe wasn't cached: %v", err)
+	}
+}
@@ -2,11 +2,20 @@ package services
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrRateLimiterStopped is returned to requests still waiting when the rate limiter stops
+var ErrRateLimiterStopped = errors.New("TMDB rate limiter is shutting down")
+
+// maxRetryAfter caps how long a request waits when TMDB's Retry-After asks for longer
+const maxRetryAfter = time.Minute
+
 // TMDBRateLimiter manages TMDB API rate limiting using token bucket algorithm
 // TMDB allows 50 requests per 10 seconds, we use 40 to be conservative
 type TMDBRateLimiter struct {
@@ -18,8 +27,13 @@ type TMDBRateLimiter struct {
 	lastRefill        time.Time     // Last time tokens were refilled
 	mutex             sync.Mutex    // Thread safety
 	requestQueue      chan *RateLimitRequest // Queue for pending requests
-	isRunning         bool          // Whether the limiter is running
-	stopChan          chan bool     // Channel to stop the limiter
+	pending           int           // Requests taken off the queue that haven't started yet
+	queuedByUser      map[int64]int // Of the pending requests, how many each user has
+	inFlightByUser    map[int64]int // Requests executing for each user
+	isRunning         atomic.Bool   // Whether the limiter is running; read by GetStats
+	logger            *slog.Logger
+	stopChan          chan struct{} // Closed to stop the limiter
+	stopOnce          sync.Once     // Guards closing stopChan
 }
 
 // RateLimitRequest represents a pending API request
@@ -27,9 +41,16 @@ type RateLimitRequest struct {
 	callback   func() error // Function to execute when rate limit allows
 	resultChan chan error   // Channel to send result back
 	priority   int          // Request priority (higher = more important)
+	userID     int64        // User the request is made for; 0 for work no user asked for
 	createdAt  time.Time    // When request was created
 }
 
+// UserRequestCounts is how many of a user's TMDB requests are waiting and executing
+type UserRequestCounts struct {
+	Queued   int `json:"queued"`
+	InFlight int `json:"in_flight"`
+}
+
 // NewTMDBRateLimiter creates a new TMDB rate limiter
 func NewTMDBRateLimiter(db *sql.DB) *TMDBRateLimiter {
 	limiter := &TMDBRateLimiter{
@@ -40,7 +61,10 @@ func NewTMDBRateLimiter(db *sql.DB) *TMDBRateLimiter {
 		tokens:         40,                // Start with full bucket
 		lastRefill:     time.Now(),
 		requestQueue:   make(chan *RateLimitRequest, 1000), // Buffer up to 1000 requests
-		stopChan:       make(chan bool),
+		queuedByUser:   make(map[int64]int),
+		inFlightByUser: make(map[int64]int),
+		stopChan:       make(chan struct{}),
+		logger:         slog.Default(),
 	}
 	
 	// Start the background processor
@@ -51,18 +75,30 @@ func NewTMDBRateLimiter(db *sql.DB) *TMDBRateLimiter {
 
 // ExecuteWithRateLimit executes a function with rate limiting
 // Priority: 0 = low (background sync), 1 = normal (user requests), 2 = high (user-triggered)
-func (r *TMDBRateLimiter) ExecuteWithRateLimit(fn func() error, priority int) error {
+// Users with requests at the same priority take turns, so one user's large import or
+// sync doesn't hold up everyone else's; userID is 0 for work no user is waiting on.
+func (r *TMDBRateLimiter) ExecuteWithRateLimit(fn func() error, priority int, userID int64) error {
 	request := &RateLimitRequest{
 		callback:   fn,
 		resultChan: make(chan error, 1),
 		priority:   priority,
+		userID:     userID,
 		createdAt:  time.Now(),
 	}
 	
+	// Don't queue anything once stopped - nothing would process it
+	select {
+	case <-r.stopChan:
+		return ErrRateLimiterStopped
+	default:
+	}
+	
 	// Add to queue (this will block if queue is full)
 	select {
 	case r.requestQueue <- request:
 		// Request queued successfully
+	case <-r.stopChan:
+		return ErrRateLimiterStopped
 	case <-time.After(30 * time.Second):
 		return fmt.Errorf("rate limiter queue is full, request timed out")
 	}
@@ -71,6 +107,8 @@ func (r *TMDBRateLimiter) ExecuteWithRateLimit(fn func() error, priority int) er
 	select {
 	case err := <-request.resultChan:
 		return err
+	case <-r.stopChan:
+		return ErrRateLimiterStopped
 	case <-time.After(5 * time.Minute):
 		return fmt.Errorf("rate limited request timed out after 5 minutes")
 	}
@@ -78,31 +116,32 @@ func (r *TMDBRateLimiter) ExecuteWithRateLimit(fn func() error, priority int) er
 
 // processRequests runs in background and processes queued requests
 func (r *TMDBRateLimiter) processRequests() {
-	r.isRunning = true
+	r.isRunning.Store(true)
 	refillTicker := time.NewTicker(r.refillRate)
 	defer refillTicker.Stop()
 	
-	// Priority queue to handle high-priority requests first
-	var pendingRequests []*RateLimitRequest
+	// Handles high-priority requests first, taking turns between users within a priority
+	pendingRequests := newFairQueue()
 	
 	for {
 		select {
 		case <-r.stopChan:
-			r.isRunning = false
+			r.isRunning.Store(false)
+			r.drainRequests(pendingRequests)
 			return
 			
 		case <-refillTicker.C:
 			r.refillTokens()
 			
 		case request := <-r.requestQueue:
-			// Add to pending requests in priority order
-			pendingRequests = r.insertByPriority(pendingRequests, request)
+			pendingRequests.push(request)
+			r.trackQueued(request)
 			
 		default:
 			// Process pending requests if we have tokens
-			if len(pendingRequests) > 0 && r.hasTokens() {
-				request := pendingRequests[0]
-				pendingRequests = pendingRequests[1:]
+			if pendingRequests.size > 0 && r.hasTokens() {
+				request := pendingRequests.pop()
+				r.trackStarted(request)
 				
 				r.consumeToken()
 				go r.executeRequest(request)
@@ -114,23 +153,86 @@ func (r *TMDBRateLimiter) processRequests() {
 	}
 }
 
-// insertByPriority inserts request in correct priority order
-func (r *TMDBRateLimiter) insertByPriority(requests []*RateLimitRequest, newRequest *RateLimitRequest) []*RateLimitRequest {
-	// Find insertion point (higher priority first, then by creation time)
-	insertAt := len(requests)
-	for i, req := range requests {
-		if newRequest.priority > req.priority || 
-		   (newRequest.priority == req.priority && newRequest.createdAt.Before(req.createdAt)) {
-			insertAt = i
-			break
-		}
+// drainRequests fails every request that was queued but never executed, so callers
+// get ErrRateLimiterStopped right away instead of waiting out their timeout
+func (r *TMDBRateLimiter) drainRequests(pendingRequests *fairQueue) {
+	for pendingRequests.size > 0 {
+		pendingRequests.pop().resultChan <- ErrRateLimiterStopped
 	}
+	r.mutex.Lock()
+	r.pending = 0
+	r.queuedByUser = make(map[int64]int)
+	r.mutex.Unlock()
 	
-	// Insert at the correct position
-	requests = append(requests, nil)
-	copy(requests[insertAt+1:], requests[insertAt:])
-	requests[insertAt] = newRequest
-	return requests
+	for {
+		select {
+		case request := <-r.requestQueue:
+			request.resultChan <- ErrRateLimiterStopped
+		default:
+			return
+		}
+	}
+}
+
+// fairQueue orders requests waiting for a token: higher priorities first, and within a
+// priority one request per user in turn, each user's requests in the order they came in
+type fairQueue struct {
+	levels map[int]*fairLevel
+	size   int
+}
+
+// fairLevel holds the waiting requests of one priority
+type fairLevel struct {
+	turns    []int64 // Users with waiting requests; the first one goes next
+	requests map[int64][]*RateLimitRequest
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{levels: make(map[int]*fairLevel)}
+}
+
+func (q *fairQueue) push(request *RateLimitRequest) {
+	level, ok := q.levels[request.priority]
+	if !ok {
+		level = &fairLevel{requests: make(map[int64][]*RateLimitRequest)}
+		q.levels[request.priority] = level
+	}
+
+	if len(level.requests[request.userID]) == 0 {
+		level.turns = append(level.turns, request.userID)
+	}
+	level.requests[request.userID] = append(level.requests[request.userID], request)
+	q.size++
+}
+
+// pop removes the next request; the queue must not be empty
+func (q *fairQueue) pop() *RateLimitRequest {
+	var level *fairLevel
+	highest := 0
+	for priority, l := range q.levels {
+		if level == nil || priority > highest {
+			level, highest = l, priority
+		}
+	}
+
+	userID := level.turns[0]
+	requests := level.requests[userID]
+	request := requests[0]
+
+	// The user goes to the back of the line if they have more requests waiting
+	level.turns = level.turns[1:]
+	if len(requests) > 1 {
+		level.requests[userID] = requests[1:]
+		level.turns = append(level.turns, userID)
+	} else {
+		delete(level.requests, userID)
+	}
+	if len(level.turns) == 0 {
+		delete(q.levels, highest)
+	}
+
+	q.size--
+	return request
 }
 
 // executeRequest executes a rate-limited request with retry logic
@@ -138,12 +240,13 @@ func (r *TMDBRateLimiter) executeRequest(request *RateLimitRequest) {
 	var err error
 	maxRetries := 3
 	backoffDelay := 1 * time.Second
+	var retryDelay time.Duration
+	
+	defer r.trackFinished(request)
 	
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		if attempt > 0 {
-			// Exponential backoff
-			time.Sleep(backoffDelay)
-			backoffDelay *= 2
+			time.Sleep(retryDelay)
 		}
 		
 		err = request.callback()
@@ -156,7 +259,19 @@ func (r *TMDBRateLimiter) executeRequest(request *RateLimitRequest) {
 		
 		// Check if it's a rate limit error that should be retried
 		if r.shouldRetry(err) && attempt < maxRetries {
-			fmt.Printf("TMDB API request failed (attempt %d/%d): %v\n", attempt+1, maxRetries+1, err)
+			// Exponential backoff, unless TMDB said how long to wait
+			retryDelay = backoffDelay
+			backoffDelay *= 2
+			var rateLimitErr *RateLimitError
+			if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+				retryDelay = rateLimitErr.RetryAfter
+				if retryDelay > maxRetryAfter {
+					retryDelay = maxRetryAfter
+				}
+				// Everyone else's requests would be rejected too until then
+				r.pauseFor(retryDelay)
+			}
+			r.logger.Warn("TMDB API request failed, retrying", "attempt", attempt+1, "max_attempts", maxRetries+1, "retry_in", retryDelay, "error", err)
 			continue
 		}
 		
@@ -175,6 +290,10 @@ func (r *TMDBRateLimiter) shouldRetry(err error) bool {
 		return false
 	}
 	
+	if errors.Is(err, ErrTMDBRateLimited) {
+		return true
+	}
+	
 	errStr := err.Error()
 	// Retry on rate limit, timeout, or temporary network errors
 	return contains(errStr, "rate limit") || 
@@ -199,6 +318,19 @@ func (r *TMDBRateLimiter) refillTokens() {
 	}
 }
 
+// pauseFor empties the bucket and stops refilling it for d, after TMDB rejected a request
+// for exceeding its rate limit
+func (r *TMDBRateLimiter) pauseFor(d time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	r.tokens = 0
+	// refillTokens adds nothing until lastRefill is in the past
+	if resume := time.Now().Add(d); resume.After(r.lastRefill) {
+		r.lastRefill = resume
+	}
+}
+
 // hasTokens checks if tokens are available
 func (r *TMDBRateLimiter) hasTokens() bool {
 	r.mutex.Lock()
@@ -206,6 +338,59 @@ func (r *TMDBRateLimiter) hasTokens() bool {
 	return r.tokens > 0
 }
 
+// trackQueued counts a request that is waiting for a token
+func (r *TMDBRateLimiter) trackQueued(request *RateLimitRequest) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.pending++
+	r.queuedByUser[request.userID]++
+}
+
+// trackStarted moves a request from waiting to executing
+func (r *TMDBRateLimiter) trackStarted(request *RateLimitRequest) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.pending--
+	if r.queuedByUser[request.userID]--; r.queuedByUser[request.userID] <= 0 {
+		delete(r.queuedByUser, request.userID)
+	}
+	r.inFlightByUser[request.userID]++
+}
+
+// trackFinished stops counting a request that has executed
+func (r *TMDBRateLimiter) trackFinished(request *RateLimitRequest) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.inFlightByUser[request.userID]--; r.inFlightByUser[request.userID] <= 0 {
+		delete(r.inFlightByUser, request.userID)
+	}
+}
+
+// UserRequests returns how many requests each user has waiting and executing. Users
+// without any are left out; user 0 is work no user asked for.
+func (r *TMDBRateLimiter) UserRequests() map[int64]UserRequestCounts {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	counts := make(map[int64]UserRequestCounts, len(r.queuedByUser)+len(r.inFlightByUser))
+	for userID, queued := range r.queuedByUser {
+		counts[userID] = UserRequestCounts{Queued: queued}
+	}
+	for userID, inFlight := range r.inFlightByUser {
+		userCounts := counts[userID]
+		userCounts.InFlight = inFlight
+		counts[userID] = userCounts
+	}
+	return counts
+}
+
+// QueueDepth returns how many requests are waiting to be executed
+func (r *TMDBRateLimiter) QueueDepth() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.requestQueue) + r.pending
+}
+
 // consumeToken removes one token from the bucket
 func (r *TMDBRateLimiter) consumeToken() {
 	r.mutex.Lock()
@@ -220,37 +405,45 @@ func (r *TMDBRateLimiter) recordSuccessfulRequest() {
 	_, err := r.db.Exec(`
 		UPDATE tmdb_rate_limits 
 		SET requests_count = requests_count + 1, 
-			last_request_at = datetime('now'),
-			updated_at = datetime('now')
+			last_request_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
 		WHERE id = 1
 	`)
 	if err != nil {
-		fmt.Printf("Failed to record successful TMDB request: %v\n", err)
+		r.logger.Warn("Failed to record successful TMDB request", "error", err)
 	}
 }
 
 // recordFailedRequest logs failed API request
 func (r *TMDBRateLimiter) recordFailedRequest(requestErr error) {
-	fmt.Printf("TMDB API request failed: %v\n", requestErr)
+	tmdbFailedRequests.Inc()
+	r.logger.Warn("TMDB API request failed", "error", requestErr)
+}
+
+// AvailableTokens returns how many requests can be made right now without waiting
+func (r *TMDBRateLimiter) AvailableTokens() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.tokens
 }
 
 // GetStats returns current rate limiter statistics
 func (r *TMDBRateLimiter) GetStats() map[string]interface{} {
 	r.mutex.Lock()
 	tokens := r.tokens
-	queueSize := len(r.requestQueue)
+	queueSize := len(r.requestQueue) + r.pending
 	r.mutex.Unlock()
 	
 	var totalRequests int
 	var lastRequest time.Time
 	
 	err := r.db.QueryRow(`
-		SELECT requests_count, COALESCE(last_request_at, datetime('now')) 
+		SELECT requests_count, COALESCE(last_request_at, CURRENT_TIMESTAMP) 
 		FROM tmdb_rate_limits WHERE id = 1
 	`).Scan(&totalRequests, &lastRequest)
 	
 	if err != nil {
-		fmt.Printf("Failed to get rate limit stats: %v\n", err)
+		r.logger.Warn("Failed to get rate limit stats", "error", err)
 	}
 	
 	return map[string]interface{}{
@@ -259,15 +452,17 @@ func (r *TMDBRateLimiter) GetStats() map[string]interface{} {
 		"queue_size":      queueSize,
 		"total_requests":  totalRequests,
 		"last_request":    lastRequest,
-		"is_running":      r.isRunning,
+		"is_running":      r.isRunning.Load(),
+		"user_requests":   r.UserRequests(),
 	}
 }
 
-// Stop gracefully stops the rate limiter
+// Stop gracefully stops the rate limiter. Requests that haven't started yet fail with
+// ErrRateLimiterStopped; it is safe to call Stop more than once.
 func (r *TMDBRateLimiter) Stop() {
-	if r.isRunning {
-		r.stopChan <- true
-	}
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
 }
 
 // Helper functions
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or the timeout passes
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestRateLimiterStopFailsQueuedRequests(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	limiter := NewTMDBRateLimiter(nil)
+	// No tokens for the rest of the test, so every request stays queued
+	limiter.pauseFor(time.Hour)
+
+	const requests = 25
+	var executed atomic.Int32
+	results := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		go func(i int) {
+			results <- limiter.ExecuteWithRateLimit(func() error {
+				executed.Add(1)
+				return nil
+			}, i%3, int64(i%4))
+		}(i)
+	}
+
+	if !waitFor(time.Second, func() bool { return limiter.QueueDepth() == requests }) {
+		t.Fatalf("queue depth = %d, want %d", limiter.QueueDepth(), requests)
+	}
+
+	stoppedAt := time.Now()
+	limiter.Stop()
+	limiter.Stop() // Stopping twice is fine
+
+	for i := 0; i < requests; i++ {
+		select {
+		case err := <-results:
+			if !errors.Is(err, ErrRateLimiterStopped) {
+				t.Errorf("request error = %v, want ErrRateLimiterStopped", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of %d requests returned within a second of Stop", i, requests)
+		}
+	}
+	if elapsed := time.Since(stoppedAt); elapsed > 500*time.Millisecond {
+		t.Errorf("waiters took %s to return after Stop", elapsed)
+	}
+	if n := executed.Load(); n != 0 {
+		t.Errorf("%d requests executed after the bucket was emptied", n)
+	}
+
+	// Requests made after Stop fail right away
+	if err := limiter.ExecuteWithRateLimit(func() error { return nil }, 2, 1); !errors.Is(err, ErrRateLimiterStopped) {
+		t.Errorf("request after Stop error = %v, want ErrRateLimiterStopped", err)
+	}
+
+	// Waiters can return before the processor has drained its queue, so give it a moment
+	if !waitFor(time.Second, func() bool { return limiter.QueueDepth() == 0 }) {
+		t.Errorf("queue depth after Stop = %d, want 0", limiter.QueueDepth())
+	}
+
+	// The processor and every waiter are gone
+	if !waitFor(2*time.Second, func() bool { return runtime.NumGoroutine() <= goroutines }) {
+		t.Errorf("%d goroutines running after Stop, %d before the limiter started", runtime.NumGoroutine(), goroutines)
+	}
+}
+
+func TestRateLimiterStatsWhileStopping(t *testing.T) {
+	limiter := NewTMDBRateLimiter(newTestDB(t))
+	if !waitFor(time.Second, func() bool { return limiter.GetStats()["is_running"] == true }) {
+		t.Fatal("limiter never reported running")
+	}
+
+	// Stats are read from request handlers while the processor stops (run with -race)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			limiter.GetStats()
+		}
+	}()
+	limiter.Stop()
+	<-done
+
+	if !waitFor(time.Second, func() bool { return limiter.GetStats()["is_running"] == false }) {
+		t.Error("limiter still reports running after Stop")
+	}
+}
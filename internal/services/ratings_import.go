@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Per-row outcomes of a ratings import
+const (
+	RatingImportImported  = "imported"
+	RatingImportUnmatched = "unmatched"
+	RatingImportFailed    = "failed"
+)
+
+// RatingImportRow is one rating to import, already normalized to our 1-10 scale
+type RatingImportRow struct {
+	Title       string     `json:"title"`
+	Year        int        `json:"year,omitempty"`
+	IMDbID      string     `json:"imdb_id,omitempty"`
+	Rating      *float64   `json:"rating,omitempty"` // nil marks the movie watched without rating it
+	WatchedDate *time.Time `json:"watched_date,omitempty"`
+}
+
+// RatingImportResult is the outcome of importing a single row
+type RatingImportResult struct {
+	Row    int    `json:"row"` // 1-based position in the uploaded file
+	Title  string `json:"title"`
+	Year   int    `json:"year,omitempty"`
+	TMDBID int    `json:"tmdb_id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RatingsImporter resolves imported ratings to TMDB movies and stores them on the user's movies
+type RatingsImporter struct {
+	db          *sql.DB
+	tmdbClient  *TMDBClient
+	rateLimiter *TMDBRateLimiter
+	jobManager  *JobManager
+}
+
+// RatingsImportJobProcessor implements JobProcessor for ratings imports too large to run inline
+type RatingsImportJobProcessor struct {
+	importer *RatingsImporter
+}
+
+// NewRatingsImporter creates a ratings importer and registers its job processor
+func NewRatingsImporter(db *sql.DB, tmdbClient *TMDBClient, rateLimiter *TMDBRateLimiter, jobManager *JobManager) *RatingsImporter {
+	importer := &RatingsImporter{
+		db:          db,
+		tmdbClient:  tmdbClient,
+		rateLimiter: rateLimiter,
+		jobManager:  jobManager,
+	}
+
+	jobManager.RegisterProcessor(&RatingsImportJobProcessor{importer: importer})
+
+	return importer
+}
+
+// GetJobType returns the job type this processor handles
+func (p *RatingsImportJobProcessor) GetJobType() JobType {
+	return JobTypeRatingsImport
+}
+
+// ProcessJob imports the rows stored in the job metadata
+func (p *RatingsImportJobProcessor) ProcessJob(ctx context.Context, job *Job) error {
+	if job.UserID == nil {
+		return fmt.Errorf("user ID is required for ratings import job")
+	}
+
+	// Metadata round-trips through JSON, so decode the rows back into their struct
+	data, err := json.Marshal(job.Metadata["rows"])
+	if err != nil {
+		return fmt.Errorf("failed to read import rows: %w", err)
+	}
+	var rows []RatingImportRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("failed to read import rows: %w", err)
+	}
+
+	results := p.importer.importRows(ctx, *job.UserID, rows, job.ID)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Keep the results (and drop the uploaded rows) so they can be read from the job status
+	summary := SummarizeRatingImport(results)
+	summary["rows"] = nil
+	summary["results"] = results
+	return p.importer.jobManager.MergeJobMetadata(job.ID, summary)
+}
+
+// ImportRatings imports the rows right away and returns the per-row results
+func (i *RatingsImporter) ImportRatings(ctx context.Context, userID int64, rows []RatingImportRow) []RatingImportResult {
+	return i.importRows(ctx, userID, rows, 0)
+}
+
+// StartImportJob queues a background job importing the rows. Results are stored in the
+// job metadata under "results" once it finishes.
+func (i *RatingsImporter) StartImportJob(userID int64, rows []RatingImportRow) (*Job, error) {
+	metadata := map[string]interface{}{
+		"user_id":    userID,
+		"total_rows": len(rows),
+		"rows":       rows,
+	}
+
+	job, err := i.jobManager.CreateJob(JobTypeRatingsImport, &userID, nil, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	return job, nil
+}
+
+// SummarizeRatingImport counts the results by outcome
+func SummarizeRatingImport(results []RatingImportResult) map[string]interface{} {
+	imported, unmatched, failed := 0, 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case RatingImportImported:
+			imported++
+		case RatingImportUnmatched:
+			unmatched++
+		default:
+			failed++
+		}
+	}
+
+	return map[string]interface{}{
+		"imported":  imported,
+		"unmatched": unmatched,
+		"failed":    failed,
+	}
+}
+
+// importRows imports each row, reporting progress on jobID when it is non-zero
+func (i *RatingsImporter) importRows(ctx context.Context, userID int64, rows []RatingImportRow, jobID int64) []RatingImportResult {
+	results := make([]RatingImportResult, 0, len(rows))
+	successful, failed := 0, 0
+
+	for n, row := range rows {
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := RatingImportResult{Row: n + 1, Title: row.Title, Year: row.Year}
+
+		movie, err := i.resolveMovie(userID, row)
+		switch {
+		case err != nil:
+			result.Status = RatingImportFailed
+			result.Error = err.Error()
+		case movie == nil:
+			result.Status = RatingImportUnmatched
+		default:
+			result.TMDBID = movie.ID
+			if err := i.saveRating(userID, *movie, row); err != nil {
+				result.Status = RatingImportFailed
+				result.Error = err.Error()
+			} else {
+				result.Status = RatingImportImported
+			}
+		}
+
+		if result.Status == RatingImportImported {
+			successful++
+		} else {
+			failed++
+		}
+		results = append(results, result)
+
+		if jobID != 0 {
+			progress := (n + 1) * 100 / len(rows)
+			i.jobManager.UpdateJobProgress(jobID, progress, fmt.Sprintf("Imported %d of %d ratings", n+1, len(rows)), n+1, successful, failed)
+		}
+	}
+
+	return results
+}
+
+// resolveMovie finds the TMDB movie for a row, by IMDb id when present and otherwise by
+// title and year. It returns nil without an error when nothing matches.
+func (i *RatingsImporter) resolveMovie(userID int64, row RatingImportRow) (*TMDBMovie, error) {
+	if imdbID := strings.TrimSpace(row.IMDbID); imdbID != "" {
+		var findResp *TMDBFindResponse
+		err := i.rateLimiter.ExecuteWithRateLimit(func() error {
+			var err error
+			findResp, err = i.tmdbClient.FindByExternalID(imdbID, "imdb_id")
+			return err
+		}, 1, userID)
+		if err != nil {
+			return nil, fmt.Errorf("TMDB lookup failed: %w", err)
+		}
+		if len(findResp.MovieResults) > 0 {
+			return &findResp.MovieResults[0], nil
+		}
+	}
+
+	if row.Title == "" {
+		return nil, nil
+	}
+
+	var searchResp *TMDBSearchResponse
+	err := i.rateLimiter.ExecuteWithRateLimit(func() error {
+		var err error
+		searchResp, err = i.tmdbClient.SearchMovies(row.Title, row.Year)
+		return err
+	}, 1, userID)
+	if err != nil {
+		return nil, fmt.Errorf("TMDB search failed: %w", err)
+	}
+	if len(searchResp.Results) == 0 {
+		return nil, nil
+	}
+
+	return &searchResp.Results[0], nil
+}
+
+// saveRating caches the movie and marks it watched with the imported rating and date.
+// Existing ratings and watch dates are kept when the row doesn't have them.
+func (i *RatingsImporter) saveRating(userID int64, movie TMDBMovie, row RatingImportRow) error {
+	movieID, err := CacheSearchResult(i.db, i.tmdbClient, movie)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = i.db.Exec(`
+		INSERT INTO user_movies (user_id, movie_id, status, rating, watched_date, created_at, updated_at)
+		VALUES (?, ?, 'watched', ?, ?, ?, ?)
+		ON CONFLICT(user_id, movie_id) DO UPDATE SET
+			status = excluded.status,
+			rating = COALESCE(excluded.rating, user_movies.rating),
+			watched_date = COALESCE(excluded.watched_date, user_movies.watched_date),
+			updated_at = excluded.updated_at
+	`, userID, movieID, row.Rating, row.WatchedDate, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to save rating: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// minTitleMatchScore is the score a TMDB search result needs to be used as the match for a
+// Plex item. An exact title is enough with the same, an adjacent or an unknown year; an
+// exact title from a different year (a remake) is not.
+const minTitleMatchScore = 0.8
+
+// Weights of the title similarity and the year proximity in a match score
+const (
+	titleMatchWeight = 0.75
+	yearMatchWeight  = 0.25
+)
+
+// titleArticles are dropped from the start and end of titles when normalizing them, so
+// "The Matrix", "Matrix, The" and "Matrix" compare equal
+var titleArticles = map[string]bool{
+	"the": true, "a": true, "an": true,
+}
+
+// diacriticFolds spells accented Latin letters without their accents, so "Amélie" and
+// "Amelie" compare equal
+var diacriticFolds = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "č", "c", "ć", "c",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ě", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i",
+	"ñ", "n", "ń", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ů", "u",
+	"ý", "y", "ÿ", "y",
+	"ß", "ss", "ł", "l", "ř", "r", "š", "s", "ś", "s", "ž", "z", "ź", "z", "ż", "z",
+)
+
+// normalizeTitle lowercases a title, folds accents, spells out "&", drops punctuation and
+// a leading or trailing article and collapses whitespace
+func normalizeTitle(title string) string {
+	title = diacriticFolds.Replace(strings.ToLower(title))
+	title = strings.ReplaceAll(title, "&", " and ")
+
+	var b strings.Builder
+	for _, r := range title {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else if r != '\'' && r != '’' { // "Schindler's" and "Schindlers" are the same word
+			b.WriteRune(' ')
+		}
+	}
+
+	// Only the ends, so "The A-Team" keeps its "a"; a one-word title keeps its article
+	words := strings.Fields(b.String())
+	if len(words) > 1 && titleArticles[words[0]] {
+		words = words[1:]
+	}
+	if len(words) > 1 && titleArticles[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// titleSimilarity returns how alike two titles are after normalizing them, from 0 to 1
+func titleSimilarity(a, b string) float64 {
+	a, b = normalizeTitle(a), normalizeTitle(b)
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(min(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// yearProximity scores how well a candidate's year fits: 1 for the same year, 0.6 for one
+// year apart (release dates differ by country) or when either year is unknown, 0 otherwise
+func yearProximity(year, candidateYear *int) float64 {
+	if year == nil || candidateYear == nil {
+		return 0.6
+	}
+
+	diff := *year - *candidateYear
+	switch {
+	case diff == 0:
+		return 1
+	case diff == 1 || diff == -1:
+		return 0.6
+	}
+	return 0
+}
+
+// scoreTitleMatch scores a TMDB movie as the match for a title and year, from 0 to 1. The
+// original title counts too, for items Plex lists under their original language title.
+func scoreTitleMatch(title string, year *int, movie TMDBMovie) float64 {
+	similarity := titleSimilarity(title, movie.Title)
+	if movie.OriginalTitle != "" {
+		similarity = math.Max(similarity, titleSimilarity(title, movie.OriginalTitle))
+	}
+
+	return titleMatchWeight*similarity + yearMatchWeight*yearProximity(year, ExtractYear(movie.ReleaseDate))
+}
+
+// bestTitleMatch returns the search result that best matches a title and year, and its
+// score. It returns nil when no result reaches minTitleMatchScore, so a doubtful item stays
+// unmatched instead of being matched to the wrong movie. Ties go to the earlier, more
+// popular result.
+func bestTitleMatch(title string, year *int, results []TMDBMovie) (*TMDBMovie, float64) {
+	var best *TMDBMovie
+	bestScore := 0.0
+	for i := range results {
+		if score := scoreTitleMatch(title, year, results[i]); score > bestScore {
+			best, bestScore = &results[i], score
+		}
+	}
+
+	if bestScore < minTitleMatchScore {
+		return nil, bestScore
+	}
+	return best, bestScore
+}
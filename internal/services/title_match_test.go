@@ -0,0 +1,154 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"The Matrix", "matrix"},
+		{"Matrix, The", "matrix"},
+		{"Schindler's List", "schindlers list"},
+		{"Schindler’s List", "schindlers list"},
+		{"Amélie", "amelie"},
+		{"Léon: The Professional", "leon the professional"},
+		{"Fast & Furious", "fast and furious"},
+		{"Spider-Man: Into the Spider-Verse", "spider man into the spider verse"},
+		{"  The   Lord of the Rings  ", "lord of the rings"},
+		{"The A-Team", "a team"},
+		{"A", "a"},
+		{"WALL·E", "wall e"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeTitle(tt.title); got != tt.want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestScoreTitleMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		year    *int
+		movie   TMDBMovie
+		want    float64
+		matched bool
+	}{
+		{
+			name:    "trailing article",
+			title:   "Matrix, The",
+			year:    intPtr(1999),
+			movie:   TMDBMovie{Title: "The Matrix", ReleaseDate: "1999-03-30"},
+			want:    1,
+			matched: true,
+		},
+		{
+			name:    "apostrophe",
+			title:   "Schindlers List",
+			year:    intPtr(1993),
+			movie:   TMDBMovie{Title: "Schindler's List", ReleaseDate: "1993-12-15"},
+			want:    1,
+			matched: true,
+		},
+		{
+			name:    "diacritics without a year",
+			title:   "Amelie",
+			movie:   TMDBMovie{Title: "Amélie", ReleaseDate: "2001-04-25"},
+			want:    0.9,
+			matched: true,
+		},
+		{
+			name:    "original title",
+			title:   "Le fabuleux destin d'Amélie Poulain",
+			year:    intPtr(2001),
+			movie:   TMDBMovie{Title: "Amélie", OriginalTitle: "Le Fabuleux Destin d'Amélie Poulain", ReleaseDate: "2001-04-25"},
+			want:    1,
+			matched: true,
+		},
+		{
+			name:    "release a year apart",
+			title:   "Parasite",
+			year:    intPtr(2020),
+			movie:   TMDBMovie{Title: "Parasite", ReleaseDate: "2019-05-30"},
+			want:    0.9,
+			matched: true,
+		},
+		{
+			name:    "remake from another year",
+			title:   "Dune",
+			year:    intPtr(2021),
+			movie:   TMDBMovie{Title: "Dune", ReleaseDate: "1984-12-14"},
+			want:    0.75,
+			matched: false,
+		},
+		{
+			// Just under the threshold: a sequel one letter apart, with no year to tell
+			name:    "similar title without a year",
+			title:   "Alien",
+			movie:   TMDBMovie{Title: "Aliens", ReleaseDate: "1986-07-18"},
+			want:    0.775,
+			matched: false,
+		},
+		{
+			name:    "subtitle missing from the Plex title",
+			title:   "Leon",
+			year:    intPtr(1994),
+			movie:   TMDBMovie{Title: "Léon: The Professional", ReleaseDate: "1994-09-14"},
+			matched: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scoreTitleMatch(tt.title, tt.year, tt.movie)
+			if tt.want != 0 && math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("score = %.3f, want %.3f", got, tt.want)
+			}
+			if matched := got >= minTitleMatchScore; matched != tt.matched {
+				t.Errorf("score %.3f matched = %v, want %v", got, matched, tt.matched)
+			}
+		})
+	}
+}
+
+func TestBestTitleMatch(t *testing.T) {
+	remakes := []TMDBMovie{
+		{ID: 841, Title: "Dune", ReleaseDate: "1984-12-14"},
+		{ID: 438631, Title: "Dune", ReleaseDate: "2021-09-15"},
+		{ID: 693134, Title: "Dune: Part Two", ReleaseDate: "2024-02-27"},
+	}
+
+	tests := []struct {
+		name    string
+		title   string
+		year    *int
+		results []TMDBMovie
+		wantID  int // 0 for no match
+	}{
+		{"picks the remake of the right year", "Dune", intPtr(2021), remakes, 438631},
+		{"picks the original of the right year", "Dune", intPtr(1984), remakes, 841},
+		{"first result wins a tie without a year", "Dune", nil, remakes, 841},
+		{"no result from the right year", "Dune", intPtr(2000), remakes[:2], 0},
+		{"no results", "Dune", intPtr(2021), nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, score := bestTitleMatch(tt.title, tt.year, tt.results)
+			switch {
+			case tt.wantID == 0 && match != nil:
+				t.Errorf("matched %d (score %.3f), want no match", match.ID, score)
+			case tt.wantID != 0 && match == nil:
+				t.Errorf("no match (best score %.3f), want %d", score, tt.wantID)
+			case tt.wantID != 0 && match.ID != tt.wantID:
+				t.Errorf("matched %d, want %d", match.ID, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Default TTLs for cached TMDB responses. Search-like results change as TMDB's popularity
+// data moves; movie details rarely change.
+const (
+	DefaultTMDBSearchCacheTTL  = time.Hour
+	DefaultTMDBDetailsCacheTTL = 24 * time.Hour
+)
+
+// tmdbCachePruneInterval is how many cache writes happen between expired-row cleanups
+const tmdbCachePruneInterval = 500
+
+// tmdbResponseCache stores raw TMDB response bodies in tmdb_response_cache keyed by a hash
+// of the endpoint and its parameters
+type tmdbResponseCache struct {
+	db         *sql.DB
+	searchTTL  time.Duration
+	detailsTTL time.Duration
+	writes     atomic.Int64
+}
+
+// EnableResponseCache makes GET requests consult the tmdb_response_cache table first.
+// Search-like endpoints (search, discover, popular, trending, similar) are kept for
+// searchTTL, everything else for detailsTTL; a zero TTL disables caching that class.
+func (c *TMDBClient) EnableResponseCache(db *sql.DB, searchTTL, detailsTTL time.Duration) {
+	c.cache = &tmdbResponseCache{
+		db:         db,
+		searchTTL:  searchTTL,
+		detailsTTL: detailsTTL,
+	}
+}
+
+// WithoutCache returns a client that always calls TMDB, for manual refreshes. The fresh
+// responses still replace what is cached.
+func (c *TMDBClient) WithoutCache() *TMDBClient {
+	clone := *c
+	clone.bypassCache = true
+	return &clone
+}
+
+// ttlFor returns how long responses from an endpoint are cached (0 = not cached)
+func (tc *tmdbResponseCache) ttlFor(endpoint string) time.Duration {
+	switch {
+	case strings.HasSuffix(endpoint, "/watch/providers"):
+		// Watch providers have their own regional cache
+		return 0
+	case strings.HasPrefix(endpoint, "/search/"),
+		strings.HasPrefix(endpoint, "/discover/"),
+		strings.HasPrefix(endpoint, "/trending/"),
+		endpoint == "/movie/popular",
+		strings.HasSuffix(endpoint, "/similar"),
+		strings.HasSuffix(endpoint, "/recommendations"):
+		return tc.searchTTL
+	default:
+		return tc.detailsTTL
+	}
+}
+
+// tmdbCacheKey hashes the endpoint with its encoded (sorted) query string
+func tmdbCacheKey(endpoint, rawQuery string) string {
+	sum := sha256.Sum256([]byte(endpoint + "?" + rawQuery))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns a cached body that hasn't expired
+func (tc *tmdbResponseCache) get(key string) ([]byte, bool) {
+	var body []byte
+	var expiresAt time.Time
+	err := tc.db.QueryRow(`
+		SELECT response_body, expires_at FROM tmdb_response_cache WHERE cache_key = ?
+	`, key).Scan(&body, &expiresAt)
+	if err != nil || !expiresAt.After(time.Now()) {
+		return nil, false
+	}
+	return body, true
+}
+
+// set stores a response body, occasionally pruning expired rows
+func (tc *tmdbResponseCache) set(key, endpoint string, body []byte, ttl time.Duration) {
+	now := time.Now()
+	_, err := tc.db.Exec(`
+		INSERT INTO tmdb_response_cache (cache_key, endpoint, response_body, cached_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(cache_key) DO UPDATE SET
+			response_body = excluded.response_body,
+			cached_at = excluded.cached_at,
+			expires_at = excluded.expires_at
+	`, key, endpoint, body, now, now.Add(ttl))
+	if err != nil {
+		slog.Warn("Failed to cache TMDB response", "endpoint", endpoint, "error", err)
+		return
+	}
+
+	if tc.writes.Add(1)%tmdbCachePruneInterval == 0 {
+		if _, err := tc.db.Exec("DELETE FROM tmdb_response_cache WHERE expires_at <= ?", now); err != nil {
+			slog.Warn("Failed to prune TMDB response cache", "error", err)
+		}
+	}
+}
+
+// cachedResponse wraps a cached body as a successful response so callers can decode it
+// as if it came from TMDB
+func cachedResponse(body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+}
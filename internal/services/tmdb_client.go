@@ -2,6 +2,7 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,10 +12,80 @@ import (
 	"time"
 )
 
+// MaxTMDBID is an upper bound for plausible TMDB movie ids (currently well under 10M)
+const MaxTMDBID = 10000000
+
+// IsPlausibleTMDBID reports whether id could be a real TMDB id
+func IsPlausibleTMDBID(id int) bool {
+	return id > 0 && id <= MaxTMDBID
+}
+
+// Errors a TMDB request can be checked against with errors.Is
+var (
+	ErrTMDBNotFound    = errors.New("not found on TMDB")
+	ErrTMDBRateLimited = errors.New("TMDB rate limit exceeded")
+)
+
+// TMDBError is a non-OK response from the TMDB API
+type TMDBError struct {
+	StatusCode int
+	Body       string
+	URL        string
+}
+
+func (e *TMDBError) Error() string {
+	return fmt.Sprintf("API request failed with status %d, response: %s, URL: %s", e.StatusCode, e.Body, e.URL)
+}
+
+// Is matches ErrTMDBNotFound and ErrTMDBRateLimited by status code
+func (e *TMDBError) Is(target error) bool {
+	switch target {
+	case ErrTMDBNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrTMDBRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// RateLimitError is a 429 response from the TMDB API. It matches ErrTMDBRateLimited, and
+// unwraps to the TMDBError.
+type RateLimitError struct {
+	*TMDBError
+	RetryAfter time.Duration // How long TMDB asked us to wait; 0 when it didn't say
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.TMDBError
+}
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or as an HTTP date.
+// It returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
 type TMDBClient struct {
-	APIKey  string
-	BaseURL string
-	client  *http.Client
+	APIKey      string
+	BaseURL     string
+	client      *http.Client
+	cache       *tmdbResponseCache // nil unless EnableResponseCache was called
+	bypassCache bool               // Skip cache reads (see WithoutCache)
 }
 
 // TMDB API Response Types
@@ -50,6 +121,7 @@ type TMDBMovieDetails struct {
 	Revenue int64   `json:"revenue"`
 	Status  string  `json:"status"`
 	Tagline string  `json:"tagline"`
+	IMDbID  *string `json:"imdb_id"`
 }
 
 type TMDBExternalIDs struct {
@@ -64,6 +136,39 @@ type Genre struct {
 	Name string `json:"name"`
 }
 
+type TMDBTVSearchResponse struct {
+	Page         int          `json:"page"`
+	Results      []TMDBTVShow `json:"results"`
+	TotalPages   int          `json:"total_pages"`
+	TotalResults int          `json:"total_results"`
+}
+
+type TMDBTVShow struct {
+	ID               int      `json:"id"`
+	Name             string   `json:"name"`
+	OriginalName     string   `json:"original_name"`
+	Overview         string   `json:"overview"`
+	FirstAirDate     string   `json:"first_air_date"`
+	PosterPath       *string  `json:"poster_path"`
+	BackdropPath     *string  `json:"backdrop_path"`
+	GenreIDs         []int    `json:"genre_ids"`
+	OriginCountry    []string `json:"origin_country"`
+	OriginalLanguage string   `json:"original_language"`
+	Popularity       float64  `json:"popularity"`
+	VoteAverage      float64  `json:"vote_average"`
+	VoteCount        int      `json:"vote_count"`
+}
+
+type TMDBTVDetails struct {
+	TMDBTVShow
+	Genres           []Genre `json:"genres"`
+	NumberOfSeasons  int     `json:"number_of_seasons"`
+	NumberOfEpisodes int     `json:"number_of_episodes"`
+	EpisodeRunTime   []int   `json:"episode_run_time"`
+	Status           string  `json:"status"`
+	Tagline          string  `json:"tagline"`
+}
+
 func NewTMDBClient(apiKey string) *TMDBClient {
 	return &TMDBClient{
 		APIKey:  apiKey,
@@ -98,6 +203,21 @@ func (c *TMDBClient) makeRequest(endpoint string, params map[string]string) (*ht
 	
 	u.RawQuery = query.Encode()
 
+	// Serve from the response cache when this endpoint is cached
+	var cacheKey string
+	var cacheTTL time.Duration
+	if c.cache != nil {
+		cacheTTL = c.cache.ttlFor(endpoint)
+		if cacheTTL > 0 {
+			cacheKey = tmdbCacheKey(endpoint, u.RawQuery)
+			if !c.bypassCache {
+				if body, ok := c.cache.get(cacheKey); ok {
+					return cachedResponse(body), nil
+				}
+			}
+		}
+	}
+
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -116,7 +236,24 @@ func (c *TMDBClient) makeRequest(endpoint string, params map[string]string) (*ht
 		// Read the response body to get detailed error information
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API request failed with status %d, response: %s, URL: %s", resp.StatusCode, string(body), req.URL.String())
+		tmdbErr := &TMDBError{StatusCode: resp.StatusCode, Body: string(body), URL: req.URL.String()}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, &RateLimitError{
+				TMDBError:  tmdbErr,
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			}
+		}
+		return nil, tmdbErr
+	}
+
+	if cacheKey != "" {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		c.cache.set(cacheKey, endpoint, body, cacheTTL)
+		return cachedResponse(body), nil
 	}
 
 	return resp, nil
@@ -165,6 +302,49 @@ func (c *TMDBClient) GetMovieDetails(tmdbID int) (*TMDBMovieDetails, error) {
 	return &movie, nil
 }
 
+// SearchTV searches for TV shows by query string
+func (c *TMDBClient) SearchTV(query string, firstAirYear int) (*TMDBTVSearchResponse, error) {
+	params := map[string]string{
+		"query": query,
+	}
+
+	// Add first air year parameter if provided
+	if firstAirYear > 0 {
+		params["first_air_date_year"] = strconv.Itoa(firstAirYear)
+	}
+
+	resp, err := c.makeRequest("/search/tv", params)
+	if err != nil {
+		return nil, fmt.Errorf("tv search request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var searchResp TMDBTVSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode tv search response: %w", err)
+	}
+
+	return &searchResp, nil
+}
+
+// GetTVDetails gets detailed information about a specific TV show
+func (c *TMDBClient) GetTVDetails(tmdbID int) (*TMDBTVDetails, error) {
+	endpoint := fmt.Sprintf("/tv/%d", tmdbID)
+
+	resp, err := c.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("tv details request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var show TMDBTVDetails
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return nil, fmt.Errorf("failed to decode tv details: %w", err)
+	}
+
+	return &show, nil
+}
+
 // GetPopularMovies gets a list of popular movies
 func (c *TMDBClient) GetPopularMovies(page int) (*TMDBSearchResponse, error) {
 	if page <= 0 {
@@ -189,15 +369,115 @@ func (c *TMDBClient) GetPopularMovies(page int) (*TMDBSearchResponse, error) {
 	return &searchResp, nil
 }
 
-// GetTrendingMovies gets a list of trending movies
-func (c *TMDBClient) GetTrendingMovies(timeWindow string) (*TMDBSearchResponse, error) {
+// DiscoverParams are the filters for browsing movies with DiscoverMovies. Zero values
+// are left out of the request.
+type DiscoverParams struct {
+	GenreIDs       []int   // Movies must have all of these genres
+	YearFrom       int     // Earliest release year, inclusive
+	YearTo         int     // Latest release year, inclusive
+	MinVoteAverage float64 // e.g. 7.0
+	MinVoteCount   int     // Keeps barely-rated movies out of vote average filters and sorts
+	SortBy         string  // e.g. "popularity.desc", "vote_average.desc"
+	Page           int
+}
+
+// DiscoverMovies browses movies by genre, release year, rating and sort order
+func (c *TMDBClient) DiscoverMovies(discover DiscoverParams) (*TMDBSearchResponse, error) {
+	params := map[string]string{
+		"include_adult": "false",
+	}
+
+	if len(discover.GenreIDs) > 0 {
+		genreIDs := make([]string, len(discover.GenreIDs))
+		for i, id := range discover.GenreIDs {
+			genreIDs[i] = strconv.Itoa(id)
+		}
+		params["with_genres"] = strings.Join(genreIDs, ",")
+	}
+	if discover.YearFrom > 0 {
+		params["primary_release_date.gte"] = fmt.Sprintf("%04d-01-01", discover.YearFrom)
+	}
+	if discover.YearTo > 0 {
+		params["primary_release_date.lte"] = fmt.Sprintf("%04d-12-31", discover.YearTo)
+	}
+	if discover.MinVoteAverage > 0 {
+		params["vote_average.gte"] = strconv.FormatFloat(discover.MinVoteAverage, 'f', -1, 64)
+	}
+	if discover.MinVoteCount > 0 {
+		params["vote_count.gte"] = strconv.Itoa(discover.MinVoteCount)
+	}
+	if discover.SortBy != "" {
+		params["sort_by"] = discover.SortBy
+	}
+	if discover.Page > 0 {
+		params["page"] = strconv.Itoa(discover.Page)
+	}
+
+	resp, err := c.makeRequest("/discover/movie", params)
+	if err != nil {
+		return nil, fmt.Errorf("discover request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var searchResp TMDBSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode discover response: %w", err)
+	}
+
+	return &searchResp, nil
+}
+
+// GetSimilarMovies gets movies TMDB considers similar (shared genres and keywords)
+func (c *TMDBClient) GetSimilarMovies(tmdbID int, page int) (*TMDBSearchResponse, error) {
+	return c.getRelatedMovies(tmdbID, "similar", page)
+}
+
+// GetMovieRecommendations gets TMDB's recommendations for a movie, based on what users
+// who liked it also liked
+func (c *TMDBClient) GetMovieRecommendations(tmdbID int, page int) (*TMDBSearchResponse, error) {
+	return c.getRelatedMovies(tmdbID, "recommendations", page)
+}
+
+// getRelatedMovies fetches a page of /movie/{id}/{route}
+func (c *TMDBClient) getRelatedMovies(tmdbID int, route string, page int) (*TMDBSearchResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+
+	params := map[string]string{
+		"page": strconv.Itoa(page),
+	}
+
+	endpoint := fmt.Sprintf("/movie/%d/%s", tmdbID, route)
+	resp, err := c.makeRequest(endpoint, params)
+	if err != nil {
+		return nil, fmt.Errorf("%s movies request failed: %w", route, err)
+	}
+	defer resp.Body.Close()
+
+	var searchResp TMDBSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode %s movies response: %w", route, err)
+	}
+
+	return &searchResp, nil
+}
+
+// GetTrendingMovies gets a page of trending movies
+func (c *TMDBClient) GetTrendingMovies(timeWindow string, page int) (*TMDBSearchResponse, error) {
 	if timeWindow != "day" && timeWindow != "week" {
 		timeWindow = "week"
 	}
+	if page <= 0 {
+		page = 1
+	}
 
 	endpoint := fmt.Sprintf("/trending/movie/%s", timeWindow)
-	
-	resp, err := c.makeRequest(endpoint, nil)
+	params := map[string]string{
+		"page": strconv.Itoa(page),
+	}
+
+	resp, err := c.makeRequest(endpoint, params)
 	if err != nil {
 		return nil, fmt.Errorf("trending movies request failed: %w", err)
 	}
@@ -211,6 +491,42 @@ func (c *TMDBClient) GetTrendingMovies(timeWindow string) (*TMDBSearchResponse,
 	return &searchResp, nil
 }
 
+// regionalTrendingWindow is how far back a regional release may be to count as trending
+const regionalTrendingWindow = 60 * 24 * time.Hour
+
+// GetRegionalTrendingMovies approximates trending movies for a country, which TMDB's trending
+// endpoint can't do: the most popular movies released there in cinemas or digitally within
+// the last two months
+func (c *TMDBClient) GetRegionalTrendingMovies(region string, page int) (*TMDBSearchResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+
+	today := time.Now().UTC()
+	params := map[string]string{
+		"include_adult":     "false",
+		"sort_by":           "popularity.desc",
+		"region":            region,
+		"with_release_type": "2|3|4", // Limited theatrical, theatrical, digital
+		"release_date.gte":  today.Add(-regionalTrendingWindow).Format("2006-01-02"),
+		"release_date.lte":  today.Format("2006-01-02"),
+		"page":              strconv.Itoa(page),
+	}
+
+	resp, err := c.makeRequest("/discover/movie", params)
+	if err != nil {
+		return nil, fmt.Errorf("regional trending request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var searchResp TMDBSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode regional trending response: %w", err)
+	}
+
+	return &searchResp, nil
+}
+
 // GetMovieExternalIDs gets external IDs (IMDb, etc.) for a movie
 func (c *TMDBClient) GetMovieExternalIDs(tmdbID int) (*TMDBExternalIDs, error) {
 	endpoint := fmt.Sprintf("/movie/%d/external_ids", tmdbID)
@@ -229,11 +545,39 @@ func (c *TMDBClient) GetMovieExternalIDs(tmdbID int) (*TMDBExternalIDs, error) {
 	return &externalIDs, nil
 }
 
+// TMDBCollection is a TMDB collection (franchise) with its movies
+type TMDBCollection struct {
+	ID           int         `json:"id"`
+	Name         string      `json:"name"`
+	Overview     string      `json:"overview"`
+	PosterPath   *string     `json:"poster_path"`
+	BackdropPath *string     `json:"backdrop_path"`
+	Parts        []TMDBMovie `json:"parts"`
+}
+
+// GetCollection gets a collection and the movies that belong to it
+func (c *TMDBClient) GetCollection(collectionID int) (*TMDBCollection, error) {
+	endpoint := fmt.Sprintf("/collection/%d", collectionID)
+
+	resp, err := c.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("collection request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var collection TMDBCollection
+	if err := json.NewDecoder(resp.Body).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("failed to decode collection: %w", err)
+	}
+
+	return &collection, nil
+}
+
 // TMDBFindResponse represents the response from TMDB find API
 type TMDBFindResponse struct {
 	MovieResults []TMDBMovie `json:"movie_results"`
 	PersonResults []interface{} `json:"person_results"`
-	TVResults []interface{} `json:"tv_results"`
+	TVResults []TMDBTVShow `json:"tv_results"`
 }
 
 // FindByExternalID finds TMDB movie by external ID (IMDb, TVDB, etc.)
@@ -314,6 +658,83 @@ func (c *TMDBClient) GetMovieWatchProviders(tmdbID int) (*TMDBWatchProvidersResp
 	return &watchProviders, nil
 }
 
+// TMDBCastMember is an actor in a movie's credits
+type TMDBCastMember struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Character   string  `json:"character"`
+	ProfilePath *string `json:"profile_path"`
+	Order       int     `json:"order"` // Billing order, 0 is top billed
+}
+
+// TMDBCrewMember is a crew member in a movie's credits
+type TMDBCrewMember struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Job         string  `json:"job"`
+	Department  string  `json:"department"`
+	ProfilePath *string `json:"profile_path"`
+}
+
+// TMDBCredits represents the response from TMDB credits API
+type TMDBCredits struct {
+	ID   int              `json:"id"`
+	Cast []TMDBCastMember `json:"cast"`
+	Crew []TMDBCrewMember `json:"crew"`
+}
+
+// GetMovieCredits gets the cast and crew of a movie
+func (c *TMDBClient) GetMovieCredits(tmdbID int) (*TMDBCredits, error) {
+	endpoint := fmt.Sprintf("/movie/%d/credits", tmdbID)
+
+	resp, err := c.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("credits request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var credits TMDBCredits
+	if err := json.NewDecoder(resp.Body).Decode(&credits); err != nil {
+		return nil, fmt.Errorf("failed to decode credits: %w", err)
+	}
+
+	return &credits, nil
+}
+
+// TMDBVideo is a trailer, teaser, clip or featurette for a movie
+type TMDBVideo struct {
+	Key         string `json:"key"`  // Site-specific id, e.g. the YouTube video id
+	Site        string `json:"site"` // "YouTube", "Vimeo"
+	Type        string `json:"type"` // "Trailer", "Teaser", "Clip", "Featurette", ...
+	Name        string `json:"name"`
+	Official    bool   `json:"official"`
+	PublishedAt string `json:"published_at"`
+}
+
+// TMDBVideosResponse represents the response from TMDB videos API
+type TMDBVideosResponse struct {
+	ID      int         `json:"id"`
+	Results []TMDBVideo `json:"results"`
+}
+
+// GetMovieVideos gets the videos (trailers, teasers, clips) of a movie
+func (c *TMDBClient) GetMovieVideos(tmdbID int) (*TMDBVideosResponse, error) {
+	endpoint := fmt.Sprintf("/movie/%d/videos", tmdbID)
+
+	resp, err := c.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("videos request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var videos TMDBVideosResponse
+	if err := json.NewDecoder(resp.Body).Decode(&videos); err != nil {
+		return nil, fmt.Errorf("failed to decode videos: %w", err)
+	}
+
+	return &videos, nil
+}
+
 // GetPosterURL generates the full URL for a movie poster
 func (c *TMDBClient) GetPosterURL(posterPath *string, size string) string {
 	if posterPath == nil || *posterPath == "" {
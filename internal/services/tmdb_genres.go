@@ -0,0 +1,32 @@
+package services
+
+// tmdbMovieGenreIDs maps TMDB's movie genre names, as stored in the movies table, to the
+// genre ids used by discover and in search results. TMDB's genre list rarely changes, so it
+// isn't fetched.
+var tmdbMovieGenreIDs = map[string]int{
+	"Action":          28,
+	"Adventure":       12,
+	"Animation":       16,
+	"Comedy":          35,
+	"Crime":           80,
+	"Documentary":     99,
+	"Drama":           18,
+	"Family":          10751,
+	"Fantasy":         14,
+	"History":         36,
+	"Horror":          27,
+	"Music":           10402,
+	"Mystery":         9648,
+	"Romance":         10749,
+	"Science Fiction": 878,
+	"TV Movie":        10770,
+	"Thriller":        53,
+	"War":             10752,
+	"Western":         37,
+}
+
+// TMDBMovieGenreID returns the TMDB id of a movie genre name
+func TMDBMovieGenreID(name string) (int, bool) {
+	id, ok := tmdbMovieGenreIDs[name]
+	return id, ok
+}
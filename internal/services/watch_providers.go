@@ -1,8 +1,14 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -11,10 +17,23 @@ type WatchProvidersService struct {
 	tmdbClient   *TMDBClient
 	plexClient   *PlexClient   // Keep for backward compatibility
 	plexgoClient *PlexgoClient // Use for new permission-aware operations
+	logger       *slog.Logger
+
+	searchMu    sync.Mutex
+	searchCache map[string]plexTitleSearchEntry // Live Plex searches, keyed by user and TMDB id
+}
+
+// plexTitleSearchTTL is how long a live Plex search for a movie is reused for a user
+const plexTitleSearchTTL = 10 * time.Minute
+
+type plexTitleSearchEntry struct {
+	providers []WatchProvider
+	expiresAt time.Time
 }
 
 // WatchProvider represents a unified watch provider (TMDB + Plex)
 type WatchProvider struct {
+	ProviderID   int     `json:"providerId,omitempty"` // TMDB provider id (not set for Plex)
 	Name         string  `json:"name"`
 	LogoPath     string  `json:"logoPath,omitempty"`
 	ProviderType string  `json:"providerType"` // "flatrate", "rent", "buy", "free", "plex"
@@ -27,13 +46,27 @@ type WatchProvider struct {
 
 // WatchProvidersResponse represents the combined response
 type WatchProvidersResponse struct {
-	TMDBID        int             `json:"tmdbId"`
-	Region        string          `json:"region"`
-	TMDBLink      string          `json:"tmdbLink,omitempty"`
-	Providers     []WatchProvider `json:"providers"`
-	PlexAvailable bool            `json:"plexAvailable"`
-	CachedAt      time.Time       `json:"cachedAt"`
-	ExpiresAt     time.Time       `json:"expiresAt"`
+	TMDBID         int             `json:"tmdbId"`
+	Region         string          `json:"region"`
+	TMDBLink       string          `json:"tmdbLink,omitempty"`
+	Providers      []WatchProvider `json:"providers"`
+	OtherProviders []WatchProvider `json:"otherProviders,omitempty"` // Unsubscribed services when grouped
+	PlexAvailable  bool            `json:"plexAvailable"`
+	CachedAt       time.Time       `json:"cachedAt"`
+	ExpiresAt      time.Time       `json:"expiresAt"`
+}
+
+// PlexLocation is a server and library where a user can find a movie
+type PlexLocation struct {
+	ServerName  string `json:"serverName"`
+	MachineID   string `json:"machineId"`
+	LibraryName string `json:"libraryName"`
+	SectionKey  int    `json:"sectionKey"`
+	RatingKey   string `json:"ratingKey"`
+	Title       string `json:"title"`
+	Year        *int   `json:"year,omitempty"`
+	Owned       bool   `json:"owned"` // The user owns the server (otherwise it is shared with them)
+	PlexURL     string `json:"plexUrl"`
 }
 
 func NewWatchProvidersService(db *sql.DB, tmdbClient *TMDBClient, plexClient *PlexClient) *WatchProvidersService {
@@ -42,152 +75,339 @@ func NewWatchProvidersService(db *sql.DB, tmdbClient *TMDBClient, plexClient *Pl
 		tmdbClient:   tmdbClient,
 		plexClient:   plexClient,        // Keep for backward compatibility during migration
 		plexgoClient: NewPlexgoClient(), // Primary client for all operations
+		logger:       slog.Default(),
+		searchCache:  make(map[string]plexTitleSearchEntry),
 	}
 }
 
+// SetLogger replaces the logger, which defaults to slog's default logger at construction
+func (s *WatchProvidersService) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+	s.plexgoClient.SetLogger(logger)
+}
+
+// DefaultWatchRegion is used when neither the request nor the user's preferences name a region
+const DefaultWatchRegion = "NO"
+
+// watchProvidersCacheTTL is how long TMDB watch providers are cached per region
+const watchProvidersCacheTTL = 48 * time.Hour
+
+// NormalizeRegion upper-cases an ISO 3166-1 alpha-2 country code, reporting whether it is valid
+func NormalizeRegion(region string) (string, bool) {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	if len(region) != 2 || region[0] < 'A' || region[0] > 'Z' || region[1] < 'A' || region[1] > 'Z' {
+		return "", false
+	}
+	return region, true
+}
+
 // GetWatchProviders gets watch provider information with caching
 func (s *WatchProvidersService) GetWatchProviders(tmdbID int, region string, userID *int) (*WatchProvidersResponse, error) {
 	if region == "" {
-		region = "US" // Default to US
-	}
-
-	// TEMPORARILY DISABLE CACHE - Try to get from cache first
-	// cached, err := s.getCachedWatchProviders(tmdbID, region)
-	// if err == nil && cached.ExpiresAt.After(time.Now()) {
-	// 	// Add Plex availability if user is provided
-	// 	if userID != nil {
-	// 		plexAvailable, plexProviders, err := s.getPlexAvailability(tmdbID, *userID)
-	// 		if err == nil {
-	// 			cached.PlexAvailable = plexAvailable
-	// 			// Add Plex providers to the list
-	// 			cached.Providers = append(cached.Providers, plexProviders...)
-	// 		}
-	// 	}
-	// 	return cached, nil
-	// }
-
-	fmt.Printf("DEBUG: CACHE DISABLED - Forcing fresh lookup for TMDB ID %d\n", tmdbID)
+		region = DefaultWatchRegion
+	} else if normalized, ok := NormalizeRegion(region); ok {
+		region = normalized
+	}
 
-	// Fetch fresh data from TMDB
-	tmdbProviders, err := s.tmdbClient.GetMovieWatchProviders(tmdbID)
+	responses, err := s.GetWatchProvidersForRegions(tmdbID, []string{region}, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get TMDB watch providers: %w", err)
+		return nil, err
+	}
+
+	return responses[region], nil
+}
+
+// GetWatchProvidersForRegions gets watch providers for several regions at once, keyed by
+// region. TMDB data is cached per (tmdb_id, region) for 48 hours; the user's Plex
+// availability is looked up fresh and added to every region.
+func (s *WatchProvidersService) GetWatchProvidersForRegions(tmdbID int, regions []string, userID *int) (map[string]*WatchProvidersResponse, error) {
+	responses := make(map[string]*WatchProvidersResponse, len(regions))
+
+	var missing []string
+	for _, region := range regions {
+		if cached, err := s.getCachedWatchProviders(tmdbID, region); err == nil {
+			responses[region] = cached
+		} else {
+			missing = append(missing, region)
+		}
 	}
 
-	// Convert TMDB data to our format
+	// One TMDB call covers every region
+	if len(missing) > 0 {
+		tmdbProviders, err := s.tmdbClient.GetMovieWatchProviders(tmdbID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get TMDB watch providers: %w", err)
+		}
+
+		for _, region := range missing {
+			response := s.buildRegionProviders(tmdbID, region, tmdbProviders)
+
+			// Cache the TMDB data (not including Plex data which is user-specific)
+			if err := s.cacheWatchProviders(response); err != nil {
+				s.logger.Warn("Failed to cache watch providers", "tmdb_id", tmdbID, "region", region, "error", err)
+			}
+
+			responses[region] = response
+		}
+	}
+
+	// Add Plex availability if user is provided
+	if userID != nil {
+		plexAvailable, plexProviders, err := s.getPlexAvailability(tmdbID, *userID)
+		if err == nil {
+			for _, response := range responses {
+				response.PlexAvailable = plexAvailable
+				response.Providers = append(response.Providers, plexProviders...)
+			}
+		}
+	}
+
+	return responses, nil
+}
+
+// buildRegionProviders converts TMDB's providers for one region to our format
+func (s *WatchProvidersService) buildRegionProviders(tmdbID int, region string, tmdbProviders *TMDBWatchProvidersResponse) *WatchProvidersResponse {
+	now := time.Now()
 	response := &WatchProvidersResponse{
 		TMDBID:    tmdbID,
 		Region:    region,
-		CachedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(48 * time.Hour), // 48 hour cache
+		CachedAt:  now,
+		ExpiresAt: now.Add(watchProvidersCacheTTL),
 		Providers: []WatchProvider{},
 	}
 
 	// Process region-specific providers
-	if regionData, exists := tmdbProviders.Results[region]; exists {
-		response.TMDBLink = regionData.Link
+	regionData, exists := tmdbProviders.Results[region]
+	if !exists {
+		return response
+	}
 
-		// Add flatrate providers (subscriptions like Netflix)
-		for _, provider := range regionData.Flatrate {
-			response.Providers = append(response.Providers, WatchProvider{
-				Name:         provider.ProviderName,
-				LogoPath:     s.tmdbClient.GetPosterURL(&provider.LogoPath, "w92"),
-				ProviderType: "flatrate",
-				Link:         regionData.Link,
-			})
-		}
+	response.TMDBLink = regionData.Link
 
-		// Add rent providers
-		for _, provider := range regionData.Rent {
-			response.Providers = append(response.Providers, WatchProvider{
-				Name:         provider.ProviderName,
-				LogoPath:     s.tmdbClient.GetPosterURL(&provider.LogoPath, "w92"),
-				ProviderType: "rent",
-				Link:         regionData.Link,
-			})
-		}
+	groups := []struct {
+		providerType string
+		providers    []TMDBWatchProvider
+	}{
+		{"flatrate", regionData.Flatrate}, // Subscriptions like Netflix
+		{"rent", regionData.Rent},
+		{"buy", regionData.Buy},
+		{"free", regionData.Free},
+	}
 
-		// Add buy providers
-		for _, provider := range regionData.Buy {
+	for _, group := range groups {
+		for _, provider := range group.providers {
 			response.Providers = append(response.Providers, WatchProvider{
+				ProviderID:   provider.ProviderID,
 				Name:         provider.ProviderName,
 				LogoPath:     s.tmdbClient.GetPosterURL(&provider.LogoPath, "w92"),
-				ProviderType: "buy",
+				ProviderType: group.providerType,
 				Link:         regionData.Link,
 			})
 		}
+	}
 
-		// Add free providers
-		for _, provider := range regionData.Free {
-			response.Providers = append(response.Providers, WatchProvider{
-				Name:         provider.ProviderName,
-				LogoPath:     s.tmdbClient.GetPosterURL(&provider.LogoPath, "w92"),
-				ProviderType: "free",
-				Link:         regionData.Link,
-			})
-		}
+	return response
+}
+
+// FilterSubscribedProviders applies a user's subscriptions to a response. Subscription
+// (flatrate) providers whose id isn't in subscribed are dropped, or moved to
+// OtherProviders when group is true. Rent, buy, free and Plex entries are kept.
+func FilterSubscribedProviders(response *WatchProvidersResponse, subscribed []int, group bool) {
+	subscribedIDs := make(map[int]bool, len(subscribed))
+	for _, id := range subscribed {
+		subscribedIDs[id] = true
 	}
 
-	// Add Plex availability if user is provided
-	if userID != nil {
-		plexAvailable, plexProviders, err := s.getPlexAvailability(tmdbID, *userID)
-		if err == nil {
-			response.PlexAvailable = plexAvailable
-			response.Providers = append(response.Providers, plexProviders...)
+	kept := make([]WatchProvider, 0, len(response.Providers))
+	for _, provider := range response.Providers {
+		if provider.ProviderType != "flatrate" || subscribedIDs[provider.ProviderID] {
+			kept = append(kept, provider)
+		} else if group {
+			response.OtherProviders = append(response.OtherProviders, provider)
 		}
 	}
+	response.Providers = kept
+}
 
-	// SKIP CACHING WHILE TESTING - Cache the TMDB data (not including Plex data which is user-specific)
-	// err = s.cacheWatchProviders(response)
-	// if err != nil {
-	// 	fmt.Printf("Failed to cache watch providers: %v\n", err)
-	// }
-	fmt.Printf("DEBUG: SKIPPING TMDB provider cache write for testing\n")
+// errWatchProvidersCacheMismatch means a cached row's data doesn't belong to its cache key
+var errWatchProvidersCacheMismatch = errors.New("cached watch providers don't match the requested movie and region")
+
+// getCachedWatchProviders returns unexpired cached TMDB providers for a movie and region
+func (s *WatchProvidersService) getCachedWatchProviders(tmdbID int, region string) (*WatchProvidersResponse, error) {
+	var data string
+	var cachedAt, expiresAt time.Time
+	err := s.db.QueryRow(`
+		SELECT providers_data, cached_at, expires_at
+		FROM watch_providers_cache
+		WHERE tmdb_id = ? AND region_code = ?
+	`, tmdbID, region).Scan(&data, &cachedAt, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("cached watch providers expired")
+	}
+
+	var response WatchProvidersResponse
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		return nil, fmt.Errorf("failed to decode cached watch providers: %w", err)
+	}
+
+	// A row written for another movie or region (e.g. before the user changed regions) must not
+	// be served; treating it as a miss refetches and overwrites it
+	if response.TMDBID != tmdbID || response.Region != region {
+		return nil, errWatchProvidersCacheMismatch
+	}
+
+	response.CachedAt = cachedAt
+	response.ExpiresAt = expiresAt
 
-	return response, nil
+	return &response, nil
 }
 
-// getPlexAvailability checks if movie is available on user's Plex servers using database query
+// cacheWatchProviders stores TMDB providers for a movie and region
+func (s *WatchProvidersService) cacheWatchProviders(response *WatchProvidersResponse) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO watch_providers_cache (tmdb_id, region_code, providers_data, cached_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(tmdb_id, region_code) DO UPDATE SET
+			providers_data = excluded.providers_data,
+			cached_at = excluded.cached_at,
+			expires_at = excluded.expires_at
+	`, response.TMDBID, response.Region, string(data), response.CachedAt.UTC(), response.ExpiresAt.UTC())
+
+	return err
+}
+
+// getPlexAvailability checks if movie is available on user's Plex servers using the synced
+// libraries, falling back to searching the servers by title
 func (s *WatchProvidersService) getPlexAvailability(tmdbID int, userID int) (bool, []WatchProvider, error) {
-	fmt.Printf("DEBUG: Starting Plex availability check for TMDB ID %d, User ID %d\n", tmdbID, userID)
+	s.logger.Debug("Checking Plex availability", "tmdb_id", tmdbID, "user_id", userID)
 
 	// TEMPORARILY DISABLE CACHE - Check cache first
 	// cachedAvailable, cachedProviders, err := s.getCachedPlexAvailability(tmdbID, userID)
 	// if err == nil {
-	// 	fmt.Printf("DEBUG: Found cached Plex availability: %v (expires check passed)\n", cachedAvailable)
+	// 	s.logger.Debug("Found cached Plex availability", "available", cachedAvailable)
 	// 	return cachedAvailable, cachedProviders, nil
 	// }
-	fmt.Printf("DEBUG: CACHE DISABLED - Skipping cache lookup for testing\n")
+	s.logger.Debug("Plex availability cache disabled, skipping lookup")
 
 	// Get detailed Plex availability with server information for clickable links
-	fmt.Printf("DEBUG: Getting detailed Plex availability using database query\n")
 	plexProviders, err := s.getPlexProvidersFromDatabase(tmdbID, userID)
 	if err != nil {
-		fmt.Printf("DEBUG: Database query failed: %v\n", err)
+		s.logger.Debug("Failed to get Plex providers", "tmdb_id", tmdbID, "user_id", userID, "error", err)
 		return false, []WatchProvider{}, nil
 	}
-	fmt.Printf("DEBUG: Database query completed. Found %d Plex providers\n", len(plexProviders))
+	s.logger.Debug("Got Plex providers", "tmdb_id", tmdbID, "count", len(plexProviders))
+
+	// Libraries synced since the last TMDB matching, or items the matching missed, are only
+	// found by searching the servers for the title
+	if len(plexProviders) == 0 {
+		plexProviders = s.searchPlexForMovie(tmdbID, userID)
+	}
 
 	isAvailable := len(plexProviders) > 0
 
 	// SKIP CACHING WHILE TESTING - Cache the result
-	fmt.Printf("DEBUG: SKIPPING cache write for testing: available=%v\n", isAvailable)
 	// s.cachePlexAvailability(tmdbID, userID, isAvailable, []string{})
 
-	fmt.Printf("DEBUG: Completed Plex availability check. Final result: %v\n", isAvailable)
+	s.logger.Debug("Checked Plex availability", "tmdb_id", tmdbID, "user_id", userID, "available", isAvailable)
 	return isAvailable, plexProviders, nil
 }
 
+// searchPlexForMovie searches the user's Plex servers for a movie by its title and year, for
+// movies the synced libraries don't have matched to TMDB. Results are reused for a while so
+// the detail page doesn't search every server on each view.
+func (s *WatchProvidersService) searchPlexForMovie(tmdbID int, userID int) []WatchProvider {
+	key := fmt.Sprintf("%d:%d", userID, tmdbID)
+	s.searchMu.Lock()
+	entry, ok := s.searchCache[key]
+	s.searchMu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.providers
+	}
+
+	// Only cached movies have a title to search for
+	var title string
+	var year *int
+	if err := s.db.QueryRow("SELECT title, year FROM movies WHERE tmdb_id = ?", tmdbID).Scan(&title, &year); err != nil {
+		return nil
+	}
+
+	plexToken, err := GetUserPlexToken(s.db, int64(userID))
+	if err != nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	servers, err := s.plexgoClient.GetServers(ctx, plexToken)
+	if err != nil {
+		s.logger.Debug("Failed to get Plex servers for title search", "user_id", userID, "error", err)
+		return nil
+	}
+
+	providers := s.searchPlexServers(ctx, servers, title, year)
+
+	s.searchMu.Lock()
+	now := time.Now()
+	for k, e := range s.searchCache {
+		if now.After(e.expiresAt) {
+			delete(s.searchCache, k)
+		}
+	}
+	s.searchCache[key] = plexTitleSearchEntry{providers: providers, expiresAt: now.Add(plexTitleSearchTTL)}
+	s.searchMu.Unlock()
+
+	return providers
+}
+
+// searchPlexServers returns a Plex provider for each server that has a movie with the title
+// and year. Servers that can't be reached are skipped.
+func (s *WatchProvidersService) searchPlexServers(ctx context.Context, servers []PlexServer, title string, year *int) []WatchProvider {
+	var providers []WatchProvider
+	for _, server := range servers {
+		connection, _ := s.plexgoClient.SelectConnection(ctx, server)
+		if connection == nil {
+			continue
+		}
+
+		found, err := s.plexgoClient.SearchMovieByTitle(ctx, server.AccessToken, s.plexgoClient.BuildServerURL(*connection), title, year)
+		if err != nil {
+			s.logger.Debug("Plex title search failed", "server", server.Name, "title", title, "error", err)
+			continue
+		}
+		if !found {
+			continue
+		}
+
+		plexURL := fmt.Sprintf("https://app.plex.tv/desktop/#!/server/%s", server.MachineID)
+		providers = append(providers, WatchProvider{
+			Name:         fmt.Sprintf("Plex (%s)", server.Name),
+			ProviderType: "plex",
+			PlexServer:   server.Name,
+			PlexURL:      plexURL,
+			Link:         plexURL,
+		})
+	}
+	return providers
+}
+
 // ClearExpiredCache removes expired cache entries
 func (s *WatchProvidersService) ClearExpiredCache() error {
 	// Clear expired TMDB watch providers cache
-	_, err := s.db.Exec("DELETE FROM watch_providers_cache WHERE expires_at <= datetime('now')")
+	_, err := s.db.Exec("DELETE FROM watch_providers_cache WHERE expires_at <= CURRENT_TIMESTAMP")
 	if err != nil {
 		return fmt.Errorf("failed to clear expired watch providers cache: %w", err)
 	}
 
 	// Clear expired Plex availability cache
-	_, err = s.db.Exec("DELETE FROM plex_availability_cache WHERE expires_at <= datetime('now')")
+	_, err = s.db.Exec("DELETE FROM plex_availability_cache WHERE expires_at <= CURRENT_TIMESTAMP")
 	if err != nil {
 		return fmt.Errorf("failed to clear expired Plex availability cache: %w", err)
 	}
@@ -251,3 +471,37 @@ func (s *WatchProvidersService) getPlexProvidersFromDatabase(tmdbID int, userID
 
 	return providers, nil
 }
+
+// GetPlexLocations lists every server and library the user can access that has the movie, using synced data
+func (s *WatchProvidersService) GetPlexLocations(tmdbID int, userID int) ([]PlexLocation, error) {
+	rows, err := s.db.Query(`
+		SELECT ps.name, ps.machine_id, pl.title, pl.section_key,
+		       pli.plex_rating_key, pli.title, pli.year,
+		       CASE WHEN ps.owner_user_id = upa.user_id THEN 1 ELSE 0 END as owned
+		FROM plex_library_items pli
+		JOIN plex_libraries pl ON pli.library_id = pl.id
+		JOIN plex_servers ps ON pl.server_id = ps.id
+		JOIN user_plex_access upa ON pl.id = upa.library_id
+		WHERE upa.user_id = ? AND pli.tmdb_id = ? AND pli.is_active = 1 AND upa.is_active = 1
+		ORDER BY owned DESC, ps.name, pl.title
+	`, userID, tmdbID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Plex locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []PlexLocation{}
+	for rows.Next() {
+		var location PlexLocation
+		err := rows.Scan(&location.ServerName, &location.MachineID, &location.LibraryName, &location.SectionKey,
+			&location.RatingKey, &location.Title, &location.Year, &location.Owned)
+		if err != nil {
+			continue
+		}
+
+		location.PlexURL = fmt.Sprintf("https://app.plex.tv/desktop/#!/server/%s/details?key=%%2Flibrary%%2Fmetadata%%2F%s", location.MachineID, location.RatingKey)
+		locations = append(locations, location)
+	}
+
+	return locations, nil
+}
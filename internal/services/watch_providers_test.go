@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"moviedb/internal/database"
+)
+
+// newTestDB opens a migrated SQLite database in a temporary directory
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", t.TempDir()+"/test.db?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// newWatchProvidersTMDB serves a movie's watch providers, with a different subscription
+// service in each region, and counts the requests made
+func newWatchProvidersTMDB(t *testing.T, tmdbID int) (*TMDBClient, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(TMDBWatchProvidersResponse{
+			ID: tmdbID,
+			Results: map[string]TMDBWatchProvidersRegion{
+				"US": {Link: "https://tmdb.test/us", Flatrate: []TMDBWatchProvider{{ProviderID: 8, ProviderName: "Netflix"}}},
+				"NO": {Link: "https://tmdb.test/no", Flatrate: []TMDBWatchProvider{{ProviderID: 76, ProviderName: "Viaplay"}}},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewTMDBClient("test-api-key-for-watch-providers")
+	client.BaseURL = server.URL
+	return client, &requests
+}
+
+// providerNames lists a response's provider names
+func providerNames(response *WatchProvidersResponse) []string {
+	names := []string{}
+	for _, provider := range response.Providers {
+		names = append(names, provider.Name)
+	}
+	return names
+}
+
+func TestWatchProvidersCachedPerRegion(t *testing.T) {
+	const tmdbID = 603
+	db := newTestDB(t)
+	tmdbClient, requests := newWatchProvidersTMDB(t, tmdbID)
+	service := NewWatchProvidersService(db, tmdbClient, NewPlexClient())
+
+	fetch := func(region string) *WatchProvidersResponse {
+		t.Helper()
+		response, err := service.GetWatchProviders(tmdbID, region, nil)
+		if err != nil {
+			t.Fatalf("GetWatchProviders(%s) error = %v", region, err)
+		}
+		return response
+	}
+	expect := func(response *WatchProvidersResponse, region, provider, link string) {
+		t.Helper()
+		names := providerNames(response)
+		if response.Region != region || len(names) != 1 || names[0] != provider || response.TMDBLink != link {
+			t.Errorf("region %s: got region %s, providers %v, link %q; want %s at %q",
+				region, response.Region, names, response.TMDBLink, provider, link)
+		}
+	}
+
+	expect(fetch("US"), "US", "Netflix", "https://tmdb.test/us")
+
+	// The US data is cached, but another region must not be served from it
+	expect(fetch("no"), "NO", "Viaplay", "https://tmdb.test/no")
+	if n := requests.Load(); n != 2 {
+		t.Errorf("TMDB requests = %d, want 2 (one per region)", n)
+	}
+
+	// Both regions are now served from their own cache rows
+	expect(fetch("US"), "US", "Netflix", "https://tmdb.test/us")
+	expect(fetch("NO"), "NO", "Viaplay", "https://tmdb.test/no")
+	if n := requests.Load(); n != 2 {
+		t.Errorf("TMDB requests = %d, want 2 after cache hits", n)
+	}
+
+	// A row holding another region's data is refetched rather than served
+	var usData string
+	if err := db.QueryRow(`SELECT providers_data FROM watch_providers_cache WHERE tmdb_id = ? AND region_code = 'US'`, tmdbID).Scan(&usData); err != nil {
+		t.Fatalf("failed to read the US cache row: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE watch_providers_cache SET providers_data = ? WHERE tmdb_id = ? AND region_code = 'NO'`, usData, tmdbID); err != nil {
+		t.Fatalf("failed to overwrite the NO cache row: %v", err)
+	}
+	expect(fetch("NO"), "NO", "Viaplay", "https://tmdb.test/no")
+	if n := requests.Load(); n != 3 {
+		t.Errorf("TMDB requests = %d, want 3 after the mismatched row", n)
+	}
+
+	// Several regions at once each get their own data
+	responses, err := service.GetWatchProvidersForRegions(tmdbID, []string{"US", "NO", "SE"}, nil)
+	if err != nil {
+		t.Fatalf("GetWatchProvidersForRegions() error = %v", err)
+	}
+	expect(responses["US"], "US", "Netflix", "https://tmdb.test/us")
+	expect(responses["NO"], "NO", "Viaplay", "https://tmdb.test/no")
+	if names := providerNames(responses["SE"]); responses["SE"].Region != "SE" || len(names) != 0 {
+		t.Errorf("region SE: got region %s, providers %v; want none", responses["SE"].Region, names)
+	}
+}
+
+func TestSearchPlexServers(t *testing.T) {
+	has := newPlexSearchServer(t, []plexSearchItem{{Title: "Dune", Year: intPtr(2021)}})
+	sequelOnly := newPlexSearchServer(t, []plexSearchItem{{Title: "Dune: Part Two", Year: intPtr(2024)}})
+	servers := []PlexServer{
+		{Name: "Home", MachineID: "home", Connections: []PlexConnection{{URI: has.URL, Local: true}}},
+		{Name: "Friend", MachineID: "friend", Connections: []PlexConnection{{URI: sequelOnly.URL}}},
+		{Name: "Offline", MachineID: "offline", Connections: []PlexConnection{{URI: unreachableURL(t)}}},
+	}
+
+	service := NewWatchProvidersService(newTestDB(t), NewTMDBClient("test-api-key-for-plex-search"), NewPlexClient())
+	providers := service.searchPlexServers(context.Background(), servers, "Dune", intPtr(2021))
+
+	if len(providers) != 1 || providers[0].PlexServer != "Home" || providers[0].ProviderType != "plex" {
+		t.Fatalf("providers = %+v, want the Home server only", providers)
+	}
+	if want := "https://app.plex.tv/desktop/#!/server/home"; providers[0].PlexURL != want {
+		t.Errorf("Plex URL = %q, want %q", providers[0].PlexURL, want)
+	}
+}
+
+func TestPlexAvailabilityFallsBackToTitleSearch(t *testing.T) {
+	const tmdbID, userID = 438631, 1
+	service := NewWatchProvidersService(newTestDB(t), NewTMDBClient("test-api-key-for-plex-search"), NewPlexClient())
+
+	// A movie that isn't cached has no title to search for, and nothing is remembered
+	if available, providers, _ := service.getPlexAvailability(tmdbID, userID); available || len(providers) != 0 {
+		t.Errorf("uncached movie: available = %v, providers = %+v", available, providers)
+	}
+	if len(service.searchCache) != 0 {
+		t.Errorf("search cache = %v, want nothing cached for a movie without a title", service.searchCache)
+	}
+
+	// A recent search is reused when the synced libraries don't have the movie
+	found := []WatchProvider{{Name: "Plex (Home)", ProviderType: "plex", PlexServer: "Home"}}
+	service.searchCache[fmt.Sprintf("%d:%d", userID, tmdbID)] = plexTitleSearchEntry{providers: found, expiresAt: time.Now().Add(time.Minute)}
+	available, providers, err := service.getPlexAvailability(tmdbID, userID)
+	if err != nil || !available || len(providers) != 1 || providers[0].PlexServer != "Home" {
+		t.Errorf("cached search: available = %v, providers = %+v, error = %v", available, providers, err)
+	}
+}
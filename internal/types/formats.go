@@ -0,0 +1,88 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Physical and digital formats a user can own a movie in
+const (
+	OwnedFormatDVD     = "dvd"
+	OwnedFormatBluray  = "bluray"
+	OwnedFormat4KUHD   = "4k_uhd"
+	OwnedFormatDigital = "digital"
+	OwnedFormatVHS     = "vhs"
+)
+
+// ownedFormats lists the allowed formats in display order
+var ownedFormats = []string{
+	OwnedFormatDVD,
+	OwnedFormatBluray,
+	OwnedFormat4KUHD,
+	OwnedFormatDigital,
+	OwnedFormatVHS,
+}
+
+// ownedFormatLabels are the display names of the allowed formats
+var ownedFormatLabels = map[string]string{
+	OwnedFormatDVD:     "DVD",
+	OwnedFormatBluray:  "Blu-ray",
+	OwnedFormat4KUHD:   "4K UHD",
+	OwnedFormatDigital: "Digital",
+	OwnedFormatVHS:     "VHS",
+}
+
+// OwnedFormatOption is an allowed owned format with its display name
+type OwnedFormatOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// ownedFormatAliases maps common spellings to their canonical format
+var ownedFormatAliases = map[string]string{
+	"4k":      OwnedFormat4KUHD,
+	"uhd":     OwnedFormat4KUHD,
+	"4k-uhd":  OwnedFormat4KUHD,
+	"blu-ray": OwnedFormatBluray,
+	"blu_ray": OwnedFormatBluray,
+}
+
+// OwnedFormats returns the allowed owned formats in display order
+func OwnedFormats() []string {
+	formats := make([]string, len(ownedFormats))
+	copy(formats, ownedFormats)
+	return formats
+}
+
+// OwnedFormatOptions returns the allowed owned formats with their display names, in display order
+func OwnedFormatOptions() []OwnedFormatOption {
+	options := make([]OwnedFormatOption, len(ownedFormats))
+	for i, format := range ownedFormats {
+		options[i] = OwnedFormatOption{Value: format, Label: ownedFormatLabels[format]}
+	}
+	return options
+}
+
+// NormalizeOwnedFormats lower-cases, resolves aliases and dedupes formats,
+// preserving the order they were given in. Unknown formats return an error.
+func NormalizeOwnedFormats(formats []string) ([]string, error) {
+	normalized := make([]string, 0, len(formats))
+	seen := make(map[string]bool)
+
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if alias, ok := ownedFormatAliases[format]; ok {
+			format = alias
+		}
+		if !containsValue(ownedFormats, format) {
+			return nil, fmt.Errorf("invalid format %q: must be one of %s", format, strings.Join(ownedFormats, ", "))
+		}
+		if seen[format] {
+			continue
+		}
+		seen[format] = true
+		normalized = append(normalized, format)
+	}
+
+	return normalized, nil
+}
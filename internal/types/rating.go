@@ -0,0 +1,51 @@
+package types
+
+import (
+	"fmt"
+	"math"
+)
+
+// User ratings are on a 1-10 scale in half steps. The rating columns keep their INTEGER
+// affinity: SQLite stores whole ratings (including every rating from before half steps were
+// allowed) as integers and half ratings as REAL, so existing ratings read back unchanged.
+const (
+	MinRating  = 1.0
+	MaxRating  = 10.0
+	RatingStep = 0.5
+)
+
+// RatingScale describes the rating scale alongside ratings in API responses
+type RatingScale struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Step float64 `json:"step"`
+}
+
+// UserRatingScale is the scale user ratings are given on
+var UserRatingScale = RatingScale{Min: MinRating, Max: MaxRating, Step: RatingStep}
+
+// ValidateRating returns an error when rating is out of range or not a whole step
+func ValidateRating(rating float64) error {
+	if math.IsNaN(rating) || rating < MinRating || rating > MaxRating {
+		return fmt.Errorf("rating must be between %g and %g", MinRating, MaxRating)
+	}
+	if math.Mod(rating, RatingStep) != 0 {
+		return fmt.Errorf("rating must be in steps of %g", RatingStep)
+	}
+	return nil
+}
+
+// RoundRating rounds a value to the nearest rating step within the scale
+func RoundRating(value float64) float64 {
+	rating := math.Round(value/RatingStep) * RatingStep
+	return math.Max(MinRating, math.Min(MaxRating, rating))
+}
+
+// RatingValues returns every rating on the scale, lowest first
+func RatingValues() []float64 {
+	var values []float64
+	for rating := MinRating; rating <= MaxRating; rating += RatingStep {
+		values = append(values, rating)
+	}
+	return values
+}
@@ -0,0 +1,80 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Watch statuses a user can set on a movie
+const (
+	MovieStatusWantToWatch = "want_to_watch"
+	MovieStatusWatching    = "watching"
+	MovieStatusWatched     = "watched"
+	MovieStatusDropped     = "dropped"
+
+	// MovieStatusNotWatched is the default for user_movies rows created by
+	// rating, notes or owned formats before any status is chosen. It is not
+	// accepted as a status update.
+	MovieStatusNotWatched = "not_watched"
+)
+
+var (
+	movieStatusesMu sync.RWMutex
+	movieStatuses   = []string{
+		MovieStatusWantToWatch,
+		MovieStatusWatching,
+		MovieStatusWatched,
+		MovieStatusDropped,
+	}
+)
+
+// RegisterMovieStatuses adds deployment-specific statuses (e.g. "rewatching")
+// to the allowed set. Values are normalized to lower case; duplicates are ignored.
+func RegisterMovieStatuses(statuses ...string) {
+	movieStatusesMu.Lock()
+	defer movieStatusesMu.Unlock()
+
+	for _, status := range statuses {
+		status = strings.ToLower(strings.TrimSpace(status))
+		if status == "" || status == MovieStatusNotWatched || containsValue(movieStatuses, status) {
+			continue
+		}
+		movieStatuses = append(movieStatuses, status)
+	}
+}
+
+// MovieStatuses returns the allowed watch statuses in display order
+func MovieStatuses() []string {
+	movieStatusesMu.RLock()
+	defer movieStatusesMu.RUnlock()
+
+	statuses := make([]string, len(movieStatuses))
+	copy(statuses, movieStatuses)
+	return statuses
+}
+
+// IsValidMovieStatus reports whether status is in the allowed set
+func IsValidMovieStatus(status string) bool {
+	movieStatusesMu.RLock()
+	defer movieStatusesMu.RUnlock()
+
+	return containsValue(movieStatuses, status)
+}
+
+// ValidateMovieStatus returns an error describing the allowed values when status is unknown
+func ValidateMovieStatus(status string) error {
+	if IsValidMovieStatus(status) {
+		return nil
+	}
+	return fmt.Errorf("invalid status %q: must be one of %s", status, strings.Join(MovieStatuses(), ", "))
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -29,10 +29,11 @@ type UserMovie struct {
 	UserID       int        `json:"user_id"`
 	MovieID      int        `json:"movie_id"`
 	Status       string     `json:"status"`
-	Rating       *int       `json:"rating"`
+	Rating       *float64   `json:"rating"` // 1-10 in half steps, see RatingStep
 	WatchedDate  *time.Time `json:"watched_date"`
 	Notes        *string    `json:"notes"`
 	OwnedFormats *string    `json:"owned_formats"` // JSON string
+	Favorite     bool       `json:"favorite"`
 	Created      time.Time  `json:"created_at"`
 	Updated      time.Time  `json:"updated_at"`
 }
@@ -47,19 +48,101 @@ type List struct {
 }
 
 type ListMovie struct {
-	ID      int       `json:"id"`
-	ListID  int       `json:"list_id"`
-	MovieID int       `json:"movie_id"`
-	Added   time.Time `json:"added_at"`
+	ID       int       `json:"id"`
+	ListID   int       `json:"list_id"`
+	MovieID  int       `json:"movie_id"`
+	Position int       `json:"position"`
+	Added    time.Time `json:"added_at"`
+}
+
+// List audit actions
+const (
+	ListAuditAdd    = "add"
+	ListAuditRemove = "remove"
+)
+
+// ListAuditEntry is a movie being added to or removed from a list
+type ListAuditEntry struct {
+	ID       int       `json:"id"`
+	Action   string    `json:"action"`
+	UserID   int       `json:"user_id"`
+	UserName string    `json:"user_name"`
+	TMDBID   int       `json:"tmdb_id"`
+	Title    string    `json:"title"`
+	Created  time.Time `json:"created_at"`
+}
+
+// MovieStats summarizes a user's watching and rating
+type MovieStats struct {
+	TotalWatched       int           `json:"total_watched"` // Distinct movies
+	TotalPlays         int           `json:"total_plays"`   // Rewatches count again
+	RatedCount         int           `json:"rated_count"`
+	RatingScale        RatingScale   `json:"rating_scale"`
+	AverageRating      *float64      `json:"average_rating"` // nil when nothing is rated
+	RatingDistribution []RatingCount `json:"rating_distribution"` // One entry per step of the scale
+	WatchedPerMonth    []MonthCount  `json:"watched_per_month"` // Plays per month, oldest month first
+	TopGenres          []GenreCount  `json:"top_genres"`
+	TotalRuntimeHours  float64       `json:"total_runtime_hours"`
+}
+
+type RatingCount struct {
+	Rating float64 `json:"rating"`
+	Count  int     `json:"count"`
+}
+
+type MonthCount struct {
+	Month string `json:"month"` // YYYY-MM
+	Count int    `json:"count"`
+}
+
+type GenreCount struct {
+	Genre string `json:"genre"`
+	Count int    `json:"count"`
 }
 
+// Friend statuses. A pending row is a request from UserID to FriendID.
+const (
+	FriendStatusPending  = "pending"
+	FriendStatusAccepted = "accepted"
+)
+
+// Friend is one direction of a friendship; accepted friendships have a row each way
 type Friend struct {
 	ID       int       `json:"id"`
 	UserID   int       `json:"user_id"`
 	FriendID int       `json:"friend_id"`
+	Status   string    `json:"status"`
 	Created  time.Time `json:"created_at"`
 }
 
+// FriendWatched is a friend who has watched a movie, as shown next to it when browsing
+type FriendWatched struct {
+	ID        int     `json:"id"`
+	AvatarURL *string `json:"avatar_url"`
+}
+
+// FriendsWatched is a capped sample of the friends who watched a movie plus the full count
+type FriendsWatched struct {
+	Friends []FriendWatched `json:"friends"`
+	Total   int             `json:"total"`
+}
+
+// FriendRequest is a pending request as seen by its recipient
+type FriendRequest struct {
+	ID            int       `json:"id"`
+	FromAuth0ID   string    `json:"from_auth0_id"`
+	FromName      string    `json:"from_name"`
+	FromUsername  *string   `json:"from_username"`
+	FromAvatarURL *string   `json:"from_avatar_url"`
+	Created       time.Time `json:"created_at"`
+}
+
+// Feed post types created from user activity
+const (
+	FeedPostWatched = "watched"
+	FeedPostRated   = "rated"
+)
+
 type FeedPost struct {
 	ID       int        `json:"id"`
 	UserID   int        `json:"user_id"`
@@ -67,11 +150,25 @@ type FeedPost struct {
 	MovieID  *int       `json:"movie_id"`
 	ListID   *int       `json:"list_id"`
 	Content  *string    `json:"content"`
-	Rating   *int       `json:"rating"`
+	Rating   *float64   `json:"rating"`
 	Metadata *string    `json:"metadata"` // JSON string
 	Created  time.Time  `json:"created_at"`
 }
 
+// FeedItem is a feed post with its author and movie, as shown in the feeds
+type FeedItem struct {
+	FeedPost
+	UserName      string  `json:"user_name"`
+	Username      *string `json:"username"`
+	UserAvatarURL *string `json:"user_avatar_url"`
+	TMDBID        *int    `json:"tmdb_id"`
+	MovieTitle    *string `json:"movie_title"`
+	PosterURL     *string `json:"poster_url"`
+	LikeCount     int     `json:"like_count"`
+	CommentCount  int     `json:"comment_count"`
+	LikedByMe     bool    `json:"liked_by_me"`
+}
+
 type PostLike struct {
 	ID      int       `json:"id"`
 	PostID  int       `json:"post_id"`
@@ -80,20 +177,38 @@ type PostLike struct {
 }
 
 type PostComment struct {
-	ID      int       `json:"id"`
-	PostID  int       `json:"post_id"`
-	UserID  int       `json:"user_id"`
-	Content string    `json:"content"`
-	Created time.Time `json:"created_at"`
+	ID      int        `json:"id"`
+	PostID  int        `json:"post_id"`
+	UserID  int        `json:"user_id"`
+	Content string     `json:"content"`
+	Created time.Time  `json:"created_at"`
+	Updated *time.Time `json:"updated_at"` // Set once the comment has been edited
 }
 
 // Request/Response types
+type UpdateUserRequest struct {
+	Name     *string `json:"name"`
+	Username *string `json:"username"`
+}
+
 type UpdateMovieStatusRequest struct {
 	Status string `json:"status"`
 }
 
+type BatchMovieStatusRequest struct {
+	TMDBIDs []int  `json:"tmdb_ids"`
+	Status  string `json:"status"`
+}
+
+// BatchMovieStatusResult is the outcome of a batch status update for one movie
+type BatchMovieStatusResult struct {
+	TMDBID  int    `json:"tmdb_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
 type RateMovieRequest struct {
-	Rating int `json:"rating"`
+	Rating float64 `json:"rating"` // e.g. 7 or 7.5
 }
 
 type UpdateNotesRequest struct {
@@ -107,7 +222,7 @@ type UpdateOwnedFormatsRequest struct {
 type CreateListRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	IsPublic    bool   `json:"is_public"`
+	IsPublic    *bool  `json:"is_public"` // nil uses the user's default_list_visibility preference
 }
 
 type UpdateListRequest struct {
@@ -116,18 +231,89 @@ type UpdateListRequest struct {
 	IsPublic    bool   `json:"is_public"`
 }
 
+type ReorderListRequest struct {
+	TMDBIDs []int `json:"tmdb_ids"`
+}
+
+// BatchListsRequest asks for several lists with their movies in one call
+type BatchListsRequest struct {
+	ListIDs []int `json:"list_ids"`
+}
+
 type AddCommentRequest struct {
 	Content string `json:"content"`
 }
 
+type UpdateCommentRequest struct {
+	Content string `json:"content"`
+}
+
+// Values for the default_list_visibility preference
+const (
+	ListVisibilityPrivate = "private"
+	ListVisibilityPublic  = "public"
+)
+
+// Values for the plex_auto_sync preference
+const (
+	PlexAutoSyncOff    = "off"
+	PlexAutoSyncDaily  = "daily"
+	PlexAutoSyncWeekly = "weekly"
+)
+
 type UserPreferences struct {
-	ID       int       `json:"id"`
-	UserID   int       `json:"user_id"`
-	DarkMode bool      `json:"dark_mode"`
-	Created  time.Time `json:"created_at"`
-	Updated  time.Time `json:"updated_at"`
+	ID                    int       `json:"id"`
+	UserID                int       `json:"user_id"`
+	DarkMode              bool      `json:"dark_mode"`
+	DefaultListVisibility string    `json:"default_list_visibility"`
+	PreferredRegion       *string   `json:"preferred_region"`    // nil uses the default watch provider region
+	PreferredProviders    []int     `json:"preferred_providers"` // TMDB provider ids the user subscribes to
+	ShareNowPlaying       bool      `json:"share_now_playing"`   // Friends can see what's playing on their Plex
+	PlexAutoSync          string    `json:"plex_auto_sync"`      // off, daily or weekly
+	ShareActivity         bool      `json:"share_activity"`      // Watching and rating movies creates feed posts
+	Created               time.Time `json:"created_at"`
+	Updated               time.Time `json:"updated_at"`
 }
 
+// UpdatePreferencesRequest updates preferences; nil fields are left unchanged
 type UpdatePreferencesRequest struct {
-	DarkMode bool `json:"darkMode"`
-}
\ No newline at end of file
+	DarkMode              *bool   `json:"darkMode"`
+	DefaultListVisibility *string `json:"defaultListVisibility"`
+	PreferredRegion       *string `json:"preferredRegion"` // "" clears it
+	PreferredProviders    *[]int  `json:"preferredProviders"`
+	ShareNowPlaying       *bool   `json:"shareNowPlaying"`
+	PlexAutoSync          *string `json:"plexAutoSync"`
+	ShareActivity         *bool   `json:"shareActivity"`
+}
+// RecommendationSeed is a highly rated movie that recommendations are based on
+type RecommendationSeed struct {
+	TMDBID int      `json:"tmdb_id"`
+	Title  string   `json:"title"`
+	Rating float64  `json:"rating"`
+	Genres []string `json:"genres"`
+}
+
+// WatchLogEntry is one time a user watched a movie
+type WatchLogEntry struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	MovieID   int       `json:"movie_id"`
+	WatchedAt time.Time `json:"watched_at"`
+	Rating    *float64  `json:"rating"` // Optional rating for this viewing
+	Created   time.Time `json:"created_at"`
+}
+
+// WatchLogItem is a watch log entry with its movie, as shown in the user's watch log
+type WatchLogItem struct {
+	WatchLogEntry
+	TMDBID    int     `json:"tmdb_id"`
+	Title     string  `json:"title"`
+	Year      *int    `json:"year"`
+	PosterURL *string `json:"poster_url"`
+}
+
+// WatchLogRequest adds or edits a watch log entry. WatchedAt defaults to now when adding.
+type WatchLogRequest struct {
+	WatchedAt *time.Time `json:"watched_at"`
+	Rating    *float64   `json:"rating"`
+}